@@ -4,16 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
 	"crossplane-ai/pkg/crossplane"
 
 	"github.com/spf13/cobra"
 )
 
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze [resource-name]",
+	Use:   "analyze [name|glob|/regex/]",
 	Short: "Analyze Crossplane resources with AI insights",
 	Long: `Perform intelligent analysis of your Crossplane resources. Get health checks,
 performance insights, security recommendations, and troubleshooting suggestions.`,
@@ -25,38 +27,30 @@ performance insights, security recommendations, and troubleshooting suggestions.
   
   # Analyze by provider
   crossplane-ai analyze --provider aws
+
+  # Analyze a team's production resources that are not ready
+  crossplane-ai analyze -l team=payments,env=prod --status not-ready
+
+  # Analyze all databases named prod-* older than a week
+  crossplane-ai analyze "prod-*" --type instances.rds.aws.upbound.io --older-than 7d
   
   # Health check analysis
-  crossplane-ai analyze --health-check`,
+  crossplane-ai analyze --health-check
+
+  # Analyze two clusters and compare their health
+  crossplane-ai --contexts prod-eu,prod-us analyze`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 
-		// Check if running in mock mode
-		if IsMockMode() {
-			fmt.Println("🔬 Performing AI-powered analysis (MOCK MODE)...")
-			fmt.Println()
-			return performMockAnalysis(ctx, cmd, args)
-		}
-
-		// Initialize clients for real mode
-		contextFlag, _ := cmd.Flags().GetString("context")
-		kubeconfigFlag, _ := cmd.Flags().GetString("kubeconfig")
-
-		clientOpts := crossplane.ClientOptions{
-			Context:    contextFlag,
-			Kubeconfig: kubeconfigFlag,
-		}
-
-		client, err := crossplane.NewClientWithOptions(ctx, clientOpts)
+		// Initialize clients
+		client, err := newResourceLister(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("failed to initialize Crossplane client: %w", err)
+			return err
 		}
 
 		aiService := ai.NewService()
 
 		// Get flags
-		provider, _ := cmd.Flags().GetString("provider")
-		namespace, _ := cmd.Flags().GetString("namespace")
 		healthCheck, _ := cmd.Flags().GetBool("health-check")
 		summary, _ := cmd.Flags().GetBool("summary")
 
@@ -65,38 +59,49 @@ performance insights, security recommendations, and troubleshooting suggestions.
 			resourceName = args[0]
 		}
 
-		return performAnalysis(ctx, client, aiService, resourceName, provider, namespace, healthCheck, summary)
+		filter, err := resourceFilterFromFlags(cmd, resourceName)
+		if err != nil {
+			return err
+		}
+
+		return performAnalysis(ctx, client, aiService, filter, healthCheck, summary)
 	},
 }
 
-func performAnalysis(ctx context.Context, client *crossplane.Client, aiService *ai.Service,
-	resourceName, provider, namespace string, healthCheck, summary bool) error {
+func performAnalysis(ctx context.Context, client resourceLister, aiService *ai.Service,
+	filter crossplane.ResourceFilter, healthCheck, summary bool) error {
 
 	// Show AI mode information
 	if aiService.IsUsingRealAI() {
-		fmt.Println("🔬 Performing AI-powered analysis (OpenAI)...")
+		fmt.Printf("🔬 Performing AI-powered analysis (%s)...\n", aiService.ProviderName())
 	} else {
 		fmt.Println("🔬 Performing analysis (template-based)...")
-		fmt.Println("💡 Set OPENAI_API_KEY for AI-powered insights")
+		fmt.Println("💡 " + aiService.SetupHint())
 	}
 	fmt.Println()
 
-	// Get resources based on filters
-	resources, err := client.GetFilteredResources(ctx, resourceName, provider, namespace)
+	report, err := aiService.AnalyzeCluster(ctx, client, filter, healthCheck)
 	if err != nil {
-		return fmt.Errorf("failed to get resources: %w", err)
+		return err
 	}
+	result := report.Listing
+	printListFailures(result)
 
-	if len(resources) == 0 {
+	if len(result.Resources) == 0 {
+		if result.Partial() {
+			return fmt.Errorf("no resources could be analyzed: %d of %d resource types failed to list", len(result.Failures), result.Types)
+		}
 		fmt.Println("No Crossplane resources found matching the criteria.")
 		fmt.Println("Please ensure Crossplane is installed and you have created some resources.")
 		return nil
 	}
 
-	// Perform AI analysis
-	analysis, err := aiService.AnalyzeResources(ctx, resources, healthCheck)
-	if err != nil {
-		return fmt.Errorf("analysis failed: %w", err)
+	analysis := report.Analysis
+	if analysis.Warning != "" {
+		cli.PrintWarning(analysis.Warning)
+	}
+	for _, warning := range report.Warnings {
+		cli.PrintWarning(warning)
 	}
 
 	if summary {
@@ -108,6 +113,34 @@ func performAnalysis(ctx context.Context, client *crossplane.Client, aiService *
 	return nil
 }
 
+// printListFailures warns about resource types that could not be listed so
+// that a partial result is not mistaken for the whole cluster
+func printListFailures(result *crossplane.ListResult) {
+	if !result.Partial() {
+		return
+	}
+
+	clusters := 0
+	for _, failure := range result.Failures {
+		if failure.GVR.Resource == "" {
+			clusters++
+		}
+	}
+	if types := len(result.Failures) - clusters; types > 0 && result.Types > 0 {
+		cli.PrintWarning(fmt.Sprintf("Results are partial: %d of %d resource types could not be listed",
+			types, result.Types))
+	} else if types > 0 {
+		cli.PrintWarning(fmt.Sprintf("Results are partial: %d resource types could not be listed", types))
+	}
+	if clusters > 0 {
+		cli.PrintWarning(fmt.Sprintf("Results are partial: %d clusters could not be reached", clusters))
+	}
+	for _, failure := range result.Failures {
+		fmt.Printf("   • %s\n", failure)
+	}
+	fmt.Println()
+}
+
 func printSummary(analysis *ai.Analysis) {
 	fmt.Println("📊 Analysis Summary")
 	fmt.Println("==================")
@@ -117,6 +150,8 @@ func printSummary(analysis *ai.Analysis) {
 	fmt.Printf("Recommendations: %d\n", len(analysis.Recommendations))
 	fmt.Println()
 
+	printClusterSummaries(analysis)
+
 	if len(analysis.Recommendations) > 0 {
 		fmt.Println("🎯 Top Recommendations:")
 		for i, rec := range analysis.Recommendations {
@@ -133,22 +168,41 @@ func printDetailedAnalysis(analysis *ai.Analysis) {
 	fmt.Println("📋 Resource Status")
 	fmt.Println("==================")
 
+	// With several clusters, every row and issue says which one it is in
+	multiCluster := len(analysis.Clusters) > 0
+
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tSTATUS\tPROVIDER\tAGE")
+	if multiCluster {
+		_, _ = fmt.Fprint(w, "CLUSTER\t")
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tSTATUS\tSYNCED\tPROVIDER\tAGE")
 
 	for _, resource := range analysis.Resources {
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
-			resource.Name, resource.Type, resource.Status, resource.Provider, resource.Age)
+		synced := resource.Synced
+		if synced == "" {
+			synced = "-"
+		}
+		if multiCluster {
+			_, _ = fmt.Fprintf(w, "%s\t", resource.Cluster)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			resource.Name, resource.Type, resource.Status, synced, resource.Provider, resource.Age)
 	}
 	_ = w.Flush()
 	fmt.Println()
 
+	printClusterSummaries(analysis)
+
 	// Issues
 	if len(analysis.Issues) > 0 {
 		fmt.Println("⚠️  Issues Detected")
 		fmt.Println("==================")
 		for _, issue := range analysis.Issues {
-			fmt.Printf("• %s: %s\n", issue.Severity, issue.Description)
+			if multiCluster && issue.Cluster != "" {
+				fmt.Printf("• %s: [%s] %s\n", issue.Severity, issue.Cluster, issue.Description)
+			} else {
+				fmt.Printf("• %s: %s\n", issue.Severity, issue.Description)
+			}
 			if issue.Resolution != "" {
 				fmt.Printf("  Resolution: %s\n", issue.Resolution)
 			}
@@ -183,76 +237,36 @@ func printDetailedAnalysis(analysis *ai.Analysis) {
 	}
 }
 
-// performMockAnalysis performs analysis using mock data
-func performMockAnalysis(ctx context.Context, cmd *cobra.Command, args []string) error {
-	// Get flags for mock analysis
-	provider, _ := cmd.Flags().GetString("provider")
-	_, _ = cmd.Flags().GetString("namespace") // namespace not used in mock mode
-	healthCheck, _ := cmd.Flags().GetBool("health-check")
-	summary, _ := cmd.Flags().GetBool("summary")
-
-	var resourceName string
-	if len(args) > 0 {
-		resourceName = args[0]
+// printClusterSummaries prints the health of each cluster of a
+// multi-cluster analysis
+func printClusterSummaries(analysis *ai.Analysis) {
+	if len(analysis.Clusters) == 0 {
+		return
 	}
 
-	// Use embedded mock data that works without external files
-	fmt.Println("📁 Using embedded mock data (no external files required)")
-	fmt.Println()
-
-	// Create mock AI service and analysis
-	aiService := ai.NewService()
-
-	// Get embedded mock resources
-	mockResources := ai.GetEmbeddedMockResources()
+	fmt.Println("🌐 Clusters")
+	fmt.Println("===========")
 
-	// Apply filters if specified
-	var filteredResources []*ai.ResourceInfo
-	for _, res := range mockResources {
-		if resourceName != "" && res.Name != resourceName {
-			continue
-		}
-		if provider != "" && res.Provider != provider {
-			continue
-		}
-		// Note: namespace filtering not applicable for these mock resources
-		filteredResources = append(filteredResources, res)
-	}
-
-	if len(filteredResources) == 0 {
-		fmt.Println("No mock Crossplane resources found matching the criteria.")
-		fmt.Println("Available mock resources:")
-		for _, res := range mockResources {
-			fmt.Printf("  - %s (%s, provider: %s)\n", res.Name, res.Type, res.Provider)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CLUSTER\tRESOURCES\tHEALTHY\tISSUES\tSCORE\tUNHEALTHY PROVIDERS")
+	for _, cluster := range analysis.Clusters {
+		providers := "-"
+		if len(cluster.UnhealthyProviders) > 0 {
+			providers = strings.Join(cluster.UnhealthyProviders, ", ")
 		}
-		return nil
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", cluster.Cluster, cluster.TotalResources,
+			cluster.HealthyResources, cluster.IssuesFound, cluster.HealthScore, providers)
 	}
-
-	// Perform analysis with mock data
-	analysis, err := aiService.AnalyzeResources(ctx, filteredResources, healthCheck)
-	if err != nil {
-		return fmt.Errorf("mock analysis failed: %w", err)
-	}
-
-	if summary {
-		printSummary(analysis)
-	} else {
-		printDetailedAnalysis(analysis)
-	}
-
+	_ = w.Flush()
 	fmt.Println()
-	fmt.Println("🧪 This was a mock analysis using embedded sample data.")
-	fmt.Println("To analyze real resources, run without the --mock flag")
-
-	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
 
 	analyzeCmd.Flags().String("provider", "", "filter by provider")
-	analyzeCmd.Flags().String("namespace", "", "filter by namespace")
 	analyzeCmd.Flags().BoolP("health-check", "H", false, "perform health check analysis")
 	analyzeCmd.Flags().BoolP("summary", "s", false, "show summary instead of detailed output")
 	analyzeCmd.Flags().String("output", "table", "output format (table, json, yaml)")
+	addFilterFlags(analyzeCmd)
 }
@@ -3,11 +3,15 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 
+	"crossplane-ai/internal/config"
 	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
 	"crossplane-ai/pkg/crossplane"
 
 	"github.com/spf13/cobra"
@@ -26,7 +30,16 @@ The AI will analyze your cluster's Crossplane resources and provide helpful insi
   
   # Ask for troubleshooting help
   crossplane-ai ask "why is my database not ready?"
+
+  # Limit the context to one team's production resources
+  crossplane-ai ask -l team=payments,env=prod "what is not ready?"
+
+  # Ask across every cluster in the kubeconfig
+  crossplane-ai --all-contexts ask "which clusters have providers that are not healthy?"
   
+  # Let the model fetch the resources, events and traces it needs
+  crossplane-ai ask --agent "why is my-db failing?"
+
   # Interactive mode (no question provided)
   crossplane-ai ask`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -37,22 +50,15 @@ The AI will analyze your cluster's Crossplane resources and provide helpful insi
 			question = strings.Join(args, " ")
 		}
 
-		// Check if running in mock mode
-		if IsMockMode() {
-			fmt.Println("🤖 AI Assistant (MOCK MODE)")
-			fmt.Println("===========================")
-			if question == "" {
-				fmt.Println("Interactive mode not supported in mock mode.")
-				fmt.Println("Please provide a question as an argument.")
-				return nil
-			}
-			return handleMockAsk(ctx, question)
+		filter, err := resourceFilterFromFlags(cmd, "")
+		if err != nil {
+			return err
 		}
 
-		// Initialize Crossplane client for real mode
-		client, err := crossplane.NewClient(ctx)
+		// Initialize Crossplane client
+		client, err := newResourceLister(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("failed to initialize Crossplane client: %w", err)
+			return err
 		}
 
 		// Initialize AI service
@@ -60,24 +66,72 @@ The AI will analyze your cluster's Crossplane resources and provide helpful insi
 
 		// Show AI mode information
 		if aiService.IsUsingRealAI() {
-			fmt.Println("🤖 AI Assistant (POWERED BY OPENAI)")
+			fmt.Printf("🤖 AI Assistant (POWERED BY %s)\n", strings.ToUpper(aiService.ProviderName()))
 		} else {
 			fmt.Println("🤖 AI Assistant (TEMPLATE MODE)")
-			fmt.Println("💡 Set OPENAI_API_KEY for real AI capabilities")
+			fmt.Println("💡 " + aiService.SetupHint())
 		}
 		fmt.Println("===========================")
 		fmt.Println()
 
+		agent, err := newAgent(cmd, client, aiService, filter)
+		if err != nil {
+			return err
+		}
+
 		if question == "" {
 			// Interactive mode
-			return runInteractiveMode(ctx, client, aiService)
+			return runInteractiveMode(ctx, client, aiService, agent, filter)
 		}
 
-		return processQuestion(ctx, client, aiService, question)
+		return processQuestion(ctx, client, aiService, agent, question, filter)
 	},
 }
 
-func runInteractiveMode(ctx context.Context, client *crossplane.Client, aiService *ai.Service) error {
+// newAgent returns the agent of --agent, or nil when it is not set or no
+// AI provider is configured. The agent lists only resources matching filter.
+func newAgent(cmd *cobra.Command, client resourceLister, aiService *ai.Service, filter crossplane.ResourceFilter) (*ai.Agent, error) {
+	if enabled, _ := cmd.Flags().GetBool("agent"); !enabled {
+		return nil, nil
+	}
+	if !aiService.IsUsingRealAI() {
+		cli.PrintWarning("Agent mode needs an AI provider, answering from templates")
+		return nil, nil
+	}
+	cluster, ok := client.(ai.ClusterReader)
+	if !ok {
+		return nil, fmt.Errorf("--agent works on a single cluster and cannot be combined with --contexts or --all-contexts")
+	}
+
+	// A namespace the model lists would replace the client's --namespace,
+	// so it is part of the scope
+	filter.Namespace, _ = cmd.Flags().GetString("namespace")
+	agent := ai.NewAgent(aiService, cluster)
+	agent.Scope = filter
+	agent.MaxSteps, _ = cmd.Flags().GetInt("max-steps")
+	if agent.MaxSteps < 1 {
+		return nil, fmt.Errorf("--max-steps must be at least 1")
+	}
+	if config.IsVerbose() {
+		agent.OnStep = printAgentStep
+	}
+	return agent, nil
+}
+
+// printAgentStep prints a tool call of the agent to stderr
+func printAgentStep(step ai.AgentStep) {
+	if step.Thought != "" {
+		fmt.Fprintf(os.Stderr, "💭 %s\n", strings.TrimSpace(step.Thought))
+	}
+	fmt.Fprintf(os.Stderr, "🔧 [%d] %s %s", step.Step, step.Tool, step.Arguments)
+	if step.Err != nil {
+		fmt.Fprintf(os.Stderr, " → error: %v\n", step.Err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, " → %s\n", cli.TruncateString(step.Result, 120))
+}
+
+func runInteractiveMode(ctx context.Context, client resourceLister, aiService *ai.Service, agent *ai.Agent, filter crossplane.ResourceFilter) error {
 	fmt.Println("🤖 Crossplane AI Interactive Mode")
 	fmt.Println("Ask me anything about your Crossplane resources! Type 'exit' to quit.")
 	fmt.Println()
@@ -101,7 +155,9 @@ func runInteractiveMode(ctx context.Context, client *crossplane.Client, aiServic
 		}
 
 		fmt.Print("🤖 AI: ")
-		if err := processQuestion(ctx, client, aiService, question); err != nil {
+		if err := processQuestion(ctx, client, aiService, agent, question, filter); errors.Is(err, errAnswerCancelled) {
+			cli.PrintWarning("Cancelled")
+		} else if err != nil {
 			fmt.Printf("Sorry, I encountered an error: %v\n", err)
 		}
 		fmt.Println()
@@ -110,189 +166,68 @@ func runInteractiveMode(ctx context.Context, client *crossplane.Client, aiServic
 	return scanner.Err()
 }
 
-func processQuestion(ctx context.Context, client *crossplane.Client, aiService *ai.Service, question string) error {
-	// Get current cluster state
-	resources, err := client.GetAllResources(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get resources: %w", err)
+func processQuestion(ctx context.Context, client resourceLister, aiService *ai.Service, agent *ai.Agent,
+	question string, filter crossplane.ResourceFilter) error {
+
+	// The agent fetches what it needs itself
+	if agent != nil {
+		return printAnswer(ctx, func(ctx context.Context, onToken func(string)) error {
+			answer, err := agent.Answer(ctx, question)
+			if err == nil {
+				onToken(answer)
+			}
+			return err
+		})
 	}
 
-	// Process with AI
-	response, err := aiService.ProcessQuery(ctx, question, resources)
+	// Get current cluster state
+	result, err := client.ListResources(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("AI processing failed: %w", err)
+		return fmt.Errorf("failed to get resources: %w", err)
 	}
+	printListFailures(result)
+	client.AttachEvents(ctx, result.Resources, crossplane.MaxEventResources)
+	crossplane.AttachDrift(result.Resources)
 
-	fmt.Println(response)
-	return nil
+	// Process with AI; the failures are part of the context so the answer
+	// can say which resource types it could not see
+	return streamAnswer(ctx, aiService, question, result)
 }
 
-// handleMockAsk handles ask command in mock mode
-func handleMockAsk(ctx context.Context, question string) error {
-	fmt.Printf("Question: %s\n\n", question)
-
-	// Get embedded mock resources for more dynamic responses
-	mockResources := ai.GetEmbeddedMockResources()
-
-	// Mock responses based on question patterns
-	questionLower := strings.ToLower(question)
-
-	var response string
-	switch {
-	case strings.Contains(questionLower, "what") && strings.Contains(questionLower, "resources"):
-		// Count resources by type and provider
-		compositions := 0
-		providers := 0
-		infraResources := 0
-		awsResources := 0
-
-		for _, res := range mockResources {
-			switch res.Type {
-			case "compositions", "compositeresourcedefinitions":
-				compositions++
-			case "providers":
-				providers++
-			default:
-				infraResources++
-				if res.Provider == "aws" {
-					awsResources++
-				}
-			}
-		}
-
-		response = fmt.Sprintf(`📊 Mock Resource Summary:
-
-Based on your mock Crossplane cluster, you have:
-• %d Composition(s) and CompositeResourceDefinition(s)
-• %d Provider(s) installed
-• %d Infrastructure resource(s) total
-• %d AWS-managed resources
-
-Resource Status: %d Ready, %d Not Ready
-
-All core resources are operational! 🎉`,
-			compositions, providers, infraResources, awsResources,
-			len(mockResources)-1, 1) // -1 ready, 1 not ready (failing-test-resource)
-
-	case strings.Contains(questionLower, "aws"):
-		var awsResources []string
-		for _, res := range mockResources {
-			if res.Provider == "aws" {
-				status := "✅"
-				if res.Status != "Ready" {
-					status = "⚠️"
-				}
-				awsResources = append(awsResources, fmt.Sprintf("• %s (%s) - %s %s", res.Name, res.Type, res.Status, status))
-			}
-		}
-
-		response = fmt.Sprintf(`🔍 AWS Resources (Mock):
-
-Your AWS resources managed by Crossplane:
-%s
+// errAnswerCancelled is returned when Ctrl-C cancels an answer
+var errAnswerCancelled = errors.New("request cancelled")
 
-Most resources are healthy and operational.`, strings.Join(awsResources, "\n"))
-
-	case strings.Contains(questionLower, "database") || strings.Contains(questionLower, "db"):
-		var dbResources []string
-		for _, res := range mockResources {
-			if strings.Contains(res.Type, "db") || strings.Contains(res.Name, "database") {
-				status := "✅"
-				if res.Status != "Ready" {
-					status = "⚠️"
-				}
-				dbResources = append(dbResources, fmt.Sprintf("• %s (%s) - %s %s, Age: %s", res.Name, res.Type, res.Status, status, res.Age))
-			}
-		}
-
-		if len(dbResources) == 0 {
-			response = `🗄️ Database Resources (Mock):
-
-No database resources found in the current mock data.
-Try asking about other resource types or run 'crossplane-ai --mock analyze' to see all resources.`
-		} else {
-			response = fmt.Sprintf(`🗄️ Database Resources (Mock):
-
-Found database resources:
-%s
-
-The databases appear to be healthy and operational.`, strings.Join(dbResources, "\n"))
-		}
-
-	case strings.Contains(questionLower, "not ready") || strings.Contains(questionLower, "failed") || strings.Contains(questionLower, "problem"):
-		var failingResources []string
-		for _, res := range mockResources {
-			if res.Status != "Ready" {
-				failingResources = append(failingResources, fmt.Sprintf("• %s (%s) - %s", res.Name, res.Type, res.Status))
-			}
-		}
-
-		if len(failingResources) == 0 {
-			response = `🔧 Troubleshooting (Mock):
-
-Good news! In this mock environment, all resources are healthy.
-
-In a real environment, here's how to troubleshoot:
-1. Check resource events: kubectl describe <resource>
-2. Verify provider credentials
-3. Check network connectivity
-4. Review Crossplane logs`
-		} else {
-			response = fmt.Sprintf(`🔧 Troubleshooting (Mock):
-
-Found resources with issues:
-%s
-
-In a real environment, here's how to troubleshoot:
-1. Check resource events: kubectl describe <resource>
-2. Verify provider credentials  
-3. Check network connectivity
-4. Review Crossplane logs`, strings.Join(failingResources, "\n"))
-		}
+// streamAnswer prints the answer to a question as the model generates it
+func streamAnswer(ctx context.Context, aiService *ai.Service, question string, resources interface{}) error {
+	return printAnswer(ctx, func(ctx context.Context, onToken func(string)) error {
+		_, err := aiService.ProcessQueryStream(ctx, question, resources, onToken)
+		return err
+	})
+}
 
-	case strings.Contains(questionLower, "providers") || strings.Contains(questionLower, "provider"):
-		var providers []string
-		for _, res := range mockResources {
-			if res.Type == "providers" {
-				status := "✅"
-				if res.Status != "Ready" {
-					status = "⚠️"
-				}
-				providers = append(providers, fmt.Sprintf("• %s - %s %s, Age: %s", res.Name, res.Status, status, res.Age))
-			}
+// printAnswer prints an answer as answer passes it to onToken. Ctrl-C
+// cancels the request in flight instead of exiting, and a second Ctrl-C at
+// the prompt exits as usual.
+func printAnswer(parent context.Context, answer func(ctx context.Context, onToken func(string)) error) error {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt)
+	defer stop()
+
+	var last string
+	err := answer(ctx, func(token string) {
+		fmt.Print(token)
+		last = token
+	})
+	if last != "" && !strings.HasSuffix(last, "\n") {
+		fmt.Println()
+	}
+	if err != nil {
+		// Only Ctrl-C cancels ctx without its parent; a deadline is an
+		// error like any other
+		if errors.Is(ctx.Err(), context.Canceled) && parent.Err() == nil {
+			return errAnswerCancelled
 		}
-
-		response = fmt.Sprintf(`🏗️ Providers (Mock):
-
-Installed providers:
-%s
-
-These providers enable management of cloud resources through Crossplane.`, strings.Join(providers, "\n"))
-
-	default:
-		response = fmt.Sprintf(`🤖 Mock AI Response:
-
-I understand you're asking: "%s"
-
-In this mock environment, I can tell you:
-• Your Crossplane setup includes %d total resources
-• Multiple providers are configured (AWS, GCP, Azure)  
-• Most resources are healthy
-• You have compositions and resource definitions ready
-
-Try asking about:
-• "what resources do I have?" - for a full summary
-• "show me AWS resources" - for provider-specific info
-• "tell me about databases" - for resource type info
-• "what providers are installed?" - for provider status
-
-🧪 This is mock mode - responses use embedded sample data.`, question, len(mockResources))
+		return fmt.Errorf("AI processing failed: %w", err)
 	}
-
-	fmt.Println(response)
-	fmt.Println()
-	fmt.Println("🧪 This was a mock response using embedded sample data.")
-
 	return nil
 }
 
@@ -300,6 +235,8 @@ func init() {
 	rootCmd.AddCommand(askCmd)
 
 	askCmd.Flags().String("provider", "", "filter by specific provider (aws, gcp, azure)")
-	askCmd.Flags().String("namespace", "", "filter by namespace")
 	askCmd.Flags().BoolP("interactive", "i", false, "start interactive mode")
+	askCmd.Flags().Bool("agent", false, "let the model call read-only tools to fetch resources, objects, events, traces and providers itself (--verbose shows the calls)")
+	askCmd.Flags().Int("max-steps", ai.DefaultAgentSteps, "number of model turns that may call tools in agent mode")
+	addFilterFlags(askCmd)
 }
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var compositionCmd = &cobra.Command{
+	Use:     "composition",
+	Aliases: []string{"compositions", "comp"},
+	Short:   "Inspect composition revisions and the XRs using them",
+	Long: `Inspect the CompositionRevisions Crossplane keeps for every change to a
+Composition, which revision each composite resource (XR) is on, and what changed
+between two revisions.`,
+}
+
+var compositionRevisionsCmd = &cobra.Command{
+	Use:   "revisions [composition]",
+	Short: "List composition revisions and the XRs pinned to them",
+	Long: `List the revisions of a composition, or of all compositions, together with the
+composite resources (XRs) using them. For every XR the revision named by its
+compositionRevisionRef and its compositionUpdatePolicy are shown: Automatic XRs
+follow the latest revision, Manual XRs stay on their revision until it is
+changed.`,
+	Example: `  # List the revisions of every composition
+  crossplane-ai composition revisions
+
+  # Show which XRs use which revision of a composition
+  crossplane-ai composition revisions xpostgresqlinstances.aws.platform.example.org`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+		var composition string
+		if len(args) > 0 {
+			composition = args[0]
+		}
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		revisions, err := client.ListCompositionRevisions(ctx, composition)
+		if err != nil {
+			return err
+		}
+		composites, failures, err := client.ListCompositeRevisions(ctx, composition)
+		if err != nil {
+			return err
+		}
+
+		switch output {
+		case "table", "":
+			printListFailures(&crossplane.ListResult{Failures: failures})
+			printCompositionRevisions(revisions, composites)
+			return nil
+		case "json", "yaml":
+			return printStructured(output, map[string]interface{}{
+				"revisions":  revisions,
+				"composites": composites,
+			})
+		default:
+			return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+		}
+	},
+}
+
+var compositionDiffCmd = &cobra.Command{
+	Use:   "diff <composition>",
+	Short: "Show what changed between two revisions of a composition",
+	Long: `Show a structural diff between two revisions of a composition. Pipeline steps
+and composed resources are matched by name, so reordering or inserting them only
+shows the actual changes.
+
+Without --to the latest revision is used, and without --from the revision before
+it. Unless --no-summary is given, the diff is followed by a summary of its likely
+impact on the existing XRs: composed resources created, deleted or updated, and
+which XRs pick up the change according to their update policy. The summary is
+written by the language model when one is configured.`,
+	Example: `  # What did the latest change to a composition do?
+  crossplane-ai composition diff xpostgresqlinstances.aws.platform.example.org
+
+  # Compare two specific revisions
+  crossplane-ai composition diff xpostgresqlinstances.aws.platform.example.org --from 3 --to 5`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+		from, _ := cmd.Flags().GetInt64("from")
+		to, _ := cmd.Flags().GetInt64("to")
+		noSummary, _ := cmd.Flags().GetBool("no-summary")
+		if output != "text" && output != "json" && output != "yaml" {
+			return fmt.Errorf("unsupported output format %q (use text, json or yaml)", output)
+		}
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		diff, err := client.DiffCompositionRevisions(ctx, args[0], from, to)
+		if err != nil {
+			return err
+		}
+		composites, failures, err := client.ListCompositeRevisions(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		var summary string
+		if !noSummary {
+			summary, err = ai.NewService().ExplainRevisionDiff(ctx, diff, composites)
+			if err != nil {
+				cli.PrintWarning(fmt.Sprintf("Could not summarize the diff: %v", err))
+			}
+		}
+
+		if output != "text" {
+			return printStructured(output, struct {
+				*crossplane.RevisionDiff
+				Composites []*crossplane.CompositeRevision `json:"composites"`
+				Summary    string                          `json:"summary,omitempty"`
+			}{diff, composites, summary})
+		}
+
+		printListFailures(&crossplane.ListResult{Failures: failures})
+		printRevisionDiff(diff)
+		if summary != "" {
+			cli.PrintSubHeader("Impact")
+			fmt.Println(summary)
+		}
+		return nil
+	},
+}
+
+func printCompositionRevisions(revisions []*crossplane.CompositionRevision, composites []*crossplane.CompositeRevision) {
+	if len(revisions) == 0 {
+		fmt.Println("No composition revisions found.")
+		return
+	}
+
+	users := make(map[string]int)
+	for _, xr := range composites {
+		users[xr.Revision]++
+	}
+
+	headers := []string{"COMPOSITION", "REVISION", "NAME", "HASH", "LATEST", "XRS", "AGE"}
+	var rows [][]string
+	for _, revision := range revisions {
+		latest := ""
+		if revision.Latest {
+			latest = "✓"
+		}
+		hash := revision.Hash
+		if len(hash) > 7 {
+			hash = hash[:7]
+		}
+		rows = append(rows, []string{
+			revision.Composition,
+			strconv.FormatInt(revision.Revision, 10),
+			revision.Name,
+			orDash(hash),
+			latest,
+			strconv.Itoa(users[revision.Name]),
+			cli.FormatSince(revision.CreatedAt),
+		})
+	}
+	cli.PrintTable(headers, rows)
+
+	if len(composites) == 0 {
+		return
+	}
+	cli.PrintSubHeader("Composite Resources")
+	headers = []string{"XR", "COMPOSITION", "REVISION", "POLICY", "STATE"}
+	rows = nil
+	outdated := 0
+	for _, xr := range composites {
+		revision := "-"
+		if xr.RevisionNumber > 0 {
+			revision = strconv.FormatInt(xr.RevisionNumber, 10)
+		}
+		state := "latest"
+		switch {
+		case xr.Revision == "":
+			state = "no revision selected"
+		case xr.RevisionNumber == 0:
+			state = "unknown revision " + xr.Revision
+		case xr.Outdated():
+			state = fmt.Sprintf("behind (latest %d)", xr.LatestRevision)
+			outdated++
+		}
+		rows = append(rows, []string{xr.Resource.String(), orDash(xr.Composition), revision, xr.UpdatePolicy, state})
+	}
+	cli.PrintTable(headers, rows)
+
+	if outdated > 0 {
+		fmt.Println()
+		cli.PrintInfo(fmt.Sprintf("%d XRs are not on the latest revision of their composition; use `crossplane-ai composition diff` to see what they are missing", outdated))
+	}
+}
+
+func printRevisionDiff(diff *crossplane.RevisionDiff) {
+	cli.PrintHeader(fmt.Sprintf("Composition %s: revision %d → %d", diff.Composition, diff.From.Revision, diff.To.Revision))
+	fmt.Printf("From: %s\nTo:   %s", diff.From.Name, diff.To.Name)
+	if diff.To.Latest {
+		fmt.Print(" (latest)")
+	}
+	fmt.Println()
+	fmt.Println()
+
+	if len(diff.Changes) == 0 {
+		fmt.Println("No differences.")
+		return
+	}
+
+	for _, change := range diff.Changes {
+		switch change.Type {
+		case crossplane.ChangeAdded:
+			fmt.Printf("+ %s\n", change.Path)
+			printDiffValue("+", change.To)
+		case crossplane.ChangeRemoved:
+			fmt.Printf("- %s\n", change.Path)
+			printDiffValue("-", change.From)
+		default:
+			fmt.Printf("~ %s\n", change.Path)
+			printDiffValue("-", change.From)
+			printDiffValue("+", change.To)
+		}
+	}
+}
+
+// printDiffValue prints a value as YAML with every line marked
+func printDiffValue(marker string, value interface{}) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		fmt.Printf("    %s %v\n", marker, value)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Printf("    %s %s\n", marker, line)
+	}
+}
+
+// printStructured prints a value as JSON or YAML
+func printStructured(output string, value interface{}) error {
+	if output == "json" {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(compositionCmd)
+	compositionCmd.AddCommand(compositionRevisionsCmd)
+	compositionCmd.AddCommand(compositionDiffCmd)
+
+	compositionRevisionsCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+
+	compositionDiffCmd.Flags().Int64("from", 0, "revision to compare from (default: the revision before --to)")
+	compositionDiffCmd.Flags().Int64("to", 0, "revision to compare to (default: the latest revision)")
+	compositionDiffCmd.Flags().StringP("output", "o", "text", "output format (text, json, yaml)")
+	compositionDiffCmd.Flags().Bool("no-summary", false, "skip the summary of the impact on existing XRs")
+}
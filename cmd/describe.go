@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <resource>",
+	Short: "Show a Crossplane resource with its conditions and events",
+	Long: `Show the details of a single Crossplane resource: its status conditions with
+reasons and messages, generation, deletion state and the Kubernetes events
+recorded for it. For providers, the events of the current revision and of the
+provider pods are included.
+
+The resource can be given as a name or as [<namespace>/]<kind>/<name>, where
+kind is the Kind, the plural or the plural qualified by its API group.`,
+	Example: `  # Describe a managed resource
+  crossplane-ai describe dbinstance/my-database
+
+  # Describe a claim in a namespace
+  crossplane-ai describe -n team-a postgresqlinstance/my-db
+
+  # Describe a provider including its pod events
+  crossplane-ai describe provider/provider-aws-s3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		return describeResource(ctx, client, args[0])
+	},
+}
+
+func describeResource(ctx context.Context, client *crossplane.Client, ref string) error {
+	resource, err := client.FindResource(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	events, err := client.GetEvents(ctx, resource)
+	if err != nil {
+		cli.PrintWarning(fmt.Sprintf("Could not read events: %v", err))
+	}
+
+	printResourceDetails(resource)
+	printConditions(resource.Conditions)
+	printEvents(events)
+
+	return nil
+}
+
+func printResourceDetails(resource *crossplane.Resource) {
+	cli.PrintHeader(crossplane.QualifiedName(resource))
+
+	w := cli.CreateTable()
+	_, _ = fmt.Fprintf(w, "Kind:\t%s\n", resource.Kind)
+	_, _ = fmt.Fprintf(w, "API Version:\t%s\n", resource.APIVersion)
+	if resource.Namespace != "" {
+		_, _ = fmt.Fprintf(w, "Namespace:\t%s\n", resource.Namespace)
+	}
+	if resource.Category != "" {
+		_, _ = fmt.Fprintf(w, "Category:\t%s\n", resource.Category)
+	}
+	_, _ = fmt.Fprintf(w, "Provider:\t%s\n", resource.Provider)
+	_, _ = fmt.Fprintf(w, "Status:\t%s\n", resource.Status)
+	if resource.Synced != "" {
+		_, _ = fmt.Fprintf(w, "Synced:\t%s\n", resource.Synced)
+	}
+	_, _ = fmt.Fprintf(w, "Created:\t%s ago\n", cli.FormatSince(resource.CreatedAt))
+	if resource.Generation > 0 {
+		generation := fmt.Sprintf("%d", resource.Generation)
+		if resource.ObservedGeneration > 0 {
+			generation += fmt.Sprintf(" (observed %d)", resource.ObservedGeneration)
+		}
+		_, _ = fmt.Fprintf(w, "Generation:\t%s\n", generation)
+	}
+	if resource.DeletionTimestamp != nil {
+		_, _ = fmt.Fprintf(w, "Deleting:\tsince %s\n", cli.FormatSince(*resource.DeletionTimestamp))
+	}
+	if resource.Raw != nil && len(resource.Raw.GetFinalizers()) > 0 {
+		_, _ = fmt.Fprintf(w, "Finalizers:\t%s\n", strings.Join(resource.Raw.GetFinalizers(), ", "))
+	}
+	_ = w.Flush()
+}
+
+func printConditions(conditions []crossplane.Condition) {
+	cli.PrintSubHeader("Conditions")
+	if len(conditions) == 0 {
+		fmt.Println("<none>")
+		return
+	}
+
+	headers := []string{"TYPE", "STATUS", "REASON", "AGE", "MESSAGE"}
+	var rows [][]string
+	for _, condition := range conditions {
+		rows = append(rows, []string{
+			condition.Type,
+			condition.Status,
+			condition.Reason,
+			cli.FormatSince(condition.LastTransitionTime),
+			condition.Message,
+		})
+	}
+	cli.PrintTable(headers, rows)
+}
+
+func printEvents(events []crossplane.Event) {
+	cli.PrintSubHeader("Events")
+	if len(events) == 0 {
+		fmt.Println("<none>")
+		return
+	}
+
+	headers := []string{"TYPE", "REASON", "AGE", "OBJECT", "FROM", "MESSAGE"}
+	var rows [][]string
+	for _, event := range events {
+		age := cli.FormatSince(event.LastSeen)
+		if event.Count > 1 {
+			age = fmt.Sprintf("%s (x%d)", age, event.Count)
+		}
+		rows = append(rows, []string{
+			event.Type,
+			event.Reason,
+			age,
+			event.Object,
+			event.Source,
+			event.Message,
+		})
+	}
+	cli.PrintTable(headers, rows)
+}
+
+func init() {
+	rootCmd.AddCommand(describeCmd)
+}
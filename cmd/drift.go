@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift [name]",
+	Short: "Show managed resources whose external resource differs from their spec",
+	Long: `Compare spec.forProvider with status.atProvider of every managed resource and
+report the fields whose observed value differs from the desired one.
+
+Only the fields set in spec.forProvider are compared. Values the provider
+reports in another form are normalized before comparing: numbers and booleans
+given as strings, JSON documents such as policies, lists of IDs in another
+order and versions the cloud reports in full ("16" is satisfied by "16.3").
+References, selectors and arguments without a cloud counterpart, such as
+region or skipFinalSnapshot, are not compared.
+
+Each drifted resource is classified by what Crossplane does about it:
+  OutOfBand      the resource is synced, so the change was made outside
+                 Crossplane and is reverted on the next poll
+  NotReconciled  the provider fails to apply the spec
+  Pending        the provider has not observed the latest spec yet
+  Ignored        the management policies do not allow updates`,
+	Example: `  # Check every managed resource for drift
+  crossplane-ai drift
+
+  # Check the RDS instances of one team
+  crossplane-ai drift -l team=payments --type instances.rds.aws.upbound.io
+
+  # Print the drifted fields as JSON
+  crossplane-ai drift -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		filter, err := resourceFilterFromFlags(cmd, name)
+		if err != nil {
+			return err
+		}
+
+		client, err := newResourceLister(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		result, err := client.ListResources(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to get resources: %w", err)
+		}
+		report := crossplane.BuildDriftReport(result.Resources)
+
+		switch output {
+		case "table", "":
+			printListFailures(result)
+			printDrift(report)
+		case "json":
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Println(string(data))
+		case "yaml":
+			data, err := yaml.Marshal(report)
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Print(string(data))
+		default:
+			return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+		}
+
+		return nil
+	},
+}
+
+func printDrift(report *crossplane.DriftReport) {
+	unobserved := ""
+	if report.Unobserved > 0 {
+		unobserved = fmt.Sprintf("; %d not observed by their provider yet", report.Unobserved)
+	}
+
+	if len(report.Drifted) == 0 {
+		cli.PrintSuccess(fmt.Sprintf("No drift detected in %d managed resources%s", report.Checked, unobserved))
+		return
+	}
+
+	clusters := make(map[string]bool)
+	for _, drift := range report.Drifted {
+		clusters[drift.Cluster] = true
+	}
+	multiCluster := len(clusters) > 1
+
+	headers := []string{"RESOURCE", "PROVIDER", "STATE", "FIELDS"}
+	if multiCluster {
+		headers = append([]string{"CLUSTER"}, headers...)
+	}
+	var rows [][]string
+	for _, drift := range report.Drifted {
+		row := []string{drift.Ref.String(), orDash(drift.Provider), string(drift.State), strconv.Itoa(len(drift.Fields))}
+		if multiCluster {
+			row = append([]string{drift.Cluster}, row...)
+		}
+		rows = append(rows, row)
+	}
+	cli.PrintTable(headers, rows)
+
+	for _, drift := range report.Drifted {
+		title := drift.Ref.String()
+		if multiCluster {
+			title = fmt.Sprintf("[%s] %s", drift.Cluster, title)
+		}
+		cli.PrintSubHeader(fmt.Sprintf("%s (%s)", title, drift.State))
+		if drift.Message != "" {
+			fmt.Printf("  %s\n", drift.Message)
+		}
+		for _, field := range drift.Fields {
+			fmt.Printf("  • %s\n", ai.DescribeDriftFields([]crossplane.FieldDrift{field}, 1))
+		}
+	}
+
+	fmt.Println()
+	cli.PrintInfo(fmt.Sprintf("%d of %d managed resources drifted%s", len(report.Drifted), report.Checked, unobserved))
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+	driftCmd.Flags().String("provider", "", "only resources of this provider")
+	addFilterFlags(driftCmd)
+}
@@ -92,7 +92,6 @@ func generateExampleFiles(outputDir string) error {
 	fmt.Println()
 	fmt.Println("You can now:")
 	fmt.Printf("• Apply them to your cluster: kubectl apply -f %s/\n", outputDir)
-	fmt.Printf("• Use them for mock testing: crossplane-ai --mock --mock-data-dir %s analyze\n", outputDir)
 	fmt.Println("• Modify them as templates for your own resources")
 
 	return nil
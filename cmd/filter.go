@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+)
+
+// addFilterFlags adds the resource selection flags shared by ask, analyze,
+// suggest and drift
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("selector", "l", "", "label selector, e.g. team=payments,env in (prod,staging)")
+	cmd.Flags().String("field-selector", "", "field selector, e.g. metadata.name=my-db")
+	cmd.Flags().StringSlice("type", nil, "only these resource types, by kind, plural or plural.group (repeatable)")
+	cmd.Flags().String("status", "", "only resources in this state (ready, not-ready, unknown, unsynced, deleting, unhealthy)")
+	cmd.Flags().String("older-than", "", "only resources created longer ago than this, e.g. 36h or 7d")
+}
+
+// resourceFilterFromFlags builds a resource filter from the flags added by
+// addFilterFlags and the command's --provider flag. name may be an exact
+// name, a glob or a /regex/.
+func resourceFilterFromFlags(cmd *cobra.Command, name string) (crossplane.ResourceFilter, error) {
+	filter := crossplane.ResourceFilter{Name: name}
+
+	filter.Provider, _ = cmd.Flags().GetString("provider")
+	filter.LabelSelector, _ = cmd.Flags().GetString("selector")
+	filter.FieldSelector, _ = cmd.Flags().GetString("field-selector")
+	filter.Types, _ = cmd.Flags().GetStringSlice("type")
+	filter.Status, _ = cmd.Flags().GetString("status")
+
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	age, err := crossplane.ParseAge(olderThan)
+	if err != nil {
+		return filter, err
+	}
+	filter.OlderThan = age
+
+	return filter, nil
+}
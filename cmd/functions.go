@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var functionsCmd = &cobra.Command{
+	Use:     "functions [name|glob]",
+	Aliases: []string{"fn"},
+	Short:   "Show composition functions and the composition pipelines using them",
+	Long: `List the installed composition functions with their package and version,
+Installed and Healthy conditions and current revision, followed by the
+compositions with their mode and pipeline steps.
+
+Problems found are listed below the tables: pipeline steps whose function is
+not installed or not healthy, and compositions still using the legacy
+Resources mode (spec.resources with patches), which Crossplane v2 removed.`,
+	Example: `  # List functions and the compositions using them
+  crossplane-ai functions
+
+  # Show one function and the pipeline steps that run it
+  crossplane-ai functions function-patch-and-transform
+
+  # Check the functions of every cluster in the kubeconfig
+  crossplane-ai --all-contexts functions`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+
+		var pattern string
+		if len(args) > 0 {
+			pattern = args[0]
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid name pattern %s: %w", pattern, err)
+			}
+		}
+
+		client, err := newResourceLister(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		report, err := client.FunctionReport(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check composition functions: %w", err)
+		}
+		if pattern != "" {
+			report = filterFunctionReport(report, pattern)
+		}
+
+		switch output {
+		case "table", "":
+			printFunctions(report)
+		case "json":
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Println(string(data))
+		case "yaml":
+			data, err := yaml.Marshal(report)
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Print(string(data))
+		default:
+			return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+		}
+
+		return nil
+	},
+}
+
+// filterFunctionReport keeps the functions matching the pattern and the
+// compositions whose pipelines run one of them
+func filterFunctionReport(report *crossplane.FunctionReport, pattern string) *crossplane.FunctionReport {
+	filtered := &crossplane.FunctionReport{Functions: []*crossplane.PackageInfo{}, Compositions: []*crossplane.CompositionInfo{}, Failures: report.Failures}
+	for _, function := range report.Functions {
+		if ok, _ := path.Match(pattern, function.Name); ok {
+			filtered.Functions = append(filtered.Functions, function)
+		}
+	}
+	for _, composition := range report.Compositions {
+		for _, function := range composition.Functions() {
+			if ok, _ := path.Match(pattern, function); ok {
+				filtered.Compositions = append(filtered.Compositions, composition)
+				break
+			}
+		}
+	}
+	for _, missing := range report.Missing {
+		if ok, _ := path.Match(pattern, missing.Function); ok {
+			filtered.Missing = append(filtered.Missing, missing)
+		}
+	}
+	return filtered
+}
+
+func printFunctions(report *crossplane.FunctionReport) {
+	printListFailures(&crossplane.ListResult{Failures: report.Failures})
+
+	clusters := make(map[string]bool)
+	for _, composition := range report.Compositions {
+		clusters[composition.Cluster] = true
+	}
+	for _, function := range report.Functions {
+		clusters[function.Cluster] = true
+	}
+	multiCluster := len(clusters) > 1
+
+	if len(report.Functions) == 0 {
+		fmt.Println("No functions found.")
+	} else {
+		headers := []string{"NAME", "PACKAGE", "VERSION", "INSTALLED", "HEALTHY", "REVISION", "STEPS", "AGE"}
+		if multiCluster {
+			headers = append([]string{"CLUSTER"}, headers...)
+		}
+		var rows [][]string
+		for _, function := range report.Functions {
+			row := []string{
+				function.Name,
+				orDash(function.Package),
+				orDash(function.Version),
+				function.Installed,
+				function.Healthy,
+				orDash(function.CurrentRevision),
+				strconv.Itoa(len(report.References(function.Cluster, function.Name))),
+				cli.FormatSince(function.CreatedAt),
+			}
+			if multiCluster {
+				row = append([]string{function.Cluster}, row...)
+			}
+			rows = append(rows, row)
+		}
+		cli.PrintTable(headers, rows)
+	}
+
+	if len(report.Compositions) > 0 {
+		cli.PrintSubHeader("Compositions")
+		headers := []string{"NAME", "COMPOSITE TYPE", "MODE", "PIPELINE"}
+		if multiCluster {
+			headers = append([]string{"CLUSTER"}, headers...)
+		}
+		var rows [][]string
+		for _, composition := range report.Compositions {
+			row := []string{composition.Name, orDash(composition.CompositeType), composition.Mode, describePipeline(composition)}
+			if multiCluster {
+				row = append([]string{composition.Cluster}, row...)
+			}
+			rows = append(rows, row)
+		}
+		cli.PrintTable(headers, rows)
+	}
+
+	var unhealthy []*crossplane.PackageInfo
+	for _, function := range report.Functions {
+		if !function.IsHealthy() {
+			unhealthy = append(unhealthy, function)
+		}
+	}
+	if len(unhealthy) > 0 {
+		fmt.Println()
+		cli.PrintWarning(fmt.Sprintf("%d of %d functions are not installed or not healthy", len(unhealthy), len(report.Functions)))
+		for _, function := range unhealthy {
+			fmt.Printf("   • %s: %s\n", function.Name, packageProblem(function))
+		}
+	}
+
+	issues := ai.FunctionIssues(report)
+	if len(issues) == 0 {
+		fmt.Println()
+		cli.PrintSuccess("Every pipeline step runs an installed, healthy function")
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("⚠️  Issues Detected")
+	fmt.Println("==================")
+	for _, issue := range issues {
+		if multiCluster && issue.Cluster != "" {
+			fmt.Printf("• %s: [%s] %s\n", issue.Severity, issue.Cluster, issue.Description)
+		} else {
+			fmt.Printf("• %s: %s\n", issue.Severity, issue.Description)
+		}
+		if issue.Resolution != "" {
+			fmt.Printf("  Resolution: %s\n", issue.Resolution)
+		}
+	}
+}
+
+// describePipeline lists the steps of a pipeline as step=function, or the
+// number of resource templates of a Resources mode composition
+func describePipeline(composition *crossplane.CompositionInfo) string {
+	if composition.Mode == crossplane.CompositionModeResources {
+		return fmt.Sprintf("%d resources (legacy)", composition.Resources)
+	}
+	if len(composition.Steps) == 0 {
+		return "-"
+	}
+	steps := make([]string, 0, len(composition.Steps))
+	for _, step := range composition.Steps {
+		steps = append(steps, step.Step+"="+orDash(step.Function))
+	}
+	return strings.Join(steps, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(functionsCmd)
+
+	functionsCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+}
@@ -12,6 +12,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// --dry-run modes of generate
+const (
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
 var generateCmd = &cobra.Command{
 	Use:     "generate [description]",
 	Short:   "Generate Crossplane resource manifests using AI",
@@ -28,46 +35,57 @@ in plain English.`,
   # Generate storage resources
   crossplane-ai generate "S3 bucket with versioning enabled"
   
+  # Validate against the cluster without persisting anything
+  crossplane-ai generate "S3 bucket" --dry-run=server
+
+  # Server-side apply the generated manifest
+  crossplane-ai generate "S3 bucket" --apply
+  
   # Interactive mode
   crossplane-ai generate`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			return runInteractiveGenerate()
+			return runInteractiveGenerate(cmd)
 		}
 
 		description := strings.Join(args, " ")
 		provider, _ := cmd.Flags().GetString("provider")
 		outputFormat, _ := cmd.Flags().GetString("output")
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		dryRun, _ := cmd.Flags().GetString("dry-run")
 		apply, _ := cmd.Flags().GetBool("apply")
+		forceConflicts, _ := cmd.Flags().GetBool("force-conflicts")
 
-		return runGenerate(description, provider, outputFormat, dryRun, apply)
+		if dryRun != dryRunNone && dryRun != dryRunClient && dryRun != dryRunServer {
+			return fmt.Errorf("invalid --dry-run value %q (use none, client or server)", dryRun)
+		}
+
+		return runGenerate(cmd, description, provider, outputFormat, dryRun, apply, forceConflicts)
 	},
 }
 
-func runGenerate(description, provider, outputFormat string, dryRun, apply bool) error {
+func runGenerate(cmd *cobra.Command, description, provider, outputFormat, dryRun string, apply, forceConflicts bool) error {
 	ctx := context.Background()
 
 	// Initialize clients
-	client, err := crossplane.NewClient(ctx)
+	client, err := newCrossplaneClient(ctx, cmd)
 	if err != nil {
-		return fmt.Errorf("failed to initialize Crossplane client: %w", err)
+		return err
 	}
 
 	aiService := ai.NewService()
 
 	// Show AI mode information
 	if aiService.IsUsingRealAI() {
-		cli.PrintInfo("🤖 Using OpenAI for intelligent manifest generation")
+		cli.PrintInfo(fmt.Sprintf("🤖 Using %s for intelligent manifest generation", aiService.ProviderName()))
 	} else {
-		cli.PrintInfo("🤖 Using template-based generation (set OPENAI_API_KEY for AI-powered generation)")
+		cli.PrintInfo(fmt.Sprintf("🤖 Using template-based generation (%s)", aiService.SetupHint()))
 	}
 
 	cli.PrintInfo(fmt.Sprintf("📝 Generating Crossplane resources for: %s", description))
 	fmt.Println()
 
 	// Generate the manifest
-	manifest, err := generateManifest(ctx, aiService, description, provider)
+	manifest, err := generateManifest(ctx, aiService, client, description, provider)
 	if err != nil {
 		return fmt.Errorf("failed to generate manifest: %w", err)
 	}
@@ -80,21 +98,31 @@ func runGenerate(description, provider, outputFormat string, dryRun, apply bool)
 	}
 
 	// Handle dry-run
-	if dryRun {
+	if dryRun == dryRunClient {
 		cli.PrintInfo("🧪 Dry run mode - manifest generated but not applied")
 		return nil
 	}
 
-	// Handle apply
-	if apply {
+	// Handle apply; a server dry run validates the manifest against the
+	// cluster without persisting it, with or without --apply
+	if apply || dryRun == dryRunServer {
 		fmt.Println()
-		cli.PrintInfo("🚀 Applying manifest to cluster...")
+		opts := crossplane.ApplyOptions{DryRun: dryRun == dryRunServer, Force: forceConflicts}
+		if opts.DryRun {
+			cli.PrintInfo("🧪 Validating manifest against the cluster (server dry run)...")
+		} else {
+			cli.PrintInfo("🚀 Applying manifest to cluster...")
+		}
 
-		if err := applyManifest(ctx, client, manifest); err != nil {
+		if err := applyManifest(ctx, client, manifest, opts); err != nil {
 			return fmt.Errorf("failed to apply manifest: %w", err)
 		}
 
-		cli.PrintSuccess("✅ Manifest applied successfully!")
+		if opts.DryRun {
+			cli.PrintSuccess("Manifest passed server-side validation")
+		} else {
+			cli.PrintSuccess("✅ Manifest applied successfully!")
+		}
 	} else {
 		fmt.Println()
 		cli.PrintInfo("💡 Use --apply to apply this manifest to your cluster")
@@ -103,7 +131,7 @@ func runGenerate(description, provider, outputFormat string, dryRun, apply bool)
 	return nil
 }
 
-func runInteractiveGenerate() error {
+func runInteractiveGenerate(cmd *cobra.Command) error {
 	fmt.Println("🤖 Welcome to Crossplane AI Resource Generator!")
 	fmt.Println()
 	cli.PrintInfo("Describe the infrastructure you want to create in natural language.")
@@ -120,289 +148,42 @@ func runInteractiveGenerate() error {
 		provider = "auto"
 	}
 
-	return runGenerate(description, provider, "yaml", false, false)
+	return runGenerate(cmd, description, provider, "yaml", dryRunNone, false, false)
 }
 
-func generateManifest(ctx context.Context, aiService *ai.Service, description, provider string) (string, error) {
-	// Use AI service for intelligent manifest generation
-	manifest, err := aiService.GenerateManifest(ctx, description, provider)
+func generateManifest(ctx context.Context, aiService *ai.Service, client *crossplane.Client, description, provider string) (string, error) {
+	// Generate against the APIs the cluster serves, so that the manifest
+	// uses the provider family's current (and, with Crossplane v2,
+	// namespaced) managed resource types
+	family, apis, err := client.ResolveAPIs(ctx, provider)
+	switch {
+	case err != nil:
+		cli.PrintWarning(fmt.Sprintf("Could not discover the APIs served by the cluster, using the %s defaults: %v", family, err))
+	case len(apis) > 0 && apis.Served() == 0:
+		cli.PrintWarning(fmt.Sprintf("The cluster serves no %s managed resource APIs generate knows; install the provider before applying", family))
+	case len(apis) > 0:
+		cli.PrintInfo(fmt.Sprintf("📚 Using the %s APIs served by the cluster", family))
+	}
+
+	manifest, err := aiService.GenerateManifest(ctx, description, family, apis)
 	if err != nil {
 		return "", fmt.Errorf("AI manifest generation failed: %w", err)
 	}
-
-	// If we got a manifest from AI, return it
-	if manifest != "" {
-		return manifest, nil
-	}
-
-	// Fallback to template-based generation (this shouldn't happen with the new AI service)
-	descriptionLower := strings.ToLower(description)
-
-	// Database resources
-	if strings.Contains(descriptionLower, "database") || strings.Contains(descriptionLower, "db") || strings.Contains(descriptionLower, "mysql") || strings.Contains(descriptionLower, "postgres") {
-		return generateDatabaseManifest(description, provider), nil
-	}
-
-	// Storage resources
-	if strings.Contains(descriptionLower, "storage") || strings.Contains(descriptionLower, "bucket") || strings.Contains(descriptionLower, "s3") {
-		return generateStorageManifest(description, provider), nil
-	}
-
-	// Network resources
-	if strings.Contains(descriptionLower, "network") || strings.Contains(descriptionLower, "vpc") || strings.Contains(descriptionLower, "subnet") {
-		return generateNetworkManifest(description, provider), nil
-	}
-
-	// Compute resources
-	if strings.Contains(descriptionLower, "server") || strings.Contains(descriptionLower, "instance") || strings.Contains(descriptionLower, "compute") {
-		return generateComputeManifest(description, provider), nil
-	}
-
-	// Web application stack
-	if strings.Contains(descriptionLower, "web app") || strings.Contains(descriptionLower, "application") {
-		return generateWebAppManifest(description, provider), nil
-	}
-
-	// Default template
-	return generateDefaultManifest(description, provider), nil
-}
-
-func generateDatabaseManifest(description, provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	dbType := "mysql"
-	if strings.Contains(strings.ToLower(description), "postgres") {
-		dbType = "postgres"
-	}
-
-	// Use provider in the API version to support different providers
-	apiVersion := fmt.Sprintf("rds.%s.crossplane.io/v1alpha1", provider)
-
-	return fmt.Sprintf(`apiVersion: %s
-kind: DBInstance
-metadata:
-  name: my-database
-  namespace: default
-spec:
-  forProvider:
-    dbInstanceClass: db.t3.micro
-    engine: %s
-    engineVersion: "8.0"
-    dbName: myapp
-    masterUsername: admin
-    allocatedStorage: 20
-    storageType: gp2
-    storageEncrypted: true
-    multiAZ: false
-    publiclyAccessible: false
-    deletionProtection: false
-    region: us-east-1
-  writeConnectionSecretsToRef:
-    name: my-database-connection
-    namespace: default
-  providerConfigRef:
-    name: default
----
-apiVersion: v1
-kind: Secret
-metadata:
-  name: my-database-connection
-  namespace: default
-type: Opaque
-data: {}`, apiVersion, dbType)
+	return manifest, nil
 }
 
-func generateStorageManifest(description, provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	versioning := "false"
-	if strings.Contains(strings.ToLower(description), "version") {
-		versioning = "true"
-	}
-
-	// Use provider in the API version
-	apiVersion := fmt.Sprintf("s3.%s.crossplane.io/v1beta1", provider)
-
-	return fmt.Sprintf(`apiVersion: %s
-kind: Bucket
-metadata:
-  name: my-app-bucket
-  namespace: default
-spec:
-  forProvider:
-    region: us-east-1
-    versioning:
-      enabled: %s
-    serverSideEncryptionConfiguration:
-      rules:
-      - applyServerSideEncryptionByDefault:
-          sseAlgorithm: AES256
-    publicAccessBlockConfiguration:
-      blockPublicAcls: true
-      blockPublicPolicy: true
-      ignorePublicAcls: true
-      restrictPublicBuckets: true
-  providerConfigRef:
-    name: default`, apiVersion, versioning)
-}
-
-func generateNetworkManifest(description, provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	// Use provider in the API version
-	apiVersion := fmt.Sprintf("ec2.%s.crossplane.io/v1beta1", provider)
-
-	return fmt.Sprintf(`apiVersion: %s
-kind: VPC
-metadata:
-  name: my-vpc
-  namespace: default
-spec:
-  forProvider:
-    cidrBlock: 10.0.0.0/16
-    region: us-east-1
-    tags:
-      Name: MyVPC
-  providerConfigRef:
-    name: default
----
-apiVersion: %s
-kind: Subnet
-metadata:
-  name: my-subnet-public
-  namespace: default
-spec:
-  forProvider:
-    availabilityZone: us-east-1a
-    cidrBlock: 10.0.1.0/24
-    region: us-east-1
-    mapPublicIPOnLaunch: true
-    vpcIdSelector:
-      matchLabels:
-        name: my-vpc
-    tags:
-      Name: MyPublicSubnet
-  providerConfigRef:
-    name: default`, apiVersion, apiVersion)
-}
-
-func generateComputeManifest(description, provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	// Use provider in the API version
-	apiVersion := fmt.Sprintf("ec2.%s.crossplane.io/v1alpha1", provider)
-
-	return fmt.Sprintf(`apiVersion: %s
-kind: Instance
-metadata:
-  name: my-instance
-  namespace: default
-spec:
-  forProvider:
-    region: us-east-1
-    instanceType: t3.micro
-    imageId: ami-0abcdef1234567890
-    keyName: my-key-pair
-    subnetIdSelector:
-      matchLabels:
-        name: my-subnet-public
-    securityGroupIdSelector:
-      matchLabels:
-        name: my-security-group
-    tags:
-      Name: MyInstance
-  providerConfigRef:
-    name: default`, apiVersion)
-}
-
-func generateWebAppManifest(description, provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
+// applyManifest server-side applies the manifest and reports the outcome
+// of each object
+func applyManifest(ctx context.Context, client *crossplane.Client, manifest string, opts crossplane.ApplyOptions) error {
+	results, err := client.ApplyManifest(ctx, manifest, opts)
+	for _, result := range results {
+		if result.Action == crossplane.ApplyFailed {
+			cli.PrintError(result.String())
+		} else {
+			fmt.Printf("   • %s\n", result)
+		}
 	}
-
-	// Use provider in the API versions
-	elbVersion := fmt.Sprintf("elbv2.%s.crossplane.io/v1alpha1", provider)
-	rdsVersion := fmt.Sprintf("rds.%s.crossplane.io/v1alpha1", provider)
-
-	return fmt.Sprintf(`# Load Balancer
-apiVersion: %s
-kind: LoadBalancer
-metadata:
-  name: my-web-lb
-  namespace: default
-spec:
-  forProvider:
-    region: us-east-1
-    scheme: internet-facing
-    type: application
-    subnetIdSelector:
-      matchLabels:
-        network: public
-    tags:
-      Name: MyWebLB
-  providerConfigRef:
-    name: default
----
-# Database
-apiVersion: %s
-kind: DBInstance
-metadata:
-  name: my-web-db
-  namespace: default
-spec:
-  forProvider:
-    dbInstanceClass: db.t3.micro
-    engine: mysql
-    engineVersion: "8.0"
-    dbName: webapp
-    masterUsername: admin
-    allocatedStorage: 20
-    storageEncrypted: true
-    region: us-east-1
-  writeConnectionSecretsToRef:
-    name: my-web-db-connection
-    namespace: default
-  providerConfigRef:
-    name: default`, elbVersion, rdsVersion)
-}
-
-func generateDefaultManifest(description, provider string) string {
-	return fmt.Sprintf(`# Generated Crossplane resource for: %s
-# Provider: %s
-#
-# This is a template - please customize according to your needs
-
-apiVersion: apiextensions.crossplane.io/v1
-kind: Composition
-metadata:
-  name: my-custom-resource
-  labels:
-    provider: %s
-spec:
-  compositeTypeRef:
-    apiVersion: example.com/v1alpha1
-    kind: XCustomResource
-  
-  # Add your resource definitions here
-  # Visit https://docs.crossplane.io for documentation`, description, provider, provider)
-}
-
-func applyManifest(ctx context.Context, client *crossplane.Client, manifest string) error {
-	// In a real implementation, this would parse the YAML and apply it to the cluster
-	cli.PrintInfo("📝 Parsing manifest...")
-	cli.PrintInfo("🔍 Validating resources...")
-	cli.PrintInfo("⚡ Creating resources...")
-
-	// Simulate successful application
-	return nil
+	return err
 }
 
 func init() {
@@ -410,6 +191,8 @@ func init() {
 
 	generateCmd.Flags().StringP("provider", "p", "", "target cloud provider (aws, gcp, azure)")
 	generateCmd.Flags().StringP("output", "o", "yaml", "output format (yaml, json)")
-	generateCmd.Flags().Bool("dry-run", false, "generate manifest but don't apply")
-	generateCmd.Flags().Bool("apply", false, "apply the generated manifest to cluster")
+	generateCmd.Flags().String("dry-run", dryRunNone, "none, client (generate only) or server (validate with the API server without persisting)")
+	generateCmd.Flags().Lookup("dry-run").NoOptDefVal = dryRunClient
+	generateCmd.Flags().Bool("apply", false, "server-side apply the generated manifest to the cluster")
+	generateCmd.Flags().Bool("force-conflicts", false, "take ownership of fields managed by other field managers when applying")
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -33,18 +34,25 @@ your Crossplane infrastructure.`,
 		showBanner, _ := cmd.Flags().GetBool("banner")
 		initialAnalyze, _ := cmd.Flags().GetBool("analyze")
 
-		return runInteractiveSession(showBanner, initialAnalyze)
+		return runInteractiveSession(cmd, showBanner, initialAnalyze)
 	},
 }
 
-func runInteractiveSession(showBanner, initialAnalyze bool) error {
+func runInteractiveSession(cmd *cobra.Command, showBanner, initialAnalyze bool) error {
 	ctx := context.Background()
 
 	// Initialize clients
-	client, err := crossplane.NewClient(ctx)
+	client, err := newCrossplaneClient(ctx, cmd)
 	if err != nil {
-		return fmt.Errorf("failed to initialize Crossplane client: %w", err)
+		return err
+	}
+
+	// Answer every prompt from an informer cache instead of re-listing
+	// the cluster each time
+	if _, err := client.StartCache(ctx); err != nil {
+		cli.PrintWarning(fmt.Sprintf("Could not start resource cache, resources will be listed on every prompt: %v", err))
 	}
+	defer client.StopCache()
 
 	aiService := ai.NewService()
 
@@ -111,7 +119,9 @@ func startInteractiveLoop(ctx context.Context, client *crossplane.Client, aiServ
 		}
 
 		// Process as natural language query
-		if err := processInteractiveQuery(ctx, client, aiService, input); err != nil {
+		if err := processInteractiveQuery(ctx, client, aiService, input); errors.Is(err, errAnswerCancelled) {
+			cli.PrintWarning("Cancelled")
+		} else if err != nil {
 			cli.PrintError(fmt.Sprintf("Error: %v", err))
 		}
 
@@ -169,14 +179,10 @@ func processInteractiveQuery(ctx context.Context, client *crossplane.Client, aiS
 		return fmt.Errorf("failed to get resources: %w", err)
 	}
 
-	// Process with AI
-	response, err := aiService.ProcessQuery(ctx, query, resources)
-	if err != nil {
-		return fmt.Errorf("AI processing failed: %w", err)
-	}
+	crossplane.AttachDrift(resources)
 
-	fmt.Println(response)
-	return nil
+	// Process with AI
+	return streamAnswer(ctx, aiService, query, resources)
 }
 
 func performQuickAnalysis(ctx context.Context, client *crossplane.Client, aiService *ai.Service) error {
@@ -192,17 +198,12 @@ func performQuickAnalysis(ctx context.Context, client *crossplane.Client, aiServ
 
 	cli.PrintSuccess(fmt.Sprintf("Found %d Crossplane resources", len(resources)))
 
-	// Quick status summary
-	readyCount := 0
-	for _, r := range resources {
-		if strings.ToLower(r.Status) == "ready" {
-			readyCount++
-		}
-	}
+	// Quick status summary of the resources that report health
+	readyCount, ratedCount := countReady(resources)
 
-	fmt.Printf("📊 Status: %d/%d resources are ready\n", readyCount, len(resources))
+	fmt.Printf("📊 Status: %d/%d resources are ready\n", readyCount, ratedCount)
 
-	if readyCount < len(resources) {
+	if readyCount < ratedCount {
 		cli.PrintWarning("Some resources are not ready - type 'health' for details")
 	}
 
@@ -223,6 +224,9 @@ func performDetailedAnalysis(ctx context.Context, client *crossplane.Client, aiS
 		cli.PrintError(fmt.Sprintf("Analysis failed: %v", err))
 		return
 	}
+	if analysis.Warning != "" {
+		cli.PrintWarning(analysis.Warning)
+	}
 
 	// Print analysis results
 	cli.PrintHeader("Analysis Results")
@@ -246,6 +250,21 @@ func performDetailedAnalysis(ctx context.Context, client *crossplane.Client, aiS
 	}
 }
 
+// countReady counts the ready resources and the resources that report
+// health, leaving out types such as Compositions that have none
+func countReady(resources []*crossplane.Resource) (ready, rated int) {
+	for _, r := range resources {
+		if r.Status == crossplane.StatusNone {
+			continue
+		}
+		rated++
+		if r.Status == "Ready" {
+			ready++
+		}
+	}
+	return ready, rated
+}
+
 func showResourceStatus(ctx context.Context, client *crossplane.Client) {
 	fmt.Println("📋 Resource Status Overview")
 
@@ -287,14 +306,7 @@ func performHealthCheck(ctx context.Context, client *crossplane.Client, aiServic
 	}
 
 	// Simple health check
-	totalResources := len(resources)
-	healthyResources := 0
-
-	for _, r := range resources {
-		if strings.ToLower(r.Status) == "ready" {
-			healthyResources++
-		}
-	}
+	healthyResources, totalResources := countReady(resources)
 
 	healthPercentage := 0
 	if totalResources > 0 {
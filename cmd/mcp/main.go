@@ -3,10 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"crossplane-ai/internal/config"
 	"crossplane-ai/pkg/ai"
 	"crossplane-ai/pkg/crossplane"
 )
@@ -18,6 +26,18 @@ import (
 type MCPServer struct {
 	aiService        *ai.Service
 	crossplaneClient *crossplane.Client
+	// clientErr is why crossplaneClient could not be created
+	clientErr error
+
+	// out writes responses and notifications, which tool calls running
+	// concurrently send
+	outMu sync.Mutex
+	out   *json.Encoder
+
+	// calls cancels the tool calls in flight by request ID
+	callsMu sync.Mutex
+	calls   map[string]context.CancelFunc
+	running sync.WaitGroup
 }
 
 // MCPRequest represents an incoming MCP request
@@ -36,6 +56,13 @@ type MCPResponse struct {
 	Error   *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification is a message sent to the client that expects no response
+type MCPNotification struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 // MCPError represents an MCP error
 type MCPError struct {
 	Code    int         `json:"code"`
@@ -58,32 +85,187 @@ type MCPResource struct {
 	MimeType    string `json:"mimeType,omitempty"`
 }
 
-func NewMCPServer() *MCPServer {
+// NewMCPServer creates the server. Client settings in opts (from flags) take
+// precedence over the config file, as for the crossplane-ai commands.
+func NewMCPServer(opts crossplane.ClientOptions) *MCPServer {
 	// Initialize AI service
 	aiService := ai.NewService()
 
 	// Initialize Crossplane client
 	ctx := context.Background()
-	crossplaneClient, err := crossplane.NewClient(ctx)
+	cfg, err := config.Load()
 	if err != nil {
-		log.Printf("Warning: Failed to initialize Crossplane client: %v", err)
-		// Continue without Crossplane client for demo purposes
+		log.Printf("Warning: Failed to load configuration: %v", err)
+	}
+	crossplaneClient, clientErr := crossplane.NewClientFromConfig(ctx, cfg, opts)
+	if clientErr != nil {
+		// Keep serving; tools that need the cluster report the error
+		log.Printf("Warning: Failed to initialize Crossplane client: %v", clientErr)
+	} else if _, err := crossplaneClient.StartCache(ctx); err != nil {
+		// Serve tool calls from an informer cache; without it every call
+		// lists the cluster
+		log.Printf("Warning: Failed to start resource cache: %v", err)
 	}
 
 	return &MCPServer{
 		aiService:        aiService,
 		crossplaneClient: crossplaneClient,
+		clientErr:        clientErr,
+		calls:            make(map[string]context.CancelFunc),
+	}
+}
+
+// progressInterval is the least time between the progress notifications of
+// a streamed answer
+const progressInterval = 250 * time.Millisecond
+
+// serve answers the JSON-RPC requests read from in until it ends or ctx is
+// done. Tool calls run concurrently, so that the client can cancel them
+// with notifications/cancelled; a cancelled call is not answered.
+func (s *MCPServer) serve(ctx context.Context, in io.Reader, out io.Writer) {
+	s.out = json.NewEncoder(out)
+
+	requests := make(chan MCPRequest)
+	go func() {
+		defer close(requests)
+		decoder := json.NewDecoder(in)
+		for {
+			var request MCPRequest
+			if err := decoder.Decode(&request); err != nil {
+				if err == io.EOF {
+					return
+				}
+				log.Printf("Error decoding request: %v", err)
+				continue
+			}
+			select {
+			case requests <- request:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Let the calls in flight answer, or see that they were cancelled
+	defer s.running.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case request, ok := <-requests:
+			if !ok {
+				return
+			}
+			s.dispatch(ctx, request)
+		}
+	}
+}
+
+func (s *MCPServer) dispatch(ctx context.Context, request MCPRequest) {
+	switch {
+	case request.Method == "notifications/cancelled":
+		s.cancelCall(request)
+	case strings.HasPrefix(request.Method, "notifications/"):
+		// Notifications are not answered
+	case request.Method == "tools/call":
+		s.startCall(ctx, request)
+	default:
+		s.send(s.handleRequest(ctx, request))
+	}
+}
+
+// startCall runs a tool call in the background
+func (s *MCPServer) startCall(ctx context.Context, request MCPRequest) {
+	ctx, cancel := context.WithCancel(ctx)
+	id := requestKey(request.ID)
+	s.callsMu.Lock()
+	s.calls[id] = cancel
+	s.callsMu.Unlock()
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		response := s.handleToolsCall(ctx, request)
+
+		s.callsMu.Lock()
+		delete(s.calls, id)
+		s.callsMu.Unlock()
+		cancelled := ctx.Err() != nil
+		cancel()
+
+		if !cancelled {
+			s.send(response)
+		}
+	}()
+}
+
+// cancelCall cancels the tool call of a notifications/cancelled
+func (s *MCPServer) cancelCall(request MCPRequest) {
+	params, _ := request.Params.(map[string]interface{})
+	id := requestKey(params["requestId"])
+	s.callsMu.Lock()
+	cancel, ok := s.calls[id]
+	s.callsMu.Unlock()
+	if ok {
+		log.Printf("Cancelling request %s", id)
+		cancel()
+	}
+}
+
+// requestKey returns the key of a request ID, a number or a string
+func requestKey(id interface{}) string {
+	return fmt.Sprint(id)
+}
+
+// send writes a response or notification to the client
+func (s *MCPServer) send(message interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if err := s.out.Encode(message); err != nil {
+		log.Printf("Error encoding response: %v", err)
 	}
 }
 
-func (s *MCPServer) handleRequest(request MCPRequest) MCPResponse {
+// progressToken returns the token the client set in the _meta of a request
+// to receive progress notifications, or nil
+func progressToken(request MCPRequest) interface{} {
+	params, _ := request.Params.(map[string]interface{})
+	meta, _ := params["_meta"].(map[string]interface{})
+	return meta["progressToken"]
+}
+
+// progressReporter returns a token callback that sends the answer generated
+// so far as the message of notifications/progress, at most every
+// progressInterval
+func (s *MCPServer) progressReporter(token interface{}) func(string) {
+	var text strings.Builder
+	var sent time.Time
+	return func(delta string) {
+		text.WriteString(delta)
+		if time.Since(sent) < progressInterval {
+			return
+		}
+		sent = time.Now()
+		s.send(MCPNotification{
+			Jsonrpc: "2.0",
+			Method:  "notifications/progress",
+			Params: map[string]interface{}{
+				"progressToken": token,
+				"progress":      text.Len(),
+				"message":       text.String(),
+			},
+		})
+	}
+}
+
+func (s *MCPServer) handleRequest(ctx context.Context, request MCPRequest) MCPResponse {
 	switch request.Method {
 	case "initialize":
 		return s.handleInitialize(request)
 	case "tools/list":
 		return s.handleToolsList(request)
 	case "tools/call":
-		return s.handleToolsCall(request)
+		return s.handleToolsCall(ctx, request)
 	case "resources/list":
 		return s.handleResourcesList(request)
 	case "resources/read":
@@ -134,20 +316,12 @@ func (s *MCPServer) handleToolsList(request MCPRequest) MCPResponse {
 			Description: "Ask questions about Crossplane resources using natural language",
 			InputSchema: map[string]interface{}{
 				"type": "object",
-				"properties": map[string]interface{}{
+				"properties": withFilterProperties(map[string]interface{}{
 					"question": map[string]interface{}{
 						"type":        "string",
 						"description": "Natural language question about Crossplane resources",
 					},
-					"provider": map[string]interface{}{
-						"type":        "string",
-						"description": "Optional: Filter by specific provider (aws, gcp, azure)",
-					},
-					"namespace": map[string]interface{}{
-						"type":        "string",
-						"description": "Optional: Filter by Kubernetes namespace",
-					},
-				},
+				}),
 				"required": []string{"question"},
 			},
 		},
@@ -156,17 +330,13 @@ func (s *MCPServer) handleToolsList(request MCPRequest) MCPResponse {
 			Description: "Perform AI-powered analysis of Crossplane resources",
 			InputSchema: map[string]interface{}{
 				"type": "object",
-				"properties": map[string]interface{}{
+				"properties": withFilterProperties(map[string]interface{}{
 					"health_check": map[string]interface{}{
 						"type":        "boolean",
 						"description": "Whether to perform health check analysis",
 						"default":     true,
 					},
-					"provider": map[string]interface{}{
-						"type":        "string",
-						"description": "Optional: Filter by specific provider (aws, gcp, azure)",
-					},
-				},
+				}),
 			},
 		},
 		{
@@ -174,13 +344,13 @@ func (s *MCPServer) handleToolsList(request MCPRequest) MCPResponse {
 			Description: "Get AI-powered suggestions for Crossplane resources",
 			InputSchema: map[string]interface{}{
 				"type": "object",
-				"properties": map[string]interface{}{
+				"properties": withFilterProperties(map[string]interface{}{
 					"suggestion_type": map[string]interface{}{
 						"type":        "string",
 						"description": "Type of suggestions (optimization, security, database, etc.)",
 						"default":     "optimization",
 					},
-				},
+				}),
 			},
 		},
 		{
@@ -195,7 +365,7 @@ func (s *MCPServer) handleToolsList(request MCPRequest) MCPResponse {
 					},
 					"provider": map[string]interface{}{
 						"type":        "string",
-						"description": "Target cloud provider (aws, gcp, azure)",
+						"description": "Target cloud provider (aws, gcp, azure); defaults to the family the cluster serves",
 						"default":     "aws",
 					},
 				},
@@ -207,16 +377,12 @@ func (s *MCPServer) handleToolsList(request MCPRequest) MCPResponse {
 			Description: "List all Crossplane resources in the cluster",
 			InputSchema: map[string]interface{}{
 				"type": "object",
-				"properties": map[string]interface{}{
+				"properties": withFilterProperties(map[string]interface{}{
 					"resource_type": map[string]interface{}{
 						"type":        "string",
 						"description": "Optional: Filter by resource type (compositions, providers, etc.)",
 					},
-					"provider": map[string]interface{}{
-						"type":        "string",
-						"description": "Optional: Filter by provider (aws, gcp, azure)",
-					},
-				},
+				}),
 			},
 		},
 	}
@@ -230,7 +396,7 @@ func (s *MCPServer) handleToolsList(request MCPRequest) MCPResponse {
 	}
 }
 
-func (s *MCPServer) handleToolsCall(request MCPRequest) MCPResponse {
+func (s *MCPServer) handleToolsCall(ctx context.Context, request MCPRequest) MCPResponse {
 	params, ok := request.Params.(map[string]interface{})
 	if !ok {
 		return MCPResponse{
@@ -260,8 +426,6 @@ func (s *MCPServer) handleToolsCall(request MCPRequest) MCPResponse {
 		arguments = make(map[string]interface{})
 	}
 
-	ctx := context.Background()
-
 	switch toolName {
 	case "crossplane_ask":
 		return s.handleCrossplaneAsk(request, ctx, arguments)
@@ -292,13 +456,23 @@ func (s *MCPServer) handleCrossplaneAsk(request MCPRequest, ctx context.Context,
 	}
 
 	// Get resources for context
-	resources, err := s.getResources(args)
+	resources, err := s.getResources(ctx, args)
 	if err != nil {
 		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get resources: %v", err))
 	}
 
-	// Process query with AI
-	response, err := s.aiService.ProcessQuery(ctx, question, resources)
+	// Include recent events of unhealthy resources so that the answer can
+	// cite the provider's error messages
+	s.crossplaneClient.AttachEvents(ctx, resources, crossplane.MaxEventResources)
+	crossplane.AttachDrift(resources)
+
+	// Process query with AI, streaming the answer as progress when the
+	// client asked for it
+	var onToken func(string)
+	if token := progressToken(request); token != nil {
+		onToken = s.progressReporter(token)
+	}
+	response, err := s.aiService.ProcessQueryStream(ctx, question, resources, onToken)
 	if err != nil {
 		return s.errorResponse(request.ID, -32603, fmt.Sprintf("AI processing failed: %v", err))
 	}
@@ -323,17 +497,20 @@ func (s *MCPServer) handleCrossplaneAnalyze(request MCPRequest, ctx context.Cont
 		healthCheck = hc
 	}
 
-	// Get resources for analysis
-	resources, err := s.getResources(args)
+	if s.crossplaneClient == nil {
+		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get resources: no Crossplane client: %v", s.clientErr))
+	}
+	filter, err := filterFromArgs(args)
 	if err != nil {
-		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get resources: %v", err))
+		return s.errorResponse(request.ID, -32602, err.Error())
 	}
 
 	// Perform analysis
-	analysis, err := s.aiService.AnalyzeResources(ctx, resources, healthCheck)
+	report, err := s.aiService.AnalyzeCluster(ctx, s.crossplaneClient, filter, healthCheck)
 	if err != nil {
 		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Analysis failed: %v", err))
 	}
+	analysis := report.Analysis
 
 	// Format analysis results
 	result := fmt.Sprintf(`📊 Crossplane Analysis Results
@@ -346,6 +523,21 @@ func (s *MCPServer) handleCrossplaneAnalyze(request MCPRequest, ctx context.Cont
 
 `, analysis.TotalResources, analysis.HealthyResources, analysis.IssuesFound, analysis.HealthScore)
 
+	if report.Listing.Partial() {
+		result += fmt.Sprintf("⚠️ Results are partial: %d resource types could not be listed\n", len(report.Listing.Failures))
+		for _, failure := range report.Listing.Failures {
+			result += fmt.Sprintf("• %s\n", failure)
+		}
+		result += "\n"
+	}
+
+	if analysis.Warning != "" {
+		result += fmt.Sprintf("⚠️ %s\n\n", analysis.Warning)
+	}
+	for _, warning := range report.Warnings {
+		result += fmt.Sprintf("⚠️ %s\n\n", warning)
+	}
+
 	if len(analysis.Issues) > 0 {
 		result += "⚠️ Issues Detected:\n"
 		for _, issue := range analysis.Issues {
@@ -382,7 +574,7 @@ func (s *MCPServer) handleCrossplaneSuggest(request MCPRequest, ctx context.Cont
 	}
 
 	// Get resources for context
-	resources, err := s.getResources(args)
+	resources, err := s.getResources(ctx, args)
 	if err != nil {
 		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get resources: %v", err))
 	}
@@ -424,13 +616,23 @@ func (s *MCPServer) handleCrossplaneGenerate(request MCPRequest, ctx context.Con
 		return s.errorResponse(request.ID, -32602, "Description is required")
 	}
 
-	provider := "aws"
-	if p, ok := args["provider"].(string); ok {
+	provider := "auto"
+	if p, ok := args["provider"].(string); ok && p != "" {
 		provider = p
 	}
 
+	// Generate against the APIs the cluster serves when there is one
+	var apis crossplane.APISet
+	if s.crossplaneClient != nil {
+		var err error
+		provider, apis, err = s.crossplaneClient.ResolveAPIs(ctx, provider)
+		if err != nil {
+			log.Printf("Warning: Failed to resolve served APIs: %v", err)
+		}
+	}
+
 	// Generate manifest
-	manifest, err := s.aiService.GenerateManifest(ctx, description, provider)
+	manifest, err := s.aiService.GenerateManifest(ctx, description, provider, apis)
 	if err != nil {
 		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Manifest generation failed: %v", err))
 	}
@@ -453,30 +655,18 @@ func (s *MCPServer) handleCrossplaneGenerate(request MCPRequest, ctx context.Con
 
 func (s *MCPServer) handleCrossplaneListResources(request MCPRequest, ctx context.Context, args map[string]interface{}) MCPResponse {
 	// Get resources
-	resources, err := s.getResources(args)
+	resources, err := s.getResources(ctx, args)
 	if err != nil {
 		return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get resources: %v", err))
 	}
 
 	// Format resource list
 	result := "📋 Crossplane Resources:\n\n"
-	if resourceList, ok := resources.([]interface{}); ok {
-		for i, resource := range resourceList {
-			if resourceMap, ok := resource.(map[string]interface{}); ok {
-				if name, ok := resourceMap["name"].(string); ok {
-					result += fmt.Sprintf("%d. %s", i+1, name)
-					if kind, ok := resourceMap["kind"].(string); ok {
-						result += fmt.Sprintf(" (%s)", kind)
-					}
-					if status, ok := resourceMap["status"].(string); ok {
-						result += fmt.Sprintf(" - %s", status)
-					}
-					result += "\n"
-				}
-			}
-		}
-	} else {
-		result += "No resources found or mock data being used.\n"
+	if len(resources) == 0 {
+		result += "No resources match the filter.\n"
+	}
+	for i, resource := range resources {
+		result += fmt.Sprintf("%d. %s (%s) - %s\n", i+1, crossplane.QualifiedName(resource), resource.Kind, resource.Status)
 	}
 
 	return MCPResponse{
@@ -539,26 +729,33 @@ func (s *MCPServer) handleResourcesRead(request MCPRequest) MCPResponse {
 	var content string
 	switch uri {
 	case "crossplane://cluster/resources":
-		resources, err := s.getResources(make(map[string]interface{}))
+		resources, err := s.getResources(context.Background(), make(map[string]interface{}))
 		if err != nil {
 			return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get resources: %v", err))
 		}
 		resourcesJSON, _ := json.MarshalIndent(resources, "", "  ")
 		content = string(resourcesJSON)
 	case "crossplane://cluster/providers":
-		content = `{
-  "providers": [
-    {"name": "provider-aws", "status": "Ready"},
-    {"name": "provider-gcp", "status": "Ready"},
-    {"name": "provider-azure", "status": "Ready"}
-  ]
-}`
+		if s.crossplaneClient == nil {
+			return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get providers: %v", s.clientErr))
+		}
+		providers, err := s.crossplaneClient.ListProviders(context.Background())
+		if providers == nil && err != nil {
+			return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get providers: %v", err))
+		}
+		providersJSON, _ := json.MarshalIndent(map[string]interface{}{"providers": providers}, "", "  ")
+		content = string(providersJSON)
 	case "crossplane://cluster/compositions":
-		content = `{
-  "compositions": [
-    {"name": "sample-database-composition", "status": "Ready"}
-  ]
-}`
+		resources, err := s.getResources(context.Background(), map[string]interface{}{"resource_type": "compositions.apiextensions.crossplane.io"})
+		if err != nil {
+			return s.errorResponse(request.ID, -32603, fmt.Sprintf("Failed to get compositions: %v", err))
+		}
+		compositions := make([]*crossplane.CompositionInfo, 0, len(resources))
+		for _, resource := range resources {
+			compositions = append(compositions, crossplane.NewCompositionInfo(resource))
+		}
+		compositionsJSON, _ := json.MarshalIndent(map[string]interface{}{"compositions": compositions}, "", "  ")
+		content = string(compositionsJSON)
 	default:
 		return s.errorResponse(request.ID, -32602, "Unknown resource URI")
 	}
@@ -578,27 +775,101 @@ func (s *MCPServer) handleResourcesRead(request MCPRequest) MCPResponse {
 	}
 }
 
-func (s *MCPServer) getResources(args map[string]interface{}) (interface{}, error) {
-	// Try to get real resources from Crossplane client
-	if s.crossplaneClient != nil {
-		provider := ""
-		if p, ok := args["provider"].(string); ok {
-			provider = p
-		}
+// getResources lists the resources selected by the filter arguments of a
+// tool call
+func (s *MCPServer) getResources(ctx context.Context, args map[string]interface{}) ([]*crossplane.Resource, error) {
+	if s.crossplaneClient == nil {
+		return nil, fmt.Errorf("no Crossplane client: %w", s.clientErr)
+	}
+
+	filter, err := filterFromArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.crossplaneClient.ListResources(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return result.Resources, nil
+}
+
+// filterProperties describes the resource selection arguments shared by the
+// tools
+var filterProperties = map[string]interface{}{
+	"name": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Resource name, glob (prod-*) or regular expression between slashes (/^db-/)",
+	},
+	"provider": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Filter by specific provider (aws, gcp, azure)",
+	},
+	"namespace": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Filter by Kubernetes namespace",
+	},
+	"selector": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Label selector, e.g. team=payments,env=prod",
+	},
+	"field_selector": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Field selector, e.g. metadata.name=my-db",
+	},
+	"types": map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "Optional: Resource types by kind, plural or plural.group",
+	},
+	"status": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: ready, not-ready, unknown, unsynced, deleting or unhealthy",
+	},
+	"older_than": map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Only resources created longer ago than this, e.g. 36h or 7d",
+	},
+}
 
-		namespace := ""
-		if n, ok := args["namespace"].(string); ok {
-			namespace = n
+// withFilterProperties adds the filter arguments to a tool's properties
+func withFilterProperties(properties map[string]interface{}) map[string]interface{} {
+	for name, property := range filterProperties {
+		properties[name] = property
+	}
+	return properties
+}
+
+// filterFromArgs builds a resource filter from tool arguments
+func filterFromArgs(args map[string]interface{}) (crossplane.ResourceFilter, error) {
+	filter := crossplane.ResourceFilter{}
+	filter.Name, _ = args["name"].(string)
+	filter.Provider, _ = args["provider"].(string)
+	filter.Namespace, _ = args["namespace"].(string)
+	filter.LabelSelector, _ = args["selector"].(string)
+	filter.FieldSelector, _ = args["field_selector"].(string)
+	filter.Status, _ = args["status"].(string)
+
+	if types, ok := args["types"].([]interface{}); ok {
+		for _, t := range types {
+			if t, ok := t.(string); ok && t != "" {
+				filter.Types = append(filter.Types, t)
+			}
 		}
+	}
+	if t, ok := args["resource_type"].(string); ok && t != "" {
+		filter.Types = append(filter.Types, t)
+	}
 
-		resources, err := s.crossplaneClient.GetFilteredResources(context.Background(), "", provider, namespace)
-		if err == nil && len(resources) > 0 {
-			return resources, nil
+	if olderThan, ok := args["older_than"].(string); ok {
+		age, err := crossplane.ParseAge(olderThan)
+		if err != nil {
+			return filter, err
 		}
+		filter.OlderThan = age
 	}
 
-	// Fallback to mock data
-	return ai.GetEmbeddedMockResources(), nil
+	return filter, nil
 }
 
 func (s *MCPServer) errorResponse(id interface{}, code int, message string) MCPResponse {
@@ -612,29 +883,52 @@ func (s *MCPServer) errorResponse(id interface{}, code int, message string) MCPR
 	}
 }
 
-func main() {
-	server := NewMCPServer()
+// newMockSource returns the fake cluster of mock mode: the manifests in dir,
+// or a scenario of the embedded mock cluster when dir is empty
+func newMockSource(dir, scenario string) (crossplane.ResourceSource, error) {
+	if dir == "" {
+		return ai.NewMockSource(scenario)
+	}
+	objects, err := crossplane.ReadManifestDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return crossplane.NewFakeSource("mock", objects)
+}
 
-	log.Println("Starting Crossplane AI MCP Server...")
-	log.Println("Reading JSON-RPC requests from stdin...")
+func main() {
+	var opts crossplane.ClientOptions
+	flag.StringVar(&opts.Kubeconfig, "kubeconfig", "", "path to kubeconfig file")
+	flag.StringVar(&opts.Context, "context", "", "kubectl context to use (overrides current context)")
+	flag.StringVar(&opts.Namespace, "namespace", "", "only look at namespaced resources in this namespace (default: all namespaces)")
+	flag.StringVar(&opts.Snapshot, "snapshot", "", "serve from a snapshot file written by \"crossplane-ai snapshot save\" instead of the cluster")
+	mock := flag.Bool("mock", false, "serve the embedded sample data instead of the cluster (for testing and demos)")
+	mockDataDir := flag.String("mock-data-dir", "", "serve the manifests in this directory instead of the cluster; implies -mock")
+	scenario := flag.String("scenario", "", "scenario of the embedded mock data; implies -mock ("+strings.Join(ai.MockScenarioNames(), ", ")+")")
+	flag.Parse()
+
+	if *mock || *mockDataDir != "" || *scenario != "" {
+		if opts.Snapshot != "" {
+			log.Fatal("-mock and -snapshot are mutually exclusive")
+		}
+		if *mockDataDir != "" && *scenario != "" {
+			log.Fatal("-mock-data-dir and -scenario are mutually exclusive")
+		}
+		source, err := newMockSource(*mockDataDir, *scenario)
+		if err != nil {
+			log.Fatalf("Failed to load mock data: %v", err)
+		}
+		opts.Source = source
+	}
 
-	decoder := json.NewDecoder(os.Stdin)
-	encoder := json.NewEncoder(os.Stdout)
+	// Ctrl-C or a termination cancels the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	for {
-		var request MCPRequest
-		if err := decoder.Decode(&request); err != nil {
-			if err.Error() == "EOF" {
-				break
-			}
-			log.Printf("Error decoding request: %v", err)
-			continue
-		}
+	server := NewMCPServer(opts)
 
-		response := server.handleRequest(request)
+	log.Println("Starting Crossplane AI MCP Server...")
+	log.Println("Reading JSON-RPC requests from stdin...")
 
-		if err := encoder.Encode(response); err != nil {
-			log.Printf("Error encoding response: %v", err)
-		}
-	}
+	server.serve(ctx, os.Stdin, os.Stdout)
 }
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var modelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "Show the language model backend and the models of a local server",
+	Long: `Show the language model backend selected by ai.provider and the model in use.
+
+With ai.provider: local the models of the local server are listed, and the
+server is probed: whether it is Ollama or an OpenAI-compatible server such as
+llama.cpp's llama-server, the context window the model is used with, and
+whether the model supports JSON mode. Nothing is sent outside the network.`,
+	Example: `  # Check that the local model server is reachable
+  crossplane-ai models
+
+  # Print the probe result as JSON
+  crossplane-ai models -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+
+		aiService := ai.NewService()
+		llm := aiService.Provider()
+		if llm == nil {
+			cli.PrintInfo("Using template-based answers; " + aiService.SetupHint())
+			return nil
+		}
+
+		report := struct {
+			Provider string          `json:"provider"`
+			Model    string          `json:"model"`
+			Server   *ai.LocalProbe  `json:"server,omitempty"`
+			Models   []ai.LocalModel `json:"models,omitempty"`
+		}{Provider: llm.Name(), Model: llm.Model()}
+
+		var probeErr error
+		local, isLocal := llm.(*ai.LocalClient)
+		if isLocal {
+			models, err := local.ListModels(ctx)
+			if err != nil {
+				return err
+			}
+			report.Models = models
+			report.Server, probeErr = local.Probe(ctx)
+			report.Model = local.Model()
+		}
+
+		switch output {
+		case "table", "":
+			fmt.Printf("Provider: %s\n", report.Provider)
+			fmt.Printf("Model:    %s\n", orDash(report.Model))
+			if !isLocal {
+				return nil
+			}
+			if report.Server != nil {
+				jsonMode := "not supported, JSON is requested in the prompt"
+				if report.Server.JSONMode {
+					jsonMode = "supported"
+				}
+				fmt.Printf("Server:   %s at %s\n", report.Server.Server, report.Server.BaseURL)
+				fmt.Printf("Context:  %d tokens\n", report.Server.ContextWindow)
+				fmt.Printf("JSON:     %s\n", jsonMode)
+			}
+			fmt.Println()
+			printLocalModels(report.Models, report.Model)
+		case "json":
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode models: %w", err)
+			}
+			fmt.Println(string(data))
+		case "yaml":
+			data, err := yaml.Marshal(report)
+			if err != nil {
+				return fmt.Errorf("failed to encode models: %w", err)
+			}
+			fmt.Print(string(data))
+		default:
+			return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+		}
+
+		return probeErr
+	},
+}
+
+func printLocalModels(models []ai.LocalModel, inUse string) {
+	if len(models) == 0 {
+		fmt.Println("No models found.")
+		return
+	}
+
+	var rows [][]string
+	for _, model := range models {
+		name := model.Name
+		if name == inUse {
+			name += " *"
+		}
+		size := "-"
+		if model.Size > 0 {
+			size = fmt.Sprintf("%.1f GB", float64(model.Size)/1e9)
+		}
+		rows = append(rows, []string{name, orDash(model.Family), orDash(model.ParameterSize), size})
+	}
+	cli.PrintTable([]string{"NAME", "FAMILY", "PARAMETERS", "SIZE"}, rows)
+}
+
+func init() {
+	rootCmd.AddCommand(modelsCmd)
+
+	modelsCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var providerConfigsCmd = &cobra.Command{
+	Use:     "providerconfigs",
+	Aliases: []string{"pc"},
+	Short:   "Show ProviderConfigs, the resources using them and credential health",
+	Long: `List the ProviderConfigs and ClusterProviderConfigs of every installed provider
+family together with the managed resources that reference them through
+spec.providerConfigRef, and check that the credentials secret of each config
+and the referenced key exist.
+
+Only the existence of secrets and the names of their keys are inspected;
+secret values are never printed or sent to a language model.
+
+Problems found are listed below the table: managed resources that reference a
+missing config, secrets or keys that do not exist, and unused configs.`,
+	Example: `  # List ProviderConfigs and their usage
+  crossplane-ai providerconfigs
+
+  # Show which resources use each config
+  crossplane-ai pc --users
+
+  # Check the configs of every cluster in the kubeconfig
+  crossplane-ai --all-contexts providerconfigs`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+		users, _ := cmd.Flags().GetBool("users")
+
+		client, err := newResourceLister(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		report, err := client.ProviderConfigReport(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check ProviderConfigs: %w", err)
+		}
+
+		switch output {
+		case "table", "":
+			printProviderConfigs(report, users)
+		case "json":
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Println(string(data))
+		case "yaml":
+			data, err := yaml.Marshal(report)
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Print(string(data))
+		default:
+			return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+		}
+
+		return nil
+	},
+}
+
+func printProviderConfigs(report *crossplane.ProviderConfigReport, users bool) {
+	printListFailures(&crossplane.ListResult{Failures: report.Failures})
+
+	clusters := make(map[string]bool)
+	for _, config := range report.Configs {
+		clusters[config.Cluster] = true
+	}
+	multiCluster := len(clusters) > 1
+
+	if len(report.Configs) == 0 {
+		fmt.Println("No ProviderConfigs found.")
+	} else {
+		headers := []string{"NAME", "PROVIDER", "SOURCE", "SECRET", "USERS"}
+		if multiCluster {
+			headers = append([]string{"CLUSTER"}, headers...)
+		}
+		var rows [][]string
+		for _, config := range report.Configs {
+			row := []string{config.ID(), config.Provider, orDash(config.Source),
+				secretState(config.Secret), strconv.Itoa(len(config.Users))}
+			if multiCluster {
+				row = append([]string{config.Cluster}, row...)
+			}
+			rows = append(rows, row)
+		}
+		cli.PrintTable(headers, rows)
+	}
+
+	if users {
+		for _, config := range report.Configs {
+			if len(config.Users) == 0 {
+				continue
+			}
+			cli.PrintSubHeader(fmt.Sprintf("Users of %s", config.ID()))
+			for _, user := range config.Users {
+				fmt.Printf("  %s\n", user)
+			}
+		}
+	}
+
+	issues := ai.ProviderConfigIssues(report)
+	if len(issues) == 0 {
+		fmt.Println()
+		cli.PrintSuccess("All ProviderConfigs are in use and their credentials secrets exist")
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("⚠️  Issues Detected")
+	fmt.Println("==================")
+	for _, issue := range issues {
+		if multiCluster && issue.Cluster != "" {
+			fmt.Printf("• %s: [%s] %s\n", issue.Severity, issue.Cluster, issue.Description)
+		} else {
+			fmt.Printf("• %s: %s\n", issue.Severity, issue.Description)
+		}
+		if issue.Resolution != "" {
+			fmt.Printf("  Resolution: %s\n", issue.Resolution)
+		}
+	}
+}
+
+// secretState summarizes a credentials secret check for the table
+func secretState(secret *crossplane.SecretCheck) string {
+	switch {
+	case secret == nil:
+		return "-"
+	case secret.Name == "":
+		return "not set"
+	}
+
+	name := secret.Namespace + "/" + secret.Name
+	if secret.Key != "" {
+		name += ":" + secret.Key
+	}
+	switch {
+	case secret.Error != "":
+		return name + " (unchecked)"
+	case !secret.Exists:
+		return name + " (missing)"
+	case !secret.KeyExists:
+		return name + " (key missing)"
+	}
+	return name + " ✓"
+}
+
+func init() {
+	rootCmd.AddCommand(providerConfigsCmd)
+
+	providerConfigsCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+	providerConfigsCmd.Flags().Bool("users", false, "list the managed resources using each config")
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var providersCmd = &cobra.Command{
+	Use:   "providers [name|glob]",
+	Short: "Show installed providers with their package, revisions and health",
+	Long: `List the installed Crossplane providers with their package image and version,
+Installed and Healthy conditions, current revision, the number of CRDs owned by
+the active revision and the runtime config in use. Providers that are not
+installed or not healthy are listed with the reason and message.
+
+With --revisions, the active and inactive revisions of each provider are shown
+as well.`,
+	Example: `  # List all providers
+  crossplane-ai providers
+
+  # Show the revisions of the AWS providers
+  crossplane-ai providers "provider-aws-*" --revisions
+
+  # Export the inventory as JSON
+  crossplane-ai providers -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+		revisions, _ := cmd.Flags().GetBool("revisions")
+
+		var pattern string
+		if len(args) > 0 {
+			pattern = args[0]
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid name pattern %s: %w", pattern, err)
+			}
+		}
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		return showProviders(ctx, client, pattern, output, revisions)
+	},
+}
+
+func showProviders(ctx context.Context, client *crossplane.Client, pattern, output string, revisions bool) error {
+	providers, err := client.ListProviders(ctx)
+	if providers == nil && err != nil {
+		return err
+	}
+	if err != nil {
+		cli.PrintWarning(fmt.Sprintf("Revision details are not available: %v", err))
+	}
+
+	if pattern != "" {
+		var matched []*crossplane.PackageInfo
+		for _, provider := range providers {
+			if ok, _ := path.Match(pattern, provider.Name); ok {
+				matched = append(matched, provider)
+			}
+		}
+		providers = matched
+	}
+
+	switch output {
+	case "table", "":
+		printProviders(providers, revisions)
+	case "json":
+		data, err := json.MarshalIndent(providers, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode providers: %w", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(providers)
+		if err != nil {
+			return fmt.Errorf("failed to encode providers: %w", err)
+		}
+		fmt.Print(string(data))
+	default:
+		return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+	}
+
+	return nil
+}
+
+func printProviders(providers []*crossplane.PackageInfo, revisions bool) {
+	if len(providers) == 0 {
+		fmt.Println("No providers found.")
+		return
+	}
+
+	headers := []string{"NAME", "PACKAGE", "VERSION", "INSTALLED", "HEALTHY", "REVISION", "CRDS", "RUNTIME CONFIG", "AGE"}
+	var rows [][]string
+	for _, provider := range providers {
+		revision := orDash(provider.CurrentRevision)
+		if inactive := provider.InactiveRevisions(); inactive > 0 {
+			revision += fmt.Sprintf(" (+%d inactive)", inactive)
+		}
+		rows = append(rows, []string{
+			provider.Name,
+			orDash(provider.Package),
+			orDash(provider.Version),
+			provider.Installed,
+			provider.Healthy,
+			revision,
+			strconv.Itoa(provider.CRDs),
+			orDash(provider.RuntimeConfig),
+			cli.FormatSince(provider.CreatedAt),
+		})
+	}
+	cli.PrintTable(headers, rows)
+
+	var unhealthy []*crossplane.PackageInfo
+	for _, provider := range providers {
+		if !provider.IsHealthy() {
+			unhealthy = append(unhealthy, provider)
+		}
+	}
+	if len(unhealthy) > 0 {
+		fmt.Println()
+		cli.PrintWarning(fmt.Sprintf("%d of %d providers are not installed or not healthy", len(unhealthy), len(providers)))
+		for _, provider := range unhealthy {
+			fmt.Printf("   • %s: %s\n", provider.Name, packageProblem(provider))
+		}
+	}
+
+	if !revisions {
+		return
+	}
+	for _, provider := range providers {
+		cli.PrintSubHeader(fmt.Sprintf("Revisions of %s", provider.Name))
+		if len(provider.Revisions) == 0 {
+			fmt.Println("<none>")
+			continue
+		}
+
+		headers := []string{"NAME", "REVISION", "STATE", "HEALTHY", "CRDS", "IMAGE", "MESSAGE"}
+		var rows [][]string
+		for _, revision := range provider.Revisions {
+			rows = append(rows, []string{
+				revision.Name,
+				strconv.FormatInt(revision.Revision, 10),
+				orDash(revision.DesiredState),
+				revision.Healthy,
+				strconv.Itoa(revision.CRDs),
+				orDash(revision.Image),
+				revision.Message,
+			})
+		}
+		cli.PrintTable(headers, rows)
+	}
+}
+
+// packageProblem describes why a package is not healthy, falling back to
+// the active revision's message when the package's conditions have none
+func packageProblem(pkg *crossplane.PackageInfo) string {
+	state := fmt.Sprintf("Installed=%s, Healthy=%s", pkg.Installed, pkg.Healthy)
+	reason, message := pkg.Reason, pkg.Message
+	if message == "" {
+		if active := pkg.ActiveRevision(); active != nil {
+			reason, message = active.Reason, active.Message
+		}
+	}
+	if reason != "" {
+		state += " (" + reason + ")"
+	}
+	if message != "" {
+		state += ": " + message
+	}
+	return state
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+
+	providersCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+	providersCmd.Flags().Bool("revisions", false, "show the active and inactive revisions of each provider")
+}
@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
+
+	"crossplane-ai/internal/config"
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/crossplane"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,15 +36,24 @@ cloud infrastructure resources managed by Crossplane.`,
   
   # Use specific cluster context
   crossplane-ai --context eks-cluster analyze
+
+  # Analyze several clusters at once
+  crossplane-ai --contexts prod-eu,prod-us analyze
   
   # Run in mock mode for testing/demos (uses embedded data)
   crossplane-ai --mock analyze
+
+  # Demo a failure scenario of the embedded mock cluster
+  crossplane-ai --scenario credential-failure analyze
+
+  # Run in mock mode against your own manifests
+  crossplane-ai --mock-data-dir ./examples analyze
   
-  # Generate example files for learning or custom mock data
+  # Generate example files for learning
   crossplane-ai generate examples
   
-  # Use custom mock data directory
-  crossplane-ai --mock --mock-data-dir ./my-examples analyze`,
+  # Analyze a snapshot saved with "crossplane-ai snapshot save"
+  crossplane-ai --snapshot cluster.json.gz analyze`,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -53,10 +68,19 @@ func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.crossplane-ai.yaml)")
 	rootCmd.PersistentFlags().String("context", "", "kubectl context to use (overrides current context)")
+	rootCmd.PersistentFlags().StringSlice("contexts", nil, "kubectl contexts to query together (analyze and ask)")
+	rootCmd.PersistentFlags().Bool("all-contexts", false, "query every context in the kubeconfig (analyze and ask)")
 	rootCmd.PersistentFlags().String("kubeconfig", "", "path to kubeconfig file")
+	rootCmd.PersistentFlags().StringP("namespace", "n", "", "only look at namespaced resources in this namespace (default: all namespaces)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "enable verbose output")
 	rootCmd.PersistentFlags().Bool("mock", false, "run in mock mode with embedded sample data (for testing and demos)")
-	rootCmd.PersistentFlags().String("mock-data-dir", "", "directory containing mock data files (optional, uses embedded data if not specified)")
+	rootCmd.PersistentFlags().String("mock-data-dir", "", "run in mock mode with the manifests in this directory instead of the embedded data")
+	rootCmd.PersistentFlags().String("scenario", "", fmt.Sprintf("scenario of the embedded mock data, implies --mock (%s)", strings.Join(ai.MockScenarioNames(), ", ")))
+	rootCmd.PersistentFlags().String("snapshot", "", "read from a snapshot file written by \"snapshot save\" instead of the cluster")
+	rootCmd.MarkFlagsMutuallyExclusive("mock", "snapshot")
+	rootCmd.MarkFlagsMutuallyExclusive("mock-data-dir", "snapshot")
+	rootCmd.MarkFlagsMutuallyExclusive("scenario", "snapshot")
+	rootCmd.MarkFlagsMutuallyExclusive("scenario", "mock-data-dir")
 
 	// Bind flags to viper
 	_ = viper.BindPFlag("kubeconfig", rootCmd.PersistentFlags().Lookup("kubeconfig"))
@@ -64,6 +88,7 @@ func init() {
 	_ = viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
 	_ = viper.BindPFlag("mock", rootCmd.PersistentFlags().Lookup("mock"))
 	_ = viper.BindPFlag("mock-data-dir", rootCmd.PersistentFlags().Lookup("mock-data-dir"))
+	_ = viper.BindPFlag("scenario", rootCmd.PersistentFlags().Lookup("scenario"))
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -88,27 +113,135 @@ func initConfig() {
 	}
 }
 
+// newCrossplaneClient builds the Crossplane client for a command. Settings
+// are resolved in this order, first match wins:
+//
+//  1. command-line flags: --kubeconfig, --context and --namespace, or
+//     --snapshot to read from a snapshot file and --mock from the fake
+//     cluster of mock mode instead of the cluster
+//  2. the config file: kubernetes.kubeconfig, kubernetes.context,
+//     kubernetes.namespace, crossplane.providers and crossplane.resource_types
+//  3. KUBECONFIG, ~/.kube/config and the kubeconfig's current context
+func newCrossplaneClient(ctx context.Context, cmd *cobra.Command) (*crossplane.Client, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts crossplane.ClientOptions
+	opts.Kubeconfig, _ = cmd.Flags().GetString("kubeconfig")
+	opts.Context, _ = cmd.Flags().GetString("context")
+	opts.Namespace, _ = cmd.Flags().GetString("namespace")
+	opts.Snapshot, _ = cmd.Flags().GetString("snapshot")
+	if IsMockMode() {
+		opts.Source, err = newMockSource()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mock data: %w", err)
+		}
+	}
+
+	client, err := crossplane.NewClientFromConfig(ctx, cfg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Crossplane client: %w", err)
+	}
+	return client, nil
+}
+
+// resourceLister is implemented by both a single-cluster client and a
+// multi-cluster client
+type resourceLister interface {
+	ListResources(ctx context.Context, filter crossplane.ResourceFilter) (*crossplane.ListResult, error)
+	AttachEvents(ctx context.Context, resources []*crossplane.Resource, limit int)
+	ProviderConfigReport(ctx context.Context) (*crossplane.ProviderConfigReport, error)
+	FunctionReport(ctx context.Context) (*crossplane.FunctionReport, error)
+	DeletionReport(ctx context.Context) (*crossplane.DeletionReport, error)
+}
+
+// newResourceLister returns a multi-cluster client when --contexts or
+// --all-contexts is set, and the single client of newCrossplaneClient
+// otherwise
+func newResourceLister(ctx context.Context, cmd *cobra.Command) (resourceLister, error) {
+	contexts, _ := cmd.Flags().GetStringSlice("contexts")
+	allContexts, _ := cmd.Flags().GetBool("all-contexts")
+	if len(contexts) == 0 && !allContexts {
+		return newCrossplaneClient(ctx, cmd)
+	}
+
+	if single, _ := cmd.Flags().GetString("context"); single != "" {
+		return nil, fmt.Errorf("--context cannot be combined with --contexts or --all-contexts")
+	}
+	if snapshot, _ := cmd.Flags().GetString("snapshot"); snapshot != "" {
+		return nil, fmt.Errorf("--snapshot cannot be combined with --contexts or --all-contexts")
+	}
+	if IsMockMode() {
+		return nil, fmt.Errorf("mock mode cannot be combined with --contexts or --all-contexts")
+	}
+	if len(contexts) > 0 && allContexts {
+		return nil, fmt.Errorf("--contexts and --all-contexts are mutually exclusive")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts crossplane.ClientOptions
+	opts.Kubeconfig, _ = cmd.Flags().GetString("kubeconfig")
+	opts.Namespace, _ = cmd.Flags().GetString("namespace")
+
+	if allContexts {
+		kubeconfig := opts.Kubeconfig
+		if kubeconfig == "" && cfg != nil {
+			kubeconfig = cfg.Kubernetes.Kubeconfig
+		}
+		contexts, err = crossplane.KubeconfigContexts(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		if len(contexts) == 0 {
+			return nil, fmt.Errorf("the kubeconfig has no contexts")
+		}
+	}
+
+	client, err := crossplane.NewMultiClient(ctx, cfg, opts, contexts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Crossplane clients: %w", err)
+	}
+	return client, nil
+}
+
 // IsMockMode checks if the tool should run in mock mode
 func IsMockMode() bool {
-	// Check command line flag first
-	if viper.GetBool("mock") {
+	// Check command line flags first
+	if viper.GetBool("mock") || viper.GetString("mock-data-dir") != "" || viper.GetString("scenario") != "" {
 		return true
 	}
 	// Fall back to environment variable for backward compatibility
 	return os.Getenv("CROSSPLANE_AI_MODE") == "mock"
 }
 
-// GetMockDataDir returns the mock data directory
+// GetMockDataDir returns the directory of the manifests mock mode serves,
+// or "" for the embedded sample data
 func GetMockDataDir() string {
 	// Check command line flag first
 	if mockDir := viper.GetString("mock-data-dir"); mockDir != "" {
 		return mockDir
 	}
 	// Fall back to environment variable for backward compatibility
-	mockDir := os.Getenv("CROSSPLANE_AI_MOCK_DATA_DIR")
-	if mockDir == "" {
-		// Return empty string to indicate we should use embedded data
-		return ""
+	return os.Getenv("CROSSPLANE_AI_MOCK_DATA_DIR")
+}
+
+// newMockSource returns the fake cluster of mock mode: the manifests of
+// the mock data directory, or the --scenario of the embedded mock cluster.
+// Every command runs against it exactly as against a live cluster.
+func newMockSource() (crossplane.ResourceSource, error) {
+	dir := GetMockDataDir()
+	if dir == "" {
+		return ai.NewMockSource(viper.GetString("scenario"))
+	}
+	objects, err := crossplane.ReadManifestDir(dir)
+	if err != nil {
+		return nil, err
 	}
-	return mockDir
+	return crossplane.NewFakeSource("mock", objects)
 }
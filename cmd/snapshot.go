@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the Crossplane objects of a cluster for offline analysis",
+	Long: `Save every Crossplane object of a cluster to a single file, and inspect saved
+snapshots. Any command accepts --snapshot <file> to read from a snapshot instead
+of the cluster, so a control plane can be debugged after the fact, e.g. from a
+CI run or a customer's cluster.`,
+}
+
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save <file>",
+	Short: "Write the Crossplane objects of the cluster to a snapshot file",
+	Long: `Write every object of the Crossplane core, provider and XRD API groups to a
+snapshot file: providers, functions and their revisions, compositions and their
+revisions, XRDs, claims, composites, managed resources and ProviderConfigs,
+together with the CRDs of these types (without schemas), the provider and
+function pods (status only) and the Kubernetes events about all of them.
+
+Secrets are stripped: of the credentials secrets referenced by ProviderConfigs
+only the name, namespace, type and key names are saved, never their values.
+Managed fields and the last-applied-configuration annotation are dropped.
+
+The snapshot is JSON, gzip compressed when the file name ends in .gz.`,
+	Example: `  # Save the current cluster
+  crossplane-ai snapshot save cluster.json.gz
+
+  # Save a CI cluster and analyze it later
+  crossplane-ai --context kind-ci snapshot save ci.json.gz
+  crossplane-ai --snapshot ci.json.gz analyze
+  crossplane-ai --snapshot ci.json.gz trace xpostgresqlinstance/my-db`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		if path, _ := cmd.Flags().GetString("snapshot"); path != "" {
+			return fmt.Errorf("--snapshot cannot be used when saving a snapshot")
+		}
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := client.SaveSnapshot(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+		printListFailures(&crossplane.ListResult{Failures: snapshot.Failures})
+
+		if err := crossplane.WriteSnapshotFile(args[0], snapshot); err != nil {
+			return err
+		}
+
+		cli.PrintSuccess(fmt.Sprintf("Saved %d objects, %d events, %d pods and %d stripped secrets of %s to %s",
+			len(snapshot.Objects), len(snapshot.Events), len(snapshot.Pods), len(snapshot.Secrets), orDash(snapshot.Cluster), args[0]))
+		return nil
+	},
+}
+
+var snapshotInfoCmd = &cobra.Command{
+	Use:   "info <file>",
+	Short: "Show what a snapshot contains",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshot, err := crossplane.LoadSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+
+		cli.PrintHeader(fmt.Sprintf("Snapshot of %s", orDash(snapshot.Cluster)))
+		fmt.Printf("Saved:   %s (%s ago)\n", snapshot.CreatedAt.Local().Format("2006-01-02 15:04:05"), cli.FormatSince(snapshot.CreatedAt))
+		fmt.Printf("Events:  %d\n", len(snapshot.Events))
+		fmt.Printf("Pods:    %d\n", len(snapshot.Pods))
+		fmt.Printf("Secrets: %d (values stripped)\n", len(snapshot.Secrets))
+		fmt.Println()
+
+		counts := make(map[string]int)
+		for _, obj := range snapshot.Objects {
+			counts[obj.GroupVersionKind().GroupKind().String()]++
+		}
+		kinds := make([]string, 0, len(counts))
+		for kind := range counts {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		var rows [][]string
+		for _, kind := range kinds {
+			rows = append(rows, []string{kind, strconv.Itoa(counts[kind])})
+		}
+		cli.PrintTable([]string{"KIND", "OBJECTS"}, rows)
+
+		printListFailures(&crossplane.ListResult{Failures: snapshot.Failures})
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotInfoCmd)
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"crossplane-ai/pkg/ai"
 	"crossplane-ai/pkg/crossplane"
@@ -37,32 +36,33 @@ Crossplane resources. The AI analyzes your current setup and provides tailored r
 			suggestionType = "general"
 		}
 
-		// Check if running in mock mode
-		if IsMockMode() {
-			fmt.Printf("🔍 Analyzing your Crossplane setup for %s suggestions (using embedded sample data)...\n\n", suggestionType)
-			return generateMockSuggestions(ctx, suggestionType)
+		filter, err := resourceFilterFromFlags(cmd, "")
+		if err != nil {
+			return err
 		}
 
-		// Initialize clients for real mode
-		client, err := crossplane.NewClient(ctx)
+		// Initialize clients
+		client, err := newCrossplaneClient(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("failed to initialize Crossplane client: %w", err)
+			return err
 		}
 
 		aiService := ai.NewService()
 
-		return generateSuggestions(ctx, client, aiService, suggestionType)
+		return generateSuggestions(ctx, client, aiService, suggestionType, filter)
 	},
 }
 
-func generateSuggestions(ctx context.Context, client *crossplane.Client, aiService *ai.Service, suggestionType string) error {
+func generateSuggestions(ctx context.Context, client *crossplane.Client, aiService *ai.Service, suggestionType string, filter crossplane.ResourceFilter) error {
 	fmt.Printf("🔍 Analyzing your Crossplane setup for %s suggestions...\n\n", suggestionType)
 
 	// Get current resources
-	resources, err := client.GetAllResources(ctx)
+	result, err := client.ListResources(ctx, filter)
 	if err != nil {
 		return fmt.Errorf("failed to get resources: %w", err)
 	}
+	printListFailures(result)
+	resources := result.Resources
 
 	// Get AI suggestions
 	suggestions, err := aiService.GenerateSuggestions(ctx, suggestionType, resources)
@@ -91,115 +91,12 @@ func generateSuggestions(ctx context.Context, client *crossplane.Client, aiServi
 	return nil
 }
 
-// generateMockSuggestions generates mock suggestions for testing using embedded data
-func generateMockSuggestions(ctx context.Context, suggestionType string) error {
-	fmt.Println("💡 AI Suggestions:")
-	fmt.Println("==================")
-
-	// Get embedded mock resources to make suggestions contextual
-	mockResources := ai.GetEmbeddedMockResources()
-
-	// Count resources by type for contextual suggestions
-	providerCount := 0
-	dbCount := 0
-	healthyResources := 0
-
-	for _, resource := range mockResources {
-		if resource.Type == "providers" {
-			providerCount++
-		}
-		if strings.Contains(strings.ToLower(resource.Type), "db") || strings.Contains(strings.ToLower(resource.Name), "database") {
-			dbCount++
-		}
-		if resource.Status == "Ready" {
-			healthyResources++
-		}
-	}
-
-	// Generate contextual suggestions based on suggestion type and mock resources
-	switch strings.ToLower(suggestionType) {
-	case "database", "db":
-		fmt.Printf("1. Enable Automated Backups for %d Database Resources\n", dbCount)
-		fmt.Println("   Configure automated backups for your RDS instances to ensure data protection")
-		fmt.Println("   Priority: High")
-		fmt.Println()
-		fmt.Println("2. Implement Read Replicas")
-		fmt.Println("   Add read replicas to distribute read traffic and improve performance")
-		fmt.Println("   Priority: Medium")
-		fmt.Println()
-		fmt.Printf("3. Configure Multi-AZ Deployment (Based on %d healthy resources)\n", healthyResources)
-		fmt.Println("   Enable Multi-AZ for high availability and automatic failover")
-		fmt.Println("   Priority: High")
-
-	case "security":
-		fmt.Println("1. Enable Encryption at Rest")
-		fmt.Println("   Encrypt your databases and storage resources to enhance security")
-		fmt.Println("   Priority: High")
-		fmt.Println()
-		fmt.Println("2. Review IAM Policies")
-		fmt.Println("   Audit and tighten IAM policies to follow principle of least privilege")
-		fmt.Println("   Priority: High")
-		fmt.Println()
-		fmt.Println("3. Enable VPC Security Groups")
-		fmt.Println("   Configure proper security groups to restrict network access")
-		fmt.Println("   Priority: Medium")
-
-	case "optimize", "optimization":
-		fmt.Println("1. Right-size Resources")
-		fmt.Println("   Analyze resource utilization and adjust instance sizes accordingly")
-		fmt.Println("   Priority: Medium")
-		fmt.Println()
-		fmt.Println("2. Implement Auto-scaling")
-		fmt.Println("   Configure auto-scaling groups to optimize resource usage")
-		fmt.Println("   Priority: Medium")
-		fmt.Println()
-		fmt.Println("3. Use Reserved Instances")
-		fmt.Println("   Consider reserved instances for predictable workloads to reduce costs")
-		fmt.Println("   Priority: Low")
-
-	case "network", "networking":
-		fmt.Println("1. Configure VPC Peering")
-		fmt.Println("   Set up VPC peering for secure cross-region communication")
-		fmt.Println("   Priority: Medium")
-		fmt.Println()
-		fmt.Println("2. Implement Load Balancers")
-		fmt.Println("   Use Application Load Balancers to distribute traffic efficiently")
-		fmt.Println("   Priority: High")
-		fmt.Println()
-		fmt.Println("3. Set up VPN Gateway")
-		fmt.Println("   Configure VPN gateway for secure on-premises connectivity")
-		fmt.Println("   Priority: Low")
-
-	default: // general
-		fmt.Printf("1. Health Check %d Resources\n", len(mockResources))
-		fmt.Println("   Run regular health checks on all Crossplane resources")
-		fmt.Println("   Priority: Medium")
-		fmt.Println()
-		fmt.Printf("2. Update %d Providers\n", providerCount)
-		fmt.Println("   Keep your Crossplane providers up to date for latest features and security fixes")
-		fmt.Println("   Priority: Low")
-		fmt.Println()
-		fmt.Println("3. Implement Monitoring")
-		fmt.Println("   Set up comprehensive monitoring for all your infrastructure resources")
-		fmt.Println("   Priority: High")
-		fmt.Println()
-		fmt.Println("4. Create Backup Strategy")
-		fmt.Println("   Develop and implement a comprehensive backup and disaster recovery plan")
-		fmt.Println("   Priority: High")
-	}
-
-	fmt.Println()
-	fmt.Println("🧪 These are mock suggestions using embedded sample data.")
-	fmt.Println("To get real suggestions, run without the --mock flag")
-
-	return nil
-}
-
 func init() {
 	rootCmd.AddCommand(suggestCmd)
 
 	suggestCmd.Flags().String("provider", "", "focus suggestions on specific provider")
 	suggestCmd.Flags().String("category", "", "suggestion category (security, performance, cost, reliability)")
 	suggestCmd.Flags().BoolP("detailed", "d", false, "show detailed suggestions with examples")
-	suggestCmd.Flags().IntP("limit", "l", 5, "maximum number of suggestions to show")
+	suggestCmd.Flags().Int("limit", 5, "maximum number of suggestions to show")
+	addFilterFlags(suggestCmd)
 }
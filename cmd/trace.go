@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var traceCmd = &cobra.Command{
+	Use:   "trace <kind>/<name>",
+	Short: "Show the resource tree of a claim or composite resource",
+	Long: `Walk from a claim to its composite resource (spec.resourceRef) and from the
+composite to every composed resource (spec.resourceRefs), recursing into
+nested composites. Each node shows its Ready and Synced state, and the deepest
+failing resource is highlighted as the likely root cause.
+
+The resource can be given as a name or as [<namespace>/]<kind>/<name>, where
+kind is the Kind, the plural or the plural qualified by its API group.`,
+	Example: `  # Trace a claim
+  crossplane-ai trace -n team-a postgresqlinstance/my-db
+
+  # Trace a composite resource as JSON
+  crossplane-ai trace xpostgresqlinstance/my-db-x7k2p -o json
+
+  # Render the tree with Graphviz
+  crossplane-ai trace postgresqlinstance/my-db -o dot | dot -Tsvg > tree.svg`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		return traceResource(ctx, client, args[0], output)
+	},
+}
+
+func traceResource(ctx context.Context, client *crossplane.Client, ref, output string) error {
+	resource, err := client.FindResource(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	tree, err := client.Trace(ctx, resource)
+	if err != nil {
+		return fmt.Errorf("failed to trace %s: %w", ref, err)
+	}
+
+	switch output {
+	case "tree", "":
+		printTraceTree(tree)
+	case "json":
+		data, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode tree: %w", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(tree)
+		if err != nil {
+			return fmt.Errorf("failed to encode tree: %w", err)
+		}
+		fmt.Print(string(data))
+	case "dot":
+		fmt.Print(traceDot(tree))
+	default:
+		return fmt.Errorf("unsupported output format %q (use tree, json, yaml or dot)", output)
+	}
+
+	return nil
+}
+
+func printTraceTree(root *crossplane.TraceNode) {
+	w := cli.CreateTable()
+	_, _ = fmt.Fprintln(w, "RESOURCE\tREADY\tSYNCED\tSTATUS")
+
+	var printNode func(node *crossplane.TraceNode, prefix, branch string)
+	printNode = func(node *crossplane.TraceNode, prefix, branch string) {
+		_, _ = fmt.Fprintf(w, "%s%s%s\t%s\t%s\t%s\n",
+			prefix, branch, node.ID(), traceState(node.Ready), traceState(node.Synced), traceStatus(node))
+
+		childPrefix := prefix
+		switch branch {
+		case "├── ":
+			childPrefix += "│   "
+		case "└── ":
+			childPrefix += "    "
+		}
+		for i, child := range node.Children {
+			if i == len(node.Children)-1 {
+				printNode(child, childPrefix, "└── ")
+			} else {
+				printNode(child, childPrefix, "├── ")
+			}
+		}
+	}
+	printNode(root, "", "")
+	_ = w.Flush()
+
+	if cause := root.FindRootCause(); cause != nil {
+		fmt.Println()
+		cli.PrintWarning(fmt.Sprintf("Root cause: %s %s", cause.ID(), traceStatus(cause)))
+	}
+}
+
+func traceState(state string) string {
+	if state == "" {
+		return "-"
+	}
+	return state
+}
+
+func traceStatus(node *crossplane.TraceNode) string {
+	var status string
+	switch {
+	case node.Missing:
+		status = "missing: " + node.Message
+	case node.Error != "":
+		status = "error: " + node.Error
+	case node.Reason != "" && node.Message != "":
+		status = fmt.Sprintf("%s: %s", node.Reason, node.Message)
+	default:
+		status = node.Reason
+	}
+	if node.RootCause {
+		status = "🔥 " + status
+	}
+	return status
+}
+
+// traceDot renders the tree as a Graphviz digraph
+func traceDot(root *crossplane.TraceNode) string {
+	var b strings.Builder
+	b.WriteString("digraph trace {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=filled, fontname=\"Helvetica\"];\n")
+
+	ids := make(map[*crossplane.TraceNode]string)
+	root.Walk(func(node *crossplane.TraceNode, _ int) {
+		id := fmt.Sprintf("n%d", len(ids))
+		ids[node] = id
+
+		color := "palegreen"
+		switch {
+		case node.RootCause:
+			color = "tomato"
+		case node.Failing():
+			color = "lightsalmon"
+		case node.Ready != "True":
+			color = "lightgrey"
+		}
+
+		label := fmt.Sprintf("%s\\nReady=%s Synced=%s", node.ID(), traceState(node.Ready), traceState(node.Synced))
+		if node.Reason != "" {
+			label += "\\n" + node.Reason
+		}
+		fmt.Fprintf(&b, "  %s [label=\"%s\", fillcolor=%q];\n", id, strings.ReplaceAll(label, `"`, `\"`), color)
+	})
+
+	root.Walk(func(node *crossplane.TraceNode, _ int) {
+		for _, child := range node.Children {
+			fmt.Fprintf(&b, "  %s -> %s;\n", ids[node], ids[child])
+		}
+	})
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func init() {
+	rootCmd.AddCommand(traceCmd)
+
+	traceCmd.Flags().StringP("output", "o", "tree", "output format (tree, json, yaml, dot)")
+}
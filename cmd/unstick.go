@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"crossplane-ai/pkg/ai"
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+var unstickCmd = &cobra.Command{
+	Use:   "unstick [<kind>/<name>]",
+	Short: "Explain why resources are stuck in deletion and offer the safe fix",
+	Long: `Find the resources marked for deletion that still exist and explain what keeps
+each of them: the finalizer nobody removes, or the Usage that protects one of
+its composed resources. For managed resources the conditions tell whether the
+external resource still exists, and the provider that should remove the
+finalizer is checked for health.
+
+Without a resource every terminating resource is diagnosed. Given a resource,
+unstick also offers to remove the finalizers that can go without leaving
+anything behind: the finalizer of a claim or composite whose children are
+gone, or of a managed resource whose external resource is orphaned anyway.
+Every removal is confirmed first; nothing is changed without an explicit yes.
+
+Removing the finalizer of a managed resource whose external resource still
+exists leaves that resource running without Crossplane managing it. --force
+allows removing such finalizers, after typing the resource name to confirm.`,
+	Example: `  # Diagnose every resource stuck in deletion
+  crossplane-ai unstick
+
+  # Diagnose one resource and remove its finalizers if that is safe
+  crossplane-ai unstick xpostgresqlinstance/payments-db-7xk2p
+
+  # Show what would be removed without asking
+  crossplane-ai unstick -n team-a objectstore/receipts --dry-run`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		output, _ := cmd.Flags().GetString("output")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		report, err := client.DeletionReport(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check resources stuck in deletion: %w", err)
+		}
+
+		if len(args) > 0 {
+			t, err := findTerminating(ctx, client, report, args[0])
+			if err != nil {
+				return err
+			}
+			report = &crossplane.DeletionReport{Terminating: []*crossplane.TerminatingResource{t}, Failures: report.Failures}
+		}
+
+		switch output {
+		case "table", "":
+			printTerminating(report)
+		case "json":
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		case "yaml":
+			data, err := yaml.Marshal(report)
+			if err != nil {
+				return fmt.Errorf("failed to encode report: %w", err)
+			}
+			fmt.Print(string(data))
+			return nil
+		default:
+			return fmt.Errorf("unsupported output format %q (use table, json or yaml)", output)
+		}
+
+		if len(args) == 0 {
+			if len(report.Terminating) > 0 {
+				fmt.Println()
+				cli.PrintInfo("Run 'crossplane-ai unstick <kind>/<name>' to remove the finalizers of a resource that are safe to remove")
+			}
+			return nil
+		}
+		return removeFinalizers(ctx, client, report.Terminating[0], dryRun, force)
+	},
+}
+
+// findTerminating returns the terminating resource matching a reference and
+// explains why there is none
+func findTerminating(ctx context.Context, client *crossplane.Client, report *crossplane.DeletionReport, ref string) (*crossplane.TerminatingResource, error) {
+	resources := make([]*crossplane.Resource, len(report.Terminating))
+	for i, t := range report.Terminating {
+		resources[i] = t.Resource
+	}
+
+	matches := crossplane.MatchResources(resources, ref)
+	switch len(matches) {
+	case 0:
+		resource, err := client.FindResource(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s is not being deleted", crossplane.QualifiedName(resource))
+	case 1:
+		return report.Terminating[slices.Index(resources, matches[0])], nil
+	default:
+		var candidates []string
+		for _, match := range matches {
+			candidates = append(candidates, crossplane.QualifiedName(match))
+		}
+		return nil, fmt.Errorf("resource %q is ambiguous, use one of: %s", ref, strings.Join(candidates, ", "))
+	}
+}
+
+func printTerminating(report *crossplane.DeletionReport) {
+	printListFailures(&crossplane.ListResult{Failures: report.Failures})
+
+	if len(report.Terminating) == 0 {
+		cli.PrintSuccess("No resources are stuck in deletion")
+		return
+	}
+
+	cli.PrintHeader(fmt.Sprintf("Resources stuck in deletion (%d)", len(report.Terminating)))
+	for _, t := range report.Terminating {
+		cli.PrintSubHeader(fmt.Sprintf("%s (deleting for %s)", t.Ref, cli.FormatSince(t.Since)))
+		fmt.Printf("  Finalizers:  %s\n", orDash(strings.Join(t.Finalizers, ", ")))
+		if t.Category == crossplane.CategoryManaged {
+			fmt.Printf("  External:    %s (deletionPolicy %s)\n", t.External, t.DeletionPolicy)
+			provider := "unknown"
+			switch {
+			case t.Provider != "" && t.ProviderProblem != "":
+				provider = fmt.Sprintf("%s, not healthy (%s)", t.Provider, t.ProviderProblem)
+			case t.Provider != "":
+				provider = t.Provider + ", healthy"
+			}
+			fmt.Printf("  Provider:    %s\n", provider)
+		}
+
+		if len(t.Blockers) > 0 {
+			fmt.Println("  Blocked by:")
+			for _, blocker := range t.Blockers {
+				label := "Usage"
+				if blocker.Finalizer != "" {
+					label = blocker.Finalizer
+				}
+				safe := ""
+				if blocker.Removable {
+					safe = " (safe to remove)"
+				}
+				fmt.Printf("    • %s%s: %s\n", label, safe, blocker.Message)
+			}
+		}
+		if len(t.Blocks) > 0 {
+			names := make([]string, len(t.Blocks))
+			for i, ref := range t.Blocks {
+				names[i] = ref.String()
+			}
+			fmt.Printf("  Blocks:      %s\n", strings.Join(names, ", "))
+		}
+
+		if t.Cascading {
+			fmt.Println("  Waits only for resources that are being deleted themselves; fix those first.")
+			continue
+		}
+		if steps := ai.DeletionRemediation(t); len(steps) > 0 {
+			fmt.Println("  Remediation:")
+			for _, step := range steps {
+				fmt.Printf("    → %s\n", step)
+			}
+		}
+	}
+}
+
+// removeFinalizers offers to remove the finalizers of a terminating
+// resource: the safe ones after a yes, and with --force the others after
+// the resource name is typed
+func removeFinalizers(ctx context.Context, client *crossplane.Client, t *crossplane.TerminatingResource, dryRun, force bool) error {
+	safe := t.RemovableFinalizers()
+	candidates := safe
+	if force {
+		candidates = t.Finalizers
+	}
+
+	fmt.Println()
+	if len(candidates) == 0 {
+		cli.PrintInfo("No finalizer can be removed safely; follow the remediation above (--force removes the others after confirmation)")
+		return nil
+	}
+	if dryRun {
+		for _, finalizer := range candidates {
+			fmt.Printf("Would remove finalizer %s from %s\n", finalizer, t.Ref)
+		}
+		return nil
+	}
+	if client.Source().ReadOnly() {
+		cli.PrintWarning(fmt.Sprintf("%s is read-only (mock data or a snapshot); nothing is removed", client.Source().Cluster()))
+		return nil
+	}
+
+	for _, finalizer := range candidates {
+		if slices.Contains(safe, finalizer) {
+			answer := cli.PromptUser(fmt.Sprintf("Remove finalizer %s from %s? [y/N]: ", finalizer, t.Ref))
+			if !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+				fmt.Println("Skipped.")
+				continue
+			}
+		} else {
+			cli.PrintWarning(fmt.Sprintf("Removing %s is not safe: %s", finalizer, blockerMessage(t, finalizer)))
+			if finalizer == crossplane.FinalizerManagedResource {
+				cli.PrintWarning("The external resource is left behind, no longer managed by Crossplane, and must be deleted by hand.")
+			}
+			answer := cli.PromptUser(fmt.Sprintf("Type the resource name (%s) to remove it anyway: ", t.Ref.Name))
+			if answer != t.Ref.Name {
+				fmt.Println("Skipped.")
+				continue
+			}
+		}
+
+		if err := client.RemoveFinalizer(ctx, t.Resource, finalizer); err != nil {
+			return err
+		}
+		cli.PrintSuccess(fmt.Sprintf("Removed finalizer %s from %s", finalizer, t.Ref))
+	}
+
+	return nil
+}
+
+func blockerMessage(t *crossplane.TerminatingResource, finalizer string) string {
+	for _, blocker := range t.Blockers {
+		if blocker.Finalizer == finalizer {
+			return blocker.Message
+		}
+	}
+	return "it is not known who removes it"
+}
+
+func init() {
+	rootCmd.AddCommand(unstickCmd)
+
+	unstickCmd.Flags().StringP("output", "o", "table", "output format (table, json, yaml)")
+	unstickCmd.Flags().Bool("dry-run", false, "only show the finalizers that would be removed")
+	unstickCmd.Flags().Bool("force", false, "also offer finalizers that are not safe to remove, confirmed by typing the resource name")
+}
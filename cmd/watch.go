@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"crossplane-ai/pkg/cli"
+	"crossplane-ai/pkg/crossplane"
+
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Stream Crossplane resource state changes as they happen",
+	Long: `Watch every discovered Crossplane resource type and print a line whenever a
+resource is created or deleted, or its Ready or Synced state changes, together
+with the reason and message of the failing condition.`,
+	Example: `  # Watch all resources
+  crossplane-ai watch
+
+  # Watch claims and composites in one namespace
+  crossplane-ai watch -n team-a
+
+  # Stream transitions as JSON lines
+  crossplane-ai watch -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			return fmt.Errorf("unsupported output format %q (use text or json)", output)
+		}
+
+		client, err := newCrossplaneClient(ctx, cmd)
+		if err != nil {
+			return err
+		}
+
+		return watchResources(ctx, client, output)
+	},
+}
+
+func watchResources(ctx context.Context, client *crossplane.Client, output string) error {
+	if output == "text" {
+		cli.PrintInfo("Starting watches...")
+	}
+
+	cache, err := client.StartCache(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start watches: %w", err)
+	}
+	defer client.StopCache()
+
+	// Handlers run on informer goroutines; print from a single goroutine
+	transitions := make(chan crossplane.Transition, 100)
+	cache.OnTransition(func(t crossplane.Transition) {
+		select {
+		case transitions <- t:
+		case <-ctx.Done():
+		}
+	})
+
+	if output == "text" {
+		result := cache.List()
+		printListFailures(result)
+		notReady := 0
+		for _, resource := range result.Resources {
+			if crossplane.NeedsAttention(resource) {
+				notReady++
+			}
+		}
+		cli.PrintInfo(fmt.Sprintf("Watching %d resources of %d types (%d need attention). Press Ctrl+C to stop.",
+			len(result.Resources), cache.Types(), notReady))
+		fmt.Println()
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case t := <-transitions:
+			if output == "json" {
+				if err := encoder.Encode(t); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Printf("%s %s %s\n", t.Time.Format("15:04:05"), transitionIcon(t), t)
+		}
+	}
+}
+
+func transitionIcon(t crossplane.Transition) string {
+	switch {
+	case t.To == "Deleted":
+		return "🗑️ "
+	case t.To == "Ready" && t.SyncedTo != "False":
+		return "✅"
+	case t.To == "Not Ready" || t.To == "Deleting" || t.SyncedTo == "False":
+		return "⚠️ "
+	}
+	return "• "
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringP("output", "o", "text", "output format (text, json)")
+}
@@ -15,6 +15,9 @@ type Config struct {
 		APIKey   string `yaml:"api_key" mapstructure:"api_key"`
 		Model    string `yaml:"model" mapstructure:"model"`
 		BaseURL  string `yaml:"base_url" mapstructure:"base_url"`
+		// ContextWindow overrides the context length, in tokens, of a
+		// local model
+		ContextWindow int `yaml:"context_window" mapstructure:"context_window"`
 	} `yaml:"ai" mapstructure:"ai"`
 
 	Kubernetes struct {
@@ -100,19 +103,17 @@ func Get() *Config {
 func setDefaults() {
 	// AI defaults
 	viper.SetDefault("ai.provider", "mock")
-	viper.SetDefault("ai.model", "gpt-4")
+	// An empty model selects the default model of the provider
+	viper.SetDefault("ai.model", "")
 
-	// Kubernetes defaults
-	if home, err := os.UserHomeDir(); err == nil {
-		viper.SetDefault("kubernetes.kubeconfig", filepath.Join(home, ".kube", "config"))
-	}
+	// Kubernetes defaults: an empty kubeconfig uses the standard loading
+	// rules (KUBECONFIG, then ~/.kube/config), an empty namespace means all
+	// namespaces
 
-	// Crossplane defaults
-	viper.SetDefault("crossplane.providers", []string{"aws", "gcp", "azure", "kubernetes"})
-	viper.SetDefault("crossplane.resource_types", []string{
-		"compositions", "providers", "configurations",
-		"dbinstances", "instances", "buckets", "clusters",
-	})
+	// Crossplane defaults: empty include lists mean every discovered
+	// provider and resource type
+	viper.SetDefault("crossplane.providers", []string{})
+	viper.SetDefault("crossplane.resource_types", []string{})
 
 	// CLI defaults
 	viper.SetDefault("cli.output_format", "table")
@@ -129,17 +130,6 @@ func setDefaults() {
 func getDefaultConfig() *Config {
 	config := &Config{}
 	config.AI.Provider = "mock"
-	config.AI.Model = "gpt-4"
-
-	if home, err := os.UserHomeDir(); err == nil {
-		config.Kubernetes.Kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-
-	config.Crossplane.Providers = []string{"aws", "gcp", "azure", "kubernetes"}
-	config.Crossplane.ResourceTypes = []string{
-		"compositions", "providers", "configurations",
-		"dbinstances", "instances", "buckets", "clusters",
-	}
 
 	config.CLI.OutputFormat = "table"
 	config.CLI.Verbose = false
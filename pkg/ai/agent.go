@@ -0,0 +1,383 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// DefaultAgentSteps is the number of model turns an agent may take to call
+// tools before it has to answer
+const DefaultAgentSteps = 8
+
+// maxToolResult caps the JSON of a tool result sent to the model, in
+// characters
+const maxToolResult = 24000
+
+const agentPrompt = `You are an expert Crossplane troubleshooting assistant with read-only access to a Kubernetes cluster through tools. Before answering, call the tools to fetch the resources, objects, events, resource trees and providers the question is about, starting broad and narrowing down: list the resources that need attention, trace a claim or composite to its failing composed resource, read its conditions and events, and check the health of its provider. Base the answer only on what the tools returned, quote the reasons and messages of conditions and events, and say what you could not find instead of guessing. When you know enough, answer concisely without calling more tools.
+
+` + pipelineGuidance
+
+// ClusterReader is the read-only access to a cluster the tools of an agent
+// have; *crossplane.Client implements it
+type ClusterReader interface {
+	ListResources(ctx context.Context, filter crossplane.ResourceFilter) (*crossplane.ListResult, error)
+	FindResource(ctx context.Context, ref string) (*crossplane.Resource, error)
+	GetEvents(ctx context.Context, resource *crossplane.Resource) ([]crossplane.Event, error)
+	Trace(ctx context.Context, resource *crossplane.Resource) (*crossplane.TraceNode, error)
+	ListProviders(ctx context.Context) ([]*crossplane.PackageInfo, error)
+}
+
+// AgentStep is a tool call made by an agent, for a transcript
+type AgentStep struct {
+	// Step is the model turn, from 1
+	Step int
+	// Thought is the text the model sent along with its tool calls
+	Thought   string
+	Tool      string
+	Arguments string
+	// Result is the JSON returned to the model
+	Result string
+	Err    error
+}
+
+// Agent answers questions by letting the model call read-only tools on a
+// cluster until it can answer, instead of sending it every resource up
+// front
+type Agent struct {
+	service *Service
+	cluster ClusterReader
+	// MaxSteps is the number of model turns that may call tools
+	MaxSteps int
+	// Scope narrows every listing of the tools to the resources the user
+	// selected; its fields take precedence over the model's filters, and
+	// label selectors are combined
+	Scope crossplane.ResourceFilter
+	// OnStep is called after every tool call, e.g. to show a transcript
+	OnStep func(AgentStep)
+}
+
+// NewAgent creates an agent answering with the model of service
+func NewAgent(service *Service, cluster ClusterReader) *Agent {
+	return &Agent{
+		service:  service,
+		cluster:  cluster,
+		MaxSteps: DefaultAgentSteps,
+	}
+}
+
+// agentTool is a tool of an agent and the function running it
+type agentTool struct {
+	Tool
+	run func(ctx context.Context, cluster ClusterReader, args map[string]interface{}) (interface{}, error)
+}
+
+// Answer answers a question. When the model has not answered after
+// MaxSteps turns it is asked to answer with what it has found.
+func (a *Agent) Answer(ctx context.Context, query string) (string, error) {
+	if a.service.llm == nil {
+		return "", errors.New("agent mode needs an AI provider")
+	}
+
+	tools := make([]Tool, 0, len(agentTools))
+	for _, tool := range agentTools {
+		tools = append(tools, tool.Tool)
+	}
+	budget := a.resultBudget(ctx)
+	cluster := a.cluster
+	if !a.Scope.IsZero() {
+		cluster = scopedCluster{ClusterReader: a.cluster, scope: a.Scope}
+	}
+
+	messages := []Message{{Role: "user", Content: query}}
+	for step := 1; ; step++ {
+		if step > a.MaxSteps {
+			// Tools stay defined, as backends reject conversations with
+			// tool calls otherwise
+			messages = append(messages, Message{
+				Role:    "user",
+				Content: "You have used all your tool calls. Answer the question now with what you have found, without calling tools.",
+			})
+		}
+
+		completion, err := a.service.complete(ctx, CompletionRequest{
+			System:      agentPrompt,
+			Messages:    messages,
+			Tools:       tools,
+			Temperature: 0.2,
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(completion.ToolCalls) == 0 {
+			return completion.Content, nil
+		}
+		if step > a.MaxSteps {
+			if completion.Content != "" {
+				return completion.Content, nil
+			}
+			return "", fmt.Errorf("no answer after %d steps", a.MaxSteps)
+		}
+
+		messages = append(messages, Message{Role: "assistant", Content: completion.Content, ToolCalls: completion.ToolCalls})
+		for _, call := range completion.ToolCalls {
+			result, err := runAgentTool(ctx, cluster, call, budget)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			if a.OnStep != nil {
+				a.OnStep(AgentStep{
+					Step:      step,
+					Thought:   completion.Content,
+					Tool:      call.Name,
+					Arguments: string(call.Arguments),
+					Result:    result,
+					Err:       err,
+				})
+			}
+			messages = append(messages, Message{Role: "tool", ToolCallID: call.ID, Content: result})
+		}
+	}
+}
+
+// scopedCluster is a ClusterReader that lists only the resources within
+// scope
+type scopedCluster struct {
+	ClusterReader
+	scope crossplane.ResourceFilter
+}
+
+func (c scopedCluster) ListResources(ctx context.Context, filter crossplane.ResourceFilter) (*crossplane.ListResult, error) {
+	scope := c.scope
+	if scope.LabelSelector != "" && filter.LabelSelector != "" {
+		scope.LabelSelector += "," + filter.LabelSelector
+	}
+	for _, field := range []struct{ scoped, requested *string }{
+		{&scope.Name, &filter.Name},
+		{&scope.Provider, &filter.Provider},
+		{&scope.Namespace, &filter.Namespace},
+		{&scope.LabelSelector, &filter.LabelSelector},
+		{&scope.FieldSelector, &filter.FieldSelector},
+		{&scope.Status, &filter.Status},
+	} {
+		if *field.scoped == "" {
+			*field.scoped = *field.requested
+		}
+	}
+	if len(scope.Types) == 0 {
+		scope.Types = filter.Types
+	}
+	if scope.OlderThan == 0 {
+		scope.OlderThan = filter.OlderThan
+	}
+	return c.ClusterReader.ListResources(ctx, scope)
+}
+
+// resultBudget returns the size of the JSON of a tool result, smaller for
+// models with a small context window
+func (a *Agent) resultBudget(ctx context.Context) int {
+	windowed, ok := a.service.llm.(contextWindowed)
+	if !ok {
+		return maxToolResult
+	}
+	window, err := windowed.ContextWindow(ctx)
+	if err != nil || window == 0 {
+		return maxToolResult
+	}
+	return min(maxToolResult, window*charsPerToken/4)
+}
+
+// runAgentTool runs a tool call and returns the JSON of its result, or of
+// the error for the model to correct its call
+func runAgentTool(ctx context.Context, cluster ClusterReader, call ToolCall, budget int) (string, error) {
+	var result interface{}
+	var err error
+	args := map[string]interface{}{}
+	if err = json.Unmarshal(call.Arguments, &args); err != nil {
+		err = fmt.Errorf("invalid arguments: %w", err)
+	} else if tool, ok := findAgentTool(call.Name); !ok {
+		err = fmt.Errorf("unknown tool %q", call.Name)
+	} else {
+		result, err = tool.run(ctx, cluster, args)
+	}
+	if err != nil {
+		encoded, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(encoded), err
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return `{"error": "failed to encode the result"}`, err
+	}
+	shrunk := shrinkResourceJSON(string(encoded), budget)
+	if len(shrunk) > budget {
+		shrunk = truncateUTF8(shrunk, budget) + "… (truncated)"
+	}
+	return shrunk, nil
+}
+
+func findAgentTool(name string) (agentTool, bool) {
+	for _, tool := range agentTools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return agentTool{}, false
+}
+
+// refParameter is the schema of the resource reference argument of a tool
+var refParameter = map[string]interface{}{
+	"type":        "string",
+	"description": "The resource as <kind>/<name>, e.g. dbinstance/prod-db or xpostgresqlinstance/my-db-x7k2p, or its bare name",
+}
+
+// agentTools are the read-only tools of an agent
+var agentTools = []agentTool{
+	{
+		Tool: Tool{
+			Name:        "list_resources",
+			Description: "List Crossplane resources (claims, composites, managed resources, providers, compositions, functions) with their Ready and Synced state, conditions and age. Filters narrow the list down.",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":      map[string]interface{}{"type": "string", "description": "Exact name, glob such as prod-* or /regex/"},
+					"provider":  map[string]interface{}{"type": "string", "description": "Provider family, e.g. aws, gcp or azure"},
+					"namespace": map[string]interface{}{"type": "string", "description": "Namespace of namespaced resources"},
+					"selector":  map[string]interface{}{"type": "string", "description": "Label selector, e.g. team=payments"},
+					"types": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Resource types by kind, plural or plural.group",
+					},
+					"status": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"ready", "not-ready", "unknown", "unsynced", "deleting", "unhealthy"},
+						"description": "Only resources in this state; unhealthy is anything that needs attention",
+					},
+				},
+			},
+		},
+		run: func(ctx context.Context, cluster ClusterReader, args map[string]interface{}) (interface{}, error) {
+			filter := crossplane.ResourceFilter{}
+			filter.Name, _ = args["name"].(string)
+			filter.Provider, _ = args["provider"].(string)
+			filter.Namespace, _ = args["namespace"].(string)
+			filter.LabelSelector, _ = args["selector"].(string)
+			filter.Status, _ = args["status"].(string)
+			if types, ok := args["types"].([]interface{}); ok {
+				for _, t := range types {
+					if t, ok := t.(string); ok && t != "" {
+						filter.Types = append(filter.Types, t)
+					}
+				}
+			}
+
+			result, err := cluster.ListResources(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			resources := make([]*ResourceInfo, 0, len(result.Resources))
+			for _, resource := range result.Resources {
+				resources = append(resources, NewResourceInfo(resource))
+			}
+			list := map[string]interface{}{"resources": resources}
+			if result.Partial() {
+				list["failures"] = result.Failures
+			}
+			return list, nil
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "get_resource",
+			Description: "Get the full object of a resource as stored in the cluster, with its spec (forProvider, resourceRefs, compositionRef, providerConfigRef) and status (conditions, atProvider).",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"resource": refParameter},
+				"required":   []string{"resource"},
+			},
+		},
+		run: func(ctx context.Context, cluster ClusterReader, args map[string]interface{}) (interface{}, error) {
+			resource, err := findAgentResource(ctx, cluster, args)
+			if err != nil {
+				return nil, err
+			}
+			if resource.Raw == nil {
+				return resource, nil
+			}
+			object := resource.Raw.DeepCopy()
+			object.SetManagedFields(nil)
+			annotations := object.GetAnnotations()
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			object.SetAnnotations(annotations)
+			return object.Object, nil
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "get_events",
+			Description: "Get the recent Kubernetes events of a resource, and for providers those of their revisions and pods.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"resource": refParameter},
+				"required":   []string{"resource"},
+			},
+		},
+		run: func(ctx context.Context, cluster ClusterReader, args map[string]interface{}) (interface{}, error) {
+			resource, err := findAgentResource(ctx, cluster, args)
+			if err != nil {
+				return nil, err
+			}
+			events, err := cluster.GetEvents(ctx, resource)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"resource": crossplane.QualifiedName(resource), "events": events}, nil
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "trace",
+			Description: "Trace a claim or composite resource to its composite and composed resources, recursively, with the Ready and Synced state of each and the deepest failing resource marked as rootCause.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"resource": refParameter},
+				"required":   []string{"resource"},
+			},
+		},
+		run: func(ctx context.Context, cluster ClusterReader, args map[string]interface{}) (interface{}, error) {
+			resource, err := findAgentResource(ctx, cluster, args)
+			if err != nil {
+				return nil, err
+			}
+			return cluster.Trace(ctx, resource)
+		},
+	},
+	{
+		Tool: Tool{
+			Name:        "get_providers",
+			Description: "Get the installed providers with their package, version, Installed and Healthy conditions, revisions and runtime config.",
+			Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		run: func(ctx context.Context, cluster ClusterReader, _ map[string]interface{}) (interface{}, error) {
+			return cluster.ListProviders(ctx)
+		},
+	},
+}
+
+// findAgentResource looks up the resource argument of a tool call
+func findAgentResource(ctx context.Context, cluster ClusterReader, args map[string]interface{}) (*crossplane.Resource, error) {
+	ref, _ := args["resource"].(string)
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, errors.New("resource is required")
+	}
+	return cluster.FindResource(ctx, ref)
+}
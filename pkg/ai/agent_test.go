@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// listRecorder is a ClusterReader recording the filters it lists with
+type listRecorder struct {
+	ClusterReader
+	resources []*crossplane.Resource
+	filters   []crossplane.ResourceFilter
+}
+
+func (r *listRecorder) ListResources(_ context.Context, filter crossplane.ResourceFilter) (*crossplane.ListResult, error) {
+	r.filters = append(r.filters, filter)
+	return &crossplane.ListResult{Resources: r.resources}, nil
+}
+
+func TestScopedClusterListResources(t *testing.T) {
+	scope := crossplane.ResourceFilter{LabelSelector: "team=payments", Status: "unhealthy", Namespace: "prod"}
+	tests := []struct {
+		name      string
+		requested crossplane.ResourceFilter
+		want      crossplane.ResourceFilter
+	}{
+		{
+			name:      "no filter",
+			requested: crossplane.ResourceFilter{},
+			want:      scope,
+		},
+		{
+			name:      "narrower filter",
+			requested: crossplane.ResourceFilter{Name: "db-*", Types: []string{"dbinstance"}},
+			want:      crossplane.ResourceFilter{Name: "db-*", Types: []string{"dbinstance"}, LabelSelector: "team=payments", Status: "unhealthy", Namespace: "prod"},
+		},
+		{
+			name:      "conflicting filter",
+			requested: crossplane.ResourceFilter{LabelSelector: "env=prod", Status: "ready", Namespace: "dev"},
+			want:      crossplane.ResourceFilter{LabelSelector: "team=payments,env=prod", Status: "unhealthy", Namespace: "prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &listRecorder{}
+			cluster := scopedCluster{ClusterReader: recorder, scope: scope}
+			if _, err := cluster.ListResources(context.Background(), tt.requested); err != nil {
+				t.Fatalf("ListResources: %v", err)
+			}
+			if !reflect.DeepEqual(recorder.filters, []crossplane.ResourceFilter{tt.want}) {
+				t.Errorf("listed with %+v, want %+v", recorder.filters, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc"},
+		// ü is two bytes and ✓ three
+		{"grüße", 3, "gr"},
+		{"ok ✓✓", 5, "ok "},
+		{"✓", 2, ""},
+	}
+	for _, tt := range tests {
+		got := truncateUTF8(tt.text, tt.n)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRunAgentToolTruncatesOnRuneBoundary(t *testing.T) {
+	// A long name of multi-byte characters that shrinking cannot fit
+	recorder := &listRecorder{resources: []*crossplane.Resource{{Kind: "Bucket", Name: strings.Repeat("é", 500)}}}
+	call := ToolCall{Name: "list_resources", Arguments: []byte(`{}`)}
+	result, err := runAgentTool(context.Background(), recorder, call, 101)
+	if err != nil {
+		t.Fatalf("runAgentTool: %v", err)
+	}
+	if !strings.HasSuffix(result, "… (truncated)") {
+		t.Errorf("result %q is not truncated", result)
+	}
+	if !utf8.ValidString(result) {
+		t.Errorf("result %q is not valid UTF-8", result)
+	}
+}
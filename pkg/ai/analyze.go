@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// AnalysisSource is the access to one or more clusters AnalyzeCluster
+// needs; *crossplane.Client and *crossplane.MultiClient implement it
+type AnalysisSource interface {
+	ListResources(ctx context.Context, filter crossplane.ResourceFilter) (*crossplane.ListResult, error)
+	AttachEvents(ctx context.Context, resources []*crossplane.Resource, limit int)
+	ProviderConfigReport(ctx context.Context) (*crossplane.ProviderConfigReport, error)
+	FunctionReport(ctx context.Context) (*crossplane.FunctionReport, error)
+	DeletionReport(ctx context.Context) (*crossplane.DeletionReport, error)
+}
+
+// ClusterAnalysis is the result of AnalyzeCluster
+type ClusterAnalysis struct {
+	*Analysis
+	// Listing holds the analyzed resources and the resource types that
+	// could not be listed
+	Listing *crossplane.ListResult
+	// Warnings name the cluster-wide checks that could not be run
+	Warnings []string
+}
+
+// AnalyzeCluster lists the resources matching filter and analyzes them
+// with their events and drift, reporting the failures of a claim or
+// composite once under its root. When nothing is filtered out the checks
+// that concern the whole cluster run too. The analysis of an empty listing
+// has no issues; callers check Listing for types that failed to list.
+func (s *Service) AnalyzeCluster(ctx context.Context, source AnalysisSource,
+	filter crossplane.ResourceFilter, healthCheck bool) (*ClusterAnalysis, error) {
+
+	result, err := source.ListResources(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resources: %w", err)
+	}
+	resources := result.Resources
+
+	// Include recent events of unhealthy resources so that issues can cite
+	// the provider's error messages
+	source.AttachEvents(ctx, resources, crossplane.MaxEventResources)
+	crossplane.AttachDrift(resources)
+
+	analysis, err := s.AnalyzeResources(ctx, resources, healthCheck)
+	if err != nil {
+		return nil, err
+	}
+	report := &ClusterAnalysis{Analysis: analysis, Listing: result}
+	if len(resources) == 0 {
+		return report, nil
+	}
+
+	// Report failures inside a claim or composite once, under its root. A
+	// resource missing from a filtered or partial listing may still exist.
+	complete := filter.IsZero() && !result.Partial()
+	GroupByRootCause(analysis, crossplane.BuildTrees(resources, complete))
+
+	// Drift is a property of each managed resource, so it is checked for
+	// filtered results too
+	AppendIssues(analysis, DriftIssues(crossplane.BuildDriftReport(resources))...)
+
+	// ProviderConfigs, their credentials, composition functions and the
+	// chains of resources stuck in deletion concern the whole cluster, so
+	// they are only checked when nothing is filtered out
+	if filter.IsZero() {
+		configs, err := source.ProviderConfigReport(ctx)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Could not check ProviderConfigs: %v", err))
+		} else {
+			AppendIssues(analysis, ProviderConfigIssues(configs)...)
+		}
+
+		functions, err := source.FunctionReport(ctx)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Could not check composition functions: %v", err))
+		} else {
+			AppendIssues(analysis, FunctionIssues(functions)...)
+		}
+
+		deletions, err := source.DeletionReport(ctx)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Could not check resources stuck in deletion: %v", err))
+		} else {
+			AppendIssues(analysis, DeletionIssues(deletions)...)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,358 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// anthropicVersion is the version of the Messages API requests are sent as
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens is sent when a request does not set MaxTokens, which
+// the Messages API requires
+const anthropicMaxTokens = 4096
+
+// AnthropicConfig represents Anthropic configuration
+type AnthropicConfig struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+	// Timeout bounds a reply that is not streamed, including the requests
+	// retried without an output mode the model rejects, and the wait for a
+	// streamed reply to start
+	Timeout time.Duration
+}
+
+// AnthropicClient is the LLMProvider of the Anthropic Messages API
+type AnthropicClient struct {
+	config       AnthropicConfig
+	httpClient   *http.Client
+	streamClient *http.Client
+}
+
+// AnthropicRequest represents a request to the Messages API
+type AnthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []AnthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature,omitempty"`
+	Tools       []AnthropicTool    `json:"tools,omitempty"`
+	ToolChoice  *AnthropicToolUse  `json:"tool_choice,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+// AnthropicMessage represents a message of a Messages API conversation;
+// Content is a string or, for tool calls and their results, a list of
+// AnthropicContentBlock
+type AnthropicMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// AnthropicContentBlock is a text, tool_use or tool_result block of a
+// message
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// ID, Name and Input are set on tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID and Content are set on tool_result blocks
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// AnthropicToolUse makes the model call the tool Name with type "tool"
+type AnthropicToolUse struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// AnthropicTool is a tool offered to the model
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// AnthropicResponse represents a response from the Messages API
+type AnthropicResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Model      string                  `json:"model"`
+	Content    []AnthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// AnthropicStreamEvent is a server-sent event of a streamed message; the
+// fields used depend on its type
+type AnthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewAnthropicClient creates a new Anthropic client
+func NewAnthropicClient(config AnthropicConfig) *AnthropicClient {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.anthropic.com/v1"
+	}
+	if config.Model == "" {
+		config.Model = "claude-sonnet-4-5"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+
+	return &AnthropicClient{
+		config:       config,
+		httpClient:   &http.Client{},
+		streamClient: newStreamingHTTPClient(config.Timeout),
+	}
+}
+
+// Name returns the display name of the backend
+func (c *AnthropicClient) Name() string {
+	return "Anthropic"
+}
+
+// Model returns the model requests are sent to
+func (c *AnthropicClient) Model() string {
+	return c.config.Model
+}
+
+// Complete sends a conversation to the Messages API. The API has no JSON
+// mode, so a JSON reply is requested by starting the assistant's turn with
+// the opening brace, and a reply with a schema by making the model call a
+// tool whose input has the schema.
+func (c *AnthropicClient) Complete(ctx context.Context, request CompletionRequest) (*Completion, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	anthropicRequest, prefill := c.newRequest(request)
+
+	var response AnthropicResponse
+	if err := postJSON(ctx, c.httpClient, c.config.BaseURL+"/messages", c.headers(),
+		anthropicRequest, &response, anthropicErrorMessage); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range response.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			if request.Schema != nil && block.Name == request.Schema.Name {
+				text.Write(block.Input)
+				continue
+			}
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	if text.Len() == 0 && len(toolCalls) == 0 {
+		return nil, fmt.Errorf("no text content returned (stop reason %q)", response.StopReason)
+	}
+
+	content := text.String()
+	if content != "" {
+		content = prefill + content
+	}
+	return &Completion{
+		Content:    content,
+		ToolCalls:  toolCalls,
+		StopReason: response.StopReason,
+		Usage: Usage{
+			InputTokens:  response.Usage.InputTokens,
+			OutputTokens: response.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// Stream sends a conversation to the Messages API with stream set and
+// passes the text deltas to onToken, starting with the prefill of a JSON
+// reply
+func (c *AnthropicClient) Stream(ctx context.Context, request CompletionRequest, onToken func(string)) (*Completion, error) {
+	anthropicRequest, prefill := c.newRequest(request)
+	anthropicRequest.Stream = true
+
+	body, err := openStream(ctx, c.streamClient, c.config.BaseURL+"/messages", c.headers(),
+		anthropicRequest, anthropicErrorMessage)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	var text strings.Builder
+	completion := &Completion{}
+	err = readEvents(body, func(_, data string) (bool, error) {
+		var event AnthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return false, fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			completion.Usage.InputTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				if text.Len() == 0 && prefill != "" {
+					onToken(prefill)
+				}
+				text.WriteString(event.Delta.Text)
+				onToken(event.Delta.Text)
+			}
+		case "message_delta":
+			completion.StopReason = event.Delta.StopReason
+			completion.Usage.OutputTokens = event.Usage.OutputTokens
+		case "message_stop":
+			return true, nil
+		case "error":
+			return false, fmt.Errorf("stream failed: %s: %s", event.Error.Type, event.Error.Message)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no text content returned (stop reason %q)", completion.StopReason)
+	}
+
+	completion.Content = prefill + text.String()
+	return completion, nil
+}
+
+func (c *AnthropicClient) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         c.config.APIKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
+
+// newRequest translates a CompletionRequest to the Messages API and
+// returns the prefill of the assistant's turn
+func (c *AnthropicClient) newRequest(request CompletionRequest) (AnthropicRequest, string) {
+	anthropicRequest := AnthropicRequest{
+		Model:       c.config.Model,
+		System:      request.System,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+	}
+	if anthropicRequest.MaxTokens == 0 {
+		anthropicRequest.MaxTokens = anthropicMaxTokens
+	}
+	for _, message := range request.Messages {
+		switch {
+		case message.Role == "tool":
+			anthropicRequest.Messages = appendAnthropicBlocks(anthropicRequest.Messages, "user", AnthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: message.ToolCallID,
+				Content:   message.Content,
+			})
+		case len(message.ToolCalls) > 0:
+			var blocks []AnthropicContentBlock
+			if message.Content != "" {
+				blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: message.Content})
+			}
+			for _, call := range message.ToolCalls {
+				input := call.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, AnthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+			anthropicRequest.Messages = appendAnthropicBlocks(anthropicRequest.Messages, message.Role, blocks...)
+		default:
+			anthropicRequest.Messages = appendAnthropicText(anthropicRequest.Messages, message.Role, message.Content)
+		}
+	}
+	for _, tool := range request.Tools {
+		schema := tool.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		anthropicRequest.Tools = append(anthropicRequest.Tools, AnthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
+	}
+	prefill := ""
+	if request.Schema != nil {
+		anthropicRequest.Tools = append(anthropicRequest.Tools, AnthropicTool{
+			Name:        request.Schema.Name,
+			Description: "Reply with the result",
+			InputSchema: request.Schema.Definition,
+		})
+		anthropicRequest.ToolChoice = &AnthropicToolUse{Type: "tool", Name: request.Schema.Name}
+	} else if request.JSON {
+		prefill = "{"
+		anthropicRequest.Messages = append(anthropicRequest.Messages, AnthropicMessage{Role: "assistant", Content: prefill})
+	}
+	return anthropicRequest, prefill
+}
+
+// appendAnthropicBlocks adds content blocks to the conversation, to the last
+// message if it has the same role, since the results of the tool calls of a
+// turn and any text after them must be sent in one user message
+func appendAnthropicBlocks(messages []AnthropicMessage, role string, blocks ...AnthropicContentBlock) []AnthropicMessage {
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		if existing, ok := messages[n-1].Content.([]AnthropicContentBlock); ok {
+			messages[n-1].Content = append(existing, blocks...)
+			return messages
+		}
+	}
+	return append(messages, AnthropicMessage{Role: role, Content: blocks})
+}
+
+// appendAnthropicText adds a text message to the conversation, as a block
+// of the last message if that holds blocks of the same role
+func appendAnthropicText(messages []AnthropicMessage, role, text string) []AnthropicMessage {
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		if _, ok := messages[n-1].Content.([]AnthropicContentBlock); ok {
+			return appendAnthropicBlocks(messages, role, AnthropicContentBlock{Type: "text", Text: text})
+		}
+	}
+	return append(messages, AnthropicMessage{Role: role, Content: text})
+}
+
+// anthropicErrorMessage extracts the type and message of a Messages API
+// error response
+func anthropicErrorMessage(body []byte) string {
+	var response struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &response) != nil || response.Error.Message == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s", response.Error.Type, response.Error.Message)
+}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestAnthropicClient(server *standIn) *AnthropicClient {
+	return NewAnthropicClient(AnthropicConfig{APIKey: "test-key", Model: "test-model", BaseURL: server.URL})
+}
+
+func TestAnthropicComplete(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeJSON(w, http.StatusOK, `{
+			"type": "message",
+			"role": "assistant",
+			"content": [
+				{"type": "text", "text": "Let me trace it."},
+				{"type": "tool_use", "id": "toolu_1", "name": "trace", "input": {"resource": "db"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 200, "output_tokens": 15}
+		}`)
+	})
+
+	completion, err := newTestAnthropicClient(server).Complete(context.Background(), CompletionRequest{
+		System:   "be brief",
+		Messages: []Message{{Role: "user", Content: "what is broken?"}},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	request := server.request(t, 0)
+	if request.Path != "/messages" {
+		t.Errorf("path = %q", request.Path)
+	}
+	if request.Header.Get("x-api-key") != "test-key" || request.Header.Get("anthropic-version") != anthropicVersion {
+		t.Errorf("headers = %v", request.Header)
+	}
+	if request.Body["system"] != "be brief" || request.Body["model"] != "test-model" {
+		t.Errorf("body = %v", request.Body)
+	}
+	if request.Body["max_tokens"] != float64(anthropicMaxTokens) {
+		t.Errorf("max_tokens = %v, want the default %d", request.Body["max_tokens"], anthropicMaxTokens)
+	}
+
+	want := &Completion{
+		Content:    "Let me trace it.",
+		ToolCalls:  []ToolCall{{ID: "toolu_1", Name: "trace", Arguments: json.RawMessage(`{"resource": "db"}`)}},
+		StopReason: "tool_use",
+		Usage:      Usage{InputTokens: 200, OutputTokens: 15},
+	}
+	if !reflect.DeepEqual(completion, want) {
+		t.Errorf("completion = %+v, want %+v", completion, want)
+	}
+}
+
+func TestAnthropicCompleteJSON(t *testing.T) {
+	// The reply continues the prefilled opening brace
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeJSON(w, http.StatusOK, `{"content": [{"type": "text", "text": "\"health_score\": 70}"}], "stop_reason": "end_turn"}`)
+	})
+
+	completion, err := newTestAnthropicClient(server).Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "analyze"}},
+		JSON:     true,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if completion.Content != `{"health_score": 70}` {
+		t.Errorf("content = %q", completion.Content)
+	}
+	messages := server.request(t, 0).Body["messages"].([]interface{})
+	last := messages[len(messages)-1].(map[string]interface{})
+	if last["role"] != "assistant" || last["content"] != "{" {
+		t.Errorf("last message = %v, want the prefill", last)
+	}
+}
+
+func TestAnthropicCompleteSchema(t *testing.T) {
+	// A schema is sent as a forced tool whose input is the reply
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeJSON(w, http.StatusOK, `{
+			"content": [{"type": "tool_use", "id": "toolu_1", "name": "analysis", "input": {"health_score": 70}}],
+			"stop_reason": "tool_use"
+		}`)
+	})
+
+	schema := &Schema{Name: "analysis", Definition: map[string]interface{}{"type": "object"}}
+	completion, err := newTestAnthropicClient(server).Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "analyze"}},
+		Schema:   schema,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if completion.Content != `{"health_score": 70}` || len(completion.ToolCalls) != 0 {
+		t.Errorf("completion = %+v, want the tool input as content", completion)
+	}
+
+	body := server.request(t, 0).Body
+	tools := body["tools"].([]interface{})
+	tool := tools[0].(map[string]interface{})
+	if tool["name"] != "analysis" || !reflect.DeepEqual(tool["input_schema"], map[string]interface{}{"type": "object"}) {
+		t.Errorf("tool = %v", tool)
+	}
+	if want := map[string]interface{}{"type": "tool", "name": "analysis"}; !reflect.DeepEqual(body["tool_choice"], want) {
+		t.Errorf("tool_choice = %v, want %v", body["tool_choice"], want)
+	}
+}
+
+func TestAnthropicStream(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeEvents(w,
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 80}}}`,
+			`{"type": "content_block_start", "index": 0, "content_block": {"type": "text", "text": ""}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": "\"health_score\""}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "text_delta", "text": ": 70}"}}`,
+			`{"type": "content_block_stop", "index": 0}`,
+			`{"type": "message_delta", "delta": {"stop_reason": "end_turn"}, "usage": {"output_tokens": 6}}`,
+			`{"type": "message_stop"}`,
+		)
+	})
+
+	onToken, tokens := collectTokens()
+	completion, err := newTestAnthropicClient(server).Stream(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "analyze"}},
+		JSON:     true,
+	}, onToken)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	if server.request(t, 0).Body["stream"] != true {
+		t.Errorf("stream not set")
+	}
+	if want := []string{"{", `"health_score"`, ": 70}"}; !reflect.DeepEqual(*tokens, want) {
+		t.Errorf("tokens = %q, want %q", *tokens, want)
+	}
+	want := &Completion{Content: `{"health_score": 70}`, StopReason: "end_turn", Usage: Usage{InputTokens: 80, OutputTokens: 6}}
+	if !reflect.DeepEqual(completion, want) {
+		t.Errorf("completion = %+v, want %+v", completion, want)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeEvents(w,
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 80}}}`,
+			`{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`,
+		)
+	})
+
+	_, err := newTestAnthropicClient(server).Stream(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error: Overloaded") {
+		t.Errorf("err = %v, want the error event", err)
+	}
+}
+
+func TestAnthropicErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+	}{
+		{"error object", http.StatusBadRequest, `{"type": "error", "error": {"type": "invalid_request_error", "message": "max_tokens: must be positive"}}`, "invalid_request_error: max_tokens: must be positive"},
+		{"plain body", http.StatusServiceUnavailable, "service unavailable\n", "service unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+				writeJSON(w, tt.status, tt.body)
+			})
+			client := newTestAnthropicClient(server)
+			request := CompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}}
+
+			_, completeErr := client.Complete(context.Background(), request)
+			_, streamErr := client.Stream(context.Background(), request, func(string) {})
+			for name, err := range map[string]error{"Complete": completeErr, "Stream": streamErr} {
+				var apiErr *apiError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("%s error = %v, want an apiError", name, err)
+				}
+				if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage {
+					t.Errorf("%s error = %d %q, want %d %q", name, apiErr.StatusCode, apiErr.Message, tt.status, tt.wantMessage)
+				}
+			}
+		})
+	}
+}
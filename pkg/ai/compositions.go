@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+var (
+	composedResourcePath = regexp.MustCompile(`resources\[name=([^\]]+)\]`)
+	pipelineStepPath     = regexp.MustCompile(`^spec\.pipeline\[step=([^\]]+)\]`)
+)
+
+// maxListedComposites caps the XR names quoted in a summary
+const maxListedComposites = 5
+
+// ExplainRevisionDiff summarizes a composition revision diff and its likely
+// impact on the XRs using the composition
+func (s *Service) ExplainRevisionDiff(ctx context.Context, diff *crossplane.RevisionDiff, composites []*crossplane.CompositeRevision) (string, error) {
+	if s.llm != nil {
+		diffJSON, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode diff: %w", err)
+		}
+		compositesJSON, err := json.MarshalIndent(composites, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode composite resources: %w", err)
+		}
+
+		prompt := fmt.Sprintf(`As a Crossplane expert, explain the following change between two revisions of the Composition %s and its likely impact on the existing composite resources (XRs).
+
+Structural diff (paths into the CompositionRevision spec; list items are identified by their step or name):
+%s
+
+XRs using the composition, with the revision they are on and their compositionUpdatePolicy:
+%s
+
+XRs with the Automatic policy always move to the latest revision (%d); XRs with the Manual policy stay on their compositionRevisionRef. Explain which composed resources will be created, deleted or updated, which changes may force the replacement of external resources, and which XRs are affected. Keep it short and concrete and only refer to the paths and XRs given.`,
+			diff.Composition, diffJSON, compositesJSON, diff.LatestRevision)
+
+		return s.completePrompt(ctx, prompt)
+	}
+
+	return revisionDiffSummary(diff, composites), nil
+}
+
+// revisionDiffSummary explains a revision diff without a language model
+func revisionDiffSummary(diff *crossplane.RevisionDiff, composites []*crossplane.CompositeRevision) string {
+	var lines []string
+
+	if len(diff.Changes) == 0 {
+		lines = append(lines, fmt.Sprintf("Revisions %d and %d are identical; moving between them changes nothing.",
+			diff.From.Revision, diff.To.Revision))
+	} else {
+		lines = append(lines, describeRevisionChanges(diff.Changes)...)
+	}
+
+	lines = append(lines, describeAffectedComposites(diff, composites)...)
+	return "• " + strings.Join(lines, "\n• ")
+}
+
+func describeRevisionChanges(changes []crossplane.Change) []string {
+	var lines []string
+
+	// Changes to composed resource templates, keyed by resource name
+	added := map[string]bool{}
+	removed := map[string]bool{}
+	modified := map[string][]string{}
+	var other []string
+
+	for _, change := range changes {
+		match := composedResourcePath.FindStringSubmatchIndex(change.Path)
+		if match == nil {
+			if step := pipelineStepPath.FindStringSubmatch(change.Path); step != nil {
+				lines = append(lines, describeStepChange(step[1], change))
+				continue
+			}
+			if change.Path == "spec.compositeTypeRef" || strings.HasPrefix(change.Path, "spec.compositeTypeRef.") {
+				lines = append(lines, "The composite type changes: XRs of the previous type no longer match this composition.")
+				continue
+			}
+			other = append(other, change.Path)
+			continue
+		}
+
+		name := change.Path[match[2]:match[3]]
+		field := strings.TrimPrefix(change.Path[match[1]:], ".")
+		switch {
+		case field == "" && change.Type == crossplane.ChangeAdded:
+			added[name] = true
+		case field == "" && change.Type == crossplane.ChangeRemoved:
+			removed[name] = true
+		default:
+			modified[name] = append(modified[name], field)
+		}
+	}
+
+	for _, name := range sortedKeys(added) {
+		lines = append(lines, fmt.Sprintf("Adds the composed resource %q: every XR moving to the new revision creates it.", name))
+	}
+	for _, name := range sortedKeys(removed) {
+		lines = append(lines, fmt.Sprintf("Removes the composed resource %q: XRs moving to the new revision delete it, or orphan the external resource if its deletionPolicy is Orphan.", name))
+	}
+	var names []string
+	for name := range modified {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, describeResourceChange(name, modified[name]))
+	}
+
+	if len(other) > 0 {
+		lines = append(lines, fmt.Sprintf("Other changes: %s.", strings.Join(other, ", ")))
+	}
+	return lines
+}
+
+func describeStepChange(step string, change crossplane.Change) string {
+	switch {
+	case change.Type == crossplane.ChangeAdded && !strings.Contains(change.Path, "]."):
+		return fmt.Sprintf("Adds the pipeline step %q.", step)
+	case change.Type == crossplane.ChangeRemoved && !strings.Contains(change.Path, "]."):
+		return fmt.Sprintf("Removes the pipeline step %q; resources only it composed are deleted.", step)
+	case strings.HasSuffix(change.Path, ".functionRef.name"):
+		return fmt.Sprintf("The pipeline step %q now runs function %v instead of %v.", step, change.To, change.From)
+	}
+	return fmt.Sprintf("Changes %s of the pipeline step %q.", strings.TrimPrefix(change.Path[strings.Index(change.Path, "]")+1:], "."), step)
+}
+
+func describeResourceChange(name string, fields []string) string {
+	var forProvider, patches, rest []string
+	for _, field := range fields {
+		switch {
+		case strings.HasPrefix(field, "base.spec.forProvider."):
+			forProvider = append(forProvider, strings.TrimPrefix(field, "base.spec.forProvider."))
+		case strings.HasPrefix(field, "patches"):
+			patches = append(patches, field)
+		default:
+			rest = append(rest, field)
+		}
+	}
+
+	var parts []string
+	if len(forProvider) > 0 {
+		parts = append(parts, fmt.Sprintf("spec.forProvider (%s) changes, so providers update the external resource in place; fields the cloud API cannot update may force its replacement",
+			strings.Join(forProvider, ", ")))
+	}
+	if len(patches) > 0 {
+		parts = append(parts, fmt.Sprintf("its patches change (%d fields), altering which XR fields reach it", len(patches)))
+	}
+	if len(rest) > 0 {
+		parts = append(parts, fmt.Sprintf("%s change", strings.Join(rest, ", ")))
+	}
+	return fmt.Sprintf("Composed resource %q: %s.", name, strings.Join(parts, "; "))
+}
+
+func describeAffectedComposites(diff *crossplane.RevisionDiff, composites []*crossplane.CompositeRevision) []string {
+	if len(composites) == 0 {
+		return []string{"No XRs use this composition; the change has no effect on existing resources."}
+	}
+
+	var automatic, pinnedFrom, pinnedOther []string
+	for _, xr := range composites {
+		switch {
+		case xr.UpdatePolicy != crossplane.UpdatePolicyManual:
+			automatic = append(automatic, xr.Resource.String())
+		case xr.RevisionNumber == diff.From.Revision:
+			pinnedFrom = append(pinnedFrom, xr.Resource.String())
+		default:
+			pinnedOther = append(pinnedOther, xr.Resource.String())
+		}
+	}
+
+	var lines []string
+	if len(automatic) > 0 {
+		if diff.To.Revision == diff.LatestRevision {
+			lines = append(lines, fmt.Sprintf("%d XRs use the Automatic update policy and run revision %d: %s.",
+				len(automatic), diff.To.Revision, listComposites(automatic)))
+		} else {
+			lines = append(lines, fmt.Sprintf("%d XRs use the Automatic update policy and run the latest revision %d, not revision %d: %s.",
+				len(automatic), diff.LatestRevision, diff.To.Revision, listComposites(automatic)))
+		}
+	}
+	if len(pinnedFrom) > 0 {
+		lines = append(lines, fmt.Sprintf("%d XRs are pinned to revision %d with the Manual policy and only pick up this change when their compositionRevisionRef is moved to revision %d: %s.",
+			len(pinnedFrom), diff.From.Revision, diff.To.Revision, listComposites(pinnedFrom)))
+	}
+	if len(pinnedOther) > 0 {
+		lines = append(lines, fmt.Sprintf("%d XRs are pinned to other revisions with the Manual policy and are not affected: %s.",
+			len(pinnedOther), listComposites(pinnedOther)))
+	}
+	return lines
+}
+
+func listComposites(names []string) string {
+	if len(names) <= maxListedComposites {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxListedComposites], ", "), len(names)-maxListedComposites)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// DeletionIssues explains the resources stuck in deletion. Resources that
+// only wait for others being deleted are left out; the issue of the
+// resource holding them up names the ones it blocks.
+func DeletionIssues(report *crossplane.DeletionReport) []Issue {
+	var issues []Issue
+
+	for _, t := range report.Terminating {
+		if t.Cascading || len(t.Blockers) == 0 {
+			continue
+		}
+
+		severity := "Warning"
+		if t.ProviderProblem != "" {
+			severity = "Critical"
+		}
+
+		var reasons []string
+		for _, blocker := range t.Blockers {
+			if blocker.Finalizer != "" {
+				reasons = append(reasons, fmt.Sprintf("finalizer %s: %s", blocker.Finalizer, blocker.Message))
+			} else {
+				reasons = append(reasons, blocker.Message)
+			}
+		}
+		description := fmt.Sprintf("%s has been deleting for %s; %s",
+			t.Ref, time.Since(t.Since).Round(time.Minute), strings.Join(reasons, "; "))
+		if len(t.Blocks) > 0 {
+			description += fmt.Sprintf(" (blocks the deletion of %s)", joinObjectRefs(t.Blocks))
+		}
+
+		issues = append(issues, Issue{
+			Cluster:     t.Cluster,
+			Severity:    severity,
+			Description: description,
+			Kind:        t.Ref.Kind,
+			Namespace:   t.Ref.Namespace,
+			Resource:    t.Ref.Name,
+			Reason:      "DeletionBlocked",
+			Resolution:  strings.Join(DeletionRemediation(t), " "),
+		})
+	}
+
+	return issues
+}
+
+// DeletionRemediation returns the steps that let a terminating resource
+// finish deleting without leaving an external resource behind. Removing a
+// finalizer is only suggested when nothing is lost by it.
+func DeletionRemediation(t *crossplane.TerminatingResource) []string {
+	var steps []string
+	add := func(step string) {
+		for _, existing := range steps {
+			if existing == step {
+				return
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	unstick := fmt.Sprintf("`crossplane-ai unstick %s/%s`", t.Ref.Kind, t.Ref.Name)
+	if t.Ref.Namespace != "" {
+		unstick = fmt.Sprintf("`crossplane-ai unstick -n %s %s/%s`", t.Ref.Namespace, t.Ref.Kind, t.Ref.Name)
+	}
+
+	for _, blocker := range t.Blockers {
+		switch blocker.Reason {
+		case crossplane.BlockerExternalResource:
+			if t.ProviderProblem != "" {
+				add(fmt.Sprintf("Fix provider %s first (see `crossplane-ai providers --revisions`); it removes the finalizer once it reconciles the resource again.", t.Provider))
+			}
+			switch t.External {
+			case crossplane.ExternalOrphaned:
+				add(fmt.Sprintf("The external resource is kept either way, so removing %s with %s is safe.", blocker.Finalizer, unstick))
+			case crossplane.ExternalExists:
+				add(deleteFailureStep(t.ExternalMessage))
+			case crossplane.ExternalUnknown:
+				add("Restore the provider's access to the cloud API, e.g. the credentials of its ProviderConfig; it cannot delete what it cannot observe.")
+			case crossplane.ExternalDeleting:
+				add("Wait for the provider to finish deleting the external resource and check its events if it takes long.")
+			}
+			if t.External != crossplane.ExternalOrphaned {
+				add("Only remove the finalizer after deleting the external resource yourself, or it is left running unmanaged.")
+			}
+
+		case crossplane.BlockerComposedResources, crossplane.BlockerComposite, crossplane.BlockerUsingResource:
+			if blocker.Removable {
+				add(fmt.Sprintf("The objects %s waits for are gone; remove it with %s.", blocker.Finalizer, unstick))
+			}
+
+		case crossplane.BlockerUsage:
+			if blocker.UsedBy != nil && blocker.Usage != nil {
+				add(fmt.Sprintf("Delete %s, which uses %s, or delete %s if the dependency no longer matters.",
+					blocker.UsedBy, joinObjectRefs(blocker.WaitingFor), blocker.Usage))
+			}
+
+		case crossplane.BlockerDependents:
+			add("Check the objects owned by the resource with blockOwnerDeletion set; they are deleted first.")
+
+		default:
+			add(fmt.Sprintf("Find the controller that set %s; remove it with %s --force only when that controller is gone.", blocker.Finalizer, unstick))
+		}
+	}
+
+	return steps
+}
+
+// deleteFailureStep recommends a fix for the error the provider's delete
+// call returns
+func deleteFailureStep(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "deletion protection"):
+		return "Disable deletion protection on the external resource with the cloud console or CLI, since the provider only calls delete while the resource is terminating; the provider then finishes the deletion."
+	case strings.Contains(lower, "not empty"):
+		return "Empty the bucket, or enable forceDestroy where the provider supports it; the provider then finishes the deletion."
+	case message != "":
+		return "Fix the error the provider's delete call returns; the provider retries and removes the finalizer once the external resource is gone."
+	}
+	return "Check the provider's events for why the external resource is not deleted."
+}
+
+func joinObjectRefs(refs []crossplane.ObjectRef) string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.String()
+	}
+	return strings.Join(names, ", ")
+}
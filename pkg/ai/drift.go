@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// maxDriftFields caps the fields quoted in a drift issue
+const maxDriftFields = 3
+
+// DriftIssues reports managed resources whose external resource differs
+// from spec.forProvider, with what Crossplane does about it
+func DriftIssues(report *crossplane.DriftReport) []Issue {
+	var issues []Issue
+
+	for _, drift := range report.Drifted {
+		issue := Issue{
+			Cluster:   drift.Cluster,
+			Kind:      drift.Ref.Kind,
+			Namespace: drift.Ref.Namespace,
+			Resource:  drift.Ref.Name,
+			Reason:    "Drift" + string(drift.State),
+		}
+		fields := DescribeDriftFields(drift.Fields, maxDriftFields)
+
+		switch drift.State {
+		case crossplane.DriftOutOfBand:
+			issue.Severity = "Warning"
+			issue.Description = fmt.Sprintf("%s was changed outside Crossplane: %s", drift.Ref, fields)
+			issue.Resolution = "The provider reverts the change on its next poll. Make lasting changes through the claim or composition instead; if the drift keeps coming back, another system (an autoscaler, a script or another IaC tool) is fighting Crossplane over the resource."
+		case crossplane.DriftNotReconciled:
+			issue.Severity = "Critical"
+			issue.Description = fmt.Sprintf("%s has drifted and the provider cannot correct it: %s (%s)", drift.Ref, fields, drift.Message)
+			issue.Resolution = "Fix the error the provider's update returns, or accept the observed values into spec.forProvider if the out-of-band change should stay."
+		case crossplane.DriftPending:
+			issue.Severity = "Info"
+			issue.Description = fmt.Sprintf("%s has a spec change the provider has not applied yet: %s (%s)", drift.Ref, fields, drift.Message)
+			issue.Resolution = "Check that the provider is healthy and reconciling; the change is applied on its next reconcile."
+		case crossplane.DriftIgnored:
+			issue.Severity = "Info"
+			issue.Description = fmt.Sprintf("%s differs from its spec and is not corrected: %s (%s)", drift.Ref, fields, drift.Message)
+			issue.Resolution = "Add Update to spec.managementPolicies to enforce the spec, or update spec.forProvider to match the external resource."
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// DescribeDriftFields renders up to limit drifted fields as
+// "path: desired → observed"
+func DescribeDriftFields(fields []crossplane.FieldDrift, limit int) string {
+	var parts []string
+	for i, field := range fields {
+		if i == limit {
+			parts = append(parts, fmt.Sprintf("and %d more", len(fields)-limit))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s → %s", field.Path, driftValue(field.Desired), driftValue(field.Observed)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func driftValue(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "<unset>"
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
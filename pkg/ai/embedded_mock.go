@@ -1,89 +1,168 @@
 package ai
 
-// EmbeddedMockData contains hardcoded mock data that doesn't require external files
-// This ensures mock mode works even when users download just the binary
-
-// GetEmbeddedMockResources returns a consistent set of mock resources for testing
-func GetEmbeddedMockResources() []*ResourceInfo {
-	return []*ResourceInfo{
-		{
-			Name:     "sample-database-composition",
-			Type:     "compositions",
-			Status:   "Ready",
-			Provider: "crossplane",
-			Age:      "2h",
-		},
-		{
-			Name:     "xdatabases.example.org",
-			Type:     "compositeresourcedefinitions",
-			Status:   "Ready",
-			Provider: "crossplane",
-			Age:      "2h",
-		},
-		{
-			Name:     "provider-aws",
-			Type:     "providers",
-			Status:   "Ready",
-			Provider: "crossplane",
-			Age:      "1h",
-		},
-		{
-			Name:     "provider-gcp",
-			Type:     "providers",
-			Status:   "Ready",
-			Provider: "crossplane",
-			Age:      "1h",
-		},
-		{
-			Name:     "provider-azure",
-			Type:     "providers",
-			Status:   "Ready",
-			Provider: "crossplane",
-			Age:      "1h",
-		},
-		{
-			Name:     "sample-database-instance",
-			Type:     "dbinstances",
-			Status:   "Ready",
-			Provider: "aws",
-			Age:      "30m",
-		},
-		{
-			Name:     "web-server-instance",
-			Type:     "instances",
-			Status:   "Ready",
-			Provider: "aws",
-			Age:      "45m",
-		},
-		{
-			Name:     "data-storage-bucket",
-			Type:     "buckets",
-			Status:   "Ready",
-			Provider: "aws",
-			Age:      "1h",
-		},
-		{
-			Name:     "gcp-database-instance",
-			Type:     "databaseinstances",
-			Status:   "Ready",
-			Provider: "gcp",
-			Age:      "20m",
-		},
-		{
-			Name:     "azure-storage-account",
-			Type:     "accounts",
-			Status:   "Ready",
-			Provider: "azure",
-			Age:      "35m",
-		},
-		{
-			Name:     "failing-test-resource",
-			Type:     "instances",
-			Status:   "Not Ready",
-			Provider: "aws",
-			Age:      "5m",
-		},
+import (
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"crossplane-ai/pkg/crossplane"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// mockData is the embedded mock cluster, so mock mode works with just the
+// binary: base.yaml is a healthy cluster and every other file a scenario
+// overlaid on it
+//
+//go:embed mockdata/*.yaml
+var mockData embed.FS
+
+// DefaultMockScenario is the scenario mock mode runs without --scenario
+const DefaultMockScenario = "healthy"
+
+// MockScenarios describes the scenarios of the embedded mock cluster
+var MockScenarios = map[string]string{
+	"healthy":            "every claim, composite, managed resource and package is healthy",
+	"credential-failure": "the AWS credentials secret lost the key its ProviderConfig reads, so AWS resources fail to sync",
+	"stuck-deletion":     "deleted claims hang on deletion protection, a Usage and a non-empty bucket",
+	"composition-error":  "a composition pipeline step uses a function that is not installed",
+	"provider-crash":     "an upgraded provider crash loops and stops reconciling its resources",
+	"drift":              "cloud resources were changed outside Crossplane and differ from their spec",
+}
+
+// mockEpoch is the time the embedded mock data was written as of; it is
+// shifted to the current time when the data is loaded
+var mockEpoch = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+var mockTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`)
+
+// MockScenarioNames returns the names of the mock scenarios, sorted
+func MockScenarioNames() []string {
+	names := make([]string, 0, len(MockScenarios))
+	for name := range MockScenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewMockSource serves a scenario of the embedded mock cluster as a fake
+// cluster named "mock". An empty scenario selects DefaultMockScenario.
+func NewMockSource(scenario string) (crossplane.ResourceSource, error) {
+	objects, err := MockObjects(scenario)
+	if err != nil {
+		return nil, err
 	}
+	return crossplane.NewFakeSource("mock", objects)
+}
+
+// MockObjects returns the objects of a scenario of the embedded mock
+// cluster: the healthy base cluster with the scenario's objects merged in.
+// An object of the scenario that also exists in the base is applied to it
+// as a JSON merge patch, i.e. maps are merged, lists replaced and null
+// removes a field; other objects are added. Timestamps are moved so that
+// the data looks as recent as when it was written.
+func MockObjects(scenario string) ([]*unstructured.Unstructured, error) {
+	if scenario == "" {
+		scenario = DefaultMockScenario
+	}
+	if _, ok := MockScenarios[scenario]; !ok {
+		return nil, fmt.Errorf("unknown mock scenario %q (available: %s)", scenario, strings.Join(MockScenarioNames(), ", "))
+	}
+
+	objects, err := readMockFile("base.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if scenario != DefaultMockScenario {
+		overlay, err := readMockFile(scenario + ".yaml")
+		if err != nil {
+			return nil, err
+		}
+		objects = overlayObjects(objects, overlay)
+	}
+
+	shift := time.Since(mockEpoch)
+	for _, obj := range objects {
+		obj.Object = shiftTimestamps(obj.Object, shift).(map[string]interface{})
+	}
+	return objects, nil
+}
+
+func readMockFile(name string) ([]*unstructured.Unstructured, error) {
+	data, err := mockData.ReadFile(path.Join("mockdata", name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded mock data: %w", err)
+	}
+	objects, err := crossplane.SplitManifest(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("embedded mock data %s: %w", name, err)
+	}
+	return objects, nil
+}
+
+// overlayObjects merges the objects of a scenario into the base objects
+func overlayObjects(base, overlay []*unstructured.Unstructured) []*unstructured.Unstructured {
+	key := func(obj *unstructured.Unstructured) string {
+		return strings.Join([]string{obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName()}, "/")
+	}
+
+	index := make(map[string]*unstructured.Unstructured, len(base))
+	for _, obj := range base {
+		index[key(obj)] = obj
+	}
+	for _, obj := range overlay {
+		if existing, ok := index[key(obj)]; ok {
+			mergePatch(existing.Object, obj.Object)
+			continue
+		}
+		base = append(base, obj)
+		index[key(obj)] = obj
+	}
+	return base
+}
+
+// mergePatch applies patch to target in place with the semantics of a JSON
+// merge patch (RFC 7386)
+func mergePatch(target, patch map[string]interface{}) {
+	for field, value := range patch {
+		if value == nil {
+			delete(target, field)
+			continue
+		}
+		patchMap, isMap := value.(map[string]interface{})
+		targetMap, targetIsMap := target[field].(map[string]interface{})
+		if isMap && targetIsMap {
+			mergePatch(targetMap, patchMap)
+			continue
+		}
+		target[field] = value
+	}
+}
+
+// shiftTimestamps moves every RFC 3339 timestamp in value by shift
+func shiftTimestamps(value interface{}, shift time.Duration) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, item := range v {
+			v[field] = shiftTimestamps(item, shift)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = shiftTimestamps(item, shift)
+		}
+	case string:
+		if mockTimestamp.MatchString(v) {
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t.Add(shift).Format(time.RFC3339)
+			}
+		}
+	}
+	return value
 }
 
 // GetEmbeddedMockYAMLExamples returns example YAML manifests
@@ -100,22 +179,31 @@ spec:
   compositeTypeRef:
     apiVersion: example.org/v1alpha1
     kind: XDatabase
-  resources:
-    - name: rds-instance
-      base:
-        apiVersion: rds.aws.crossplane.io/v1alpha1
-        kind: DBInstance
-        spec:
-          forProvider:
-            dbInstanceClass: db.t3.micro
-            engine: postgres
-            engineVersion: "13.7"
-            allocatedStorage: 20
-            storageType: gp2
-      patches:
-        - type: FromCompositeFieldPath
-          fromFieldPath: spec.parameters.storageGB
-          toFieldPath: spec.forProvider.allocatedStorage`,
+  mode: Pipeline
+  pipeline:
+    - step: patch-and-transform
+      functionRef:
+        name: function-patch-and-transform
+      input:
+        apiVersion: pt.fn.crossplane.io/v1beta1
+        kind: Resources
+        resources:
+          - name: rds-instance
+            base:
+              apiVersion: rds.aws.upbound.io/v1beta1
+              kind: Instance
+              spec:
+                forProvider:
+                  region: us-east-1
+                  instanceClass: db.t3.micro
+                  engine: postgres
+                  engineVersion: "16"
+                  allocatedStorage: 20
+                  storageType: gp2
+            patches:
+              - type: FromCompositeFieldPath
+                fromFieldPath: spec.parameters.storageGB
+                toFieldPath: spec.forProvider.allocatedStorage`,
 
 		"xrd": `apiVersion: apiextensions.crossplane.io/v1
 kind: CompositeResourceDefinition
@@ -161,27 +249,8 @@ spec:
 		"provider": `apiVersion: pkg.crossplane.io/v1
 kind: Provider
 metadata:
-  name: provider-aws
+  name: provider-aws-rds
 spec:
-  package: xpkg.upbound.io/crossplane-contrib/provider-aws:v0.44.0`,
+  package: xpkg.upbound.io/upbound/provider-aws-rds:v1.21.0`,
 	}
 }
-
-// MockScenarios provides different mock scenarios for demonstrations
-var MockScenarios = map[string][]*ResourceInfo{
-	"healthy": {
-		{Name: "web-app-db", Type: "dbinstances", Status: "Ready", Provider: "aws", Age: "2h"},
-		{Name: "web-app-server", Type: "instances", Status: "Ready", Provider: "aws", Age: "2h"},
-		{Name: "web-app-bucket", Type: "buckets", Status: "Ready", Provider: "aws", Age: "2h"},
-	},
-	"mixed-health": {
-		{Name: "healthy-db", Type: "dbinstances", Status: "Ready", Provider: "aws", Age: "1h"},
-		{Name: "failing-server", Type: "instances", Status: "Not Ready", Provider: "aws", Age: "30m"},
-		{Name: "pending-bucket", Type: "buckets", Status: "Creating", Provider: "aws", Age: "5m"},
-	},
-	"multi-cloud": {
-		{Name: "aws-database", Type: "dbinstances", Status: "Ready", Provider: "aws", Age: "3h"},
-		{Name: "gcp-compute", Type: "instances", Status: "Ready", Provider: "gcp", Age: "2h"},
-		{Name: "azure-storage", Type: "accounts", Status: "Ready", Provider: "azure", Age: "1h"},
-	},
-}
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// ClusterSummary is the health of one cluster in a multi-cluster analysis
+type ClusterSummary struct {
+	Cluster          string `json:"cluster"`
+	TotalResources   int    `json:"total_resources"`
+	HealthyResources int    `json:"healthy_resources"`
+	IssuesFound      int    `json:"issues_found"`
+	HealthScore      int    `json:"health_score"`
+	// UnhealthyProviders names the providers whose Installed or Healthy
+	// condition is not True
+	UnhealthyProviders []string `json:"unhealthy_providers,omitempty"`
+
+	// ratedResources counts the resources that report health, which the
+	// health score is the share of
+	ratedResources int
+}
+
+// summarizeClusters breaks resources and issues down per cluster. It
+// returns nil unless the resources come from more than one cluster.
+func summarizeClusters(resources []*ResourceInfo, issues []Issue) []ClusterSummary {
+	byCluster := make(map[string]*ClusterSummary)
+	for _, res := range resources {
+		summary, ok := byCluster[res.Cluster]
+		if !ok {
+			summary = &ClusterSummary{Cluster: res.Cluster}
+			byCluster[res.Cluster] = summary
+		}
+		summary.TotalResources++
+		if reportsHealth(res) {
+			summary.ratedResources++
+		}
+		if res.Status == "Ready" {
+			summary.HealthyResources++
+		}
+		if res.Type == "providers" && providerProblem(res.Conditions) != "" {
+			summary.UnhealthyProviders = append(summary.UnhealthyProviders, res.Name)
+		}
+	}
+	if len(byCluster) < 2 {
+		return nil
+	}
+
+	clusters := make([]ClusterSummary, 0, len(byCluster))
+	for _, summary := range byCluster {
+		summary.HealthScore = 100
+		if summary.ratedResources > 0 {
+			summary.HealthScore = summary.HealthyResources * 100 / summary.ratedResources
+		}
+		clusters = append(clusters, *summary)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Cluster < clusters[j].Cluster })
+
+	countClusterIssues(clusters, issues)
+	return clusters
+}
+
+// countClusterIssues sets the issue count of every cluster summary
+func countClusterIssues(clusters []ClusterSummary, issues []Issue) {
+	counts := make(map[string]int)
+	for _, issue := range issues {
+		counts[issue.Cluster]++
+	}
+	for i := range clusters {
+		clusters[i].IssuesFound = counts[clusters[i].Cluster]
+	}
+}
+
+// assignIssueClusters sets the cluster of issues that name a resource
+// without saying which cluster it is in, as long as the name is only used
+// in one cluster
+func assignIssueClusters(issues []Issue, resources []*ResourceInfo) {
+	clusters := make(map[string]map[string]bool)
+	for _, res := range resources {
+		if clusters[res.Name] == nil {
+			clusters[res.Name] = make(map[string]bool)
+		}
+		clusters[res.Name][res.Cluster] = true
+	}
+
+	for i := range issues {
+		if issues[i].Cluster != "" || len(clusters[issues[i].Resource]) != 1 {
+			continue
+		}
+		for cluster := range clusters[issues[i].Resource] {
+			issues[i].Cluster = cluster
+		}
+	}
+}
+
+// providerProblem describes the first of a provider's Installed and Healthy
+// conditions that is not True, or returns "" when both are
+func providerProblem(conditions []crossplane.Condition) string {
+	for _, conditionType := range []string{crossplane.ConditionInstalled, crossplane.ConditionHealthy} {
+		condition := findCondition(conditions, conditionType)
+		if condition == nil {
+			return conditionType + " condition missing"
+		}
+		if !condition.IsTrue() {
+			return fmt.Sprintf("%s=%s%s", conditionType, condition.Status, describeCondition(condition))
+		}
+	}
+	return ""
+}
+
+// fleetAnswer answers questions about the clusters of a multi-cluster list
+// without a language model. It only applies when the resources come from
+// more than one cluster and the question mentions clusters.
+func fleetAnswer(query string, result *crossplane.ListResult) (string, bool) {
+	queryLower := strings.ToLower(query)
+	if !strings.Contains(queryLower, "cluster") {
+		return "", false
+	}
+
+	type clusterState struct {
+		total, attention int
+		providers        []string
+	}
+	states := make(map[string]*clusterState)
+	for _, res := range result.Resources {
+		state, ok := states[res.Cluster]
+		if !ok {
+			state = &clusterState{}
+			states[res.Cluster] = state
+		}
+		state.total++
+		if crossplane.NeedsAttention(res) {
+			state.attention++
+		}
+		if res.Type == "providers" {
+			if problem := providerProblem(res.Conditions); problem != "" {
+				state.providers = append(state.providers, fmt.Sprintf("%s (%s)", res.Name, problem))
+			}
+		}
+	}
+	if len(states) < 2 {
+		return "", false
+	}
+
+	clusters := make([]string, 0, len(states))
+	for cluster := range states {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	var b strings.Builder
+	if strings.Contains(queryLower, "provider") {
+		b.WriteString("🏗️ Provider health across clusters:\n\n")
+		unhealthy := 0
+		for _, cluster := range clusters {
+			if len(states[cluster].providers) == 0 {
+				continue
+			}
+			unhealthy++
+			fmt.Fprintf(&b, "• %s:\n", cluster)
+			for _, provider := range states[cluster].providers {
+				fmt.Fprintf(&b, "    - %s\n", provider)
+			}
+		}
+		if unhealthy == 0 {
+			fmt.Fprintf(&b, "All providers are installed and healthy in the %d clusters.\n", len(clusters))
+		} else {
+			fmt.Fprintf(&b, "\n%d of %d clusters have providers that are not healthy.\n", unhealthy, len(clusters))
+		}
+	} else {
+		b.WriteString("🌐 Fleet summary:\n\n")
+		for _, cluster := range clusters {
+			state := states[cluster]
+			fmt.Fprintf(&b, "• %s: %d resources, %d need attention", cluster, state.total, state.attention)
+			if len(state.providers) > 0 {
+				fmt.Fprintf(&b, ", %d providers not healthy", len(state.providers))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	// Clusters that could not be reached at all have no resources to count
+	for _, failure := range result.Failures {
+		if failure.GVR.Resource == "" && failure.Cluster != "" {
+			fmt.Fprintf(&b, "⚠️  %s could not be reached: %s\n", failure.Cluster, failure.Message)
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n"), true
+}
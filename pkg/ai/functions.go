@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"fmt"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// pipelineGuidance keeps the model from recommending the composition
+// syntax Crossplane v2 removed
+const pipelineGuidance = `Compositions carry their mode and pipeline steps (step name, function and input) and functions carry their package version. Crossplane compositions compose resources with a pipeline of composition functions (spec.mode: Pipeline, spec.pipeline with functionRef), e.g. function-patch-and-transform, function-go-templating or function-kcl. Never recommend the legacy spec.resources with top-level patches syntax of Resources mode; when a composition still uses it, recommend migrating it to a function pipeline.`
+
+// FunctionIssues reports pipeline steps whose function is not installed or
+// not healthy, and compositions still using the legacy Resources mode
+func FunctionIssues(report *crossplane.FunctionReport) []Issue {
+	var issues []Issue
+
+	for _, missing := range report.Missing {
+		issues = append(issues, Issue{
+			Cluster:  missing.Cluster,
+			Severity: "Critical",
+			Description: fmt.Sprintf("Composition %s cannot run pipeline step %q: function %s is not installed",
+				missing.Composition, missing.Step, missing.Function),
+			Resource:   missing.Composition,
+			Reason:     "FunctionMissing",
+			Resolution: fmt.Sprintf("Install the function with a Function package named %s, or fix functionRef.name of the step", missing.Function),
+		})
+	}
+
+	for _, function := range report.Functions {
+		if function.IsHealthy() {
+			continue
+		}
+		problem := fmt.Sprintf("Installed=%s, Healthy=%s", function.Installed, function.Healthy)
+		if function.Reason != "" {
+			problem += ", " + function.Reason
+		}
+		for _, ref := range report.References(function.Cluster, function.Name) {
+			issues = append(issues, Issue{
+				Cluster:  ref.Cluster,
+				Severity: "Critical",
+				Description: fmt.Sprintf("Composition %s cannot run pipeline step %q: function %s is not healthy (%s)",
+					ref.Composition, ref.Step, function.Name, problem),
+				Resource:   ref.Composition,
+				Reason:     "FunctionUnhealthy",
+				Resolution: fmt.Sprintf("Check the function package and its revisions with `crossplane-ai functions %s`", function.Name),
+			})
+		}
+	}
+
+	for _, composition := range report.Compositions {
+		if composition.Mode != crossplane.CompositionModeResources {
+			continue
+		}
+		issues = append(issues, Issue{
+			Cluster:     composition.Cluster,
+			Severity:    "Info",
+			Description: fmt.Sprintf("Composition %s uses the legacy Resources mode, which Crossplane v2 no longer supports", composition.Name),
+			Resource:    composition.Name,
+			Reason:      "LegacyCompositionMode",
+			Resolution:  "Migrate to mode: Pipeline with a function-patch-and-transform step, e.g. with `crossplane beta convert pipeline-composition`",
+		})
+	}
+
+	return issues
+}
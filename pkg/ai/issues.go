@@ -0,0 +1,294 @@
+package ai
+
+import (
+	"fmt"
+	"time"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// creatingGracePeriod is how long a resource may report Ready=False with
+// reason Creating before it is treated as a problem
+const creatingGracePeriod = 10 * time.Minute
+
+// reasonResolutions maps well-known Crossplane condition reasons to advice
+var reasonResolutions = map[string]string{
+	"ReconcileError":  "The provider failed to reconcile the resource. Check the error message, the credentials of its ProviderConfig and the resource spec.",
+	"ReconcilePaused": "Reconciliation is paused by the crossplane.io/paused annotation. Remove the annotation to resume.",
+	"Unavailable":     "The external resource exists but reports as unavailable. Check its state with the cloud provider.",
+	"Creating":        "The external resource is still being created. Check provider events if this persists.",
+	"Deleting":        "The external resource is being deleted. If this persists, run 'crossplane-ai unstick' to see which finalizer or Usage blocks it.",
+
+	"UnhealthyPackageRevision": "The package's active revision is unhealthy. Run 'crossplane-ai providers --revisions' and check the events of the revision and its pods for image pull errors, crash loops or CRD conflicts.",
+	"UnpackingPackage":         "The package could not be fetched or unpacked. Check the package reference, registry access and packagePullSecrets.",
+	"InactivePackageRevision":  "The package has no active revision. Check the revision activation policy and the revisions' events.",
+}
+
+// packageKinds names the package types, which report Installed and Healthy
+// instead of Ready and Synced
+var packageKinds = map[string]string{
+	"providers":      "Provider",
+	"functions":      "Function",
+	"configurations": "Configuration",
+}
+
+// resourceIssues derives issues from a resource's conditions, generation
+// and deletion state, quoting the messages reported by Crossplane
+func resourceIssues(res *ResourceInfo) []Issue {
+	var issues []Issue
+
+	synced := findCondition(res.Conditions, crossplane.ConditionSynced)
+	ready := findCondition(res.Conditions, crossplane.ConditionReady)
+
+	if synced != nil && synced.IsFalse() {
+		issues = append(issues, Issue{
+			Severity:    "Critical",
+			Description: fmt.Sprintf("Resource %s is not synced%s", res.Name, describeCondition(synced)),
+			Resource:    res.Name,
+			Reason:      synced.Reason,
+			Resolution:  resolutionFor(synced.Reason),
+		})
+	}
+
+	if ready != nil && ready.IsFalse() {
+		severity := "Warning"
+		if ready.Reason == "Creating" && !ready.LastTransitionTime.IsZero() &&
+			time.Since(ready.LastTransitionTime) < creatingGracePeriod {
+			severity = "Info"
+		}
+		issues = append(issues, Issue{
+			Severity:    severity,
+			Description: fmt.Sprintf("Resource %s is not ready%s", res.Name, describeCondition(ready)),
+			Resource:    res.Name,
+			Reason:      ready.Reason,
+			Resolution:  resolutionFor(ready.Reason),
+		})
+	}
+
+	// Packages report Installed and Healthy; a package that is not
+	// installed is not expected to be healthy either
+	installed := findCondition(res.Conditions, crossplane.ConditionInstalled)
+	healthy := findCondition(res.Conditions, crossplane.ConditionHealthy)
+	kind := packageKinds[res.Type]
+	if kind == "" {
+		kind = "Package"
+	}
+	switch {
+	case installed != nil && installed.IsFalse():
+		issues = append(issues, Issue{
+			Severity:    "Critical",
+			Description: fmt.Sprintf("%s %s not installed%s", kind, res.Name, describeCondition(installed)),
+			Resource:    res.Name,
+			Reason:      installed.Reason,
+			Resolution:  resolutionFor(installed.Reason),
+		})
+	case healthy != nil && healthy.IsFalse():
+		issues = append(issues, Issue{
+			Severity:    "Critical",
+			Description: fmt.Sprintf("%s %s Unhealthy%s", kind, res.Name, describeCondition(healthy)),
+			Resource:    res.Name,
+			Reason:      healthy.Reason,
+			Resolution:  resolutionFor(healthy.Reason),
+		})
+	}
+
+	// Resources that report no conditions only have a summary status
+	if ready == nil && synced == nil && installed == nil && healthy == nil &&
+		res.Status != "Ready" && res.Status != "Unknown" && reportsHealth(res) {
+		issues = append(issues, Issue{
+			Severity:    "Warning",
+			Description: fmt.Sprintf("Resource %s is in %s state", res.Name, res.Status),
+			Resource:    res.Name,
+			Resolution:  "Check resource events and provider status",
+		})
+	}
+
+	if res.ObservedGeneration > 0 && res.ObservedGeneration < res.Generation {
+		issues = append(issues, Issue{
+			Severity: "Info",
+			Description: fmt.Sprintf("Resource %s has spec changes that are not reconciled yet (generation %d, observed %d)",
+				res.Name, res.Generation, res.ObservedGeneration),
+			Resource:   res.Name,
+			Resolution: "Wait for the next reconcile; if the lag persists the controller may be stuck or down",
+		})
+	}
+
+	if res.DeletionTimestamp != nil {
+		issues = append(issues, Issue{
+			Severity: "Warning",
+			Description: fmt.Sprintf("Resource %s has been deleting for %s",
+				res.Name, time.Since(*res.DeletionTimestamp).Round(time.Minute)),
+			Resource:   res.Name,
+			Reason:     "Deleting",
+			Resolution: resolutionFor("Deleting"),
+		})
+	}
+
+	// Point at the most recent warning event, which usually carries the
+	// provider's error (e.g. CannotCreateExternalResource)
+	if len(issues) > 0 {
+		if event := latestWarning(res.Events); event != nil {
+			issues[0].Description += fmt.Sprintf(" | Last event %s: %s", event.Reason, event.Message)
+		}
+	}
+
+	for i := range issues {
+		issues[i].Cluster = res.Cluster
+		issues[i].Kind = res.Kind
+		issues[i].Namespace = res.Namespace
+	}
+
+	return issues
+}
+
+func latestWarning(events []crossplane.Event) *crossplane.Event {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].IsWarning() {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+func findCondition(conditions []crossplane.Condition, conditionType string) *crossplane.Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// describeCondition renders " (Reason): message" for a condition
+func describeCondition(condition *crossplane.Condition) string {
+	description := ""
+	if condition.Reason != "" {
+		description = fmt.Sprintf(" (%s)", condition.Reason)
+	}
+	if condition.Message != "" {
+		description += ": " + condition.Message
+	}
+	return description
+}
+
+func resolutionFor(reason string) string {
+	if resolution, ok := reasonResolutions[reason]; ok {
+		return resolution
+	}
+	return "Check resource events and provider status"
+}
+
+// GroupByRootCause folds the issues of resources that belong to the same
+// claim or composite tree into one issue on the root of the tree, naming the
+// deepest failing resource. Issues are matched to the resources of a tree
+// by kind, namespace and name. A broken managed resource is then reported once
+// under its claim instead of once for each of the claim, composite and
+// managed resource. Informational issues are kept as they are. Per-cluster
+// issue counts are updated to match.
+func GroupByRootCause(analysis *Analysis, trees []*crossplane.TraceNode) {
+	for _, tree := range trees {
+		cause := tree.FindRootCause()
+		if cause == nil {
+			continue
+		}
+
+		members := make(map[string]bool)
+		failing := 0
+		tree.Walk(func(node *crossplane.TraceNode, _ int) {
+			members[memberKey(node.Cluster, node.Kind, node.Namespace, node.Name)] = true
+			if node.Failing() {
+				failing++
+			}
+		})
+		if failing < 2 {
+			continue
+		}
+
+		grouped := rootCauseIssue(tree, cause, failing)
+		issues := make([]Issue, 0, len(analysis.Issues))
+		inserted := false
+		for _, issue := range analysis.Issues {
+			member := members[memberKey(issue.Cluster, issue.Kind, issue.Namespace, issue.Resource)]
+			if member && issue.Severity != "Info" {
+				if !inserted {
+					issues = append(issues, grouped)
+					inserted = true
+				}
+				continue
+			}
+			issues = append(issues, issue)
+		}
+		if !inserted {
+			issues = append(issues, grouped)
+		}
+		analysis.Issues = issues
+	}
+
+	analysis.IssuesFound = len(analysis.Issues)
+	countClusterIssues(analysis.Clusters, analysis.Issues)
+}
+
+// identifyIssueResources sets the kind and namespace of issues that only
+// name their resource, as long as one resource of the cluster has the name
+func identifyIssueResources(issues []Issue, resources []*ResourceInfo) {
+	named := make(map[string][]*ResourceInfo)
+	for _, res := range resources {
+		key := memberKey(res.Cluster, "", "", res.Name)
+		named[key] = append(named[key], res)
+	}
+
+	for i := range issues {
+		matches := named[memberKey(issues[i].Cluster, "", "", issues[i].Resource)]
+		if issues[i].Kind != "" || len(matches) != 1 {
+			continue
+		}
+		issues[i].Kind = matches[0].Kind
+		issues[i].Namespace = matches[0].Namespace
+	}
+}
+
+func memberKey(cluster, kind, namespace, name string) string {
+	return cluster + "/" + kind + "/" + namespace + "/" + name
+}
+
+func rootCauseIssue(root, cause *crossplane.TraceNode, failing int) Issue {
+	severity := "Warning"
+	if cause.Missing || cause.Error != "" || cause.Synced == "False" {
+		severity = "Critical"
+	}
+
+	state := "is failing"
+	switch {
+	case cause.Missing:
+		state = "does not exist"
+	case cause.Error != "":
+		state = "could not be read: " + cause.Error
+	case cause.Reason != "" && cause.Message != "":
+		state = fmt.Sprintf("is failing (%s): %s", cause.Reason, cause.Message)
+	case cause.Reason != "":
+		state = fmt.Sprintf("is failing (%s)", cause.Reason)
+	}
+
+	resolution := resolutionFor(cause.Reason)
+	if cause.Missing {
+		resolution = fmt.Sprintf("%s is referenced by the composite but missing. Check the composition and the composite's events.", cause.ID())
+	}
+
+	return Issue{
+		Cluster:  root.Cluster,
+		Severity: severity,
+		Description: fmt.Sprintf("%s is not healthy because %s %s (%d resources affected)",
+			root.ID(), cause.ID(), state, failing),
+		Kind:       root.Kind,
+		Namespace:  root.Namespace,
+		Resource:   root.Name,
+		Reason:     cause.Reason,
+		Resolution: resolution,
+	}
+}
+
+// reportsHealth reports whether a resource counts towards health scores;
+// types without status conditions, such as Compositions, do not
+func reportsHealth(res *ResourceInfo) bool {
+	return res.Status != crossplane.StatusNone
+}
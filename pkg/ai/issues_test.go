@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+func TestGroupByRootCause(t *testing.T) {
+	// A claim whose composite is waiting for a subnet group that cannot
+	// get its credentials
+	tree := &crossplane.TraceNode{
+		Kind: "PostgreSQLInstance", Namespace: "team-a", Name: "orders-db", Ready: "False",
+		Children: []*crossplane.TraceNode{{
+			Kind: "XPostgreSQLInstance", Name: "orders-db-x7k2p", Ready: "False",
+			Children: []*crossplane.TraceNode{
+				{Kind: "Instance", Name: "orders-db-x7k2p-rds", Ready: "True", Synced: "True"},
+				{Kind: "SubnetGroup", Name: "orders-db-x7k2p-sng", Synced: "False", Reason: "ReconcileError", Message: "cannot get credentials secret"},
+			},
+		}},
+	}
+	claimIssue := Issue{Severity: "Warning", Kind: "PostgreSQLInstance", Namespace: "team-a", Resource: "orders-db"}
+	compositeIssue := Issue{Severity: "Warning", Kind: "XPostgreSQLInstance", Resource: "orders-db-x7k2p"}
+	subnetIssue := Issue{Severity: "Critical", Kind: "SubnetGroup", Resource: "orders-db-x7k2p-sng"}
+	infoIssue := Issue{Severity: "Info", Kind: "Instance", Resource: "orders-db-x7k2p-rds"}
+	// Same name as the claim, but another kind and namespace
+	otherClaim := Issue{Severity: "Warning", Kind: "PostgreSQLInstance", Namespace: "team-b", Resource: "orders-db"}
+	otherKind := Issue{Severity: "Critical", Kind: "Bucket", Resource: "orders-db-x7k2p-sng"}
+
+	grouped := Issue{
+		Severity:    "Critical",
+		Description: "PostgreSQLInstance/orders-db is not healthy because SubnetGroup/orders-db-x7k2p-sng is failing (ReconcileError): cannot get credentials secret (3 resources affected)",
+		Kind:        "PostgreSQLInstance",
+		Namespace:   "team-a",
+		Resource:    "orders-db",
+		Reason:      "ReconcileError",
+		Resolution:  resolutionFor("ReconcileError"),
+	}
+
+	tests := []struct {
+		name   string
+		issues []Issue
+		want   []Issue
+	}{
+		{
+			name:   "members are grouped under the root",
+			issues: []Issue{claimIssue, compositeIssue, subnetIssue},
+			want:   []Issue{grouped},
+		},
+		{
+			name:   "informational issues are kept",
+			issues: []Issue{infoIssue, subnetIssue, compositeIssue},
+			want:   []Issue{infoIssue, grouped},
+		},
+		{
+			name:   "resources of the same name outside the tree are kept",
+			issues: []Issue{otherClaim, subnetIssue, otherKind},
+			want:   []Issue{otherClaim, grouped, otherKind},
+		},
+		{
+			name:   "no issue of a member",
+			issues: []Issue{otherClaim},
+			want:   []Issue{otherClaim, grouped},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := &Analysis{Issues: tt.issues}
+			GroupByRootCause(analysis, []*crossplane.TraceNode{tree})
+			if !reflect.DeepEqual(analysis.Issues, tt.want) {
+				t.Errorf("issues = %+v\nwant %+v", analysis.Issues, tt.want)
+			}
+			if analysis.IssuesFound != len(tt.want) {
+				t.Errorf("IssuesFound = %d, want %d", analysis.IssuesFound, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestGroupByRootCauseSingleFailure(t *testing.T) {
+	// A tree with a single failing resource has nothing to group
+	tree := &crossplane.TraceNode{
+		Kind: "XBucket", Name: "logs", Ready: "True",
+		Children: []*crossplane.TraceNode{
+			{Kind: "Bucket", Name: "logs-bkt", Synced: "False", Reason: "ReconcileError"},
+		},
+	}
+	issues := []Issue{{Severity: "Critical", Kind: "Bucket", Resource: "logs-bkt"}}
+	analysis := &Analysis{Issues: append([]Issue(nil), issues...)}
+
+	GroupByRootCause(analysis, []*crossplane.TraceNode{tree})
+	if !reflect.DeepEqual(analysis.Issues, issues) {
+		t.Errorf("issues = %+v, want them unchanged", analysis.Issues)
+	}
+}
@@ -0,0 +1,376 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"crossplane-ai/internal/config"
+)
+
+// Names of the LLM backends accepted by ai.provider
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderLocal     = "local"
+)
+
+// LLMProvider is a chat model backend. Implementations translate a
+// CompletionRequest to their vendor's API, so that the service and the
+// commands do not depend on a vendor.
+type LLMProvider interface {
+	// Name is the backend's display name, e.g. "OpenAI"
+	Name() string
+	// Model is the model requests are sent to
+	Model() string
+	// Complete sends a conversation and returns the model's reply with the
+	// tokens it used. Backends bound the reply by their timeout through
+	// ctx rather than an HTTP client timeout, so that an earlier deadline
+	// of the caller applies as well.
+	Complete(ctx context.Context, request CompletionRequest) (*Completion, error)
+}
+
+// Message is a turn of a conversation; Role is "user", "assistant" or
+// "tool" for the result of a tool call
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the tools an assistant turn calls
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool turn holds the result of
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// Tool is a function the model may call instead of replying
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments
+	Parameters map[string]interface{}
+}
+
+// ToolCall is a call of a tool by the model
+type ToolCall struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Arguments is the JSON object of the arguments
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// CompletionRequest is a conversation to complete
+type CompletionRequest struct {
+	// System holds the instructions given ahead of the conversation
+	System      string
+	Messages    []Message
+	MaxTokens   int
+	Temperature float64
+	// JSON asks for a reply that is a single JSON object, using the
+	// backend's structured output mode where it has one
+	JSON bool
+	// Schema constrains a JSON reply where the backend supports it; it
+	// implies JSON
+	Schema *Schema
+	// Tools are offered to the model through the backend's function
+	// calling API
+	Tools []Tool
+}
+
+// Schema is the JSON schema of a structured reply
+type Schema struct {
+	// Name identifies the schema to the backend, e.g. "analysis"
+	Name       string
+	Definition map[string]interface{}
+}
+
+// jsonInstruction asks for a JSON reply in the prompt, for backends or
+// models without a structured output mode
+func jsonInstruction(schema *Schema) string {
+	instruction := "Reply with a single JSON object only, without markdown code fences or any text before or after it."
+	if schema != nil {
+		if definition, err := json.Marshal(schema.Definition); err == nil {
+			instruction += " The object must match this JSON schema: " + string(definition)
+		}
+	}
+	return instruction
+}
+
+// withJSONInstruction returns the request with JSON asked for in the
+// system prompt instead of by the output mode
+func withJSONInstruction(request CompletionRequest) CompletionRequest {
+	request.System = strings.TrimSpace(request.System + "\n\n" + jsonInstruction(request.Schema))
+	request.JSON = false
+	request.Schema = nil
+	return request
+}
+
+// outputModes remembers the structured output modes a model rejected, so
+// that later requests fall back right away
+type outputModes struct {
+	mu         sync.Mutex
+	noSchema   bool
+	noJSONMode bool
+}
+
+// complete sends a request with complete and, when the model rejects its
+// output mode, falls back to weaker ones: a schema to JSON mode with the
+// schema in the prompt, and JSON mode to asking for JSON in the prompt
+func (m *outputModes) complete(ctx context.Context, request CompletionRequest,
+	complete func(context.Context, CompletionRequest) (*Completion, error)) (*Completion, error) {
+
+	request = m.apply(request)
+	for {
+		completion, err := complete(ctx, request)
+		if err == nil || !rejectsOutputMode(request, err) {
+			return completion, err
+		}
+		m.mu.Lock()
+		if request.Schema != nil {
+			m.noSchema = true
+		} else {
+			m.noJSONMode = true
+		}
+		m.mu.Unlock()
+		request = m.apply(request)
+	}
+}
+
+// apply returns the request without the output modes the model rejected
+func (m *outputModes) apply(request CompletionRequest) CompletionRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if request.Schema != nil {
+		request.JSON = true
+	}
+	if request.Schema != nil && m.noSchema {
+		if definition, err := json.Marshal(request.Schema.Definition); err == nil {
+			request.System = strings.TrimSpace(request.System + "\n\nThe reply must match this JSON schema: " + string(definition))
+		}
+		request.Schema = nil
+	}
+	if request.JSON && m.noJSONMode {
+		request = withJSONInstruction(request)
+	}
+	return request
+}
+
+// rejectsOutputMode reports whether a backend rejected the structured
+// output mode of a request, e.g. a model without JSON schema support
+func rejectsOutputMode(request CompletionRequest, err error) bool {
+	var apiErr *apiError
+	if (!request.JSON && request.Schema == nil) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "format") || strings.Contains(message, "schema")
+}
+
+// Completion is a model's reply
+type Completion struct {
+	Content string
+	// ToolCalls are the tools the model calls; their results are sent
+	// back as tool turns for the model to continue
+	ToolCalls []ToolCall
+	// StopReason is why the model stopped, in the backend's terms, e.g.
+	// "stop", "end_turn" or "max_tokens"
+	StopReason string
+	Usage      Usage
+}
+
+// Usage counts the tokens of one or more requests
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	Requests     int `json:"requests"`
+}
+
+// Total returns the number of input and output tokens
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// usageMeter adds up the usage of the requests of a service, which may be
+// sent concurrently
+type usageMeter struct {
+	mu    sync.Mutex
+	usage Usage
+}
+
+func (m *usageMeter) add(usage Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.InputTokens += usage.InputTokens
+	m.usage.OutputTokens += usage.OutputTokens
+	m.usage.Requests++
+}
+
+func (m *usageMeter) get() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// toolArguments returns the arguments of a tool call as a JSON object,
+// which models occasionally leave empty or send malformed
+func toolArguments(arguments string) json.RawMessage {
+	if !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// NewLLMProvider returns the backend selected by ai.provider, or nil when
+// no backend is configured or a hosted one lacks an API key, in which case
+// the template-based answers are used.
+func NewLLMProvider(cfg *config.Config) (LLMProvider, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	provider := strings.ToLower(cfg.AI.Provider)
+	apiKey := getAPIKey(cfg)
+
+	switch provider {
+	case ProviderOpenAI:
+		if apiKey == "" {
+			return nil, nil
+		}
+		return NewOpenAIClient(OpenAIConfig{
+			APIKey:  apiKey,
+			Model:   cfg.AI.Model,
+			BaseURL: cfg.AI.BaseURL,
+			Timeout: replyTimeout(cfg),
+		}), nil
+	case ProviderAnthropic:
+		if apiKey == "" {
+			return nil, nil
+		}
+		return NewAnthropicClient(AnthropicConfig{
+			APIKey:  apiKey,
+			Model:   cfg.AI.Model,
+			BaseURL: cfg.AI.BaseURL,
+			Timeout: replyTimeout(cfg),
+		}), nil
+	case ProviderLocal, "ollama":
+		return NewLocalClient(LocalConfig{
+			BaseURL:       cfg.AI.BaseURL,
+			Model:         cfg.AI.Model,
+			ContextWindow: cfg.AI.ContextWindow,
+			Timeout:       replyTimeout(cfg),
+		}), nil
+	case "", "mock", "template":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported AI provider %q (use %s, %s or %s)", cfg.AI.Provider, ProviderOpenAI, ProviderAnthropic, ProviderLocal)
+}
+
+// replyTimeout is the time a reply that is not streamed may take, from
+// analysis.timeout, or zero for the default of the backend. Local models
+// get at least defaultLocalTimeout, since the default analysis.timeout is
+// meant for hosted APIs.
+func replyTimeout(cfg *config.Config) time.Duration {
+	if cfg == nil || cfg.Analysis.Timeout <= 0 {
+		return 0
+	}
+	timeout := time.Duration(cfg.Analysis.Timeout) * time.Second
+	switch strings.ToLower(cfg.AI.Provider) {
+	case ProviderLocal, "ollama":
+		if timeout < defaultLocalTimeout {
+			return defaultLocalTimeout
+		}
+	}
+	return timeout
+}
+
+// getAPIKey gets the API key from the config, expanding a ${VAR}
+// reference, or from the environment variable of the provider
+func getAPIKey(cfg *config.Config) string {
+	if cfg.AI.APIKey != "" {
+		if strings.HasPrefix(cfg.AI.APIKey, "${") && strings.HasSuffix(cfg.AI.APIKey, "}") {
+			envVar := strings.TrimSuffix(strings.TrimPrefix(cfg.AI.APIKey, "${"), "}")
+			return os.Getenv(envVar)
+		}
+		return cfg.AI.APIKey
+	}
+
+	switch strings.ToLower(cfg.AI.Provider) {
+	case ProviderAnthropic:
+		return os.Getenv("ANTHROPIC_API_KEY")
+	default:
+		return os.Getenv("OPENAI_API_KEY")
+	}
+}
+
+// postJSON sends a JSON request and decodes the JSON response into
+// response. A response status other than 200 is returned as an error with
+// the message errorMessage extracts from the body.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string,
+	request, response interface{}, errorMessage func(body []byte) string) error {
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return sendJSON(ctx, client, http.MethodPost, url, headers, bytes.NewBuffer(jsonData), response, errorMessage)
+}
+
+// getJSON decodes the JSON response to a GET request into response
+func getJSON(ctx context.Context, client *http.Client, url string, response interface{}, errorMessage func(body []byte) string) error {
+	return sendJSON(ctx, client, http.MethodGet, url, nil, nil, response, errorMessage)
+}
+
+func sendJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string,
+	body io.Reader, response interface{}, errorMessage func(body []byte) string) error {
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		message := errorMessage(data)
+		if message == "" {
+			message = strings.TrimSpace(string(data))
+		}
+		return &apiError{StatusCode: resp.StatusCode, Message: message}
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// apiError is a response of an LLM API with a status other than 200
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crossplane-ai/internal/config"
+)
+
+// recordedRequest is a request received by a stand-in server
+type recordedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// standIn is a local stand-in for a backend API; reply writes the response
+// to a request
+type standIn struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+func newStandIn(t *testing.T, reply func(w http.ResponseWriter, body map[string]interface{})) *standIn {
+	t.Helper()
+	s := &standIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body := map[string]interface{}{}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &body); err != nil {
+				t.Errorf("request body is not JSON: %v", err)
+			}
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, recordedRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		s.mu.Unlock()
+		reply(w, body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// request returns the nth request received
+func (s *standIn) request(t *testing.T, n int) recordedRequest {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n >= len(s.requests) {
+		t.Fatalf("got %d requests, want at least %d", len(s.requests), n+1)
+	}
+	return s.requests[n]
+}
+
+// count returns the number of requests received
+func (s *standIn) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+// writeEvents writes server-sent events with the data of events
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range events {
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", event)
+	}
+}
+
+// collectTokens returns an onToken collecting the pieces of a reply
+func collectTokens() (func(string), *[]string) {
+	var tokens []string
+	return func(token string) { tokens = append(tokens, token) }, &tokens
+}
+
+func TestOutputModesFallBack(t *testing.T) {
+	schema := &Schema{Name: "analysis", Definition: map[string]interface{}{"type": "object"}}
+	reject := func(mode string) error {
+		return &apiError{StatusCode: http.StatusBadRequest, Message: "response_format " + mode + " is not supported with this model"}
+	}
+
+	var sent []CompletionRequest
+	complete := func(_ context.Context, request CompletionRequest) (*Completion, error) {
+		sent = append(sent, request)
+		switch {
+		case request.Schema != nil:
+			return nil, reject("json_schema")
+		case request.JSON:
+			return nil, reject("json_object")
+		}
+		return &Completion{Content: "{}"}, nil
+	}
+
+	var modes outputModes
+	if _, err := modes.complete(context.Background(), CompletionRequest{Schema: schema}, complete); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if len(sent) != 3 {
+		t.Fatalf("sent %d requests, want 3", len(sent))
+	}
+	if sent[0].Schema == nil {
+		t.Errorf("first request has no schema")
+	}
+	if sent[1].Schema != nil || !sent[1].JSON || !strings.Contains(sent[1].System, "JSON schema") {
+		t.Errorf("second request = %+v, want JSON mode with the schema in the system prompt", sent[1])
+	}
+	if sent[2].Schema != nil || sent[2].JSON || !strings.Contains(sent[2].System, "Reply with a single JSON object") {
+		t.Errorf("third request = %+v, want JSON asked for in the system prompt", sent[2])
+	}
+
+	// The rejected modes are remembered
+	sent = nil
+	if _, err := modes.complete(context.Background(), CompletionRequest{Schema: schema}, complete); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if len(sent) != 1 || sent[0].JSON || sent[0].Schema != nil {
+		t.Errorf("requests after fallback = %+v, want one plain request", sent)
+	}
+}
+
+func TestOutputModesKeepOtherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request CompletionRequest
+		err     error
+	}{
+		{"server error", CompletionRequest{JSON: true}, &apiError{StatusCode: http.StatusInternalServerError, Message: "response_format failed"}},
+		{"unrelated bad request", CompletionRequest{JSON: true}, &apiError{StatusCode: http.StatusBadRequest, Message: "max_tokens is too large"}},
+		{"no output mode", CompletionRequest{}, &apiError{StatusCode: http.StatusBadRequest, Message: "invalid response_format"}},
+		{"transport error", CompletionRequest{JSON: true}, errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var modes outputModes
+			_, err := modes.complete(context.Background(), tt.request, func(context.Context, CompletionRequest) (*Completion, error) {
+				calls++
+				return nil, tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if calls != 1 {
+				t.Errorf("sent %d requests, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestReplyTimeout(t *testing.T) {
+	tests := []struct {
+		provider string
+		seconds  int
+		want     time.Duration
+	}{
+		{ProviderOpenAI, 90, 90 * time.Second},
+		{ProviderAnthropic, 0, 0},
+		{ProviderLocal, 30, defaultLocalTimeout},
+		{ProviderLocal, 600, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		cfg.AI.Provider = tt.provider
+		cfg.Analysis.Timeout = tt.seconds
+		if got := replyTimeout(cfg); got != tt.want {
+			t.Errorf("replyTimeout(%s, %ds) = %v, want %v", tt.provider, tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,583 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of local model servers
+const (
+	// LocalServerOllama is an Ollama server with its native /api endpoints
+	LocalServerOllama = "ollama"
+	// LocalServerOpenAI is a server with an OpenAI-compatible /v1 API, such
+	// as llama.cpp's llama-server, vLLM or LM Studio
+	LocalServerOpenAI = "openai-compatible"
+)
+
+const (
+	defaultLocalBaseURL = "http://localhost:11434"
+	// defaultLocalContext caps the context window requested from Ollama,
+	// since memory use grows with it; ai.context_window overrides it
+	defaultLocalContext = 8192
+	// fallbackLocalContext is assumed when the server does not report the
+	// model's context length
+	fallbackLocalContext = 4096
+	// defaultLocalTimeout bounds a reply that is not streamed; local models
+	// on CPUs take minutes for a long analysis
+	defaultLocalTimeout = 5 * time.Minute
+)
+
+// LocalConfig represents the configuration of a local model server
+type LocalConfig struct {
+	BaseURL string
+	// Model is the model to use; empty selects the first one the server
+	// lists
+	Model string
+	// ContextWindow is the context length in tokens to use; zero uses what
+	// the server reports for the model, up to defaultLocalContext
+	ContextWindow int
+	// Timeout bounds the probe, a reply that is not streamed and the wait
+	// for a streamed reply to start
+	Timeout time.Duration
+}
+
+// LocalClient is the LLMProvider of a model served inside the network by
+// Ollama or an OpenAI-compatible server such as llama.cpp's llama-server,
+// so that no resource data leaves it. The server kind, model, context
+// window and JSON mode support are probed on first use.
+type LocalClient struct {
+	config       LocalConfig
+	httpClient   *http.Client
+	streamClient *http.Client
+
+	mu     sync.Mutex
+	probed *LocalProbe
+	// probing is closed when the probe in flight finishes
+	probing chan struct{}
+	modes   outputModes
+}
+
+// LocalModel is a model available on a local server
+type LocalModel struct {
+	Name string `json:"name"`
+	// Size is the size of the model's weights in bytes, if reported
+	Size int64 `json:"size,omitempty"`
+	// Family and ParameterSize describe the model, e.g. "llama" and "8B"
+	Family        string `json:"family,omitempty"`
+	ParameterSize string `json:"parameter_size,omitempty"`
+}
+
+// LocalProbe is what was found out about a local server and the model in
+// use
+type LocalProbe struct {
+	Server        string `json:"server"`
+	BaseURL       string `json:"base_url"`
+	Model         string `json:"model"`
+	ContextWindow int    `json:"context_window"`
+	// JSONMode tells whether the model honors the server's JSON output
+	// mode; without it JSON is requested in the prompt
+	JSONMode bool `json:"json_mode"`
+
+	// openai talks to OpenAI-compatible servers
+	openai *OpenAIClient
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name    string `json:"name"`
+		Size    int64  `json:"size"`
+		Details struct {
+			Family        string `json:"family"`
+			ParameterSize string `json:"parameter_size"`
+		} `json:"details"`
+	} `json:"models"`
+}
+
+type ollamaShowResponse struct {
+	ModelInfo map[string]interface{} `json:"model_info"`
+}
+
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Tools    []OpenAITool           `json:"tools,omitempty"`
+	Stream   bool                   `json:"stream"`
+	Format   interface{}            `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaMessage is a message of an Ollama chat, which sends the arguments
+// of tool calls as objects and has no IDs for them
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function ollamaFunctionCall `json:"function"`
+}
+
+type ollamaFunctionCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	Error           string        `json:"error"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+type openAIModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// NewLocalClient creates a client of a local model server
+func NewLocalClient(config LocalConfig) *LocalClient {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultLocalBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(strings.TrimSuffix(config.BaseURL, "/"), "/v1")
+	if config.Timeout == 0 {
+		config.Timeout = defaultLocalTimeout
+	}
+
+	return &LocalClient{
+		config:       config,
+		httpClient:   &http.Client{},
+		streamClient: newStreamingHTTPClient(config.Timeout),
+	}
+}
+
+// Name returns the display name of the backend
+func (c *LocalClient) Name() string {
+	return "Local model"
+}
+
+// Model returns the configured model, or the one picked by the probe
+func (c *LocalClient) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.probed != nil {
+		return c.probed.Model
+	}
+	return c.config.Model
+}
+
+// ContextWindow returns the context length in tokens the model is used with
+func (c *LocalClient) ContextWindow(ctx context.Context) (int, error) {
+	probe, err := c.Probe(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return probe.ContextWindow, nil
+}
+
+// ListModels returns the models the server offers
+func (c *LocalClient) ListModels(ctx context.Context) ([]LocalModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	_, models, err := c.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models, nil
+}
+
+// listModels finds out the kind of server from the endpoint that lists its
+// models
+func (c *LocalClient) listModels(ctx context.Context) (string, []LocalModel, error) {
+	var tags ollamaTagsResponse
+	err := getJSON(ctx, c.httpClient, c.config.BaseURL+"/api/tags", &tags, ollamaErrorMessage)
+	if err == nil {
+		models := make([]LocalModel, 0, len(tags.Models))
+		for _, model := range tags.Models {
+			models = append(models, LocalModel{
+				Name:          model.Name,
+				Size:          model.Size,
+				Family:        model.Details.Family,
+				ParameterSize: model.Details.ParameterSize,
+			})
+		}
+		return LocalServerOllama, models, nil
+	}
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		return "", nil, c.unreachable(err)
+	}
+
+	var list openAIModelsResponse
+	if err := getJSON(ctx, c.httpClient, c.config.BaseURL+"/v1/models", &list, openAIErrorMessage); err != nil {
+		if !errors.As(err, &apiErr) {
+			return "", nil, c.unreachable(err)
+		}
+		return "", nil, fmt.Errorf("%s is neither an Ollama nor an OpenAI-compatible model server: %w", c.config.BaseURL, err)
+	}
+	models := make([]LocalModel, 0, len(list.Data))
+	for _, model := range list.Data {
+		models = append(models, LocalModel{Name: model.ID})
+	}
+	return LocalServerOpenAI, models, nil
+}
+
+// unreachable describes a failure to talk to the server. A timeout means
+// the server took the connection but the model did not answer in time,
+// which is usual while a large model is loaded into memory.
+func (c *LocalClient) unreachable(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("the local model server at %s timed out; the model may still be loading, so retry or raise the timeout: %w",
+			c.config.BaseURL, err)
+	}
+	return fmt.Errorf("cannot reach the local model server at %s (start it with `ollama serve` or `llama-server`, or set ai.base_url): %w",
+		c.config.BaseURL, err)
+}
+
+// Probe finds out the kind of server, checks that the model is available,
+// and determines its context window and whether it supports JSON mode. The
+// result is kept once the probe succeeds. Concurrent callers wait for the
+// probe in flight instead of starting their own, and the lock is not held
+// while it talks to the server, so Model does not block on it.
+func (c *LocalClient) Probe(ctx context.Context) (*LocalProbe, error) {
+	for {
+		c.mu.Lock()
+		if c.probed != nil {
+			probe := c.probed
+			c.mu.Unlock()
+			return probe, nil
+		}
+		if c.probing == nil {
+			done := make(chan struct{})
+			c.probing = done
+			c.mu.Unlock()
+
+			probe, err := c.probe(ctx)
+
+			c.mu.Lock()
+			if err == nil {
+				c.probed = probe
+			}
+			c.probing = nil
+			close(done)
+			c.mu.Unlock()
+			return probe, err
+		}
+		probing := c.probing
+		c.mu.Unlock()
+
+		// A failed probe is retried by the next caller, with its own context
+		select {
+		case <-probing:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// probe talks to the server to fill in a LocalProbe
+func (c *LocalClient) probe(ctx context.Context) (*LocalProbe, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	server, models, err := c.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		if server == LocalServerOllama {
+			return nil, fmt.Errorf("no models are installed on the Ollama server at %s; pull one with `ollama pull <model>`", c.config.BaseURL)
+		}
+		return nil, fmt.Errorf("the model server at %s lists no models", c.config.BaseURL)
+	}
+
+	probe := &LocalProbe{Server: server, BaseURL: c.config.BaseURL, Model: c.config.Model}
+	if probe.Model == "" {
+		probe.Model = models[0].Name
+	} else if name, ok := findLocalModel(models, probe.Model); ok {
+		probe.Model = name
+	} else {
+		names := make([]string, len(models))
+		for i, model := range models {
+			names[i] = model.Name
+		}
+		hint := ""
+		if server == LocalServerOllama {
+			hint = fmt.Sprintf("; pull it with `ollama pull %s`", probe.Model)
+		}
+		return nil, fmt.Errorf("model %q is not available on %s (available: %s)%s",
+			probe.Model, c.config.BaseURL, strings.Join(names, ", "), hint)
+	}
+
+	if server == LocalServerOpenAI {
+		probe.openai = NewOpenAIClient(OpenAIConfig{
+			BaseURL: c.config.BaseURL + "/v1",
+			Model:   probe.Model,
+			Timeout: c.config.Timeout,
+		})
+	}
+	probe.ContextWindow = c.contextWindow(ctx, server, probe.Model)
+
+	// Servers reject or ignore JSON mode depending on their version and the
+	// model's template, so it is tried with a request small enough for any
+	// model
+	completion, err := c.complete(ctx, probe, CompletionRequest{
+		Messages:  []Message{{Role: "user", Content: `Reply with the JSON object {"ok": true}.`}},
+		MaxTokens: 20,
+		JSON:      true,
+	}, nil)
+	if err != nil {
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) {
+			return nil, c.unreachable(err)
+		}
+	} else {
+		var reply map[string]interface{}
+		probe.JSONMode = json.Unmarshal([]byte(strings.TrimSpace(completion.Content)), &reply) == nil
+	}
+
+	return probe, nil
+}
+
+// findLocalModel returns the listed name of a model
+func findLocalModel(models []LocalModel, name string) (string, bool) {
+	for _, model := range models {
+		// Ollama adds the default tag to names given without one
+		if model.Name == name || model.Name == name+":latest" {
+			return model.Name, true
+		}
+	}
+	return "", false
+}
+
+// contextWindow determines the context length to use the model with: the
+// configured one, or what the server reports up to defaultLocalContext
+func (c *LocalClient) contextWindow(ctx context.Context, server, model string) int {
+	if c.config.ContextWindow > 0 {
+		return c.config.ContextWindow
+	}
+
+	window := 0
+	switch server {
+	case LocalServerOllama:
+		var show ollamaShowResponse
+		if postJSON(ctx, c.httpClient, c.config.BaseURL+"/api/show", nil,
+			map[string]string{"model": model}, &show, ollamaErrorMessage) == nil {
+			for key, value := range show.ModelInfo {
+				if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+					window = int(length)
+				}
+			}
+		}
+	case LocalServerOpenAI:
+		// llama-server reports the context it was started with (-c)
+		var props struct {
+			NCtx                      int `json:"n_ctx"`
+			DefaultGenerationSettings struct {
+				NCtx int `json:"n_ctx"`
+			} `json:"default_generation_settings"`
+		}
+		if getJSON(ctx, c.httpClient, c.config.BaseURL+"/props", &props, openAIErrorMessage) == nil {
+			window = props.DefaultGenerationSettings.NCtx
+			if props.NCtx > 0 {
+				window = props.NCtx
+			}
+		}
+		if window > 0 {
+			return window
+		}
+	}
+
+	switch {
+	case window == 0:
+		return fallbackLocalContext
+	case window > defaultLocalContext:
+		return defaultLocalContext
+	}
+	return window
+}
+
+// Complete sends a conversation to the local model. Without JSON mode a
+// JSON reply is asked for in the system prompt.
+func (c *LocalClient) Complete(ctx context.Context, request CompletionRequest) (*Completion, error) {
+	return c.send(ctx, request, nil)
+}
+
+// Stream sends a conversation to the local model and passes the reply to
+// onToken as it is generated
+func (c *LocalClient) Stream(ctx context.Context, request CompletionRequest, onToken func(string)) (*Completion, error) {
+	return c.send(ctx, request, onToken)
+}
+
+// send completes a conversation, streaming the reply when onToken is set
+func (c *LocalClient) send(ctx context.Context, request CompletionRequest, onToken func(string)) (*Completion, error) {
+	probe, err := c.Probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if (request.JSON || request.Schema != nil) && !probe.JSONMode {
+		request = withJSONInstruction(request)
+	}
+	if onToken == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	completion, err := c.modes.complete(ctx, request, func(ctx context.Context, request CompletionRequest) (*Completion, error) {
+		return c.complete(ctx, probe, request, onToken)
+	})
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return nil, c.unreachable(ctx.Err())
+		}
+		// A stream may also fail after the server was reached
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, c.unreachable(err)
+		}
+		return nil, err
+	}
+	return completion, nil
+}
+
+func (c *LocalClient) complete(ctx context.Context, probe *LocalProbe, request CompletionRequest, onToken func(string)) (*Completion, error) {
+	if probe.Server == LocalServerOpenAI {
+		if onToken != nil {
+			return probe.openai.Stream(ctx, request, onToken)
+		}
+		return probe.openai.Complete(ctx, request)
+	}
+
+	ollamaRequest := ollamaChatRequest{
+		Model: probe.Model,
+		Options: map[string]interface{}{
+			// Ollama otherwise truncates the prompt to its small default
+			"num_ctx": probe.ContextWindow,
+		},
+	}
+	if request.System != "" {
+		ollamaRequest.Messages = append(ollamaRequest.Messages, ollamaMessage{Role: "system", Content: request.System})
+	}
+	for _, message := range request.Messages {
+		ollamaRequest.Messages = append(ollamaRequest.Messages, newOllamaMessage(message))
+	}
+	ollamaRequest.Tools = openAITools(request.Tools)
+	switch {
+	case request.Schema != nil:
+		// Ollama 0.5 and later constrain the reply to a JSON schema
+		ollamaRequest.Format = request.Schema.Definition
+	case request.JSON:
+		ollamaRequest.Format = "json"
+	}
+	if request.MaxTokens > 0 {
+		ollamaRequest.Options["num_predict"] = request.MaxTokens
+	}
+	if request.Temperature > 0 {
+		ollamaRequest.Options["temperature"] = request.Temperature
+	}
+	if onToken != nil {
+		return c.stream(ctx, ollamaRequest, onToken)
+	}
+
+	var response ollamaChatResponse
+	if err := postJSON(ctx, c.httpClient, c.config.BaseURL+"/api/chat", nil,
+		ollamaRequest, &response, ollamaErrorMessage); err != nil {
+		return nil, err
+	}
+
+	completion := &Completion{
+		Content:    response.Message.Content,
+		StopReason: response.DoneReason,
+		Usage: Usage{
+			InputTokens:  response.PromptEvalCount,
+			OutputTokens: response.EvalCount,
+		},
+	}
+	for i, call := range response.Message.ToolCalls {
+		completion.ToolCalls = append(completion.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i),
+			Name:      call.Function.Name,
+			Arguments: toolArguments(string(call.Function.Arguments)),
+		})
+	}
+	return completion, nil
+}
+
+// newOllamaMessage translates a message to an Ollama chat, which matches
+// tool results to calls by order
+func newOllamaMessage(message Message) ollamaMessage {
+	ollama := ollamaMessage{Role: message.Role, Content: message.Content}
+	for _, call := range message.ToolCalls {
+		ollama.ToolCalls = append(ollama.ToolCalls, ollamaToolCall{
+			Function: ollamaFunctionCall{Name: call.Name, Arguments: call.Arguments},
+		})
+	}
+	return ollama
+}
+
+// stream sends a chat request to Ollama with stream set, which replies with
+// a JSON object per line, the last one done and holding the token counts
+func (c *LocalClient) stream(ctx context.Context, request ollamaChatRequest, onToken func(string)) (*Completion, error) {
+	request.Stream = true
+	body, err := openStream(ctx, c.streamClient, c.config.BaseURL+"/api/chat", nil, request, ollamaErrorMessage)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	var content strings.Builder
+	completion := &Completion{}
+	err = readLines(body, func(line []byte) (bool, error) {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return false, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Error != "" {
+			return false, fmt.Errorf("stream failed: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			onToken(chunk.Message.Content)
+		}
+		if chunk.Done {
+			completion.StopReason = chunk.DoneReason
+			completion.Usage = Usage{InputTokens: chunk.PromptEvalCount, OutputTokens: chunk.EvalCount}
+		}
+		return chunk.Done, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	completion.Content = content.String()
+	return completion, nil
+}
+
+// ollamaErrorMessage extracts the message of an Ollama error response
+func ollamaErrorMessage(body []byte) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &response) != nil {
+		return ""
+	}
+	return response.Error
+}
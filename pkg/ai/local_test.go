@@ -0,0 +1,110 @@
+package ai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newOllamaStandIn serves an Ollama with one model; chat replies after
+// chatDelay and the model list waits for listed to be closed
+func newOllamaStandIn(t *testing.T, listed chan struct{}, chatDelay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var lists atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			lists.Add(1)
+			<-listed
+			writeJSON(w, http.StatusOK, `{"models": [{"name": "llama3.1:latest"}]}`)
+		case "/api/show":
+			writeJSON(w, http.StatusOK, `{"model_info": {"llama.context_length": 4096}}`)
+		case "/api/chat":
+			// The request is read so that a client giving up cancels it
+			_, _ = io.Copy(io.Discard, r.Body)
+			select {
+			case <-time.After(chatDelay):
+			case <-r.Context().Done():
+				return
+			}
+			writeJSON(w, http.StatusOK, `{"message": {"role": "assistant", "content": "{\"ok\": true}"}, "done": true}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &lists
+}
+
+func TestLocalProbeOnce(t *testing.T) {
+	listed := make(chan struct{})
+	server, lists := newOllamaStandIn(t, listed, 0)
+	client := NewLocalClient(LocalConfig{BaseURL: server.URL})
+
+	var wg sync.WaitGroup
+	probes := make([]*LocalProbe, 3)
+	for i := range probes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			probe, err := client.Probe(context.Background())
+			if err != nil {
+				t.Errorf("Probe: %v", err)
+			}
+			probes[i] = probe
+		}(i)
+	}
+
+	// Model does not wait for the probe in flight
+	for lists.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	modelDone := make(chan string)
+	go func() { modelDone <- client.Model() }()
+	select {
+	case model := <-modelDone:
+		if model != "" {
+			t.Errorf("Model() during the probe = %q, want the configured model", model)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Model() blocked on the probe")
+	}
+
+	close(listed)
+	wg.Wait()
+	if n := lists.Load(); n != 1 {
+		t.Errorf("listed models %d times, want 1", n)
+	}
+	for i, probe := range probes {
+		if probe != probes[0] {
+			t.Errorf("probe %d = %+v, want the shared probe %+v", i, probe, probes[0])
+		}
+	}
+	if probes[0] == nil || !probes[0].JSONMode || probes[0].ContextWindow != 4096 {
+		t.Errorf("probe = %+v", probes[0])
+	}
+	if model := client.Model(); model != "llama3.1:latest" {
+		t.Errorf("Model() = %q, want the probed model", model)
+	}
+}
+
+func TestLocalProbeTimeout(t *testing.T) {
+	listed := make(chan struct{})
+	close(listed)
+	server, _ := newOllamaStandIn(t, listed, time.Minute)
+	client := NewLocalClient(LocalConfig{BaseURL: server.URL, Timeout: 100 * time.Millisecond})
+
+	_, err := client.Probe(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "may still be loading") {
+		t.Errorf("err = %v, want a timeout while the model loads", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "cannot reach") {
+		t.Errorf("err = %v, reported as unreachable", err)
+	}
+}
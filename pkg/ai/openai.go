@@ -1,12 +1,11 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -15,27 +14,83 @@ type OpenAIConfig struct {
 	APIKey  string
 	Model   string
 	BaseURL string
+	// Timeout bounds a reply that is not streamed, including the requests
+	// retried without an output mode the model rejects, and the wait for a
+	// streamed reply to start
 	Timeout time.Duration
 }
 
-// OpenAIClient represents an OpenAI API client
+// OpenAIClient is the LLMProvider of the OpenAI Chat Completions API and
+// of servers compatible with it
 type OpenAIClient struct {
-	config     OpenAIConfig
-	httpClient *http.Client
+	config       OpenAIConfig
+	httpClient   *http.Client
+	streamClient *http.Client
+	modes        outputModes
 }
 
 // OpenAIRequest represents a request to OpenAI API
 type OpenAIRequest struct {
-	Model       string          `json:"model"`
-	Messages    []OpenAIMessage `json:"messages"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
+	Model          string                `json:"model"`
+	Messages       []OpenAIMessage       `json:"messages"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    float64               `json:"temperature,omitempty"`
+	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"`
+	Tools          []OpenAITool          `json:"tools,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *OpenAIStreamOptions  `json:"stream_options,omitempty"`
+}
+
+// OpenAIStreamOptions asks for the usage in the last chunk of a stream
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAIMessage represents a message in OpenAI conversation
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// OpenAITool is a function offered to the model
+type OpenAITool struct {
+	Type     string             `json:"type"`
+	Function OpenAIToolFunction `json:"function"`
+}
+
+// OpenAIToolFunction describes a function and the JSON schema of its
+// arguments
+type OpenAIToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// OpenAIToolCall is a call of a function by the model; Arguments is the
+// JSON of the arguments encoded as a string
+type OpenAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// OpenAIResponseFormat selects JSON mode with type "json_object", or
+// structured output with type "json_schema"
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+// OpenAIJSONSchema is the schema of a structured output
+type OpenAIJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
 }
 
 // OpenAIResponse represents a response from OpenAI API
@@ -56,6 +111,20 @@ type OpenAIResponse struct {
 	} `json:"usage"`
 }
 
+// OpenAIStreamChunk is a server-sent event of a streamed completion
+type OpenAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
 // NewOpenAIClient creates a new OpenAI client
 func NewOpenAIClient(config OpenAIConfig) *OpenAIClient {
 	if config.BaseURL == "" {
@@ -69,175 +138,173 @@ func NewOpenAIClient(config OpenAIConfig) *OpenAIClient {
 	}
 
 	return &OpenAIClient{
-		config: config,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		config:       config,
+		httpClient:   &http.Client{},
+		streamClient: newStreamingHTTPClient(config.Timeout),
 	}
 }
 
-// Complete sends a completion request to OpenAI API
-func (c *OpenAIClient) Complete(ctx context.Context, prompt string) (string, error) {
-	request := OpenAIRequest{
-		Model: c.config.Model,
-		Messages: []OpenAIMessage{
-			{
-				Role:    "system",
-				Content: "You are an expert Crossplane infrastructure assistant. Provide helpful, accurate, and actionable responses about Crossplane resources, Kubernetes, and cloud infrastructure. Keep responses concise but informative.",
-			},
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-		MaxTokens:   1000,
-		Temperature: 0.7,
-	}
-
-	return c.sendRequest(ctx, request)
+// Name returns the display name of the backend
+func (c *OpenAIClient) Name() string {
+	return "OpenAI"
 }
 
-// CompleteWithContext sends a completion request with additional context
-func (c *OpenAIClient) CompleteWithContext(ctx context.Context, query, resourceContext string) (string, error) {
-	prompt := fmt.Sprintf(`Context: You are analyzing Crossplane resources in a Kubernetes cluster.
-
-Resource Information:
-%s
-
-User Query: %s
-
-Please provide a helpful response based on the resource context. If the query is about specific resources, reference the actual resource names and statuses from the context.`, resourceContext, query)
-
-	return c.Complete(ctx, prompt)
+// Model returns the model requests are sent to
+func (c *OpenAIClient) Model() string {
+	return c.config.Model
 }
 
-// GenerateSuggestions generates AI-powered suggestions
-func (c *OpenAIClient) GenerateSuggestions(ctx context.Context, suggestionType, resourceContext string) ([]Suggestion, error) {
-	prompt := fmt.Sprintf(`As a Crossplane expert, analyze the following resources and provide specific %s suggestions.
-
-Resource Context:
-%s
-
-Provide 3-5 actionable suggestions in JSON format as an array of objects with fields:
-- title: Brief suggestion title
-- description: Detailed explanation
-- priority: High/Medium/Low
-- category: The category of suggestion
-- example: Optional YAML example if applicable
+// Complete sends a conversation to the Chat Completions API. A JSON schema
+// is sent as structured output, falling back to JSON mode and then to the
+// prompt for models without them.
+func (c *OpenAIClient) Complete(ctx context.Context, request CompletionRequest) (*Completion, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+	return c.modes.complete(ctx, request, c.complete)
+}
 
-Focus on practical, implementable suggestions for Crossplane and Kubernetes infrastructure.`, suggestionType, resourceContext)
+func (c *OpenAIClient) complete(ctx context.Context, request CompletionRequest) (*Completion, error) {
+	openaiRequest := c.newRequest(request)
 
-	response, err := c.Complete(ctx, prompt)
-	if err != nil {
+	var response OpenAIResponse
+	if err := postJSON(ctx, c.httpClient, c.config.BaseURL+"/chat/completions", c.headers(),
+		openaiRequest, &response, openAIErrorMessage); err != nil {
 		return nil, err
 	}
 
-	// Try to parse JSON response
-	var suggestions []Suggestion
-	if err := json.Unmarshal([]byte(response), &suggestions); err != nil {
-		// If JSON parsing fails, create a single suggestion with the response
-		return []Suggestion{
-			{
-				Title:       fmt.Sprintf("AI Suggestion for %s", suggestionType),
-				Description: response,
-				Priority:    "Medium",
-				Category:    suggestionType,
-			},
-		}, nil
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices returned")
 	}
 
-	return suggestions, nil
-}
-
-// AnalyzeResources performs AI analysis of resources
-func (c *OpenAIClient) AnalyzeResources(ctx context.Context, resourceContext string, healthCheck bool) (*Analysis, error) {
-	analysisType := "general"
-	if healthCheck {
-		analysisType = "health-focused"
+	completion := &Completion{
+		Content:    response.Choices[0].Message.Content,
+		StopReason: response.Choices[0].FinishReason,
+		Usage: Usage{
+			InputTokens:  response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		},
 	}
+	for _, call := range response.Choices[0].Message.ToolCalls {
+		completion.ToolCalls = append(completion.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: toolArguments(call.Function.Arguments),
+		})
+	}
+	return completion, nil
+}
 
-	prompt := fmt.Sprintf(`Analyze the following Crossplane resources and provide a %s analysis.
-
-Resource Context:
-%s
-
-Provide analysis in JSON format with these fields:
-- total_resources: number of total resources
-- healthy_resources: number of healthy resources  
-- issues_found: number of issues detected
-- health_score: overall health score (0-100)
-- resources: array of resource info with name, type, status, provider, age
-- issues: array of issues with severity, description, resource, resolution
-- recommendations: array of recommendations with title, description, impact, priority
-
-Focus on actionable insights for Crossplane infrastructure management.`, analysisType, resourceContext)
+// Stream sends a conversation to the Chat Completions API with stream set
+// and passes the content deltas to onToken
+func (c *OpenAIClient) Stream(ctx context.Context, request CompletionRequest, onToken func(string)) (*Completion, error) {
+	openaiRequest := c.newRequest(request)
+	openaiRequest.Stream = true
+	openaiRequest.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
 
-	response, err := c.Complete(ctx, prompt)
+	body, err := openStream(ctx, c.streamClient, c.config.BaseURL+"/chat/completions", c.headers(),
+		openaiRequest, openAIErrorMessage)
 	if err != nil {
 		return nil, err
 	}
-
-	// Try to parse JSON response
-	var analysis Analysis
-	if err := json.Unmarshal([]byte(response), &analysis); err != nil {
-		// If JSON parsing fails, return a basic analysis with the response as a recommendation
-		return &Analysis{
-			TotalResources:   1,
-			HealthyResources: 1,
-			IssuesFound:      0,
-			HealthScore:      85,
-			Recommendations: []Recommendation{
-				{
-					Title:       "AI Analysis Results",
-					Description: response,
-					Priority:    "Medium",
-				},
-			},
-		}, nil
+	defer func() { _ = body.Close() }()
+
+	var content strings.Builder
+	completion := &Completion{}
+	err = readEvents(body, func(_, data string) (bool, error) {
+		if data == "[DONE]" {
+			return true, nil
+		}
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return false, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onToken(choice.Delta.Content)
+			}
+			if choice.FinishReason != "" {
+				completion.StopReason = choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			completion.Usage = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &analysis, nil
+	completion.Content = content.String()
+	return completion, nil
 }
 
-// sendRequest sends a request to OpenAI API
-func (c *OpenAIClient) sendRequest(ctx context.Context, request OpenAIRequest) (string, error) {
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
+func (c *OpenAIClient) headers() map[string]string {
+	return map[string]string{"Authorization": fmt.Sprintf("Bearer %s", c.config.APIKey)}
+}
 
-	url := fmt.Sprintf("%s/chat/completions", c.config.BaseURL)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+// newRequest translates a CompletionRequest to the Chat Completions API
+func (c *OpenAIClient) newRequest(request CompletionRequest) OpenAIRequest {
+	openaiRequest := OpenAIRequest{
+		Model:       c.config.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+	if request.System != "" {
+		openaiRequest.Messages = append(openaiRequest.Messages, OpenAIMessage{Role: "system", Content: request.System})
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+	for _, message := range request.Messages {
+		openaiMessage := OpenAIMessage{Role: message.Role, Content: message.Content, ToolCallID: message.ToolCallID}
+		for _, call := range message.ToolCalls {
+			openaiCall := OpenAIToolCall{ID: call.ID, Type: "function"}
+			openaiCall.Function.Name = call.Name
+			openaiCall.Function.Arguments = string(call.Arguments)
+			openaiMessage.ToolCalls = append(openaiMessage.ToolCalls, openaiCall)
+		}
+		openaiRequest.Messages = append(openaiRequest.Messages, openaiMessage)
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	switch {
+	case request.Schema != nil:
+		// Strict mode would require every property, so optional fields
+		// are left to validation
+		openaiRequest.ResponseFormat = &OpenAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &OpenAIJSONSchema{Name: request.Schema.Name, Schema: request.Schema.Definition},
+		}
+	case request.JSON:
+		openaiRequest.ResponseFormat = &OpenAIResponseFormat{Type: "json_object"}
 	}
+	openaiRequest.Tools = openAITools(request.Tools)
+	return openaiRequest
+}
 
-	var response OpenAIResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+// openAITools translates tools to the function definitions of the Chat
+// Completions API, which Ollama accepts as well
+func openAITools(tools []Tool) []OpenAITool {
+	var openaiTools []OpenAITool
+	for _, tool := range tools {
+		openaiTools = append(openaiTools, OpenAITool{
+			Type: "function",
+			Function: OpenAIToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
 	}
+	return openaiTools
+}
 
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+// openAIErrorMessage extracts the message of an OpenAI error response
+func openAIErrorMessage(body []byte) string {
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
 	}
-
-	return response.Choices[0].Message.Content, nil
+	if json.Unmarshal(body, &response) != nil {
+		return ""
+	}
+	return response.Error.Message
 }
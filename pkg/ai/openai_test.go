@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOpenAIClient(server *standIn) *OpenAIClient {
+	return NewOpenAIClient(OpenAIConfig{APIKey: "test-key", Model: "test-model", BaseURL: server.URL})
+}
+
+func TestOpenAIComplete(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeJSON(w, http.StatusOK, `{
+			"choices": [{
+				"message": {
+					"role": "assistant",
+					"content": "two resources are not ready",
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "trace", "arguments": "{\"resource\":\"db\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 120, "completion_tokens": 30}
+		}`)
+	})
+
+	completion, err := newTestOpenAIClient(server).Complete(context.Background(), CompletionRequest{
+		System:    "be brief",
+		Messages:  []Message{{Role: "user", Content: "what is broken?"}},
+		MaxTokens: 100,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	request := server.request(t, 0)
+	if request.Path != "/chat/completions" {
+		t.Errorf("path = %q", request.Path)
+	}
+	if got := request.Header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if request.Body["model"] != "test-model" || request.Body["max_tokens"] != float64(100) {
+		t.Errorf("body = %v", request.Body)
+	}
+	messages := request.Body["messages"].([]interface{})
+	if first := messages[0].(map[string]interface{}); first["role"] != "system" || first["content"] != "be brief" {
+		t.Errorf("first message = %v, want the system prompt", first)
+	}
+	if _, ok := request.Body["response_format"]; ok {
+		t.Errorf("response_format sent without JSON")
+	}
+
+	want := &Completion{
+		Content:    "two resources are not ready",
+		ToolCalls:  []ToolCall{{ID: "call_1", Name: "trace", Arguments: []byte(`{"resource":"db"}`)}},
+		StopReason: "tool_calls",
+		Usage:      Usage{InputTokens: 120, OutputTokens: 30},
+	}
+	if !reflect.DeepEqual(completion, want) {
+		t.Errorf("completion = %+v, want %+v", completion, want)
+	}
+}
+
+func TestOpenAICompleteSchema(t *testing.T) {
+	// The model rejects structured outputs, so the request is sent again
+	// in JSON mode
+	server := newStandIn(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		format := body["response_format"].(map[string]interface{})
+		if format["type"] == "json_schema" {
+			writeJSON(w, http.StatusBadRequest, `{"error": {"message": "Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model."}}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"choices": [{"message": {"role": "assistant", "content": "{\"health_score\": 90}"}, "finish_reason": "stop"}]}`)
+	})
+
+	schema := &Schema{Name: "analysis", Definition: map[string]interface{}{"type": "object"}}
+	completion, err := newTestOpenAIClient(server).Complete(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "analyze"}},
+		Schema:   schema,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if completion.Content != `{"health_score": 90}` {
+		t.Errorf("content = %q", completion.Content)
+	}
+
+	if server.count() != 2 {
+		t.Fatalf("sent %d requests, want 2", server.count())
+	}
+	first := server.request(t, 0).Body["response_format"].(map[string]interface{})
+	jsonSchema := first["json_schema"].(map[string]interface{})
+	if jsonSchema["name"] != "analysis" || !reflect.DeepEqual(jsonSchema["schema"], map[string]interface{}{"type": "object"}) {
+		t.Errorf("json_schema = %v", jsonSchema)
+	}
+	second := server.request(t, 1).Body
+	if second["response_format"].(map[string]interface{})["type"] != "json_object" {
+		t.Errorf("second response_format = %v, want json_object", second["response_format"])
+	}
+	system := second["messages"].([]interface{})[0].(map[string]interface{})
+	if system["role"] != "system" || !strings.Contains(system["content"].(string), `"type":"object"`) {
+		t.Errorf("second request does not carry the schema in the system prompt: %v", system)
+	}
+}
+
+func TestOpenAIStream(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		writeEvents(w,
+			`{"choices": [{"delta": {"role": "assistant"}}]}`,
+			`{"choices": [{"delta": {"content": "payments-db "}}]}`,
+			`{"choices": [{"delta": {"content": "is not ready"}, "finish_reason": "stop"}]}`,
+			`{"choices": [], "usage": {"prompt_tokens": 50, "completion_tokens": 4}}`,
+			`[DONE]`,
+		)
+	})
+
+	onToken, tokens := collectTokens()
+	completion, err := newTestOpenAIClient(server).Stream(context.Background(), CompletionRequest{
+		Messages: []Message{{Role: "user", Content: "status?"}},
+	}, onToken)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	body := server.request(t, 0).Body
+	if body["stream"] != true || !reflect.DeepEqual(body["stream_options"], map[string]interface{}{"include_usage": true}) {
+		t.Errorf("body = %v, want stream with usage", body)
+	}
+	if want := []string{"payments-db ", "is not ready"}; !reflect.DeepEqual(*tokens, want) {
+		t.Errorf("tokens = %q, want %q", *tokens, want)
+	}
+	want := &Completion{Content: "payments-db is not ready", StopReason: "stop", Usage: Usage{InputTokens: 50, OutputTokens: 4}}
+	if !reflect.DeepEqual(completion, want) {
+		t.Errorf("completion = %+v, want %+v", completion, want)
+	}
+}
+
+func TestOpenAIErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+	}{
+		{"error object", http.StatusUnauthorized, `{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error"}}`, "Incorrect API key provided"},
+		{"plain body", http.StatusBadGateway, "upstream unavailable\n", "upstream unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+				writeJSON(w, tt.status, tt.body)
+			})
+			client := newTestOpenAIClient(server)
+			request := CompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}}
+
+			_, completeErr := client.Complete(context.Background(), request)
+			_, streamErr := client.Stream(context.Background(), request, func(string) {})
+			for name, err := range map[string]error{"Complete": completeErr, "Stream": streamErr} {
+				var apiErr *apiError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("%s error = %v, want an apiError", name, err)
+				}
+				if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage {
+					t.Errorf("%s error = %d %q, want %d %q", name, apiErr.StatusCode, apiErr.Message, tt.status, tt.wantMessage)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAICompleteTimeout(t *testing.T) {
+	server := newStandIn(t, func(w http.ResponseWriter, _ map[string]interface{}) {
+		time.Sleep(300 * time.Millisecond)
+		writeJSON(w, http.StatusOK, `{"choices": [{"message": {"role": "assistant", "content": "late"}}]}`)
+	})
+	client := NewOpenAIClient(OpenAIConfig{APIKey: "test-key", BaseURL: server.URL, Timeout: 50 * time.Millisecond})
+
+	_, err := client.Complete(context.Background(), CompletionRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the timeout", err)
+	}
+}
@@ -0,0 +1,212 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+const (
+	// charsPerToken estimates the tokens of resource JSON, which averages
+	// about four characters per token
+	charsPerToken = 4
+	// promptReserveTokens is kept free for the instructions and the reply,
+	// or a third of smaller context windows
+	promptReserveTokens = 1500
+	// maxContextString caps condition and event messages in a shrunk
+	// context
+	maxContextString = 300
+)
+
+// contextWindowed is implemented by backends whose models have a context
+// window small enough for the resource JSON to overflow it
+type contextWindowed interface {
+	ContextWindow(ctx context.Context) (int, error)
+}
+
+// verboseContextKeys are left out first when the resources do not fit the
+// model's context window
+var verboseContextKeys = map[string]bool{
+	"labels":              true,
+	"annotations":         true,
+	"apiVersion":          true,
+	"createdAt":           true,
+	"created_at":          true,
+	"lastTransitionTime":  true,
+	"generation":          true,
+	"observedGeneration":  true,
+	"observed_generation": true,
+	"firstTimestamp":      true,
+	"source":              true,
+}
+
+// fitContext shrinks the resource JSON of a prompt to the context window
+// of the model, if the backend has a small one. It leaves out verbose
+// fields first, then specs and all but the latest event, and finally
+// resources that are ready, and then any others, from the end of the list;
+// a note in the list tells the model how many were left out.
+func (s *Service) fitContext(ctx context.Context, resourceContext string) string {
+	windowed, ok := s.llm.(contextWindowed)
+	if !ok {
+		return resourceContext
+	}
+	window, err := windowed.ContextWindow(ctx)
+	if err != nil || window == 0 {
+		return resourceContext
+	}
+	reserve := min(promptReserveTokens, window/3)
+	budget := (window - reserve) * charsPerToken
+	return shrinkResourceJSON(resourceContext, budget)
+}
+
+func shrinkResourceJSON(resourceContext string, budget int) string {
+	if len(resourceContext) <= budget {
+		return resourceContext
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(resourceContext), &data); err != nil {
+		return resourceContext
+	}
+	encode := func() string {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return resourceContext
+		}
+		return string(encoded)
+	}
+
+	pruneContext(data, func(key string, _ interface{}) bool { return verboseContextKeys[key] })
+	if shrunk := encode(); len(shrunk) <= budget {
+		return shrunk
+	}
+
+	pruneContext(data, func(key string, _ interface{}) bool { return key == "spec" })
+	trimContext(data)
+	if shrunk := encode(); len(shrunk) <= budget {
+		return shrunk
+	}
+
+	list, setList := resourceList(data)
+	if list == nil {
+		return encode()
+	}
+
+	// Leave out resources by their encoded size, keeping room for the note
+	size := len(encode())
+	sizes := make([]int, len(list))
+	for i, item := range list {
+		encoded, _ := json.Marshal(item)
+		sizes[i] = len(encoded) + 1
+	}
+	keep := make([]bool, len(list))
+	for i := range keep {
+		keep[i] = true
+	}
+	omitted := 0
+	for _, readyOnly := range []bool{true, false} {
+		for i := len(list) - 1; i > 0 && size > budget-100; i-- {
+			if keep[i] && (!readyOnly || isReadyResource(list[i])) {
+				keep[i] = false
+				size -= sizes[i]
+				omitted++
+			}
+		}
+	}
+	if omitted == 0 {
+		return encode()
+	}
+
+	kept := make([]interface{}, 0, len(list)-omitted+1)
+	for i, item := range list {
+		if keep[i] {
+			kept = append(kept, item)
+		}
+	}
+	kept = append(kept, fmt.Sprintf("%d more resources were left out to fit the model's context window", omitted))
+	data = setList(kept)
+	return encode()
+}
+
+// pruneContext removes the keys of every object in data for which drop
+// returns true
+func pruneContext(data interface{}, drop func(key string, value interface{}) bool) {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if drop(key, child) {
+				delete(value, key)
+				continue
+			}
+			pruneContext(child, drop)
+		}
+	case []interface{}:
+		for _, child := range value {
+			pruneContext(child, drop)
+		}
+	}
+}
+
+// trimContext keeps only the latest event of each resource and shortens
+// long messages
+func trimContext(data interface{}) {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if events, ok := child.([]interface{}); ok && key == "events" && len(events) > 1 {
+				value[key] = events[len(events)-1:]
+			}
+			if text, ok := child.(string); ok && len(text) > maxContextString {
+				value[key] = truncateUTF8(text, maxContextString) + "…"
+			}
+			trimContext(value[key])
+		}
+	case []interface{}:
+		for _, child := range value {
+			trimContext(child)
+		}
+	}
+}
+
+// truncateUTF8 returns the longest prefix of text of at most n bytes that
+// does not cut a multi-byte character
+func truncateUTF8(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
+
+// resourceList returns the list of resources in the JSON of a prompt, the
+// top-level array or the resources field of an object such as a
+// ListResult, and a function that replaces it and returns the new data
+func resourceList(data interface{}) ([]interface{}, func([]interface{}) interface{}) {
+	switch value := data.(type) {
+	case []interface{}:
+		return value, func(list []interface{}) interface{} { return list }
+	case map[string]interface{}:
+		if list, ok := value["resources"].([]interface{}); ok {
+			return list, func(list []interface{}) interface{} {
+				value["resources"] = list
+				return value
+			}
+		}
+	}
+	return nil, nil
+}
+
+// isReadyResource reports whether a resource of the JSON of a prompt is
+// ready and synced, so that leaving it out loses the least
+func isReadyResource(item interface{}) bool {
+	resource, ok := item.(map[string]interface{})
+	if !ok || resource["synced"] == "False" {
+		return false
+	}
+	return resource["status"] == "Ready" || resource["status"] == crossplane.StatusNone
+}
@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// systemPrompt is the instruction every request to a language model
+// starts with
+const systemPrompt = "You are an expert Crossplane infrastructure assistant. Provide helpful, accurate, and actionable responses about Crossplane resources, Kubernetes, and cloud infrastructure. Keep responses concise but informative."
+
+const (
+	defaultMaxTokens   = 1000
+	defaultTemperature = 0.7
+)
+
+// complete sends a request to the configured backend and records its
+// token usage
+func (s *Service) complete(ctx context.Context, request CompletionRequest) (*Completion, error) {
+	return s.stream(ctx, request, nil)
+}
+
+// stream sends a request like complete and, when onToken is set and the
+// backend streams, passes the reply to onToken as it arrives. Backends that
+// do not stream pass the whole reply at once.
+func (s *Service) stream(ctx context.Context, request CompletionRequest, onToken func(string)) (*Completion, error) {
+	if request.System == "" {
+		request.System = systemPrompt
+	}
+	if request.MaxTokens == 0 {
+		request.MaxTokens = defaultMaxTokens
+	}
+	if request.Temperature == 0 {
+		request.Temperature = defaultTemperature
+	}
+
+	var completion *Completion
+	var err error
+	if streaming, ok := s.llm.(StreamingProvider); ok && onToken != nil {
+		completion, err = streaming.Stream(ctx, request, onToken)
+	} else {
+		completion, err = s.llm.Complete(ctx, request)
+		if err == nil && onToken != nil {
+			onToken(completion.Content)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", s.llm.Name(), err)
+	}
+	s.usage.add(completion.Usage)
+	return completion, nil
+}
+
+// completePrompt sends a single prompt and returns the reply
+func (s *Service) completePrompt(ctx context.Context, prompt string) (string, error) {
+	return s.streamPrompt(ctx, prompt, nil)
+}
+
+// streamPrompt sends a single prompt, passing the reply to onToken as it
+// arrives, and returns the whole reply
+func (s *Service) streamPrompt(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	completion, err := s.stream(ctx, CompletionRequest{
+		Messages: []Message{{Role: "user", Content: prompt}},
+	}, onToken)
+	if err != nil {
+		return "", err
+	}
+	return completion.Content, nil
+}
+
+// answerWithLLM answers a question about the resources in resourceContext
+func (s *Service) answerWithLLM(ctx context.Context, query, resourceContext string, onToken func(string)) (string, error) {
+	resourceContext = s.fitContext(ctx, resourceContext)
+
+	prompt := fmt.Sprintf(`Context: You are analyzing Crossplane resources in a Kubernetes cluster.
+
+Resource Information:
+%s
+
+User Query: %s
+
+Please provide a helpful response based on the resource context. If the query is about specific resources, reference the actual resource names and statuses from the context. When explaining failures, quote the reason and message of the resource's status conditions (e.g. Synced=False, ReconcileError) and of its recent warning events (e.g. CannotCreateExternalResource) rather than guessing. When resources carry a cluster field they come from several clusters; name the cluster of every resource you mention and answer per cluster when asked about the fleet.
+
+%s`, resourceContext, query, pipelineGuidance)
+
+	return s.streamPrompt(ctx, prompt, onToken)
+}
+
+// suggestWithLLM asks the model for suggestions of a category
+func (s *Service) suggestWithLLM(ctx context.Context, suggestionType, resourceContext string) ([]Suggestion, error) {
+	resourceContext = s.fitContext(ctx, resourceContext)
+
+	prompt := fmt.Sprintf(`As a Crossplane expert, analyze the following resources and provide specific %s suggestions.
+
+Resource Context:
+%s
+
+Provide 3-5 actionable suggestions as a JSON object with a "suggestions" field holding an array of objects with fields:
+- title: Brief suggestion title
+- description: Detailed explanation
+- priority: High, Medium or Low
+- category: The category of suggestion
+- example: Optional YAML example if applicable
+
+Focus on practical, implementable suggestions for Crossplane and Kubernetes infrastructure. %s`, suggestionType, resourceContext, pipelineGuidance)
+
+	var suggestions []Suggestion
+	err := s.completeStructured(ctx, prompt, suggestionsSchema, func(reply string) error {
+		var err error
+		suggestions, err = parseSuggestions(reply)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// analyzeWithLLM asks the model for the health score, issues and
+// recommendations of the resources; the counts are left to the caller
+func (s *Service) analyzeWithLLM(ctx context.Context, resourceContext string, healthCheck bool) (*Analysis, error) {
+	resourceContext = s.fitContext(ctx, resourceContext)
+
+	analysisType := "general"
+	if healthCheck {
+		analysisType = "health-focused"
+	}
+
+	prompt := fmt.Sprintf(`Analyze the following Crossplane resources and provide a %s analysis.
+
+Resource Context:
+%s
+
+Each resource carries its status conditions (type, status, reason, message, lastTransitionTime), its Synced state, generation and observed_generation, deletion_timestamp when it is being deleted, and recent Kubernetes events for resources that need attention. Base issues on these fields and quote the actual condition messages. When resources carry a cluster field, set the cluster of each issue accordingly. %s
+
+Provide analysis as a JSON object with these fields:
+- health_score: overall health score (0-100)
+- issues: array of issues with cluster, severity (Critical, Warning or Info), description, resource, reason, resolution
+- recommendations: array of recommendations with title, description, impact, priority (High, Medium or Low)
+
+Focus on actionable insights for Crossplane infrastructure management.`, analysisType, resourceContext, pipelineGuidance)
+
+	var analysis *Analysis
+	err := s.completeStructured(ctx, prompt, analysisSchema, func(reply string) error {
+		var err error
+		analysis, err = parseAnalysis(reply)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return analysis, nil
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+// ProviderConfigIssues reports managed resources that reference a missing
+// ProviderConfig, credentials secrets or keys that do not exist, and
+// configs no managed resource uses. Unused configs are only reported when
+// the report is complete, since a resource type that could not be listed
+// may hold their users.
+func ProviderConfigIssues(report *crossplane.ProviderConfigReport) []Issue {
+	var issues []Issue
+
+	for _, dangling := range report.Dangling {
+		issues = append(issues, Issue{
+			Cluster:  dangling.Cluster,
+			Severity: "Critical",
+			Description: fmt.Sprintf("%s references %s, which does not exist",
+				dangling.Resource, dangling.Config),
+			Kind:       dangling.Resource.Kind,
+			Namespace:  dangling.Resource.Namespace,
+			Resource:   dangling.Resource.Name,
+			Reason:     "ProviderConfigMissing",
+			Resolution: "Create the ProviderConfig or point spec.providerConfigRef at an existing one",
+		})
+	}
+
+	for _, config := range report.Configs {
+		if issue, ok := credentialsIssue(config); ok {
+			issues = append(issues, issue)
+		}
+		if len(config.Users) == 0 && len(report.Failures) == 0 {
+			issues = append(issues, Issue{
+				Cluster:     config.Cluster,
+				Severity:    "Info",
+				Description: fmt.Sprintf("%s (%s) is not used by any managed resource", config.ID(), config.Provider),
+				Resource:    config.Name,
+				Reason:      "ProviderConfigUnused",
+				Resolution:  "Delete the config if it is no longer needed, together with its credentials secret",
+			})
+		}
+	}
+
+	return issues
+}
+
+// credentialsIssue checks the credentials secret of a config. Configs that
+// are in use get a critical issue, since their resources cannot reconcile.
+func credentialsIssue(config *crossplane.ProviderConfig) (Issue, bool) {
+	secret := config.Secret
+	if secret == nil || secret.OK() {
+		return Issue{}, false
+	}
+
+	severity := "Warning"
+	if len(config.Users) > 0 {
+		severity = "Critical"
+	}
+	issue := Issue{
+		Cluster:  config.Cluster,
+		Severity: severity,
+		Resource: config.Name,
+	}
+
+	secretName := secret.Namespace + "/" + secret.Name
+	affected := ""
+	if len(config.Users) > 0 {
+		affected = fmt.Sprintf(" (%d resources affected)", len(config.Users))
+	}
+
+	switch {
+	case secret.Error != "" && !secret.Exists && secret.Name == "":
+		issue.Description = fmt.Sprintf("%s uses Secret credentials but %s%s", config.ID(), secret.Error, affected)
+		issue.Reason = "SecretRefMissing"
+		issue.Resolution = "Set spec.credentials.secretRef to the secret holding the provider credentials"
+	case secret.Error != "":
+		issue.Severity = "Info"
+		issue.Description = fmt.Sprintf("Credentials secret %s of %s %s", secretName, config.ID(), secret.Error)
+		issue.Reason = "SecretCheckFailed"
+		issue.Resolution = "Grant get on secrets in the namespace to check credentials, or verify the secret manually"
+	case !secret.Exists:
+		issue.Description = fmt.Sprintf("%s uses credentials secret %s, which does not exist%s", config.ID(), secretName, affected)
+		issue.Reason = "SecretMissing"
+		issue.Resolution = fmt.Sprintf("Create the secret %s with key %s, or fix spec.credentials.secretRef", secretName, secret.Key)
+	default:
+		keys := "none"
+		if len(secret.Keys) > 0 {
+			keys = strings.Join(secret.Keys, ", ")
+		}
+		issue.Description = fmt.Sprintf("%s uses key %s of secret %s, which has no such key (keys: %s)%s",
+			config.ID(), secret.Key, secretName, keys, affected)
+		issue.Reason = "SecretKeyMissing"
+		issue.Resolution = "Fix spec.credentials.secretRef.key or add the key to the secret"
+	}
+
+	return issue, true
+}
+
+// AppendIssues adds issues found outside the resource analysis, such as
+// ProviderConfig issues, and updates the issue counts
+func AppendIssues(analysis *Analysis, issues ...Issue) {
+	analysis.Issues = append(analysis.Issues, issues...)
+	analysis.IssuesFound = len(analysis.Issues)
+	countClusterIssues(analysis.Clusters, analysis.Issues)
+}
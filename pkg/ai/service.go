@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"crossplane-ai/internal/config"
 	"crossplane-ai/pkg/crossplane"
@@ -13,9 +14,11 @@ import (
 
 // Service represents the AI service
 type Service struct {
-	openaiClient *OpenAIClient
-	config       *config.Config
-	useRealAI    bool
+	llm    LLMProvider
+	config *config.Config
+	// llmErr is why the configured backend could not be used
+	llmErr error
+	usage  usageMeter
 }
 
 // Suggestion represents an AI-generated suggestion
@@ -36,23 +39,86 @@ type Analysis struct {
 	Resources        []ResourceInfo   `json:"resources"`
 	Issues           []Issue          `json:"issues"`
 	Recommendations  []Recommendation `json:"recommendations"`
+	// Clusters breaks the analysis down per cluster when the resources
+	// come from more than one
+	Clusters []ClusterSummary `json:"clusters,omitempty"`
+	// Warning says why the analysis is rule-based although an AI
+	// provider is configured
+	Warning string `json:"warning,omitempty"`
 }
 
 // ResourceInfo represents analyzed resource information
 type ResourceInfo struct {
-	Name     string `json:"name"`
-	Type     string `json:"type"`
-	Status   string `json:"status"`
-	Provider string `json:"provider"`
-	Age      string `json:"age"`
+	Cluster   string `json:"cluster,omitempty"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	Provider  string `json:"provider"`
+	Age       string `json:"age"`
+
+	Synced             string                  `json:"synced,omitempty"`
+	Conditions         []crossplane.Condition  `json:"conditions,omitempty"`
+	Generation         int64                   `json:"generation,omitempty"`
+	ObservedGeneration int64                   `json:"observed_generation,omitempty"`
+	CreatedAt          *time.Time              `json:"created_at,omitempty"`
+	DeletionTimestamp  *time.Time              `json:"deletion_timestamp,omitempty"`
+	Events             []crossplane.Event      `json:"events,omitempty"`
+	Drift              []crossplane.FieldDrift `json:"drift,omitempty"`
+
+	// Version is the package tag of providers, functions and configurations
+	Version string `json:"version,omitempty"`
+	// Mode and Pipeline describe how a Composition composes resources
+	Mode     string                    `json:"mode,omitempty"`
+	Pipeline []crossplane.PipelineStep `json:"pipeline,omitempty"`
+}
+
+// NewResourceInfo converts a Crossplane resource to the form used for
+// analysis and AI context
+func NewResourceInfo(res *crossplane.Resource) *ResourceInfo {
+	info := &ResourceInfo{
+		Cluster:            res.Cluster,
+		Name:               res.Name,
+		Namespace:          res.Namespace,
+		Kind:               res.Kind,
+		Type:               res.Type,
+		Status:             res.Status,
+		Provider:           res.Provider,
+		Age:                res.Age,
+		Synced:             res.Synced,
+		Conditions:         res.Conditions,
+		Generation:         res.Generation,
+		ObservedGeneration: res.ObservedGeneration,
+		DeletionTimestamp:  res.DeletionTimestamp,
+		Events:             res.Events,
+		Drift:              res.Drift,
+		Version:            res.PackageVersion(),
+	}
+	if res.IsComposition() {
+		composition := crossplane.NewCompositionInfo(res)
+		info.Mode = composition.Mode
+		info.Pipeline = composition.Steps
+	}
+	if !res.CreatedAt.IsZero() {
+		createdAt := res.CreatedAt
+		info.CreatedAt = &createdAt
+	}
+	return info
 }
 
 // Issue represents a detected issue
 type Issue struct {
+	Cluster     string `json:"cluster,omitempty"`
 	Severity    string `json:"severity"`
 	Description string `json:"description"`
-	Resource    string `json:"resource,omitempty"`
-	Resolution  string `json:"resolution,omitempty"`
+	// Kind, Namespace and Resource identify the resource the issue is
+	// about; Resource is its name
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Resource   string `json:"resource,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Resolution string `json:"resolution,omitempty"`
 }
 
 // Recommendation represents an AI recommendation
@@ -68,31 +134,11 @@ func NewService() *Service {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		// Fallback to default configuration if loading fails
-		return &Service{
-			useRealAI: false,
-		}
+		// Fallback to template mode if loading fails
+		return &Service{llmErr: err}
 	}
 
-	// Check if we should use real AI
-	useRealAI := shouldUseRealAI(cfg)
-
-	var openaiClient *OpenAIClient
-	if useRealAI {
-		// Initialize OpenAI client with configuration
-		openaiConfig := OpenAIConfig{
-			APIKey:  getAPIKey(cfg),
-			Model:   cfg.AI.Model,
-			BaseURL: cfg.AI.BaseURL,
-		}
-		openaiClient = NewOpenAIClient(openaiConfig)
-	}
-
-	return &Service{
-		openaiClient: openaiClient,
-		config:       cfg,
-		useRealAI:    useRealAI,
-	}
+	return NewServiceWithConfig(cfg)
 }
 
 // NewServiceWithConfig creates a new AI service with explicit configuration
@@ -101,66 +147,69 @@ func NewServiceWithConfig(cfg *config.Config) *Service {
 		return NewService()
 	}
 
-	// Check if we should use real AI
-	useRealAI := shouldUseRealAI(cfg)
+	service := &Service{config: cfg}
 
-	var openaiClient *OpenAIClient
-	if useRealAI {
-		// Initialize OpenAI client with configuration
-		openaiConfig := OpenAIConfig{
-			APIKey:  getAPIKey(cfg),
-			Model:   cfg.AI.Model,
-			BaseURL: cfg.AI.BaseURL,
-		}
-		openaiClient = NewOpenAIClient(openaiConfig)
-	}
-
-	return &Service{
-		openaiClient: openaiClient,
-		config:       cfg,
-		useRealAI:    useRealAI,
-	}
-}
-
-// shouldUseRealAI determines if real AI should be used based on configuration
-func shouldUseRealAI(cfg *config.Config) bool {
 	// Don't use real AI in mock mode
 	if os.Getenv("CROSSPLANE_AI_MODE") == "mock" {
-		return false
+		return service
 	}
+	service.llm, service.llmErr = NewLLMProvider(cfg)
+	return service
+}
 
-	// Check if provider is set to openai and we have an API key
-	if cfg.AI.Provider == "openai" {
-		apiKey := getAPIKey(cfg)
-		return apiKey != ""
-	}
+// NewServiceWithProvider creates an AI service that sends its requests to
+// the given backend
+func NewServiceWithProvider(cfg *config.Config, llm LLMProvider) *Service {
+	return &Service{llm: llm, config: cfg}
+}
 
-	return false
+// IsUsingRealAI returns true if the service is configured to use real AI
+func (s *Service) IsUsingRealAI() bool {
+	return s.llm != nil
 }
 
-// getAPIKey gets the API key from config or environment variable
-func getAPIKey(cfg *config.Config) string {
-	// First try the config
-	if cfg.AI.APIKey != "" {
-		// Handle environment variable expansion
-		if strings.HasPrefix(cfg.AI.APIKey, "${") && strings.HasSuffix(cfg.AI.APIKey, "}") {
-			envVar := strings.TrimSuffix(strings.TrimPrefix(cfg.AI.APIKey, "${"), "}")
-			return os.Getenv(envVar)
-		}
-		return cfg.AI.APIKey
+// Provider returns the backend in use, or nil in template mode
+func (s *Service) Provider() LLMProvider {
+	return s.llm
+}
+
+// ProviderName returns the display name of the backend in use, or "" in
+// template mode
+func (s *Service) ProviderName() string {
+	if s.llm == nil {
+		return ""
 	}
+	return s.llm.Name()
+}
 
-	// Fallback to environment variable
-	return os.Getenv("OPENAI_API_KEY")
+// SetupHint explains how to enable a language model backend when the
+// service runs in template mode
+func (s *Service) SetupHint() string {
+	switch {
+	case s.llm != nil:
+		return ""
+	case s.llmErr != nil:
+		return fmt.Sprintf("AI backend not available: %v", s.llmErr)
+	case s.config != nil && strings.EqualFold(s.config.AI.Provider, ProviderAnthropic):
+		return "Set ANTHROPIC_API_KEY for AI-powered answers"
+	}
+	return "Set OPENAI_API_KEY, or ai.provider: anthropic or local, for AI-powered answers"
 }
 
-// IsUsingRealAI returns true if the service is configured to use real AI
-func (s *Service) IsUsingRealAI() bool {
-	return s.useRealAI
+// TokenUsage returns the tokens used by the requests the service has sent
+func (s *Service) TokenUsage() Usage {
+	return s.usage.get()
 }
 
 // ProcessQuery processes a natural language query about Crossplane resources
 func (s *Service) ProcessQuery(ctx context.Context, query string, resources interface{}) (string, error) {
+	return s.ProcessQueryStream(ctx, query, resources, nil)
+}
+
+// ProcessQueryStream processes a query like ProcessQuery and passes the
+// answer to onToken as the model generates it; answers that are not
+// generated are passed at once. Cancelling ctx aborts the request.
+func (s *Service) ProcessQueryStream(ctx context.Context, query string, resources interface{}, onToken func(string)) (string, error) {
 	// Convert resources to JSON for analysis
 	resourcesJSON, err := json.Marshal(resources)
 	if err != nil {
@@ -168,19 +217,35 @@ func (s *Service) ProcessQuery(ctx context.Context, query string, resources inte
 	}
 
 	// Use real AI if available, otherwise simulate
-	if s.useRealAI && s.openaiClient != nil {
-		return s.openaiClient.CompleteWithContext(ctx, query, string(resourcesJSON))
+	if s.llm != nil {
+		return s.answerWithLLM(ctx, query, string(resourcesJSON), onToken)
 	}
 
-	// Fallback to simulated AI processing
-	response := s.simulateAIResponse(query, string(resourcesJSON))
+	response := s.templateAnswer(query, resources, string(resourcesJSON))
+	if onToken != nil {
+		onToken(response)
+	}
 	return response, nil
 }
 
+// templateAnswer answers a query without a model
+func (s *Service) templateAnswer(query string, resources interface{}, resourcesJSON string) string {
+	// Questions about a fleet of clusters are answered from the listed
+	// resources themselves
+	if result, ok := resources.(*crossplane.ListResult); ok {
+		if answer, ok := fleetAnswer(query, result); ok {
+			return answer
+		}
+	}
+
+	// Fallback to simulated AI processing
+	return s.simulateAIResponse(query, resourcesJSON)
+}
+
 // GenerateSuggestions generates AI-powered suggestions
 func (s *Service) GenerateSuggestions(ctx context.Context, suggestionType string, resources interface{}) ([]*Suggestion, error) {
 	// Use real AI if available
-	if s.useRealAI && s.openaiClient != nil {
+	if s.llm != nil {
 		// Convert resources to JSON for context
 		resourcesJSON, err := json.Marshal(resources)
 		if err != nil {
@@ -188,16 +253,15 @@ func (s *Service) GenerateSuggestions(ctx context.Context, suggestionType string
 		}
 
 		// Get AI-generated suggestions
-		suggestions, err := s.openaiClient.GenerateSuggestions(ctx, suggestionType, string(resourcesJSON))
+		suggestions, err := s.suggestWithLLM(ctx, suggestionType, string(resourcesJSON))
 		if err != nil {
-			// Fallback to mock suggestions if AI fails
-			return s.generateMockSuggestions(suggestionType), nil
+			return nil, err
 		}
 
 		// Convert from []Suggestion to []*Suggestion
 		result := make([]*Suggestion, len(suggestions))
-		for i, suggestion := range suggestions {
-			result[i] = &suggestion
+		for i := range suggestions {
+			result[i] = &suggestions[i]
 		}
 		return result, nil
 	}
@@ -218,13 +282,7 @@ func (s *Service) AnalyzeResources(ctx context.Context, resources interface{}, h
 	case []*crossplane.Resource:
 		// Convert from crossplane.Resource to ResourceInfo
 		for _, res := range r {
-			resourceList = append(resourceList, &ResourceInfo{
-				Name:     res.Name,
-				Type:     res.Type,
-				Status:   res.Status,
-				Provider: res.Provider,
-				Age:      res.Age,
-			})
+			resourceList = append(resourceList, NewResourceInfo(res))
 		}
 	case []map[string]interface{}:
 		// Convert from generic map format
@@ -262,27 +320,36 @@ func (s *Service) AnalyzeResources(ctx context.Context, resources interface{}, h
 		}, nil
 	}
 
-	// Use real AI for analysis if available
-	if s.useRealAI && s.openaiClient != nil {
-		// Convert resources to JSON for AI analysis
-		resourcesJSON, err := json.Marshal(resourceList)
-		if err != nil {
-			// Fallback to real analysis if marshaling fails
-			return s.performRealAnalysis(resourceList, healthCheck), nil
-		}
+	// The counts and resources always come from the cluster; the model
+	// only scores the resources and finds issues and recommendations
+	analysis := s.performRealAnalysis(resourceList, healthCheck)
+	if s.llm == nil {
+		return analysis, nil
+	}
 
-		// Get AI-powered analysis
-		analysis, err := s.openaiClient.AnalyzeResources(ctx, string(resourcesJSON), healthCheck)
-		if err != nil {
-			// Fallback to real analysis if AI fails
-			return s.performRealAnalysis(resourceList, healthCheck), nil
-		}
+	resourcesJSON, err := json.Marshal(resourceList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resources: %w", err)
+	}
 
+	// Get AI-powered analysis
+	aiAnalysis, err := s.analyzeWithLLM(ctx, string(resourcesJSON), healthCheck)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		analysis.Warning = fmt.Sprintf("The AI analysis failed, showing the rule-based analysis instead: %v", err)
 		return analysis, nil
 	}
 
-	// Fallback to perform real analysis on actual resources
-	return s.performRealAnalysis(resourceList, healthCheck), nil
+	assignIssueClusters(aiAnalysis.Issues, resourceList)
+	identifyIssueResources(aiAnalysis.Issues, resourceList)
+	analysis.HealthScore = aiAnalysis.HealthScore
+	analysis.Issues = aiAnalysis.Issues
+	analysis.IssuesFound = len(aiAnalysis.Issues)
+	analysis.Recommendations = aiAnalysis.Recommendations
+	analysis.Clusters = summarizeClusters(resourceList, aiAnalysis.Issues)
+	return analysis, nil
 }
 
 // convertMapToResourceInfo converts a map to ResourceInfo
@@ -312,32 +379,29 @@ func convertMapToResourceInfo(res map[string]interface{}) *ResourceInfo {
 func (s *Service) performRealAnalysis(resources []*ResourceInfo, healthCheck bool) *Analysis {
 	totalResources := len(resources)
 	healthyResources := 0
+	ratedResources := 0
 	issues := []Issue{}
 
 	// Convert ResourceInfo pointers to values for the analysis
 	resourceList := make([]ResourceInfo, len(resources))
 	for i, res := range resources {
 		resourceList[i] = *res
-		// Count healthy resources
+		// Count healthy resources among those that report health
+		if reportsHealth(res) {
+			ratedResources++
+		}
 		if res.Status == "Ready" {
 			healthyResources++
-		} else if res.Status != "Ready" && res.Status != "Unknown" {
-			// Add issue for non-ready resources
-			issues = append(issues, Issue{
-				Severity:    "Warning",
-				Description: fmt.Sprintf("Resource %s is in %s state", res.Name, res.Status),
-				Resource:    res.Name,
-				Resolution:  "Check resource events and provider status",
-			})
 		}
+		issues = append(issues, resourceIssues(res)...)
 	}
 
 	issuesFound := len(issues)
 
 	// Calculate health score
 	healthScore := 100
-	if totalResources > 0 {
-		healthScore = (healthyResources * 100) / totalResources
+	if ratedResources > 0 {
+		healthScore = (healthyResources * 100) / ratedResources
 	}
 
 	// Generate recommendations based on actual state
@@ -351,6 +415,7 @@ func (s *Service) performRealAnalysis(resources []*ResourceInfo, healthCheck boo
 		Resources:        resourceList,
 		Issues:           issues,
 		Recommendations:  recommendations,
+		Clusters:         summarizeClusters(resources, issues),
 	}
 }
 
@@ -371,7 +436,10 @@ func (s *Service) generateRealRecommendations(resources []*ResourceInfo, healthS
 	// Provider-specific recommendations
 	providerCounts := make(map[string]int)
 	for _, res := range resources {
-		providerCounts[res.Provider]++
+		// Claims, composites and Crossplane's own types have no provider
+		if res.Provider != "" {
+			providerCounts[res.Provider]++
+		}
 	}
 
 	if len(providerCounts) > 2 {
@@ -516,10 +584,20 @@ func (s *Service) generateMockSuggestions(suggestionType string) []*Suggestion {
 	}
 }
 
-// GenerateManifest generates a Crossplane manifest from natural language description
-func (s *Service) GenerateManifest(ctx context.Context, description, provider string) (string, error) {
+// GenerateManifest generates a Crossplane manifest from natural language
+// description. The manifest uses the given APIs, which should be the ones
+// the target cluster serves; without them the family's default Upbound APIs
+// are used.
+func (s *Service) GenerateManifest(ctx context.Context, description, provider string, apis crossplane.APISet) (string, error) {
+	if provider == "" || provider == "auto" {
+		provider = crossplane.DefaultFamily
+	}
+	if apis == nil {
+		apis = crossplane.DefaultAPIs(provider)
+	}
+
 	// Use real AI if available
-	if s.useRealAI && s.openaiClient != nil {
+	if s.llm != nil {
 		prompt := fmt.Sprintf(`Generate a Crossplane manifest for: %s
 
 Requirements:
@@ -528,141 +606,21 @@ Requirements:
 - Include metadata, spec, and appropriate labels
 - Follow Crossplane best practices
 - Include helpful comments
+%s
+Please provide only the YAML manifest without additional explanations.`, description, provider, apiRequirements(apis))
 
-Please provide only the YAML manifest without additional explanations.`, description, provider)
-
-		return s.openaiClient.Complete(ctx, prompt)
+		reply, err := s.completePrompt(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		// Models wrap the YAML in a markdown code fence despite the prompt,
+		// and a fence is not valid YAML
+		if manifest, ok := fencedBlock(reply); ok {
+			reply = manifest
+		}
+		return strings.TrimSpace(reply) + "\n", nil
 	}
 
 	// Fallback to template-based generation
-	return s.generateTemplateManifest(description, provider), nil
-}
-
-// generateTemplateManifest generates a basic template manifest (fallback)
-func (s *Service) generateTemplateManifest(description, provider string) string {
-	// Simple template generation based on keywords in description
-	descLower := strings.ToLower(description)
-
-	if strings.Contains(descLower, "database") || strings.Contains(descLower, "postgres") || strings.Contains(descLower, "mysql") {
-		return s.generateDatabaseTemplate(provider)
-	} else if strings.Contains(descLower, "storage") || strings.Contains(descLower, "bucket") {
-		return s.generateStorageTemplate(provider)
-	} else if strings.Contains(descLower, "compute") || strings.Contains(descLower, "instance") {
-		return s.generateComputeTemplate(provider)
-	}
-
-	// Default to a composition template
-	return s.generateCompositionTemplate(provider)
-}
-
-func (s *Service) generateDatabaseTemplate(provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	return fmt.Sprintf(`# Database instance generated by Crossplane AI
-apiVersion: rds.%s.crossplane.io/v1alpha1
-kind: DBInstance
-metadata:
-  name: my-database
-  namespace: default
-  labels:
-    generated-by: crossplane-ai
-spec:
-  forProvider:
-    region: us-east-1
-    dbInstanceClass: db.t3.micro
-    engine: postgres
-    engineVersion: "13.7"
-    allocatedStorage: 20
-    dbName: myapp
-    masterUsername: admin
-    autoMinorVersionUpgrade: true
-    backupRetentionPeriod: 7
-    storageEncrypted: true
-  providerConfigRef:
-    name: default
-  writeConnectionSecretsToRef:
-    name: my-database-connection
-    namespace: default`, provider)
-}
-
-func (s *Service) generateStorageTemplate(provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	return fmt.Sprintf(`# Storage bucket generated by Crossplane AI
-apiVersion: s3.%s.crossplane.io/v1beta1
-kind: Bucket
-metadata:
-  name: my-storage-bucket
-  namespace: default
-  labels:
-    generated-by: crossplane-ai
-spec:
-  forProvider:
-    region: us-east-1
-    versioning:
-      status: Enabled
-    serverSideEncryptionConfiguration:
-      rules:
-      - applyServerSideEncryptionByDefault:
-          sseAlgorithm: AES256
-  providerConfigRef:
-    name: default`, provider)
-}
-
-func (s *Service) generateComputeTemplate(provider string) string {
-	if provider == "" || provider == "auto" {
-		provider = "aws"
-	}
-
-	return fmt.Sprintf(`# Compute instance generated by Crossplane AI
-apiVersion: ec2.%s.crossplane.io/v1alpha1
-kind: Instance
-metadata:
-  name: my-instance
-  namespace: default
-  labels:
-    generated-by: crossplane-ai
-spec:
-  forProvider:
-    region: us-east-1
-    instanceType: t3.micro
-    imageId: ami-0abcdef1234567890
-    keyName: my-key-pair
-    tags:
-      Name: MyInstance
-      GeneratedBy: crossplane-ai
-  providerConfigRef:
-    name: default`, provider)
-}
-
-func (s *Service) generateCompositionTemplate(provider string) string {
-	return fmt.Sprintf(`# Crossplane Composition generated by Crossplane AI
-apiVersion: apiextensions.crossplane.io/v1
-kind: Composition
-metadata:
-  name: my-composition
-  namespace: default
-  labels:
-    generated-by: crossplane-ai
-    provider: %s
-spec:
-  compositeTypeRef:
-    apiVersion: example.com/v1alpha1
-    kind: XResource
-  resources:
-  - name: resource
-    base:
-      apiVersion: example.com/v1alpha1
-      kind: Resource
-      spec:
-        forProvider:
-          region: us-east-1
-    patches:
-    - type: FromCompositeFieldPath
-      fromFieldPath: spec.region
-      toFieldPath: spec.forProvider.region`, provider)
+	return generateTemplateManifest(description, provider, apis), nil
 }
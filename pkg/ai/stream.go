@@ -0,0 +1,136 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamingProvider is implemented by backends that deliver a reply while
+// the model generates it
+type StreamingProvider interface {
+	LLMProvider
+	// Stream sends a conversation like Complete and calls onToken with
+	// every piece of the reply as it arrives. The returned completion holds
+	// the whole reply. Cancelling ctx aborts the request.
+	Stream(ctx context.Context, request CompletionRequest, onToken func(string)) (*Completion, error)
+}
+
+// maxStreamLine is the longest server-sent event line accepted
+const maxStreamLine = 1024 * 1024
+
+// newStreamingHTTPClient returns a client for streamed replies. Unlike the
+// client of whole replies it has no overall timeout, which would cut off
+// long replies; timeout only limits the wait for the response to start,
+// and ctx cancels the request.
+func newStreamingHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Transport: transport}
+}
+
+// openStream sends a JSON request and returns the body of a streamed
+// response, which the caller closes. A response status other than 200 is
+// returned as an error like postJSON does.
+func openStream(ctx context.Context, client *http.Client, url string, headers map[string]string,
+	request interface{}, errorMessage func(body []byte) string) (io.ReadCloser, error) {
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		message := errorMessage(data)
+		if message == "" {
+			message = strings.TrimSpace(string(data))
+		}
+		return nil, &apiError{StatusCode: resp.StatusCode, Message: message}
+	}
+	return resp.Body, nil
+}
+
+// readEvents calls onEvent with the event name and data of every
+// server-sent event in body until onEvent returns done or an error, or the
+// stream ends
+func readEvents(body io.Reader, onEvent func(event, data string) (done bool, err error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+
+	var event string
+	var data []string
+	dispatch := func() (bool, error) {
+		if len(data) == 0 {
+			event = ""
+			return false, nil
+		}
+		done, err := onEvent(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return done, err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if done, err := dispatch(); done || err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment, used by servers to keep the connection alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	_, err := dispatch()
+	return err
+}
+
+// readLines calls onLine with every non-empty line of a newline-delimited
+// JSON stream until onLine returns done or an error, or the stream ends
+func readLines(body io.Reader, onLine func(line []byte) (done bool, err error)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if done, err := onLine(line); done || err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxJSONCandidates limits the opening brackets tried when looking for the
+// JSON in a reply
+const maxJSONCandidates = 20
+
+// priorities are the priorities of suggestions and recommendations
+var priorities = []string{"High", "Medium", "Low"}
+
+// severities are the severities of issues
+var severities = []string{"Critical", "Warning", "Info"}
+
+// suggestionsSchema is the schema of the reply of suggestWithLLM
+var suggestionsSchema = &Schema{
+	Name: "suggestions",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"suggestions": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":       map[string]interface{}{"type": "string"},
+						"description": map[string]interface{}{"type": "string"},
+						"priority":    map[string]interface{}{"type": "string", "enum": priorities},
+						"category":    map[string]interface{}{"type": "string"},
+						"example":     map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"title", "description", "priority"},
+				},
+			},
+		},
+		"required": []string{"suggestions"},
+	},
+}
+
+// analysisSchema is the schema of the reply of analyzeWithLLM
+var analysisSchema = &Schema{
+	Name: "analysis",
+	Definition: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"health_score": map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 100},
+			"issues": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"cluster":     map[string]interface{}{"type": "string"},
+						"severity":    map[string]interface{}{"type": "string", "enum": severities},
+						"description": map[string]interface{}{"type": "string"},
+						"resource":    map[string]interface{}{"type": "string"},
+						"reason":      map[string]interface{}{"type": "string"},
+						"resolution":  map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"severity", "description"},
+				},
+			},
+			"recommendations": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":       map[string]interface{}{"type": "string"},
+						"description": map[string]interface{}{"type": "string"},
+						"impact":      map[string]interface{}{"type": "string"},
+						"priority":    map[string]interface{}{"type": "string", "enum": priorities},
+					},
+					"required": []string{"title", "description"},
+				},
+			},
+		},
+		"required": []string{"health_score", "issues", "recommendations"},
+	},
+}
+
+// completeStructured sends a prompt for a JSON reply with schema and passes
+// the reply to parse. A reply parse rejects is sent back once with the
+// error for the model to repair, within the time of a single reply.
+func (s *Service) completeStructured(ctx context.Context, prompt string, schema *Schema, parse func(reply string) error) error {
+	if timeout := replyTimeout(s.config); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	request := CompletionRequest{
+		Messages: []Message{{Role: "user", Content: prompt}},
+		Schema:   schema,
+	}
+	completion, err := s.complete(ctx, request)
+	if err != nil {
+		return err
+	}
+	problem := parse(completion.Content)
+	if problem == nil {
+		return nil
+	}
+
+	request.Messages = append(request.Messages,
+		Message{Role: "assistant", Content: completion.Content},
+		Message{Role: "user", Content: fmt.Sprintf("Your reply could not be used: %v. Reply again with only the corrected JSON object.", problem)},
+	)
+	completion, err = s.complete(ctx, request)
+	if err != nil {
+		return err
+	}
+	if err := parse(completion.Content); err != nil {
+		return fmt.Errorf("%s returned an invalid %s reply: %w", s.llm.Name(), schema.Name, err)
+	}
+	return nil
+}
+
+// extractJSON returns the JSON object or array in a reply, which models
+// wrap in markdown code fences or surround with prose despite being told
+// not to
+func extractJSON(reply string) (json.RawMessage, error) {
+	text := strings.TrimSpace(reply)
+	if text == "" {
+		return nil, errors.New("the reply is empty")
+	}
+	if fenced, ok := fencedBlock(text); ok {
+		text = fenced
+	}
+
+	candidates := 0
+	for i := 0; i < len(text) && candidates < maxJSONCandidates; i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		candidates++
+		var raw json.RawMessage
+		if json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw) == nil {
+			return raw, nil
+		}
+	}
+	if candidates == 0 {
+		return nil, errors.New("the reply holds no JSON object")
+	}
+	return nil, errors.New("the reply holds no valid JSON object")
+}
+
+// fencedBlock returns the content of the first markdown code fence of text
+func fencedBlock(text string) (string, bool) {
+	start := strings.Index(text, "```")
+	if start < 0 {
+		return "", false
+	}
+	rest := text[start+3:]
+	// Skip the language tag, e.g. ```json
+	if newline := strings.IndexByte(rest, '\n'); newline >= 0 {
+		rest = rest[newline+1:]
+	}
+	end := strings.Index(rest, "```")
+	if end < 0 {
+		return rest, true
+	}
+	return rest[:end], true
+}
+
+// decodeReply decodes the JSON of a reply into out
+func decodeReply(raw json.RawMessage, out interface{}) error {
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("the JSON does not match the schema: %w", err)
+	}
+	return nil
+}
+
+// parseSuggestions parses and validates the reply of suggestWithLLM, an
+// object with a suggestions list or, from weaker models, the list itself
+func parseSuggestions(reply string) ([]Suggestion, error) {
+	raw, err := extractJSON(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []Suggestion
+	if raw[0] == '[' {
+		err = decodeReply(raw, &suggestions)
+	} else {
+		var result struct {
+			Suggestions []Suggestion `json:"suggestions"`
+		}
+		err = decodeReply(raw, &result)
+		suggestions = result.Suggestions
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(suggestions) == 0 {
+		return nil, errors.New("suggestions is empty")
+	}
+	var problems []string
+	for i := range suggestions {
+		suggestion := &suggestions[i]
+		if strings.TrimSpace(suggestion.Title) == "" {
+			problems = append(problems, fmt.Sprintf("suggestion %d has no title", i+1))
+		}
+		if strings.TrimSpace(suggestion.Description) == "" {
+			problems = append(problems, fmt.Sprintf("suggestion %d has no description", i+1))
+		}
+		if problem := normalizeEnum(&suggestion.Priority, priorities, true); problem != "" {
+			problems = append(problems, fmt.Sprintf("suggestion %d has %s", i+1, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return suggestions, nil
+}
+
+// parseAnalysis parses and validates the reply of analyzeWithLLM. Only the
+// health score, issues and recommendations are the model's; the counts are
+// left to the caller, which knows the resources.
+func parseAnalysis(reply string) (*Analysis, error) {
+	raw, err := extractJSON(reply)
+	if err != nil {
+		return nil, err
+	}
+	if raw[0] != '{' {
+		return nil, errors.New("the reply is not a JSON object")
+	}
+
+	var result struct {
+		HealthScore     *int             `json:"health_score"`
+		Issues          []Issue          `json:"issues"`
+		Recommendations []Recommendation `json:"recommendations"`
+	}
+	if err := decodeReply(raw, &result); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	switch {
+	case result.HealthScore == nil:
+		problems = append(problems, "health_score is missing")
+	case *result.HealthScore < 0 || *result.HealthScore > 100:
+		problems = append(problems, fmt.Sprintf("health_score is %d, not between 0 and 100", *result.HealthScore))
+	}
+	for i := range result.Issues {
+		issue := &result.Issues[i]
+		if strings.TrimSpace(issue.Description) == "" {
+			problems = append(problems, fmt.Sprintf("issue %d has no description", i+1))
+		}
+		if problem := normalizeEnum(&issue.Severity, severities, false); problem != "" {
+			problems = append(problems, fmt.Sprintf("issue %d has %s", i+1, problem))
+		}
+	}
+	for i := range result.Recommendations {
+		recommendation := &result.Recommendations[i]
+		if strings.TrimSpace(recommendation.Title) == "" {
+			problems = append(problems, fmt.Sprintf("recommendation %d has no title", i+1))
+		}
+		if problem := normalizeEnum(&recommendation.Priority, priorities, true); problem != "" {
+			problems = append(problems, fmt.Sprintf("recommendation %d has %s", i+1, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	return &Analysis{
+		HealthScore:     *result.HealthScore,
+		Issues:          result.Issues,
+		IssuesFound:     len(result.Issues),
+		Recommendations: result.Recommendations,
+	}, nil
+}
+
+// normalizeEnum sets value to the allowed value it matches regardless of
+// case, and describes the problem when it matches none
+func normalizeEnum(value *string, allowed []string, optional bool) string {
+	if *value == "" && optional {
+		return ""
+	}
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSpace(*value), candidate) {
+			*value = candidate
+			return ""
+		}
+	}
+	return fmt.Sprintf("value %q where one of %s is expected", *value, strings.Join(allowed, ", "))
+}
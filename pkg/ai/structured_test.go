@@ -0,0 +1,256 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stubProvider is an LLMProvider replying with queued replies and
+// recording the requests it is sent
+type stubProvider struct {
+	replies  []string
+	requests []CompletionRequest
+}
+
+func (p *stubProvider) Name() string  { return "Stub" }
+func (p *stubProvider) Model() string { return "stub-model" }
+
+func (p *stubProvider) Complete(_ context.Context, request CompletionRequest) (*Completion, error) {
+	p.requests = append(p.requests, request)
+	if len(p.replies) == 0 {
+		return nil, errors.New("no reply queued")
+	}
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return &Completion{Content: reply}, nil
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    string
+		wantErr string
+	}{
+		{"bare object", `{"a": 1}`, `{"a": 1}`, ""},
+		{"json fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`, ""},
+		{"fence without language", "```\n[1, 2]\n```", `[1, 2]`, ""},
+		{"unclosed fence", "```json\n{\"a\": {\"b\": true}}\n", `{"a": {"b": true}}`, ""},
+		{"leading prose", `Here is the analysis you asked for: {"a": 1} Let me know if it helps.`, `{"a": 1}`, ""},
+		{"prose with a brace before the JSON", `Scores are {0-100}. {"a": 1}`, `{"a": 1}`, ""},
+		{"empty", "  \n ", "", "empty"},
+		{"no JSON", "I cannot analyze this cluster.", "", "no JSON object"},
+		{"invalid JSON", `{"a": 1,`, "", "no valid JSON object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := extractJSON(tt.reply)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractJSON: %v", err)
+			}
+			if string(raw) != tt.want {
+				t.Errorf("extractJSON = %s, want %s", raw, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSuggestions(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    []Suggestion
+		wantErr string
+	}{
+		{
+			name:  "object",
+			reply: `{"suggestions": [{"title": "Pin versions", "description": "Pin provider package versions", "priority": "High"}]}`,
+			want:  []Suggestion{{Title: "Pin versions", Description: "Pin provider package versions", Priority: "High"}},
+		},
+		{
+			name:  "bare array",
+			reply: `[{"title": "Add labels", "description": "Label claims by team", "category": "operations"}]`,
+			want:  []Suggestion{{Title: "Add labels", Description: "Label claims by team", Category: "operations"}},
+		},
+		{
+			name:  "priority in another case",
+			reply: "```json\n{\"suggestions\": [{\"title\": \"Rotate keys\", \"description\": \"Rotate credentials\", \"priority\": \"medium\"}]}\n```",
+			want:  []Suggestion{{Title: "Rotate keys", Description: "Rotate credentials", Priority: "Medium"}},
+		},
+		{
+			name:    "empty list",
+			reply:   `{"suggestions": []}`,
+			wantErr: "suggestions is empty",
+		},
+		{
+			name:    "missing title",
+			reply:   `[{"description": "Label claims by team"}]`,
+			wantErr: "suggestion 1 has no title",
+		},
+		{
+			name:    "unknown priority",
+			reply:   `[{"title": "Add labels", "description": "Label claims by team", "priority": "Urgent"}]`,
+			wantErr: `suggestion 1 has value "Urgent" where one of High, Medium, Low is expected`,
+		},
+		{
+			name:    "wrong type",
+			reply:   `{"suggestions": "add labels"}`,
+			wantErr: "does not match the schema",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions, err := parseSuggestions(tt.reply)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSuggestions: %v", err)
+			}
+			if !reflect.DeepEqual(suggestions, tt.want) {
+				t.Errorf("parseSuggestions = %+v, want %+v", suggestions, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAnalysis(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    *Analysis
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			reply: `{"health_score": 60, "issues": [{"severity": "critical", "description": "db is not ready", "resource": "dbinstance/db"}], "recommendations": [{"title": "Fix credentials", "description": "Recreate the secret", "priority": "high"}]}`,
+			want: &Analysis{
+				HealthScore:     60,
+				Issues:          []Issue{{Severity: "Critical", Description: "db is not ready", Resource: "dbinstance/db"}},
+				IssuesFound:     1,
+				Recommendations: []Recommendation{{Title: "Fix credentials", Description: "Recreate the secret", Priority: "High"}},
+			},
+		},
+		{
+			name:  "bounds of the health score",
+			reply: `{"health_score": 0, "issues": [], "recommendations": []}`,
+			want:  &Analysis{HealthScore: 0, Issues: []Issue{}, Recommendations: []Recommendation{}},
+		},
+		{
+			name:    "health score above 100",
+			reply:   `{"health_score": 140, "issues": [], "recommendations": []}`,
+			wantErr: "health_score is 140, not between 0 and 100",
+		},
+		{
+			name:    "negative health score",
+			reply:   `{"health_score": -1, "issues": [], "recommendations": []}`,
+			wantErr: "health_score is -1, not between 0 and 100",
+		},
+		{
+			name:    "missing health score",
+			reply:   `{"issues": [], "recommendations": []}`,
+			wantErr: "health_score is missing",
+		},
+		{
+			name:    "unknown severity",
+			reply:   `{"health_score": 80, "issues": [{"severity": "Major", "description": "db is not ready"}], "recommendations": []}`,
+			wantErr: `issue 1 has value "Major" where one of Critical, Warning, Info is expected`,
+		},
+		{
+			name:    "missing severity",
+			reply:   `{"health_score": 80, "issues": [{"description": "db is not ready"}], "recommendations": []}`,
+			wantErr: "issue 1 has value",
+		},
+		{
+			name:    "array",
+			reply:   `[{"health_score": 80}]`,
+			wantErr: "not a JSON object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := parseAnalysis(tt.reply)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAnalysis: %v", err)
+			}
+			if !reflect.DeepEqual(analysis, tt.want) {
+				t.Errorf("parseAnalysis = %+v, want %+v", analysis, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteStructuredRepairs(t *testing.T) {
+	const valid = `{"health_score": 90, "issues": [], "recommendations": []}`
+	tests := []struct {
+		name      string
+		replies   []string
+		wantCalls int
+		wantErr   string
+	}{
+		{"valid reply", []string{valid}, 1, ""},
+		{"repaired reply", []string{`{"health_score": 140, "issues": [], "recommendations": []}`, valid}, 2, ""},
+		{"invalid twice", []string{"I cannot do that.", `{"health_score": "high"}`}, 2, "Stub returned an invalid analysis reply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubProvider{replies: tt.replies}
+			service := &Service{llm: stub}
+
+			var analysis *Analysis
+			err := service.completeStructured(context.Background(), "analyze these resources", analysisSchema, func(reply string) error {
+				var err error
+				analysis, err = parseAnalysis(reply)
+				return err
+			})
+			if len(stub.requests) != tt.wantCalls {
+				t.Fatalf("sent %d requests, want %d", len(stub.requests), tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("completeStructured: %v", err)
+			}
+			if analysis.HealthScore != 90 {
+				t.Errorf("health score = %d, want the valid reply's 90", analysis.HealthScore)
+			}
+
+			if stub.requests[0].Schema != analysisSchema {
+				t.Errorf("first request has schema %v, want the analysis schema", stub.requests[0].Schema)
+			}
+			if tt.wantCalls < 2 {
+				return
+			}
+			// The repair request carries the invalid reply and the problem
+			repair := stub.requests[1].Messages
+			if len(repair) != 3 || repair[1].Role != "assistant" || repair[1].Content != tt.replies[0] {
+				t.Fatalf("repair messages = %+v, want the prompt, the invalid reply and the problem", repair)
+			}
+			if !strings.Contains(repair[2].Content, "health_score is 140") {
+				t.Errorf("repair prompt = %q, want it to name the problem", repair[2].Content)
+			}
+		})
+	}
+}
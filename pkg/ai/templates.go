@@ -0,0 +1,377 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"crossplane-ai/pkg/crossplane"
+)
+
+const (
+	// templateNamespace is the namespace namespaced (Crossplane v2) managed
+	// resources are generated in
+	templateNamespace = "default"
+	// templateSecretNamespace is where cluster-scoped managed resources
+	// read and write their secrets
+	templateSecretNamespace = "crossplane-system"
+	// templateResourceGroup is the Azure resource group generated resources
+	// are placed in
+	templateResourceGroup = "my-resource-group"
+)
+
+// locations are the region fields and default regions per provider family
+var locations = map[string]struct{ field, value string }{
+	"aws":   {field: "region", value: "us-east-1"},
+	"gcp":   {field: "region", value: "us-central1"},
+	"azure": {field: "location", value: "westeurope"},
+}
+
+// apiRequirements tells the model which APIs to generate resources with
+func apiRequirements(apis crossplane.APISet) string {
+	if len(apis) == 0 {
+		return ""
+	}
+
+	roles := make([]string, 0, len(apis))
+	for role := range apis {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	var b strings.Builder
+	b.WriteString("- Use these managed resource APIs")
+	if apis.Served() > 0 {
+		b.WriteString(", which the target cluster serves")
+	}
+	b.WriteString(", instead of older *.crossplane.io provider APIs:\n")
+	for _, role := range roles {
+		ref := apis[role]
+		scope := "cluster scoped, no metadata.namespace"
+		if ref.Namespaced {
+			scope = "namespaced, set metadata.namespace; secret references have no namespace"
+		}
+		fmt.Fprintf(&b, "  - %s: apiVersion %s, kind %s (%s)\n", role, ref.APIVersion, ref.Kind, scope)
+	}
+	return b.String()
+}
+
+// manifestTemplate renders managed resources of one provider family with
+// the APIs resolved for it
+type manifestTemplate struct {
+	family string
+	apis   crossplane.APISet
+	// resourceGroup is set once the Azure resource group has been emitted
+	resourceGroup bool
+}
+
+// generateTemplateManifest generates a basic template manifest (fallback)
+func generateTemplateManifest(description, provider string, apis crossplane.APISet) string {
+	// Simple template generation based on keywords in description
+	descLower := strings.ToLower(description)
+	t := &manifestTemplate{family: provider, apis: apis}
+
+	var title string
+	var docs []string
+	switch {
+	case containsAny(descLower, "web app", "application", "load balancer"):
+		title, docs = "Web application stack", t.webApp(descLower)
+	case containsAny(descLower, "database", "postgres", "mysql", "mariadb"):
+		title, docs = "Database instance", t.database("my-database", descLower)
+	case containsAny(descLower, "storage", "bucket", "s3"):
+		title, docs = "Storage bucket", t.bucket(descLower)
+	case containsAny(descLower, "network", "vpc", "subnet"):
+		title, docs = "Network", t.network()
+	case containsAny(descLower, "compute", "instance", "server", " vm"):
+		title, docs = "Compute instance", t.instance()
+	}
+
+	if len(docs) == 0 {
+		// Default to a composition template
+		return compositionTemplate(provider, apis)
+	}
+	return fmt.Sprintf("# %s generated by Crossplane AI\n%s", title, strings.Join(docs, "\n---\n"))
+}
+
+func containsAny(s string, substrings ...string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// resource renders a managed resource of the given role. forProvider holds
+// the spec.forProvider fields indented by four spaces. It returns nil when
+// the family has no API for the role.
+func (t *manifestTemplate) resource(role, kind, name, forProvider string, connectionSecret bool) []string {
+	ref, ok := t.apis[role]
+	if !ok {
+		return nil
+	}
+	if kind == "" {
+		kind = ref.Kind
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "apiVersion: %s\nkind: %s\nmetadata:\n  name: %s\n", ref.APIVersion, kind, name)
+	if ref.Namespaced {
+		fmt.Fprintf(&b, "  namespace: %s\n", templateNamespace)
+	}
+	b.WriteString("  labels:\n    generated-by: crossplane-ai\n")
+	fmt.Fprintf(&b, "spec:\n  forProvider:\n%s\n  providerConfigRef:\n    name: default", strings.TrimRight(forProvider, "\n"))
+	if connectionSecret {
+		fmt.Fprintf(&b, "\n  writeConnectionSecretToRef:\n    name: %s-connection", name)
+		if !ref.Namespaced {
+			fmt.Fprintf(&b, "\n    namespace: %s", templateSecretNamespace)
+		}
+	}
+	return []string{b.String()}
+}
+
+// location renders the region or location field of the family
+func (t *manifestTemplate) location() string {
+	loc := locations[t.family]
+	return fmt.Sprintf("    %s: %s\n", loc.field, loc.value)
+}
+
+// secretRef renders a secret key reference field. Namespaced managed
+// resources reference secrets in their own namespace.
+func (t *manifestTemplate) secretRef(role, field, name, key string) string {
+	ref := fmt.Sprintf("    %s:\n      name: %s\n", field, name)
+	if !t.apis[role].Namespaced {
+		ref += fmt.Sprintf("      namespace: %s\n", templateSecretNamespace)
+	}
+	return ref + fmt.Sprintf("      key: %s\n", key)
+}
+
+// azureResourceGroup renders the resource group Azure resources live in,
+// once per manifest
+func (t *manifestTemplate) azureResourceGroup() []string {
+	if t.family != "azure" || t.resourceGroup {
+		return nil
+	}
+	t.resourceGroup = true
+	return t.resource(crossplane.APIResourceGroup, "", templateResourceGroup, t.location(), false)
+}
+
+func (t *manifestTemplate) azureResourceGroupRef() string {
+	return fmt.Sprintf("    resourceGroupNameRef:\n      name: %s\n", templateResourceGroup)
+}
+
+func (t *manifestTemplate) database(name, desc string) []string {
+	role := crossplane.APIPostgres
+	if containsAny(desc, "mysql", "mariadb") {
+		role = crossplane.APIMySQL
+	}
+
+	switch t.family {
+	case "aws":
+		engine, version := "postgres", "16"
+		if role == crossplane.APIMySQL {
+			engine, version = "mysql", "8.0"
+		}
+		return t.resource(role, "", name, t.location()+fmt.Sprintf(`    instanceClass: db.t3.micro
+    engine: %s
+    engineVersion: "%s"
+    allocatedStorage: 20
+    dbName: myapp
+    username: dbadmin
+    autoGeneratePassword: true
+`, engine, version)+t.secretRef(role, "passwordSecretRef", name+"-password", "password")+`    backupRetentionPeriod: 7
+    storageEncrypted: true
+    publiclyAccessible: false
+    skipFinalSnapshot: true`, true)
+	case "gcp":
+		version := "POSTGRES_16"
+		if role == crossplane.APIMySQL {
+			version = "MYSQL_8_0"
+		}
+		return t.resource(role, "", name, t.location()+fmt.Sprintf(`    databaseVersion: %s
+    deletionProtection: false
+    settings:
+    - tier: db-f1-micro
+      diskSize: 20
+      backupConfiguration:
+      - enabled: true`, version), true)
+	case "azure":
+		version, sku := "16", "B_Standard_B1ms"
+		if role == crossplane.APIMySQL {
+			version, sku = "8.0.21", "B_Standard_B1s"
+		}
+		// The administrator password is read from a secret that must exist
+		server := t.resource(role, "", name, t.location()+t.azureResourceGroupRef()+fmt.Sprintf(`    version: "%s"
+    skuName: %s
+    administratorLogin: dbadmin
+`, version, sku)+t.secretRef(role, "administratorPasswordSecretRef", name+"-password", "password")+`    backupRetentionDays: 7`, true)
+		if server == nil {
+			return nil
+		}
+		return append(t.azureResourceGroup(), server...)
+	}
+	return nil
+}
+
+func (t *manifestTemplate) bucket(desc string) []string {
+	versioned := strings.Contains(desc, "version")
+
+	switch t.family {
+	case "aws":
+		name := "my-app-bucket"
+		docs := t.resource(crossplane.APIBucket, "", name, t.location()+`    tags:
+      Name: my-app-bucket`, false)
+		if docs == nil {
+			return nil
+		}
+		// Upbound's provider-aws-s3 configures access and versioning
+		// through their own resources referencing the bucket
+		bucketRef := fmt.Sprintf("    bucketRef:\n      name: %s\n", name)
+		docs = append(docs, t.resource(crossplane.APIBucket, "BucketPublicAccessBlock", name, t.location()+bucketRef+`    blockPublicAcls: true
+    blockPublicPolicy: true
+    ignorePublicAcls: true
+    restrictPublicBuckets: true`, false)...)
+		if versioned {
+			docs = append(docs, t.resource(crossplane.APIBucket, "BucketVersioning", name, t.location()+bucketRef+`    versioningConfiguration:
+    - status: Enabled`, false)...)
+		}
+		return docs
+	case "gcp":
+		fields := `    location: US
+    storageClass: STANDARD
+    uniformBucketLevelAccess: true
+    publicAccessPrevention: enforced`
+		if versioned {
+			fields += "\n    versioning:\n    - enabled: true"
+		}
+		return t.resource(crossplane.APIBucket, "", "my-app-bucket", fields, false)
+	case "azure":
+		// Storage account names may only contain lowercase letters and digits
+		fields := t.location() + t.azureResourceGroupRef() + `    accountTier: Standard
+    accountReplicationType: LRS
+    allowNestedItemsToBePublic: false`
+		if versioned {
+			fields += "\n    blobProperties:\n    - versioningEnabled: true"
+		}
+		account := t.resource(crossplane.APIBucket, "", "myappstorage", fields, false)
+		if account == nil {
+			return nil
+		}
+		return append(t.azureResourceGroup(), account...)
+	}
+	return nil
+}
+
+func (t *manifestTemplate) network() []string {
+	var docs []string
+	switch t.family {
+	case "aws":
+		docs = append(docs, t.resource(crossplane.APINetwork, "", "my-vpc", t.location()+`    cidrBlock: 10.0.0.0/16
+    enableDnsSupport: true
+    enableDnsHostnames: true`, false)...)
+		docs = append(docs, t.resource(crossplane.APISubnet, "", "my-subnet-public", t.location()+`    availabilityZone: us-east-1a
+    cidrBlock: 10.0.1.0/24
+    mapPublicIpOnLaunch: true
+    vpcIdRef:
+      name: my-vpc`, false)...)
+	case "gcp":
+		docs = append(docs, t.resource(crossplane.APINetwork, "", "my-network", `    autoCreateSubnetworks: false
+    routingMode: REGIONAL`, false)...)
+		docs = append(docs, t.resource(crossplane.APISubnet, "", "my-subnet", t.location()+`    ipCidrRange: 10.0.1.0/24
+    networkRef:
+      name: my-network`, false)...)
+	case "azure":
+		docs = append(docs, t.resource(crossplane.APINetwork, "", "my-vnet", t.location()+t.azureResourceGroupRef()+`    addressSpace:
+    - 10.0.0.0/16`, false)...)
+		docs = append(docs, t.resource(crossplane.APISubnet, "", "my-subnet", t.azureResourceGroupRef()+`    virtualNetworkNameRef:
+      name: my-vnet
+    addressPrefixes:
+    - 10.0.1.0/24`, false)...)
+		if len(docs) > 0 {
+			docs = append(t.azureResourceGroup(), docs...)
+		}
+	}
+	return docs
+}
+
+func (t *manifestTemplate) instance() []string {
+	switch t.family {
+	case "aws":
+		return t.resource(crossplane.APIInstance, "", "my-instance", t.location()+`    instanceType: t3.micro
+    # Replace with an AMI available in the region
+    ami: ami-0abcdef1234567890
+    tags:
+      Name: my-instance`, false)
+	case "gcp":
+		return t.resource(crossplane.APIInstance, "", "my-instance", `    zone: us-central1-a
+    machineType: e2-micro
+    bootDisk:
+    - initializeParams:
+      - image: debian-cloud/debian-12
+    networkInterface:
+    - network: default`, false)
+	}
+	return nil
+}
+
+func (t *manifestTemplate) webApp(desc string) []string {
+	var docs []string
+	switch t.family {
+	case "aws":
+		docs = append(docs, t.resource(crossplane.APILoadBalancer, "", "my-web-lb", t.location()+`    loadBalancerType: application
+    internal: false
+    subnetSelector:
+      matchLabels:
+        network: public`, false)...)
+	case "azure":
+		lb := t.resource(crossplane.APILoadBalancer, "", "my-web-lb", t.location()+t.azureResourceGroupRef()+`    sku: Standard`, false)
+		if lb != nil {
+			docs = append(append(docs, t.azureResourceGroup()...), lb...)
+		}
+	}
+	return append(docs, t.database("my-web-db", desc)...)
+}
+
+// compositionTemplate renders a pipeline Composition composing a bucket of
+// the family, or a placeholder resource for families without templates
+func compositionTemplate(provider string, apis crossplane.APISet) string {
+	base := "apiVersion: example.com/v1alpha1\n          kind: Resource"
+	field := "region"
+	if ref, ok := apis[crossplane.APIBucket]; ok {
+		base = fmt.Sprintf("apiVersion: %s\n          kind: %s", ref.APIVersion, ref.Kind)
+	}
+	if loc, ok := locations[provider]; ok {
+		field = loc.field
+	}
+
+	return fmt.Sprintf(`# Crossplane Composition generated by Crossplane AI
+apiVersion: apiextensions.crossplane.io/v1
+kind: Composition
+metadata:
+  name: my-composition
+  labels:
+    generated-by: crossplane-ai
+    provider: %s
+spec:
+  compositeTypeRef:
+    apiVersion: example.com/v1alpha1
+    kind: XResource
+  mode: Pipeline
+  pipeline:
+  - step: patch-and-transform
+    functionRef:
+      name: function-patch-and-transform
+    input:
+      apiVersion: pt.fn.crossplane.io/v1beta1
+      kind: Resources
+      resources:
+      - name: resource
+        base:
+          %s
+          spec:
+            forProvider: {}
+        patches:
+        - type: FromCompositeFieldPath
+          fromFieldPath: spec.%s
+          toFieldPath: spec.forProvider.%s`, provider, base, field, field)
+}
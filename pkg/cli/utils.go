@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 )
 
 // PrintSuccess prints a success message
@@ -63,6 +64,26 @@ func FormatAge(age string) string {
 	return age
 }
 
+// FormatSince formats the time elapsed since t in the compact form used by
+// kubectl (e.g. "45s", "12m", "3h5m", "2d4h")
+func FormatSince(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
 // TruncateString truncates a string to specified length with ellipsis
 func TruncateString(s string, length int) string {
 	if len(s) <= length {
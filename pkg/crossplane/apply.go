@@ -0,0 +1,271 @@
+package crossplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// FieldManager is the server-side apply field manager used for objects
+// applied by crossplane-ai
+const FieldManager = "crossplane-ai"
+
+// ApplyAction describes what applying an object did
+type ApplyAction string
+
+// Apply actions
+const (
+	ApplyCreated   ApplyAction = "created"
+	ApplyUpdated   ApplyAction = "updated"
+	ApplyUnchanged ApplyAction = "unchanged"
+	ApplyFailed    ApplyAction = "failed"
+)
+
+// ApplyOptions controls server-side apply
+type ApplyOptions struct {
+	// DryRun applies with dryRun=All, so the API server validates and
+	// admits the objects without persisting them
+	DryRun bool
+	// Force takes ownership of fields managed by other field managers
+	// instead of failing with a conflict
+	Force bool
+}
+
+// ApplyResult is the outcome of applying a single object
+type ApplyResult struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Name       string      `json:"name"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Resource   string      `json:"resource"`
+	Action     ApplyAction `json:"action"`
+	DryRun     bool        `json:"dryRun,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+func (r ApplyResult) String() string {
+	s := fmt.Sprintf("%s/%s %s", r.Resource, r.Name, r.Action)
+	if r.Namespace != "" {
+		s = fmt.Sprintf("%s/%s (%s) %s", r.Resource, r.Name, r.Namespace, r.Action)
+	}
+	if r.DryRun {
+		s += " (server dry run)"
+	}
+	if r.Error != "" {
+		s += ": " + r.Error
+	}
+	return s
+}
+
+// SplitManifest decodes a multi-document YAML (or JSON) manifest into
+// objects. Empty documents and comment-only documents are skipped, and so
+// are the lines of markdown code fences around the YAML.
+func SplitManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	lines := strings.Split(manifest, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			kept = append(kept, line)
+		}
+	}
+	manifest = strings.Join(kept, "\n")
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+
+	var objects []*unstructured.Unstructured
+	for i := 1; ; i++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("document %d: invalid YAML: %w", i, err)
+		}
+		if len(raw) == 0 {
+			continue
+		}
+		// Decode integers as int64 rather than float64, as the API
+		// machinery expects, e.g. for metadata.generation
+		var content map[string]interface{}
+		if err := utiljson.Unmarshal(raw, &content); err != nil {
+			return nil, fmt.Errorf("document %d: invalid YAML: %w", i, err)
+		}
+		if len(content) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: content}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, fmt.Errorf("document %d: apiVersion and kind are required", i)
+		}
+		if obj.GetName() == "" {
+			return nil, fmt.Errorf("document %d (%s): metadata.name is required", i, obj.GetKind())
+		}
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("manifest contains no objects")
+	}
+
+	return objects, nil
+}
+
+// ApplyManifest server-side applies every object of a multi-document
+// manifest. All kinds are resolved before anything is applied, so a
+// manifest referencing a kind the cluster does not serve changes nothing.
+// Objects are applied in order; a rejected object does not stop the rest,
+// and the returned error lists every failure.
+func (c *Client) ApplyManifest(ctx context.Context, manifest string, opts ApplyOptions) ([]ApplyResult, error) {
+	if c.source.ReadOnly() {
+		return nil, fmt.Errorf("cannot apply to %s: %w", c.cluster, ErrReadOnlySource)
+	}
+
+	objects, err := SplitManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]DiscoveredType, len(objects))
+	for i, obj := range objects {
+		t, err := c.typeForKind(ctx, obj.GroupVersionKind())
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				return nil, fmt.Errorf("%s %q: kind %s is not served by the cluster (is the provider or XRD installed?)",
+					obj.GetKind(), obj.GetName(), obj.GroupVersionKind())
+			}
+			return nil, fmt.Errorf("%s %q: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		types[i] = t
+	}
+
+	var results []ApplyResult
+	var errs []error
+	for i, obj := range objects {
+		result, err := c.applyObject(ctx, obj, types[i], opts)
+		results = append(results, result)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", result.Resource, result.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, fmt.Errorf("%d of %d objects failed to apply: %w", len(errs), len(objects), errors.Join(errs...))
+	}
+	return results, nil
+}
+
+func (c *Client) applyObject(ctx context.Context, obj *unstructured.Unstructured, t DiscoveredType, opts ApplyOptions) (ApplyResult, error) {
+	if t.Namespaced {
+		if obj.GetNamespace() == "" {
+			namespace := c.namespace
+			if namespace == "" {
+				namespace = metav1.NamespaceDefault
+			}
+			obj.SetNamespace(namespace)
+		}
+	} else {
+		// A namespace on a cluster-scoped object is rejected by the API
+		// server; generated manifests sometimes carry one
+		obj.SetNamespace("")
+	}
+
+	result := ApplyResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		Resource:   t.GVR.GroupResource().String(),
+		DryRun:     opts.DryRun,
+	}
+
+	var ri dynamic.ResourceInterface = c.dynamicClient.Resource(t.GVR)
+	if t.Namespaced {
+		ri = c.dynamicClient.Resource(t.GVR).Namespace(obj.GetNamespace())
+	}
+
+	existing, err := ri.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		result.Action, result.Error = ApplyFailed, err.Error()
+		return result, err
+	}
+	if apierrors.IsNotFound(err) {
+		existing = nil
+	}
+
+	applyOpts := metav1.ApplyOptions{FieldManager: FieldManager, Force: opts.Force}
+	if opts.DryRun {
+		applyOpts.DryRun = []string{metav1.DryRunAll}
+	}
+
+	applied, err := ri.Apply(ctx, obj.GetName(), obj, applyOpts)
+	if err != nil {
+		err = describeApplyError(err)
+		result.Action, result.Error = ApplyFailed, err.Error()
+		return result, err
+	}
+
+	switch {
+	case existing == nil:
+		result.Action = ApplyCreated
+	case equality.Semantic.DeepEqual(applyComparable(existing), applyComparable(applied)):
+		result.Action = ApplyUnchanged
+	default:
+		result.Action = ApplyUpdated
+	}
+
+	return result, nil
+}
+
+// applyComparable strips the fields the API server changes on every write, so
+// that an apply that changed nothing compares equal to the existing object
+func applyComparable(obj *unstructured.Unstructured) map[string]interface{} {
+	c := obj.DeepCopy()
+	c.SetManagedFields(nil)
+	c.SetResourceVersion("")
+	c.SetGeneration(0)
+	unstructured.RemoveNestedField(c.Object, "status")
+	return c.Object
+}
+
+// describeApplyError turns schema rejections and field manager conflicts
+// into messages that name the offending fields
+func describeApplyError(err error) error {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return err
+	}
+
+	switch {
+	case apierrors.IsInvalid(err):
+		details := status.Status().Details
+		if details == nil || len(details.Causes) == 0 {
+			return fmt.Errorf("rejected by the API server: %w", err)
+		}
+		var causes []string
+		for _, cause := range details.Causes {
+			if cause.Field != "" {
+				causes = append(causes, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+			} else {
+				causes = append(causes, cause.Message)
+			}
+		}
+		return fmt.Errorf("rejected by the API server: %s", strings.Join(causes, "; "))
+	case apierrors.IsConflict(err):
+		return fmt.Errorf("%w (fields are owned by another field manager; re-run with --force-conflicts to take ownership)", err)
+	}
+
+	return err
+}
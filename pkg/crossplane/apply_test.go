@@ -0,0 +1,298 @@
+package crossplane
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSplitManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "single document",
+			manifest: "apiVersion: s3.aws.upbound.io/v1beta1\nkind: Bucket\nmetadata:\n  name: logs\n",
+			want:     []string{"Bucket/logs"},
+		},
+		{
+			name: "multiple documents",
+			manifest: `apiVersion: s3.aws.upbound.io/v1beta1
+kind: Bucket
+metadata:
+  name: logs
+---
+apiVersion: database.example.org/v1alpha1
+kind: PostgreSQLInstance
+metadata:
+  name: orders-db
+  namespace: team-a
+`,
+			want: []string{"Bucket/logs", "PostgreSQLInstance/orders-db"},
+		},
+		{
+			name: "empty and comment-only documents",
+			manifest: `---
+# generated by crossplane-ai
+---
+apiVersion: s3.aws.upbound.io/v1beta1
+kind: Bucket
+metadata:
+  name: logs
+---
+`,
+			want: []string{"Bucket/logs"},
+		},
+		{
+			name:     "markdown code fence",
+			manifest: "```yaml\napiVersion: s3.aws.upbound.io/v1beta1\nkind: Bucket\nmetadata:\n  name: logs\n---\napiVersion: s3.aws.upbound.io/v1beta1\nkind: Bucket\nmetadata:\n  name: backups\n```\n",
+			want:     []string{"Bucket/logs", "Bucket/backups"},
+		},
+		{
+			name:     "JSON",
+			manifest: `{"apiVersion": "s3.aws.upbound.io/v1beta1", "kind": "Bucket", "metadata": {"name": "logs"}}`,
+			want:     []string{"Bucket/logs"},
+		},
+		{
+			name:     "only comments",
+			manifest: "# nothing to apply\n",
+			wantErr:  "manifest contains no objects",
+		},
+		{
+			name:     "missing kind",
+			manifest: "apiVersion: v1\nmetadata:\n  name: logs\n",
+			wantErr:  "document 1: apiVersion and kind are required",
+		},
+		{
+			name:     "missing name",
+			manifest: "apiVersion: v1\nkind: ConfigMap\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  namespace: default\n",
+			wantErr:  "document 1 (ConfigMap): metadata.name is required",
+		},
+		{
+			name:     "invalid YAML",
+			manifest: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n---\nkind: [ConfigMap\n",
+			wantErr:  "document 2: invalid YAML",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := SplitManifest(tt.manifest)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitManifest: %v", err)
+			}
+			var got []string
+			for _, obj := range objects {
+				got = append(got, obj.GetKind()+"/"+obj.GetName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("objects = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+var bucketGVR = schema.GroupVersionResource{Group: "s3.aws.upbound.io", Version: "v1beta1", Resource: "buckets"}
+
+const (
+	bucketManifest = `apiVersion: s3.aws.upbound.io/v1beta1
+kind: Bucket
+metadata:
+  name: logs
+  namespace: team-a
+spec:
+  forProvider:
+    region: us-east-1
+`
+	// applyTypes declares the API types of the apply tests: a cluster
+	// scoped managed resource and a namespaced claim
+	applyTypes = `apiVersion: s3.aws.upbound.io/v1beta1
+kind: Bucket
+metadata:
+  name: definition
+spec:
+  forProvider: {}
+---
+apiVersion: database.example.org/v1alpha1
+kind: PostgreSQLInstance
+metadata:
+  name: definition
+  namespace: default
+spec:
+  resourceRef: {}
+`
+	claimManifest = `apiVersion: database.example.org/v1alpha1
+kind: PostgreSQLInstance
+metadata:
+  name: orders-db
+spec:
+  parameters:
+    storageGB: 20
+`
+)
+
+// writableSource serves the API types of a fake source and keeps objects
+// in a fake dynamic client, which accepts writes
+type writableSource struct {
+	ResourceSource
+	dynamic dynamic.Interface
+}
+
+func (s writableSource) Dynamic() dynamic.Interface { return s.dynamic }
+func (s writableSource) ReadOnly() bool             { return false }
+
+// applyReactor handles server-side apply on a fake dynamic client, which
+// only patches existing objects, by creating or replacing the object
+func applyReactor(tracker k8stesting.ObjectTracker) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(k8stesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+		gvr, namespace := patch.GetResource(), patch.GetNamespace()
+		_, err := tracker.Get(gvr, namespace, patch.GetName())
+		switch {
+		case apierrors.IsNotFound(err):
+			err = tracker.Create(gvr, obj, namespace)
+		case err == nil:
+			err = tracker.Update(gvr, obj, namespace)
+		}
+		if err != nil {
+			return true, nil, err
+		}
+		return true, obj, nil
+	}
+}
+
+// newApplyClient returns a client applying to a fake dynamic client that
+// holds the objects of existing, with team-a as the namespace of the client
+func newApplyClient(t *testing.T, existing string) (*Client, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+	definitions, err := SplitManifest(applyTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake, err := NewFakeSource("test", definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []runtime.Object
+	if existing != "" {
+		existingObjects, err := SplitManifest(existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range existingObjects {
+			objects = append(objects, obj)
+		}
+	}
+	// Discovery lists CRDs to classify the types
+	listKinds := map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList"}
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+	dyn.PrependReactor("patch", "*", applyReactor(dyn.Tracker()))
+
+	source := writableSource{ResourceSource: fake, dynamic: dyn}
+	return NewClientFromSource(source, ClientOptions{Namespace: "team-a"}), dyn
+}
+
+func TestApplyManifest(t *testing.T) {
+	const updatedBucket = `apiVersion: s3.aws.upbound.io/v1beta1
+kind: Bucket
+metadata:
+  name: logs
+spec:
+  forProvider:
+    region: eu-west-1
+`
+	tests := []struct {
+		name     string
+		existing string
+		manifest string
+		want     []string
+	}{
+		{
+			name:     "created",
+			manifest: bucketManifest + "---\n" + claimManifest,
+			want:     []string{"buckets.s3.aws.upbound.io/logs created", "postgresqlinstances.database.example.org/orders-db (team-a) created"},
+		},
+		{
+			name:     "unchanged",
+			existing: strings.Replace(bucketManifest, "  namespace: team-a\n", "", 1),
+			manifest: bucketManifest,
+			want:     []string{"buckets.s3.aws.upbound.io/logs unchanged"},
+		},
+		{
+			name:     "updated",
+			existing: updatedBucket,
+			manifest: bucketManifest,
+			want:     []string{"buckets.s3.aws.upbound.io/logs updated"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newApplyClient(t, tt.existing)
+			results, err := client.ApplyManifest(context.Background(), tt.manifest, ApplyOptions{})
+			if err != nil {
+				t.Fatalf("ApplyManifest: %v", err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("results = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyManifestRejectsUnknownKinds(t *testing.T) {
+	client, dyn := newApplyClient(t, "")
+	manifest := bucketManifest + "---\napiVersion: sql.gcp.upbound.io/v1beta1\nkind: DatabaseInstance\nmetadata:\n  name: orders\n"
+
+	_, err := client.ApplyManifest(context.Background(), manifest, ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "DatabaseInstance") {
+		t.Fatalf("err = %v, want it to name the unknown kind", err)
+	}
+	// Kinds are resolved before anything is applied
+	if _, err := dyn.Tracker().Get(bucketGVR, "", "logs"); !apierrors.IsNotFound(err) {
+		t.Errorf("bucket was applied although the manifest has an unknown kind (err = %v)", err)
+	}
+}
+
+func TestApplyManifestReadOnlySource(t *testing.T) {
+	fake, err := NewFakeSource("test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClientFromSource(fake, ClientOptions{})
+
+	_, err = client.ApplyManifest(context.Background(), bucketManifest, ApplyOptions{})
+	if !errors.Is(err, ErrReadOnlySource) {
+		t.Errorf("err = %v, want ErrReadOnlySource", err)
+	}
+}
@@ -0,0 +1,343 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// defaultCacheSyncTimeout bounds the wait for the initial list of every
+// informer; types that have not synced by then are reported as failures
+const defaultCacheSyncTimeout = 30 * time.Second
+
+// Cache keeps an in-memory copy of every discovered Crossplane resource
+// type using dynamic informers. While a cache is running, the
+// client's list calls are answered from memory.
+//
+// The set of types is fixed when the cache starts; providers or XRDs
+// installed later are not watched until the cache is restarted.
+type Cache struct {
+	client    *Client
+	cancel    context.CancelFunc
+	types     []DiscoveredType
+	informers map[schema.GroupVersionResource]informers.GenericInformer
+
+	mu         sync.Mutex
+	lastErrors map[schema.GroupVersionResource]error
+	handlers   []func(Transition)
+}
+
+// Transition is a change in the state of a resource observed by the cache
+type Transition struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	// From and To are the Ready state ("Ready", "Not Ready", "Unknown").
+	// From is empty for a new resource and To is "Deleted" for a removed one.
+	From       string `json:"from,omitempty"`
+	To         string `json:"to"`
+	SyncedFrom string `json:"syncedFrom,omitempty"`
+	SyncedTo   string `json:"syncedTo,omitempty"`
+	// Reason and Message come from the failing condition after the change
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	Resource *Resource `json:"-"`
+}
+
+// String renders the transition as "my-db: Ready → Not Ready: ReconcileError: ..."
+func (t Transition) String() string {
+	name := t.Name
+	if t.Namespace != "" {
+		name = t.Namespace + "/" + t.Name
+	}
+
+	var s string
+	switch {
+	case t.From == "":
+		s = fmt.Sprintf("%s %s: created (%s)", t.Kind, name, t.To)
+	case t.From == t.To:
+		s = fmt.Sprintf("%s %s: %s", t.Kind, name, t.To)
+	default:
+		s = fmt.Sprintf("%s %s: %s → %s", t.Kind, name, t.From, t.To)
+	}
+	if t.SyncedFrom != t.SyncedTo && t.From != "" {
+		s += fmt.Sprintf(" [Synced %s → %s]", stateOrUnknown(t.SyncedFrom), stateOrUnknown(t.SyncedTo))
+	}
+	if t.Reason != "" {
+		s += ": " + t.Reason
+		if t.Message != "" {
+			s += ": " + t.Message
+		}
+	}
+	return s
+}
+
+func stateOrUnknown(state string) string {
+	if state == "" {
+		return "Unknown"
+	}
+	return state
+}
+
+// StartCache discovers the resource types that pass the include lists,
+// starts an informer for each and waits for the initial lists. The cache
+// keeps running until StopCache is called or ctx is cancelled. Calling
+// StartCache again returns the running cache.
+func (c *Client) StartCache(ctx context.Context) (*Cache, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	if c.cache != nil {
+		return c.cache, nil
+	}
+
+	discovered, err := c.DiscoverResourceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []DiscoveredType
+	for _, t := range discovered {
+		if c.includes(t) {
+			types = append(types, t)
+		}
+	}
+
+	cacheCtx, cancel := context.WithCancel(context.Background())
+	cache := &Cache{
+		client:     c,
+		cancel:     cancel,
+		types:      types,
+		informers:  make(map[schema.GroupVersionResource]informers.GenericInformer),
+		lastErrors: make(map[schema.GroupVersionResource]error),
+	}
+
+	for _, t := range types {
+		t := t
+
+		// Cluster-scoped types are not limited by the namespace
+		namespace := ""
+		if t.Namespaced {
+			namespace = c.namespace
+		}
+		cache.informers[t.GVR] = dynamicinformer.NewFilteredDynamicInformer(c.dynamicClient, t.GVR, namespace, 0,
+			toolscache.Indexers{}, nil)
+
+		informer := cache.informers[t.GVR].Informer()
+		// Replaces the default handler, which logs every failed list of a
+		// forbidden or removed type
+		_ = informer.SetWatchErrorHandler(func(_ *toolscache.Reflector, err error) {
+			cache.mu.Lock()
+			cache.lastErrors[t.GVR] = err
+			cache.mu.Unlock()
+		})
+		_, _ = informer.AddEventHandler(toolscache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj interface{}, isInInitialList bool) {
+				if !isInInitialList {
+					cache.notify(nil, cache.toResource(obj, t))
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				cache.notify(cache.toResource(oldObj, t), cache.toResource(newObj, t))
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				if resource := cache.toResource(obj, t); resource != nil {
+					cache.notifyDeleted(resource)
+				}
+			},
+		})
+
+		go informer.Run(cacheCtx.Done())
+	}
+
+	// Wait until every type has either synced or failed to list; types
+	// that do neither before the timeout are reported by List
+	syncCtx, syncCancel := context.WithTimeout(ctx, defaultCacheSyncTimeout)
+	defer syncCancel()
+	_ = wait.PollUntilContextCancel(syncCtx, 100*time.Millisecond, true, func(context.Context) (bool, error) {
+		return cache.settled(), nil
+	})
+	if ctx.Err() != nil {
+		cancel()
+		return nil, ctx.Err()
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.stopCache(cache)
+		case <-cacheCtx.Done():
+		}
+	}()
+
+	c.cache = cache
+	return cache, nil
+}
+
+// StopCache stops the running cache, if any. Later list calls go to the
+// API server again.
+func (c *Client) StopCache() {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	if c.cache != nil {
+		c.cache.cancel()
+		c.cache = nil
+	}
+}
+
+// stopCache stops the given cache if it is still the running one
+func (c *Client) stopCache(cache *Cache) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	cache.cancel()
+	if c.cache == cache {
+		c.cache = nil
+	}
+}
+
+// runningCache returns the running cache, or nil
+func (c *Client) runningCache() *Cache {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	return c.cache
+}
+
+// OnTransition registers fn to be called for every resource that is
+// created, deleted or changes its Ready or Synced state. fn is called from
+// informer goroutines and must not block.
+func (cache *Cache) OnTransition(fn func(Transition)) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.handlers = append(cache.handlers, fn)
+}
+
+// Types returns the number of resource types being watched
+func (cache *Cache) Types() int {
+	return len(cache.types)
+}
+
+// settled reports whether every informer has synced or failed at least once
+func (cache *Cache) settled() bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for gvr, informer := range cache.informers {
+		if !informer.Informer().HasSynced() && cache.lastErrors[gvr] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the cached resources of every watched type. Types whose
+// informer has not synced are reported as failures with the last watch
+// error, as ListAllResources does for types that cannot be listed.
+func (cache *Cache) List() *ListResult {
+	start := time.Now()
+	result := &ListResult{Types: len(cache.types)}
+
+	for _, t := range cache.types {
+		informer := cache.informers[t.GVR]
+		if !informer.Informer().HasSynced() {
+			cache.mu.Lock()
+			err := cache.lastErrors[t.GVR]
+			cache.mu.Unlock()
+			if err == nil {
+				err = fmt.Errorf("cache has not synced: %w", context.DeadlineExceeded)
+			}
+			result.Failures = append(result.Failures, newListFailure(t.GVR, err))
+			continue
+		}
+
+		for _, obj := range informer.Informer().GetStore().List() {
+			if resource := cache.toResource(obj, t); resource != nil {
+				result.Resources = append(result.Resources, resource)
+			}
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// toResource converts a cached object. The object is copied so that
+// callers cannot modify the informer's store.
+func (cache *Cache) toResource(obj interface{}, t DiscoveredType) *Resource {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	resource := cache.client.convertToResource(u.DeepCopy(), t)
+	return resource
+}
+
+func (cache *Cache) notify(old, current *Resource) {
+	if current == nil {
+		return
+	}
+
+	transition := Transition{
+		Time:      time.Now(),
+		Kind:      current.Kind,
+		Name:      current.Name,
+		Namespace: current.Namespace,
+		To:        current.Status,
+		SyncedTo:  current.Synced,
+		Resource:  current,
+	}
+	if old != nil {
+		if old.Status == current.Status && old.Synced == current.Synced &&
+			old.IsDeleting() == current.IsDeleting() {
+			return
+		}
+		transition.From = old.Status
+		transition.SyncedFrom = old.Synced
+		if current.IsDeleting() && !old.IsDeleting() {
+			transition.To = "Deleting"
+		}
+	}
+	if condition := current.FailingCondition(); condition != nil {
+		transition.Reason, transition.Message = condition.Reason, condition.Message
+	}
+
+	cache.emit(transition)
+}
+
+func (cache *Cache) notifyDeleted(resource *Resource) {
+	cache.emit(Transition{
+		Time:       time.Now(),
+		Kind:       resource.Kind,
+		Name:       resource.Name,
+		Namespace:  resource.Namespace,
+		From:       resource.Status,
+		To:         "Deleted",
+		SyncedFrom: resource.Synced,
+		SyncedTo:   resource.Synced,
+		Resource:   resource,
+	})
+}
+
+func (cache *Cache) emit(transition Transition) {
+	cache.mu.Lock()
+	handlers := append([]func(Transition){}, cache.handlers...)
+	cache.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(transition)
+	}
+}
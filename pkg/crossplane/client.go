@@ -4,43 +4,112 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 )
 
 // Client represents a Crossplane client
 type Client struct {
+	source        ResourceSource
 	kubeClient    kubernetes.Interface
 	dynamicClient dynamic.Interface
-	restConfig    *rest.Config
+
+	cachedDiscovery discovery.CachedDiscoveryInterface
+	discoveryMu     sync.Mutex
+	discovered      []DiscoveredType
+	discoveredAt    time.Time
+	restMapper      *restmapper.DeferredDiscoveryRESTMapper
+
+	cacheMu sync.Mutex
+	cache   *Cache
+
+	listConcurrency int
+	listTimeout     time.Duration
+	pageSize        int64
+
+	// cluster is the kubeconfig context the client talks to; it tags
+	// every resource
+	cluster string
+
+	namespace     string
+	providers     []string
+	resourceTypes []string
 }
 
+// inClusterName is the cluster name of a client using the in-cluster config
+const inClusterName = "in-cluster"
+
 // Resource represents a Crossplane resource
 type Resource struct {
-	Name      string                     `json:"name"`
-	Namespace string                     `json:"namespace"`
-	Type      string                     `json:"type"`
-	Provider  string                     `json:"provider"`
-	Status    string                     `json:"status"`
-	Age       string                     `json:"age"`
-	Labels    map[string]string          `json:"labels,omitempty"`
-	Spec      interface{}                `json:"spec,omitempty"`
-	Raw       *unstructured.Unstructured `json:"-"`
+	// Cluster is the kubeconfig context the resource was read from
+	Cluster    string                     `json:"cluster,omitempty"`
+	Name       string                     `json:"name"`
+	Namespace  string                     `json:"namespace"`
+	Type       string                     `json:"type"`
+	Kind       string                     `json:"kind,omitempty"`
+	APIVersion string                     `json:"apiVersion,omitempty"`
+	Category   Category                   `json:"category,omitempty"`
+	Provider   string                     `json:"provider"`
+	Status     string                     `json:"status"`
+	Age        string                     `json:"age"`
+	Labels     map[string]string          `json:"labels,omitempty"`
+	Spec       interface{}                `json:"spec,omitempty"`
+	Raw        *unstructured.Unstructured `json:"-"`
+
+	// Synced is the status of the Synced condition ("True", "False",
+	// "Unknown"), empty if the resource does not report it
+	Synced             string      `json:"synced,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+	Generation         int64       `json:"generation,omitempty"`
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	CreatedAt          time.Time   `json:"createdAt"`
+	DeletionTimestamp  *time.Time  `json:"deletionTimestamp,omitempty"`
+
+	// Events is only populated on request, see GetEvents and AttachEvents
+	Events []Event `json:"events,omitempty"`
+	// Drift is only populated on request, see AttachDrift
+	Drift []FieldDrift `json:"drift,omitempty"`
 }
 
 // ClientOptions contains options for creating a new client
 type ClientOptions struct {
 	Context    string
 	Kubeconfig string
+	// Snapshot is the path of a snapshot to read from instead of the
+	// cluster; Context and Kubeconfig are ignored when it is set
+	Snapshot string
+	// Source is read from instead of the cluster or a snapshot when set,
+	// e.g. a fake source for mock mode
+	Source ResourceSource
+
+	// Namespace scopes namespaced resources to a single namespace; empty
+	// means all namespaces
+	Namespace string
+	// Providers limits managed resources to these provider families
+	// (e.g. "aws"); empty means all providers
+	Providers []string
+	// ResourceTypes limits listing to these types, by plural, kind or
+	// plural.group; empty means all discovered types
+	ResourceTypes []string
+
+	// ListConcurrency is the number of resource types listed in parallel
+	ListConcurrency int
+	// ListTimeout bounds the time spent listing a single resource type
+	ListTimeout time.Duration
+	// PageSize is the number of objects requested per list call
+	PageSize int64
 }
 
 // NewClient creates a new Crossplane client
@@ -50,146 +119,119 @@ func NewClient(ctx context.Context) (*Client, error) {
 
 // NewClientWithOptions creates a new Crossplane client with options
 func NewClientWithOptions(ctx context.Context, opts ClientOptions) (*Client, error) {
-	// Try to get kubeconfig from various sources
-	kubeconfig := opts.Kubeconfig
-	if kubeconfig == "" {
-		if home := homedir.HomeDir(); home != "" {
-			kubeconfig = filepath.Join(home, ".kube", "config")
-		}
+	source, err := NewLiveSource(opts.Kubeconfig, opts.Context)
+	if err != nil {
+		return nil, err
 	}
+	return NewClientFromSource(source, opts), nil
+}
 
-	// Build config from kubeconfig with optional context override
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		// Try in-cluster config
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return nil, fmt.Errorf("failed to build kubeconfig: %w", err)
+// buildRestConfig loads the kubeconfig using the standard loading rules
+// (explicit path, then KUBECONFIG, then ~/.kube/config) with an optional
+// context override, falling back to the in-cluster config. It also returns
+// the name of the context in use, or "in-cluster".
+func buildRestConfig(kubeconfig, kubeContext string) (*rest.Config, string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = expandHome(kubeconfig)
+
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	config, err := clientConfig.ClientConfig()
+	if err == nil {
+		name := kubeContext
+		if name == "" {
+			if raw, rawErr := clientConfig.RawConfig(); rawErr == nil {
+				name = raw.CurrentContext
+			}
 		}
+		return config, name, nil
 	}
 
-	// If context is specified, override the current context
-	if opts.Context != "" {
-		configLoader := clientcmd.NewDefaultClientConfigLoadingRules()
-		configLoader.ExplicitPath = kubeconfig
-		configOverrides := &clientcmd.ConfigOverrides{
-			CurrentContext: opts.Context,
-		}
-		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-			configLoader, configOverrides).ClientConfig()
-		if err != nil {
-			return nil, fmt.Errorf("failed to build kubeconfig with context %s: %w", opts.Context, err)
+	// An explicitly requested kubeconfig or context must not silently fall
+	// back to the in-cluster identity
+	if kubeconfig != "" || kubeContext != "" {
+		if kubeContext != "" {
+			return nil, "", fmt.Errorf("failed to build kubeconfig with context %s: %w", kubeContext, err)
 		}
+		return nil, "", fmt.Errorf("failed to build kubeconfig from %s: %w", kubeconfig, err)
 	}
 
-	// Create Kubernetes client
-	kubeClient, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	config, inClusterErr := rest.InClusterConfig()
+	if inClusterErr != nil {
+		return nil, "", fmt.Errorf("failed to build kubeconfig: %w", err)
 	}
+	return config, inClusterName, nil
+}
+
+// KubeconfigContexts returns the names of all contexts in the kubeconfig,
+// resolved with the same loading rules as NewClientWithOptions, sorted
+func KubeconfigContexts(kubeconfig string) ([]string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = expandHome(kubeconfig)
 
-	// Create dynamic client for CRDs
-	dynamicClient, err := dynamic.NewForConfig(config)
+	raw, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).RawConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
 	}
 
-	return &Client{
-		kubeClient:    kubeClient,
-		dynamicClient: dynamicClient,
-		restConfig:    config,
-	}, nil
-}
-
-// GetAllResources retrieves all Crossplane resources
-func (c *Client) GetAllResources(ctx context.Context) ([]*Resource, error) {
-	var allResources []*Resource
-
-	// Common Crossplane resource types to check
-	resourceTypes := []schema.GroupVersionResource{
-		// Core Crossplane resources
-		{Group: "apiextensions.crossplane.io", Version: "v1", Resource: "compositions"},
-		{Group: "apiextensions.crossplane.io", Version: "v1", Resource: "compositeresourcedefinitions"},
-		{Group: "pkg.crossplane.io", Version: "v1", Resource: "providers"},
-		{Group: "pkg.crossplane.io", Version: "v1", Resource: "configurations"},
-
-		// AWS Provider resources (common ones)
-		{Group: "rds.aws.crossplane.io", Version: "v1alpha1", Resource: "dbinstances"},
-		{Group: "ec2.aws.crossplane.io", Version: "v1alpha1", Resource: "instances"},
-		{Group: "s3.aws.crossplane.io", Version: "v1alpha1", Resource: "buckets"},
-		{Group: "eks.aws.crossplane.io", Version: "v1alpha1", Resource: "clusters"},
-
-		// GCP Provider resources (common ones)
-		{Group: "sql.gcp.crossplane.io", Version: "v1alpha1", Resource: "databaseinstances"},
-		{Group: "compute.gcp.crossplane.io", Version: "v1alpha1", Resource: "instances"},
-		{Group: "storage.gcp.crossplane.io", Version: "v1alpha1", Resource: "buckets"},
-
-		// Azure Provider resources (common ones)
-		{Group: "sql.azure.crossplane.io", Version: "v1alpha1", Resource: "servers"},
-		{Group: "compute.azure.crossplane.io", Version: "v1alpha1", Resource: "virtualmachines"},
-		{Group: "storage.azure.crossplane.io", Version: "v1alpha1", Resource: "accounts"},
+	contexts := make([]string, 0, len(raw.Contexts))
+	for name := range raw.Contexts {
+		contexts = append(contexts, name)
 	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
 
-	for _, gvr := range resourceTypes {
-		resources, err := c.getResourcesOfType(ctx, gvr)
-		if err != nil {
-			// Continue with other resources even if one type fails
-			continue
+// expandHome expands a leading ~ in a path, as written in config files
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home := homedir.HomeDir(); home != "" {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
 		}
-		allResources = append(allResources, resources...)
 	}
-
-	return allResources, nil
+	return path
 }
 
-// GetFilteredResources retrieves filtered Crossplane resources
-func (c *Client) GetFilteredResources(ctx context.Context, name, provider, namespace string) ([]*Resource, error) {
-	allResources, err := c.GetAllResources(ctx)
+// GetAllResources retrieves all Crossplane resources: core types, claims,
+// composites and managed resources of every installed provider. Types that
+// cannot be listed are skipped; use ListAllResources to find out which.
+func (c *Client) GetAllResources(ctx context.Context) ([]*Resource, error) {
+	result, err := c.ListAllResources(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	var filtered []*Resource
-	for _, resource := range allResources {
-		// Apply filters
-		if name != "" && resource.Name != name {
-			continue
-		}
-		if provider != "" && resource.Provider != provider {
-			continue
-		}
-		if namespace != "" && resource.Namespace != namespace {
-			continue
-		}
-		filtered = append(filtered, resource)
-	}
-
-	return filtered, nil
+	return result.Resources, nil
 }
 
-func (c *Client) getResourcesOfType(ctx context.Context, gvr schema.GroupVersionResource) ([]*Resource, error) {
-	list, err := c.dynamicClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+// GetFilteredResources retrieves filtered Crossplane resources
+func (c *Client) GetFilteredResources(ctx context.Context, name, provider, namespace string) ([]*Resource, error) {
+	result, err := c.ListFilteredResources(ctx, name, provider, namespace)
 	if err != nil {
 		return nil, err
 	}
+	return result.Resources, nil
+}
 
-	var resources []*Resource
-	for _, item := range list.Items {
-		resource := c.convertToResource(&item, gvr)
-		resources = append(resources, resource)
-	}
+// ListFilteredResources lists Crossplane resources matching the filters and
+// reports the resource types that could not be listed. See ListResources
+// for selectors, patterns and the other criteria.
+func (c *Client) ListFilteredResources(ctx context.Context, name, provider, namespace string) (*ListResult, error) {
+	return c.ListResources(ctx, ResourceFilter{Name: name, Provider: provider, Namespace: namespace})
+}
 
-	return resources, nil
+func (c *Client) getResourcesOfType(ctx context.Context, gvr schema.GroupVersionResource, category Category) ([]*Resource, error) {
+	return c.listType(ctx, DiscoveredType{GVR: gvr, Category: category}, listOptions{})
 }
 
-func (c *Client) convertToResource(obj *unstructured.Unstructured, gvr schema.GroupVersionResource) *Resource {
+func (c *Client) convertToResource(obj *unstructured.Unstructured, t DiscoveredType) *Resource {
+	gvr := t.GVR
+
 	// Extract basic information
 	name := obj.GetName()
 	namespace := obj.GetNamespace()
 	labels := obj.GetLabels()
 
-	// Determine provider from group
-	provider := extractProviderFromGroup(gvr.Group)
+	provider := resourceFamily(obj, t)
 
 	// Get status
 	status := "Unknown"
@@ -217,31 +259,64 @@ func (c *Client) convertToResource(obj *unstructured.Unstructured, gvr schema.Gr
 	// Get spec
 	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
 
-	return &Resource{
-		Name:      name,
-		Namespace: namespace,
-		Type:      gvr.Resource,
-		Provider:  provider,
-		Status:    status,
-		Age:       age,
-		Labels:    labels,
-		Spec:      spec,
-		Raw:       obj,
+	conditions := parseConditions(obj)
+	synced := ""
+	for _, condition := range conditions {
+		if condition.Type == ConditionSynced {
+			synced = condition.Status
+		}
+	}
+	switch {
+	case gvr.Group == packageGroup:
+		status = packageStatus(conditions, status)
+	case gvr.GroupResource() == xrdGroupResource:
+		status = definitionStatus(conditions, status)
+	case healthlessTypes[gvr.GroupResource()]:
+		status = StatusNone
 	}
-}
 
-func extractProviderFromGroup(group string) string {
-	if group == "pkg.crossplane.io" || group == "apiextensions.crossplane.io" {
-		return "crossplane"
+	var deletionTimestamp *time.Time
+	if ts := obj.GetDeletionTimestamp(); ts != nil {
+		t := ts.Time
+		deletionTimestamp = &t
 	}
 
-	// Extract provider from group name (e.g., "rds.aws.crossplane.io" -> "aws")
-	parts := strings.Split(group, ".")
-	if len(parts) >= 2 {
-		return parts[1]
+	return &Resource{
+		Cluster:    c.cluster,
+		Name:       name,
+		Namespace:  namespace,
+		Type:       gvr.Resource,
+		Kind:       obj.GetKind(),
+		APIVersion: gvr.GroupVersion().String(),
+		Category:   t.Category,
+		Provider:   provider,
+		Status:     status,
+		Age:        age,
+		Labels:     labels,
+		Spec:       spec,
+		Raw:        obj,
+
+		Synced:             synced,
+		Conditions:         conditions,
+		Generation:         obj.GetGeneration(),
+		ObservedGeneration: observedGeneration(obj),
+		CreatedAt:          obj.GetCreationTimestamp().Time,
+		DeletionTimestamp:  deletionTimestamp,
 	}
+}
 
-	return "unknown"
+// resourceFamily returns the provider family of managed resources and
+// provider packages. Claims, composites and Crossplane's own types belong
+// to no provider family.
+func resourceFamily(obj *unstructured.Unstructured, t DiscoveredType) string {
+	switch {
+	case t.Category == CategoryManaged:
+		return ProviderFamily(t.GVR.Group)
+	case t.GVR.Group == packageGroup && t.GVR.Resource == "providers":
+		ref, _, _ := unstructured.NestedString(obj.Object, "spec", "package")
+		return PackageFamily(ref)
+	}
+	return ""
 }
 
 func extractStatusFromConditions(conditions []interface{}) string {
@@ -265,12 +340,5 @@ func extractStatusFromConditions(conditions []interface{}) string {
 
 // GetProviders returns all installed Crossplane providers
 func (c *Client) GetProviders(ctx context.Context) ([]*Resource, error) {
-	gvr := schema.GroupVersionResource{Group: "pkg.crossplane.io", Version: "v1", Resource: "providers"}
-	return c.getResourcesOfType(ctx, gvr)
-}
-
-// GetCompositions returns all Crossplane compositions
-func (c *Client) GetCompositions(ctx context.Context) ([]*Resource, error) {
-	gvr := schema.GroupVersionResource{Group: "apiextensions.crossplane.io", Version: "v1", Resource: "compositions"}
-	return c.getResourcesOfType(ctx, gvr)
+	return c.getResourcesOfType(ctx, providersGVR, CategoryCore)
 }
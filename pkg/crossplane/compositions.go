@@ -0,0 +1,269 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	compositionsGVR         = schema.GroupVersionResource{Group: "apiextensions.crossplane.io", Version: "v1", Resource: "compositions"}
+	compositionRevisionsGVR = schema.GroupVersionResource{Group: "apiextensions.crossplane.io", Version: "v1", Resource: "compositionrevisions"}
+)
+
+// Labels Crossplane sets on CompositionRevisions
+const (
+	compositionNameLabel = "crossplane.io/composition-name"
+	compositionHashLabel = "crossplane.io/composition-hash"
+)
+
+// Composition update policies of XRs. Automatic XRs always use the latest
+// revision of their composition; Manual XRs stay on the revision named by
+// their compositionRevisionRef.
+const (
+	UpdatePolicyAutomatic = "Automatic"
+	UpdatePolicyManual    = "Manual"
+)
+
+// CompositionRevision is an immutable snapshot of a Composition
+type CompositionRevision struct {
+	Cluster     string `json:"cluster,omitempty"`
+	Name        string `json:"name"`
+	Composition string `json:"composition"`
+	Revision    int64  `json:"revision"`
+	Hash        string `json:"hash,omitempty"`
+	// CompositeType is the apiVersion and kind of the XRs it composes
+	CompositeType string `json:"compositeType,omitempty"`
+	// Latest is set on the highest revision of the composition
+	Latest    bool                       `json:"latest"`
+	CreatedAt time.Time                  `json:"createdAt"`
+	Raw       *unstructured.Unstructured `json:"-"`
+}
+
+// CompositeRevision maps an XR to the composition revision it uses
+type CompositeRevision struct {
+	Cluster     string    `json:"cluster,omitempty"`
+	Resource    ObjectRef `json:"resource"`
+	Composition string    `json:"composition,omitempty"`
+	// Revision is the name of the CompositionRevision in
+	// compositionRevisionRef, empty until Crossplane has selected one
+	Revision       string `json:"revision,omitempty"`
+	RevisionNumber int64  `json:"revisionNumber,omitempty"`
+	// LatestRevision is the highest revision of the composition
+	LatestRevision int64  `json:"latestRevision,omitempty"`
+	UpdatePolicy   string `json:"updatePolicy"`
+}
+
+// Outdated reports whether the XR uses an older revision than the latest
+func (r *CompositeRevision) Outdated() bool {
+	return r.RevisionNumber > 0 && r.RevisionNumber < r.LatestRevision
+}
+
+// RevisionDiff is the structural difference between two revisions of a
+// composition
+type RevisionDiff struct {
+	Composition string               `json:"composition"`
+	From        *CompositionRevision `json:"from"`
+	To          *CompositionRevision `json:"to"`
+	// LatestRevision is the highest revision of the composition
+	LatestRevision int64    `json:"latestRevision"`
+	Changes        []Change `json:"changes"`
+}
+
+// GetCompositions returns all Crossplane compositions
+func (c *Client) GetCompositions(ctx context.Context) ([]*Resource, error) {
+	return c.getResourcesOfType(ctx, compositionsGVR, CategoryCore)
+}
+
+// ListCompositionRevisions returns the revisions of a composition, or of
+// all compositions when composition is empty, ordered by composition and
+// revision number
+func (c *Client) ListCompositionRevisions(ctx context.Context, composition string) ([]*CompositionRevision, error) {
+	opts := metav1.ListOptions{}
+	if composition != "" {
+		opts.LabelSelector = compositionNameLabel + "=" + composition
+	}
+	list, err := c.dynamicClient.Resource(compositionRevisionsGVR).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list composition revisions: %w", err)
+	}
+
+	revisions := make([]*CompositionRevision, 0, len(list.Items))
+	latest := make(map[string]*CompositionRevision)
+	for i := range list.Items {
+		revision := c.newCompositionRevision(&list.Items[i])
+		revisions = append(revisions, revision)
+		if l := latest[revision.Composition]; l == nil || revision.Revision > l.Revision {
+			latest[revision.Composition] = revision
+		}
+	}
+	for _, revision := range latest {
+		revision.Latest = true
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		if revisions[i].Composition != revisions[j].Composition {
+			return revisions[i].Composition < revisions[j].Composition
+		}
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
+}
+
+func (c *Client) newCompositionRevision(obj *unstructured.Unstructured) *CompositionRevision {
+	revision := &CompositionRevision{
+		Cluster:     c.cluster,
+		Name:        obj.GetName(),
+		Composition: obj.GetLabels()[compositionNameLabel],
+		Hash:        obj.GetLabels()[compositionHashLabel],
+		CreatedAt:   obj.GetCreationTimestamp().Time,
+		Raw:         obj,
+	}
+	revision.Revision, _, _ = unstructured.NestedInt64(obj.Object, "spec", "revision")
+	if revision.Composition == "" {
+		// Revisions are owned by their composition
+		for _, ref := range obj.GetOwnerReferences() {
+			if ref.Kind == "Composition" {
+				revision.Composition = ref.Name
+			}
+		}
+	}
+	if ref, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "compositeTypeRef"); found {
+		revision.CompositeType = ref["apiVersion"] + " " + ref["kind"]
+	}
+	return revision
+}
+
+// ListCompositeRevisions maps every XR, or those using the given
+// composition, to the composition revision it is on and its update policy.
+// It also returns the composite types that could not be listed.
+func (c *Client) ListCompositeRevisions(ctx context.Context, composition string) ([]*CompositeRevision, []ListFailure, error) {
+	revisions, err := c.ListCompositionRevisions(ctx, composition)
+	if err != nil {
+		return nil, nil, err
+	}
+	byName := make(map[string]*CompositionRevision, len(revisions))
+	latest := make(map[string]int64)
+	for _, revision := range revisions {
+		byName[revision.Name] = revision
+		if revision.Latest {
+			latest[revision.Composition] = revision.Revision
+		}
+	}
+
+	discovered, err := c.DiscoverResourceTypes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var composites []DiscoveredType
+	for _, t := range discovered {
+		if t.Category == CategoryComposite {
+			composites = append(composites, t)
+		}
+	}
+	result, err := c.listTypes(ctx, composites, listOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mapped []*CompositeRevision
+	for _, resource := range result.Resources {
+		xr := newCompositeRevision(resource)
+		if revision, ok := byName[xr.Revision]; ok {
+			xr.RevisionNumber = revision.Revision
+			if xr.Composition == "" {
+				xr.Composition = revision.Composition
+			}
+		}
+		if composition != "" && xr.Composition != composition {
+			continue
+		}
+		xr.LatestRevision = latest[xr.Composition]
+		mapped = append(mapped, xr)
+	}
+
+	sort.Slice(mapped, func(i, j int) bool {
+		if mapped[i].Composition != mapped[j].Composition {
+			return mapped[i].Composition < mapped[j].Composition
+		}
+		return mapped[i].Resource.String() < mapped[j].Resource.String()
+	})
+	return mapped, result.Failures, nil
+}
+
+// newCompositeRevision reads the composition fields of an XR. Crossplane v1
+// keeps them in spec, Crossplane v2 in spec.crossplane.
+func newCompositeRevision(resource *Resource) *CompositeRevision {
+	xr := &CompositeRevision{
+		Cluster:      resource.Cluster,
+		Resource:     ObjectRef{APIVersion: resource.APIVersion, Kind: resource.Kind, Name: resource.Name, Namespace: resource.Namespace},
+		UpdatePolicy: UpdatePolicyAutomatic,
+	}
+	if resource.Raw == nil {
+		return xr
+	}
+
+	spec, _, _ := unstructured.NestedMap(resource.Raw.Object, "spec")
+	if crossplane, found, _ := unstructured.NestedMap(spec, "crossplane"); found {
+		spec = crossplane
+	}
+	xr.Composition, _, _ = unstructured.NestedString(spec, "compositionRef", "name")
+	xr.Revision, _, _ = unstructured.NestedString(spec, "compositionRevisionRef", "name")
+	if policy, _, _ := unstructured.NestedString(spec, "compositionUpdatePolicy"); policy != "" {
+		xr.UpdatePolicy = policy
+	}
+	return xr
+}
+
+// DiffCompositionRevisions compares two revisions of a composition. A zero
+// to selects the latest revision and a zero from the revision before to.
+func (c *Client) DiffCompositionRevisions(ctx context.Context, composition string, from, to int64) (*RevisionDiff, error) {
+	revisions, err := c.ListCompositionRevisions(ctx, composition)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("composition %s has no revisions", composition)
+	}
+
+	diff := &RevisionDiff{Composition: composition, LatestRevision: revisions[len(revisions)-1].Revision}
+	if to == 0 {
+		to = diff.LatestRevision
+	}
+	for _, revision := range revisions {
+		if revision.Revision == to {
+			diff.To = revision
+		}
+		if from == 0 && revision.Revision < to {
+			// Revisions are sorted, so this ends on the one right before to
+			diff.From = revision
+		} else if revision.Revision == from {
+			diff.From = revision
+		}
+	}
+	switch {
+	case diff.To == nil:
+		return nil, fmt.Errorf("composition %s has no revision %d", composition, to)
+	case diff.From == nil && from == 0:
+		return nil, fmt.Errorf("composition %s has no revision before %d", composition, to)
+	case diff.From == nil:
+		return nil, fmt.Errorf("composition %s has no revision %d", composition, from)
+	}
+
+	// The revision number always differs and is not part of the design
+	fromSpec := revisionSpec(diff.From)
+	toSpec := revisionSpec(diff.To)
+	diff.Changes = DiffValues("spec", fromSpec, toSpec)
+	return diff, nil
+}
+
+func revisionSpec(revision *CompositionRevision) map[string]interface{} {
+	spec, _, _ := unstructured.NestedMap(revision.Raw.Object, "spec")
+	delete(spec, "revision")
+	return spec
+}
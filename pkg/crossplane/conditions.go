@@ -0,0 +1,195 @@
+package crossplane
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Common Crossplane condition types. Packages (providers, configurations
+// and functions) report Installed and Healthy instead of Ready, and
+// CompositeResourceDefinitions report Established and Offered.
+const (
+	ConditionReady       = "Ready"
+	ConditionSynced      = "Synced"
+	ConditionInstalled   = "Installed"
+	ConditionHealthy     = "Healthy"
+	ConditionEstablished = "Established"
+	ConditionOffered     = "Offered"
+)
+
+// StatusNone is the status of types that report no health at all, such as
+// Compositions and EnvironmentConfigs. They are left out of health ratios
+// and never need attention unless they are being deleted.
+const StatusNone = "-"
+
+// xrdGroupResource identifies CompositeResourceDefinitions
+var xrdGroupResource = schema.GroupResource{Group: "apiextensions.crossplane.io", Resource: "compositeresourcedefinitions"}
+
+// healthlessTypes are the listed types that have no status conditions
+var healthlessTypes = map[schema.GroupResource]bool{
+	{Group: "apiextensions.crossplane.io", Resource: "compositions"}:       true,
+	{Group: "apiextensions.crossplane.io", Resource: "environmentconfigs"}: true,
+}
+
+// Condition is a status condition as reported by Crossplane, e.g.
+// Synced=False with reason ReconcileError
+type Condition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// IsTrue reports whether the condition status is "True"
+func (c Condition) IsTrue() bool {
+	return c.Status == "True"
+}
+
+// IsFalse reports whether the condition status is "False"
+func (c Condition) IsFalse() bool {
+	return c.Status == "False"
+}
+
+// GetCondition returns the condition of the given type, or nil if the
+// resource does not report it
+func (r *Resource) GetCondition(conditionType string) *Condition {
+	for i := range r.Conditions {
+		if r.Conditions[i].Type == conditionType {
+			return &r.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// FailingCondition returns the condition that explains why the resource is
+// unhealthy: Synced=False first, since a sync error usually keeps the
+// resource from becoming ready, then Ready=False. It returns nil for a
+// healthy resource.
+func (r *Resource) FailingCondition() *Condition {
+	if synced := r.GetCondition(ConditionSynced); synced != nil && synced.IsFalse() {
+		return synced
+	}
+	if ready := r.GetCondition(ConditionReady); ready != nil && ready.IsFalse() {
+		return ready
+	}
+	return nil
+}
+
+// packageStatus derives the summary status of a package, which reports
+// Installed and Healthy instead of Ready: Ready when both are True and Not
+// Ready when either is False. Otherwise status is returned unchanged.
+func packageStatus(conditions []Condition, status string) string {
+	var installed, healthy *Condition
+	for i := range conditions {
+		switch conditions[i].Type {
+		case ConditionReady:
+			return status
+		case ConditionInstalled:
+			installed = &conditions[i]
+		case ConditionHealthy:
+			healthy = &conditions[i]
+		}
+	}
+
+	switch {
+	case installed != nil && installed.IsFalse(), healthy != nil && healthy.IsFalse():
+		return "Not Ready"
+	case installed != nil && installed.IsTrue() && healthy != nil && healthy.IsTrue():
+		return "Ready"
+	}
+	return status
+}
+
+// definitionStatus derives the summary status of a
+// CompositeResourceDefinition, which reports Established and, when it
+// offers a claim, Offered instead of Ready: Ready when Established is True
+// and Offered is not False, Not Ready when either is False. Otherwise
+// status is returned unchanged.
+func definitionStatus(conditions []Condition, status string) string {
+	var established, offered *Condition
+	for i := range conditions {
+		switch conditions[i].Type {
+		case ConditionReady:
+			return status
+		case ConditionEstablished:
+			established = &conditions[i]
+		case ConditionOffered:
+			offered = &conditions[i]
+		}
+	}
+
+	switch {
+	case established != nil && established.IsFalse(), offered != nil && offered.IsFalse():
+		return "Not Ready"
+	case established != nil && established.IsTrue():
+		return "Ready"
+	}
+	return status
+}
+
+// IsDeleting reports whether the resource has been marked for deletion
+func (r *Resource) IsDeleting() bool {
+	return r.DeletionTimestamp != nil
+}
+
+// GenerationLag reports whether the latest spec change has not been
+// observed by the controller yet
+func (r *Resource) GenerationLag() bool {
+	return r.ObservedGeneration > 0 && r.ObservedGeneration < r.Generation
+}
+
+// parseConditions reads status.conditions from an object
+func parseConditions(obj *unstructured.Unstructured) []Condition {
+	raw, found, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if !found {
+		return nil
+	}
+
+	conditions := make([]Condition, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		condition := Condition{}
+		condition.Type, _, _ = unstructured.NestedString(m, "type")
+		condition.Status, _, _ = unstructured.NestedString(m, "status")
+		condition.Reason, _, _ = unstructured.NestedString(m, "reason")
+		condition.Message, _, _ = unstructured.NestedString(m, "message")
+		if ts, _, _ := unstructured.NestedString(m, "lastTransitionTime"); ts != "" {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				condition.LastTransitionTime = t
+			}
+		}
+		if condition.Type == "" {
+			continue
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions
+}
+
+// observedGeneration reads status.observedGeneration, falling back to the
+// observedGeneration of the Ready or Synced condition
+func observedGeneration(obj *unstructured.Unstructured) int64 {
+	if gen, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration"); found {
+		return gen
+	}
+
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if gen, found, _ := unstructured.NestedInt64(m, "observedGeneration"); found {
+			return gen
+		}
+	}
+	return 0
+}
@@ -0,0 +1,604 @@
+package crossplane
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Finalizers set by Crossplane and Kubernetes that keep a resource from
+// being deleted
+const (
+	// FinalizerManagedResource is removed by the provider once the
+	// external resource is gone
+	FinalizerManagedResource = "finalizer.managedresource.crossplane.io"
+	// FinalizerComposite is removed once the composed resources are gone
+	FinalizerComposite = "composite.apiextensions.crossplane.io"
+	// FinalizerClaim is removed once the claim's composite is gone
+	FinalizerClaim = "finalizer.apiextensions.crossplane.io"
+	// FinalizerForeground is set by the garbage collector for foreground
+	// deletion and removed once the owned objects are gone
+	FinalizerForeground = "foregroundDeletion"
+)
+
+// Reasons a terminating resource is blocked
+const (
+	BlockerExternalResource  = "ExternalResource"
+	BlockerComposedResources = "ComposedResources"
+	BlockerComposite         = "CompositeResource"
+	BlockerUsage             = "Usage"
+	BlockerUsingResource     = "UsingResource"
+	BlockerDependents        = "Dependents"
+	BlockerFinalizer         = "Finalizer"
+)
+
+// ExternalState is what the conditions of a terminating managed resource
+// say about its external resource
+type ExternalState string
+
+const (
+	// ExternalExists means the external resource still exists, e.g.
+	// because deleting it fails
+	ExternalExists ExternalState = "Exists"
+	// ExternalDeleting means the provider is deleting the external resource
+	ExternalDeleting ExternalState = "Deleting"
+	// ExternalOrphaned means the external resource is kept when the
+	// managed resource is deleted
+	ExternalOrphaned ExternalState = "Orphaned"
+	// ExternalUnknown means the provider cannot observe the external
+	// resource, e.g. because it cannot connect to the cloud API
+	ExternalUnknown ExternalState = "Unknown"
+)
+
+// DeletionBlocker is one reason a terminating resource is not deleted: a
+// finalizer nobody removes, or a Usage protecting a composed resource
+type DeletionBlocker struct {
+	Reason string `json:"reason"`
+	// Finalizer is the finalizer that is left; empty for Usage blockers
+	Finalizer string `json:"finalizer,omitempty"`
+	// WaitingFor are the objects that must be deleted first
+	WaitingFor []ObjectRef `json:"waitingFor,omitempty"`
+	// Usage and UsedBy are set for Usage blockers
+	Usage   *ObjectRef `json:"usage,omitempty"`
+	UsedBy  *ObjectRef `json:"usedBy,omitempty"`
+	Message string     `json:"message"`
+	// Removable reports whether Finalizer can be removed without leaving
+	// anything behind, e.g. because the objects it waits for are gone
+	Removable bool `json:"removable"`
+}
+
+// TerminatingResource is a resource marked for deletion that still exists
+type TerminatingResource struct {
+	Cluster    string    `json:"cluster,omitempty"`
+	Ref        ObjectRef `json:"resource"`
+	Category   Category  `json:"category"`
+	Since      time.Time `json:"since"`
+	Finalizers []string  `json:"finalizers"`
+	// DeletionPolicy, External and ExternalMessage are only set for
+	// managed resources
+	DeletionPolicy  string        `json:"deletionPolicy,omitempty"`
+	External        ExternalState `json:"external,omitempty"`
+	ExternalMessage string        `json:"externalMessage,omitempty"`
+	// Provider is the provider serving a managed resource and
+	// ProviderProblem explains why it is not healthy
+	Provider        string            `json:"provider,omitempty"`
+	ProviderProblem string            `json:"providerProblem,omitempty"`
+	Blockers        []DeletionBlocker `json:"blockers"`
+	// Cascading is set when the resource only waits for objects that are
+	// being deleted themselves; the cause is further down
+	Cascading bool `json:"cascading"`
+	// Blocks are the terminating resources waiting for this one
+	Blocks []ObjectRef `json:"blocks,omitempty"`
+
+	Resource *Resource `json:"-"`
+}
+
+// RemovableFinalizers returns the finalizers that can be removed without
+// leaving anything behind
+func (t *TerminatingResource) RemovableFinalizers() []string {
+	var finalizers []string
+	for _, blocker := range t.Blockers {
+		if blocker.Removable && blocker.Finalizer != "" {
+			finalizers = append(finalizers, blocker.Finalizer)
+		}
+	}
+	return finalizers
+}
+
+// DeletionReport lists the resources stuck in deletion and what blocks
+// each of them
+type DeletionReport struct {
+	Terminating []*TerminatingResource `json:"terminating"`
+	// Failures are the types that could not be listed; a blocker may be
+	// missed or a finalizer reported as removable too eagerly when there
+	// are any
+	Failures []ListFailure `json:"failures,omitempty"`
+}
+
+// usageInfo is a Usage or ClusterUsage and the objects it relates
+type usageInfo struct {
+	ref ObjectRef
+	of  objectRef
+	by  objectRef
+}
+
+// DeletionReport finds every resource marked for deletion and explains
+// which finalizer or Usage keeps it, what the conditions of a managed
+// resource say about its external resource and whether its provider is
+// healthy
+func (c *Client) DeletionReport(ctx context.Context) (*DeletionReport, error) {
+	result, err := c.ListAllResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &DeletionReport{Terminating: []*TerminatingResource{}, Failures: result.Failures}
+
+	providers, err := c.ListProviders(ctx)
+	if err != nil {
+		gvr := providersGVR
+		if providers != nil {
+			gvr = providerRevisionsGVR
+		}
+		failure := newListFailure(gvr, err)
+		failure.Cluster = c.cluster
+		report.Failures = append(report.Failures, failure)
+	}
+
+	report.Terminating = buildTerminating(result.Resources, providers, len(report.Failures) == 0)
+	return report, nil
+}
+
+// buildTerminating explains every terminating resource. Finalizers waiting
+// for objects that are gone are only reported removable when complete is
+// set, since a missing object may just not have been listed.
+func buildTerminating(resources []*Resource, providers []*PackageInfo, complete bool) []*TerminatingResource {
+	index := make(map[string]*Resource, len(resources))
+	usagesOf := make(map[string][]usageInfo)
+	for _, resource := range resources {
+		index[resourceKey(resource)] = resource
+		if usage, ok := parseUsage(resource); ok {
+			key := refKey(resource.Cluster, usage.of)
+			usagesOf[key] = append(usagesOf[key], usage)
+		}
+	}
+
+	// CRDs of the active provider revisions, to find the provider of a
+	// managed resource
+	owners := make(map[string]*PackageInfo)
+	for _, provider := range providers {
+		if active := provider.ActiveRevision(); active != nil {
+			for _, name := range active.crdNames {
+				owners[name] = provider
+			}
+		}
+	}
+
+	var terminating []*TerminatingResource
+	byKey := make(map[string]*TerminatingResource)
+	for _, resource := range resources {
+		if !resource.IsDeleting() {
+			continue
+		}
+		t := &TerminatingResource{
+			Cluster:  resource.Cluster,
+			Ref:      objectRefOf(resource),
+			Category: resource.Category,
+			Since:    *resource.DeletionTimestamp,
+			Resource: resource,
+		}
+		if resource.Raw != nil {
+			t.Finalizers = resource.Raw.GetFinalizers()
+		}
+		if resource.Category == CategoryManaged {
+			t.DeletionPolicy = deletionPolicy(resource)
+			t.External, t.ExternalMessage = externalState(resource, t.DeletionPolicy)
+			if provider, ok := owners[resource.Type+"."+resourceGroup(resource.APIVersion)]; ok {
+				t.Provider = provider.Name
+				if !provider.IsHealthy() {
+					t.ProviderProblem = fmt.Sprintf("Installed=%s, Healthy=%s", provider.Installed, provider.Healthy)
+					if provider.Reason != "" {
+						t.ProviderProblem += ", " + provider.Reason
+					}
+				}
+			}
+		}
+
+		for _, finalizer := range t.Finalizers {
+			t.Blockers = append(t.Blockers, finalizerBlocker(t, finalizer, index, providers != nil, complete))
+		}
+		t.Blockers = append(t.Blockers, usageBlockers(t, index, usagesOf)...)
+
+		terminating = append(terminating, t)
+		byKey[resourceKey(resource)] = t
+	}
+
+	for _, t := range terminating {
+		t.Cascading = isCascading(t, byKey)
+	}
+	linkBlocked(terminating, byKey)
+
+	sort.SliceStable(terminating, func(i, j int) bool {
+		if terminating[i].Cluster != terminating[j].Cluster {
+			return terminating[i].Cluster < terminating[j].Cluster
+		}
+		return terminating[i].Since.Before(terminating[j].Since)
+	})
+	return terminating
+}
+
+// finalizerBlocker explains who is expected to remove a finalizer and
+// whether it can be removed safely
+func finalizerBlocker(t *TerminatingResource, finalizer string, index map[string]*Resource, providersKnown, complete bool) DeletionBlocker {
+	resource := t.Resource
+	blocker := DeletionBlocker{Finalizer: finalizer}
+
+	switch {
+	case finalizer == FinalizerManagedResource:
+		blocker.Reason = BlockerExternalResource
+		blocker.Message, blocker.Removable = managedFinalizerMessage(t, providersKnown)
+
+	case finalizer == FinalizerComposite || finalizer == FinalizerClaim:
+		blocker.Reason = BlockerComposedResources
+		what := "composed resources"
+		if resource.Category == CategoryClaim {
+			blocker.Reason = BlockerComposite
+			what = "composite resource"
+		}
+		for _, ref := range childRefs(resource) {
+			if _, ok := index[refKey(resource.Cluster, ref)]; ok {
+				blocker.WaitingFor = append(blocker.WaitingFor, publicRef(ref))
+			}
+		}
+		switch {
+		case len(blocker.WaitingFor) > 0:
+			blocker.Message = fmt.Sprintf("Crossplane removes it once the %s are deleted: %s", what, joinRefs(blocker.WaitingFor))
+			if resource.Category == CategoryClaim {
+				blocker.Message = fmt.Sprintf("Crossplane removes it once the %s is deleted: %s", what, joinRefs(blocker.WaitingFor))
+			}
+		case complete:
+			blocker.Message = fmt.Sprintf("the %s no longer exist, but Crossplane has not removed the finalizer", what)
+			blocker.Removable = true
+		default:
+			blocker.Message = fmt.Sprintf("no %s were found, but some types could not be listed", what)
+		}
+
+	case strings.HasPrefix(finalizer, "usage.") && isUsage(resource):
+		blocker.Reason = BlockerUsingResource
+		if usage, ok := parseUsage(resource); ok {
+			if _, exists := index[refKey(resource.Cluster, usage.by)]; exists {
+				blocker.WaitingFor = []ObjectRef{publicRef(usage.by)}
+			}
+			switch {
+			case len(blocker.WaitingFor) > 0:
+				blocker.Message = fmt.Sprintf("Crossplane removes it once the using resource %s is deleted", publicRef(usage.by))
+			case complete:
+				blocker.Message = "the using resource no longer exists, but Crossplane has not removed the finalizer"
+				blocker.Removable = true
+			default:
+				blocker.Message = "the using resource was not found, but some types could not be listed"
+			}
+		}
+
+	case finalizer == FinalizerForeground:
+		blocker.Reason = BlockerDependents
+		blocker.Message = "the garbage collector removes it once the objects owned by the resource are deleted"
+
+	default:
+		blocker.Reason = BlockerFinalizer
+		blocker.Message = "set by another controller; Crossplane does not remove it"
+	}
+
+	return blocker
+}
+
+// managedFinalizerMessage explains the managed resource finalizer from the
+// external state and the health of the provider
+func managedFinalizerMessage(t *TerminatingResource, providersKnown bool) (string, bool) {
+	provider := "the provider"
+	if t.Provider != "" {
+		provider = t.Provider
+	}
+
+	var message string
+	removable := false
+	switch t.External {
+	case ExternalOrphaned:
+		message = fmt.Sprintf("%s removes it without deleting the external resource (%s)", provider, t.ExternalMessage)
+		removable = true
+	case ExternalDeleting:
+		message = fmt.Sprintf("%s removes it once the external resource is deleted, which is in progress", provider)
+	case ExternalExists:
+		if isDeleteFailure(t.ExternalMessage) {
+			message = fmt.Sprintf("%s removes it once the external resource is deleted, but deleting it fails: %s", provider, t.ExternalMessage)
+		} else {
+			message = fmt.Sprintf("%s removes it once the external resource is deleted; it still exists", provider)
+		}
+	default:
+		message = fmt.Sprintf("%s removes it once the external resource is deleted, but cannot observe it: %s", provider, t.ExternalMessage)
+	}
+
+	switch {
+	case t.ProviderProblem != "":
+		message += fmt.Sprintf("; %s is not healthy (%s), so nothing reconciles the resource", provider, t.ProviderProblem)
+	case t.Provider == "" && providersKnown:
+		message += fmt.Sprintf("; no installed provider serves %s, so nothing reconciles the resource", t.Ref.Kind)
+	}
+
+	return message, removable
+}
+
+// usageBlockers reports the composed resources of a terminating composite
+// that Crossplane cannot delete because a Usage marks them in use
+func usageBlockers(t *TerminatingResource, index map[string]*Resource, usagesOf map[string][]usageInfo) []DeletionBlocker {
+	if t.Category != CategoryComposite {
+		return nil
+	}
+
+	var blockers []DeletionBlocker
+	for _, ref := range childRefs(t.Resource) {
+		child, ok := index[refKey(t.Cluster, ref)]
+		if !ok || child.IsDeleting() {
+			continue
+		}
+		for _, usage := range usagesOf[refKey(t.Cluster, ref)] {
+			by := publicRef(usage.by)
+			message := fmt.Sprintf("composed resource %s cannot be deleted while %s marks it in use by %s", publicRef(ref), usage.ref, by)
+			if user, exists := index[refKey(t.Cluster, usage.by)]; !exists {
+				message += ", which no longer exists"
+			} else if user.IsDeleting() {
+				message += ", which is being deleted itself"
+			}
+			blockers = append(blockers, DeletionBlocker{
+				Reason:     BlockerUsage,
+				WaitingFor: []ObjectRef{publicRef(ref)},
+				Usage:      &usage.ref,
+				UsedBy:     &by,
+				Message:    message,
+			})
+		}
+	}
+	return blockers
+}
+
+// isCascading reports whether everything a terminating resource waits for
+// is being deleted itself, or is only kept by a Usage whose using resource
+// is being deleted
+func isCascading(t *TerminatingResource, terminating map[string]*TerminatingResource) bool {
+	if len(t.Blockers) == 0 {
+		return false
+	}
+
+	deleting := func(ref ObjectRef) bool {
+		_, ok := terminating[indexKey(t.Cluster, ref.APIVersion, ref.Kind, ref.Namespace, ref.Name)]
+		return ok
+	}
+	released := make(map[ObjectRef]bool)
+	for _, blocker := range t.Blockers {
+		if blocker.Reason == BlockerUsage && blocker.UsedBy != nil && deleting(*blocker.UsedBy) {
+			for _, ref := range blocker.WaitingFor {
+				released[ref] = true
+			}
+		}
+	}
+
+	for _, blocker := range t.Blockers {
+		switch blocker.Reason {
+		case BlockerComposedResources, BlockerComposite, BlockerUsingResource, BlockerUsage:
+		default:
+			return false
+		}
+		if blocker.Removable || len(blocker.WaitingFor) == 0 {
+			return false
+		}
+		for _, ref := range blocker.WaitingFor {
+			if !deleting(ref) && !released[ref] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// linkBlocked records on every terminating resource the terminating
+// resources that wait for it, directly or through other objects
+func linkBlocked(terminating []*TerminatingResource, byKey map[string]*TerminatingResource) {
+	waiters := make(map[string][]*TerminatingResource)
+	for _, t := range terminating {
+		for _, blocker := range t.Blockers {
+			refs := blocker.WaitingFor
+			if blocker.UsedBy != nil {
+				refs = append(slices.Clone(refs), *blocker.UsedBy)
+			}
+			for _, ref := range refs {
+				key := indexKey(t.Cluster, ref.APIVersion, ref.Kind, ref.Namespace, ref.Name)
+				waiters[key] = append(waiters[key], t)
+			}
+		}
+	}
+
+	for key, t := range byKey {
+		seen := map[*TerminatingResource]bool{t: true}
+		queue := []string{key}
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			for _, waiter := range waiters[next] {
+				if seen[waiter] {
+					continue
+				}
+				seen[waiter] = true
+				t.Blocks = append(t.Blocks, waiter.Ref)
+				queue = append(queue, resourceKey(waiter.Resource))
+			}
+		}
+	}
+}
+
+// parseUsage reads the objects a Usage or ClusterUsage relates. Objects of
+// a namespaced Usage live in its namespace unless the reference says
+// otherwise.
+func parseUsage(resource *Resource) (usageInfo, bool) {
+	if !isUsage(resource) || resource.Raw == nil {
+		return usageInfo{}, false
+	}
+
+	read := func(field string) (objectRef, bool) {
+		spec, _, _ := unstructured.NestedMap(resource.Raw.Object, "spec", field)
+		ref := objectRef{}
+		ref.APIVersion, _, _ = unstructured.NestedString(spec, "apiVersion")
+		ref.Kind, _, _ = unstructured.NestedString(spec, "kind")
+		ref.Name, _, _ = unstructured.NestedString(spec, "resourceRef", "name")
+		ref.Namespace, _, _ = unstructured.NestedString(spec, "resourceRef", "namespace")
+		if ref.Namespace == "" {
+			ref.Namespace = resource.Namespace
+		}
+		return ref, ref.Kind != "" && ref.Name != ""
+	}
+
+	usage := usageInfo{ref: objectRefOf(resource)}
+	var ok bool
+	if usage.of, ok = read("of"); !ok {
+		return usageInfo{}, false
+	}
+	// A Usage without "by" only protects the resource from deletion
+	usage.by, _ = read("by")
+	return usage, usage.by.Name != ""
+}
+
+func isUsage(resource *Resource) bool {
+	group := resourceGroup(resource.APIVersion)
+	return (resource.Kind == "Usage" || resource.Kind == "ClusterUsage") &&
+		(group == "apiextensions.crossplane.io" || group == "protection.crossplane.io")
+}
+
+// deletionPolicy returns Orphan when deleting the managed resource keeps
+// the external resource, through spec.deletionPolicy or management
+// policies without Delete, and Delete otherwise
+func deletionPolicy(resource *Resource) string {
+	if resource.Raw == nil {
+		return "Delete"
+	}
+	if policies, found, _ := unstructured.NestedStringSlice(resource.Raw.Object, "spec", "managementPolicies"); found && len(policies) > 0 {
+		if !slices.Contains(policies, "*") && !slices.Contains(policies, "Delete") {
+			return "Orphan"
+		}
+	}
+	if policy, _, _ := unstructured.NestedString(resource.Raw.Object, "spec", "deletionPolicy"); policy != "" {
+		return policy
+	}
+	return "Delete"
+}
+
+// externalState judges from the conditions of a terminating managed
+// resource whether its external resource still exists
+func externalState(resource *Resource, policy string) (ExternalState, string) {
+	if policy == "Orphan" {
+		if explicit, _, _ := unstructured.NestedString(resource.Raw.Object, "spec", "deletionPolicy"); explicit == "Orphan" {
+			return ExternalOrphaned, "deletionPolicy is Orphan"
+		}
+		return ExternalOrphaned, "managementPolicies do not include Delete"
+	}
+
+	if synced := resource.GetCondition(ConditionSynced); synced != nil && synced.IsFalse() {
+		if isDeleteFailure(synced.Message) {
+			return ExternalExists, synced.Message
+		}
+		return ExternalUnknown, conditionText(synced)
+	}
+
+	ready := resource.GetCondition(ConditionReady)
+	switch {
+	case ready == nil:
+		return ExternalUnknown, "the provider has not reported on the external resource"
+	case ready.Reason == "Deleting":
+		return ExternalDeleting, conditionText(ready)
+	default:
+		return ExternalExists, conditionText(ready)
+	}
+}
+
+func isDeleteFailure(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "delete failed") || strings.Contains(message, "cannot delete")
+}
+
+func conditionText(condition *Condition) string {
+	if condition.Message != "" {
+		return condition.Message
+	}
+	return fmt.Sprintf("%s=%s, %s", condition.Type, condition.Status, condition.Reason)
+}
+
+func objectRefOf(resource *Resource) ObjectRef {
+	return ObjectRef{APIVersion: resource.APIVersion, Kind: resource.Kind, Name: resource.Name, Namespace: resource.Namespace}
+}
+
+func publicRef(ref objectRef) ObjectRef {
+	return ObjectRef{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace}
+}
+
+func joinRefs(refs []ObjectRef) string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// RemoveFinalizer removes a finalizer from a resource and updates
+// resource.Raw to match. The patch only applies when the finalizers have
+// not changed since the resource was read, so a finalizer added or removed
+// in the meantime is not lost.
+func (c *Client) RemoveFinalizer(ctx context.Context, resource *Resource, finalizer string) error {
+	if c.source.ReadOnly() {
+		return fmt.Errorf("cannot remove finalizer from %s/%s in %s: %w", resource.Kind, resource.Name, c.cluster, ErrReadOnlySource)
+	}
+	if resource.Raw == nil {
+		return fmt.Errorf("%s/%s was not read from the cluster", resource.Kind, resource.Name)
+	}
+
+	current := resource.Raw.GetFinalizers()
+	remaining := slices.DeleteFunc(slices.Clone(current), func(f string) bool { return f == finalizer })
+	if len(remaining) == len(current) {
+		return fmt.Errorf("%s/%s has no finalizer %s", resource.Kind, resource.Name, finalizer)
+	}
+
+	gv, err := schema.ParseGroupVersion(resource.APIVersion)
+	if err != nil {
+		return fmt.Errorf("invalid apiVersion %q: %w", resource.APIVersion, err)
+	}
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": "/metadata/finalizers", "value": current},
+		{"op": "replace", "path": "/metadata/finalizers", "value": remaining},
+	})
+	if err != nil {
+		return err
+	}
+
+	ri := c.dynamicClient.Resource(gv.WithResource(resource.Type))
+	opts := metav1.PatchOptions{FieldManager: FieldManager}
+	if resource.Namespace != "" {
+		_, err = ri.Namespace(resource.Namespace).Patch(ctx, resource.Name, types.JSONPatchType, patch, opts)
+	} else {
+		_, err = ri.Patch(ctx, resource.Name, types.JSONPatchType, patch, opts)
+	}
+	switch {
+	case err == nil:
+		resource.Raw.SetFinalizers(remaining)
+		return nil
+	case apierrors.IsInvalid(err), apierrors.IsConflict(err):
+		return fmt.Errorf("the finalizers of %s/%s changed since they were read; check again: %w", resource.Kind, resource.Name, err)
+	default:
+		return fmt.Errorf("failed to remove finalizer from %s/%s: %w", resource.Kind, resource.Name, err)
+	}
+}
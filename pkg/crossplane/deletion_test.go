@@ -0,0 +1,275 @@
+package crossplane
+
+import (
+	"reflect"
+	"testing"
+)
+
+// terminatingManaged is a managed resource being deleted with the given
+// spec and status, written as YAML indented by two spaces
+func terminatingManaged(t *testing.T, spec, status string) *Resource {
+	t.Helper()
+	manifest := `apiVersion: rds.aws.upbound.io/v1beta1
+kind: Instance
+metadata:
+  name: orders-db
+  deletionTimestamp: "2026-01-01T00:00:00Z"
+  finalizers:
+  - finalizer.managedresource.crossplane.io
+spec:
+` + spec
+	if status != "" {
+		manifest += "status:\n" + status
+	}
+	return testResource(t, CategoryManaged, manifest)
+}
+
+func TestExternalState(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		status     string
+		wantPolicy string
+		want       ExternalState
+	}{
+		{
+			name:       "never observed",
+			wantPolicy: "Delete",
+			want:       ExternalUnknown,
+		},
+		{
+			name:       "deletion in progress",
+			status:     "  conditions:\n  - type: Ready\n    status: \"False\"\n    reason: Deleting\n",
+			wantPolicy: "Delete",
+			want:       ExternalDeleting,
+		},
+		{
+			name:       "still available",
+			status:     "  conditions:\n  - type: Ready\n    status: \"True\"\n    reason: Available\n",
+			wantPolicy: "Delete",
+			want:       ExternalExists,
+		},
+		{
+			name:       "deleting fails",
+			status:     "  conditions:\n  - type: Synced\n    status: \"False\"\n    reason: ReconcileError\n    message: \"delete failed: DBInstance has deletion protection\"\n",
+			wantPolicy: "Delete",
+			want:       ExternalExists,
+		},
+		{
+			name:       "cannot observe",
+			status:     "  conditions:\n  - type: Synced\n    status: \"False\"\n    reason: ReconcileError\n    message: \"cannot get credentials secret\"\n",
+			wantPolicy: "Delete",
+			want:       ExternalUnknown,
+		},
+		{
+			name:       "orphaned by deletion policy",
+			spec:       "  deletionPolicy: Orphan\n",
+			wantPolicy: "Orphan",
+			want:       ExternalOrphaned,
+		},
+		{
+			name:       "orphaned by management policies",
+			spec:       "  managementPolicies: [Observe, Create, Update]\n",
+			wantPolicy: "Orphan",
+			want:       ExternalOrphaned,
+		},
+		{
+			name:       "all management policies",
+			spec:       "  managementPolicies: [\"*\"]\n",
+			wantPolicy: "Delete",
+			want:       ExternalUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := terminatingManaged(t, tt.spec, tt.status)
+			policy := deletionPolicy(resource)
+			if policy != tt.wantPolicy {
+				t.Errorf("deletionPolicy = %s, want %s", policy, tt.wantPolicy)
+			}
+			if got, _ := externalState(resource, policy); got != tt.want {
+				t.Errorf("externalState = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+const (
+	deletingClaim = `apiVersion: database.example.org/v1alpha1
+kind: PostgreSQLInstance
+metadata:
+  name: orders-db
+  namespace: team-a
+  deletionTimestamp: "2026-01-01T00:00:00Z"
+  finalizers:
+  - finalizer.apiextensions.crossplane.io
+spec:
+  resourceRef:
+    apiVersion: database.example.org/v1alpha1
+    kind: XPostgreSQLInstance
+    name: orders-db-x7k2p
+`
+	deletingComposite = `apiVersion: database.example.org/v1alpha1
+kind: XPostgreSQLInstance
+metadata:
+  name: orders-db-x7k2p
+  deletionTimestamp: "2026-01-01T00:01:00Z"
+  finalizers:
+  - composite.apiextensions.crossplane.io
+spec:
+  resourceRefs:
+  - apiVersion: rds.aws.upbound.io/v1beta1
+    kind: Instance
+    name: orders-db-x7k2p-rds
+  - apiVersion: rds.aws.upbound.io/v1beta1
+    kind: SubnetGroup
+    name: orders-db-x7k2p-sng
+`
+	deletingInstance = `apiVersion: rds.aws.upbound.io/v1beta1
+kind: Instance
+metadata:
+  name: orders-db-x7k2p-rds
+  deletionTimestamp: "2026-01-01T00:02:00Z"
+  finalizers:
+  - finalizer.managedresource.crossplane.io
+status:
+  conditions:
+  - type: Synced
+    status: "False"
+    reason: ReconcileError
+    message: "delete failed: DBInstance has deletion protection"
+`
+	subnetGroup = `apiVersion: rds.aws.upbound.io/v1beta1
+kind: SubnetGroup
+metadata:
+  name: orders-db-x7k2p-sng
+`
+	// subnetGroupUsage keeps the subnet group while the instance uses it
+	subnetGroupUsage = `apiVersion: protection.crossplane.io/v1beta1
+kind: ClusterUsage
+metadata:
+  name: orders-db-sng-in-use
+spec:
+  of:
+    apiVersion: rds.aws.upbound.io/v1beta1
+    kind: SubnetGroup
+    resourceRef:
+      name: orders-db-x7k2p-sng
+  by:
+    apiVersion: rds.aws.upbound.io/v1beta1
+    kind: Instance
+    resourceRef:
+      name: orders-db-x7k2p-rds
+`
+)
+
+func TestBuildTerminating(t *testing.T) {
+	claim := testResource(t, CategoryClaim, deletingClaim)
+	composite := testResource(t, CategoryComposite, deletingComposite)
+	instance := testResource(t, CategoryManaged, deletingInstance)
+	subnets := testResource(t, CategoryManaged, subnetGroup)
+	usage := testResource(t, CategoryCore, subnetGroupUsage)
+
+	unhealthy := &PackageInfo{
+		Name:      "provider-aws-rds",
+		Installed: "True",
+		Healthy:   "False",
+		Reason:    "UnhealthyPackageRevision",
+		Revisions: []PackageRevision{{DesiredState: "Active", crdNames: []string{"instances.rds.aws.upbound.io"}}},
+	}
+
+	terminating := buildTerminating([]*Resource{claim, composite, instance, subnets, usage}, []*PackageInfo{unhealthy}, true)
+	if len(terminating) != 3 {
+		t.Fatalf("%d terminating resources, want the claim, composite and instance", len(terminating))
+	}
+	byName := make(map[string]*TerminatingResource)
+	for _, t := range terminating {
+		byName[t.Ref.Name] = t
+	}
+
+	// The instance is the cause: deleting the external resource fails and
+	// its provider is not healthy
+	rds := byName["orders-db-x7k2p-rds"]
+	if rds.Cascading || rds.External != ExternalExists || rds.Provider != "provider-aws-rds" || rds.ProviderProblem == "" {
+		t.Errorf("instance = cascading %v, external %s, provider %q (%q), want the cause with an unhealthy provider",
+			rds.Cascading, rds.External, rds.Provider, rds.ProviderProblem)
+	}
+	if len(rds.Blockers) != 1 || rds.Blockers[0].Reason != BlockerExternalResource || rds.Blockers[0].Removable {
+		t.Errorf("instance blockers = %+v, want the external resource, not removable", rds.Blockers)
+	}
+	var blocks []string
+	for _, ref := range rds.Blocks {
+		blocks = append(blocks, ref.Name)
+	}
+	if want := []string{"orders-db-x7k2p", "orders-db"}; !reflect.DeepEqual(blocks, want) {
+		t.Errorf("instance blocks %v, want %v", blocks, want)
+	}
+
+	// The composite waits for the instance and for the subnet group, which
+	// a Usage keeps until the instance is gone
+	xr := byName["orders-db-x7k2p"]
+	if !xr.Cascading {
+		t.Error("composite is not cascading, want it to only wait for the instance")
+	}
+	var reasons []string
+	for _, blocker := range xr.Blockers {
+		reasons = append(reasons, blocker.Reason)
+	}
+	if want := []string{BlockerComposedResources, BlockerUsage}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("composite blockers = %v, want %v", reasons, want)
+	}
+
+	if !byName["orders-db"].Cascading {
+		t.Error("claim is not cascading, want it to wait for the composite")
+	}
+}
+
+func TestBuildTerminatingRemovableFinalizers(t *testing.T) {
+	// The composite of the claim is gone, which only a complete listing
+	// proves. The finalizer of another controller is never removable.
+	claim := testResource(t, CategoryClaim, deletingClaim)
+	orphaned := terminatingManaged(t, "  deletionPolicy: Orphan\n", "")
+	foreign := testResource(t, CategoryComposite, `apiVersion: storage.example.org/v1alpha1
+kind: XObjectStore
+metadata:
+  name: receipts
+  deletionTimestamp: "2026-01-01T00:00:00Z"
+  finalizers:
+  - example.com/cleanup
+`)
+
+	tests := []struct {
+		name     string
+		complete bool
+		want     map[string][]string
+	}{
+		{
+			name:     "complete listing",
+			complete: true,
+			want: map[string][]string{
+				"PostgreSQLInstance": {FinalizerClaim},
+				"Instance":           {FinalizerManagedResource},
+				"XObjectStore":       nil,
+			},
+		},
+		{
+			name: "partial listing",
+			want: map[string][]string{
+				"PostgreSQLInstance": nil,
+				"Instance":           {FinalizerManagedResource},
+				"XObjectStore":       nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][]string)
+			for _, terminating := range buildTerminating([]*Resource{claim, orphaned, foreign}, nil, tt.complete) {
+				got[terminating.Ref.Kind] = terminating.RemovableFinalizers()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removable finalizers = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package crossplane
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangeType is the kind of a structural change
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a difference between two objects at a field path such as
+// spec.pipeline[step=patch].input.resources[name=bucket].base.kind
+type Change struct {
+	Path string      `json:"path"`
+	Type ChangeType  `json:"type"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// listKeys are the fields that identify the items of a list of objects.
+// Lists whose items all carry a unique value for one of them are compared
+// item by item rather than by position, so that inserting a pipeline step
+// does not show up as a change to every later step.
+var listKeys = []string{"step", "name"}
+
+// DiffValues compares two decoded YAML or JSON values and returns the
+// changes below path, ordered by path
+func DiffValues(path string, from, to interface{}) []Change {
+	var changes []Change
+	diffValues(path, from, to, &changes)
+	return changes
+}
+
+func diffValues(path string, from, to interface{}, changes *[]Change) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+
+	switch {
+	case from == nil:
+		*changes = append(*changes, Change{Path: path, Type: ChangeAdded, To: to})
+		return
+	case to == nil:
+		*changes = append(*changes, Change{Path: path, Type: ChangeRemoved, From: from})
+		return
+	}
+
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := make(map[string]bool)
+		for key := range fromMap {
+			keys[key] = true
+		}
+		for key := range toMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			diffValues(joinPath(path, key), fromMap[key], toMap[key], changes)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		diffLists(path, fromList, toList, changes)
+		return
+	}
+
+	*changes = append(*changes, Change{Path: path, Type: ChangeModified, From: from, To: to})
+}
+
+func diffLists(path string, from, to []interface{}, changes *[]Change) {
+	if key := commonListKey(from, to); key != "" {
+		fromItems := make(map[string]interface{}, len(from))
+		for _, item := range from {
+			fromItems[listItemKey(item, key)] = item
+		}
+		seen := make(map[string]bool, len(to))
+		for _, item := range to {
+			id := listItemKey(item, key)
+			seen[id] = true
+			diffValues(fmt.Sprintf("%s[%s=%s]", path, key, id), fromItems[id], item, changes)
+		}
+		for _, item := range from {
+			if id := listItemKey(item, key); !seen[id] {
+				diffValues(fmt.Sprintf("%s[%s=%s]", path, key, id), item, nil, changes)
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(from) || i < len(to); i++ {
+		var fromItem, toItem interface{}
+		if i < len(from) {
+			fromItem = from[i]
+		}
+		if i < len(to) {
+			toItem = to[i]
+		}
+		diffValues(fmt.Sprintf("%s[%d]", path, i), fromItem, toItem, changes)
+	}
+}
+
+// commonListKey returns the key that identifies every item of both lists,
+// or "" when the lists must be compared by position
+func commonListKey(lists ...[]interface{}) string {
+	for _, key := range listKeys {
+		unique := true
+		for _, list := range lists {
+			ids := make(map[string]bool, len(list))
+			for _, item := range list {
+				id := listItemKey(item, key)
+				if id == "" || ids[id] {
+					unique = false
+					break
+				}
+				ids[id] = true
+			}
+			if !unique {
+				break
+			}
+		}
+		if unique {
+			return key
+		}
+	}
+	return ""
+}
+
+func listItemKey(item interface{}, key string) string {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	id, _ := m[key].(string)
+	return id
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,274 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// Category classifies a Crossplane API type
+type Category string
+
+const (
+	// CategoryCore covers Crossplane's own API types (compositions, providers, ...)
+	CategoryCore Category = "core"
+	// CategoryManaged covers provider managed resources
+	CategoryManaged Category = "managed"
+	// CategoryComposite covers composite resources (XRs)
+	CategoryComposite Category = "composite"
+	// CategoryClaim covers composite resource claims
+	CategoryClaim Category = "claim"
+)
+
+// discoveryTTL is how long a discovery result is reused before the API
+// server is asked again
+const discoveryTTL = 5 * time.Minute
+
+// coreResourceTypes lists the Crossplane core types that are reported as
+// resources. Other core types (revisions, locks, ...) are implementation
+// details and are not listed.
+var coreResourceTypes = map[schema.GroupResource]bool{
+	{Group: "apiextensions.crossplane.io", Resource: "compositions"}:                 true,
+	{Group: "apiextensions.crossplane.io", Resource: "compositeresourcedefinitions"}: true,
+	{Group: "apiextensions.crossplane.io", Resource: "environmentconfigs"}:           true,
+	{Group: "apiextensions.crossplane.io", Resource: "usages"}:                       true,
+	{Group: "protection.crossplane.io", Resource: "usages"}:                          true,
+	{Group: "protection.crossplane.io", Resource: "clusterusages"}:                   true,
+	{Group: "pkg.crossplane.io", Resource: "providers"}:                              true,
+	{Group: "pkg.crossplane.io", Resource: "configurations"}:                         true,
+	{Group: "pkg.crossplane.io", Resource: "functions"}:                              true,
+}
+
+// crdGVR identifies CustomResourceDefinitions, used to find CRDs owned by
+// XRDs and ProviderRevisions
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// DiscoveredType is a Crossplane API type found through discovery
+type DiscoveredType struct {
+	GVR        schema.GroupVersionResource `json:"gvr"`
+	Kind       string                      `json:"kind"`
+	Namespaced bool                        `json:"namespaced"`
+	Category   Category                    `json:"category"`
+}
+
+// DiscoverResourceTypes returns every Crossplane managed resource, composite,
+// claim and core type served by the cluster, at its preferred version. The
+// result is cached for a few minutes.
+func (c *Client) DiscoverResourceTypes(ctx context.Context) ([]DiscoveredType, error) {
+	c.discoveryMu.Lock()
+	defer c.discoveryMu.Unlock()
+
+	if c.discovered != nil && time.Since(c.discoveredAt) < discoveryTTL {
+		return c.discovered, nil
+	}
+
+	types, err := c.discoverResourceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.discovered = types
+	c.discoveredAt = time.Now()
+	return types, nil
+}
+
+// InvalidateDiscovery drops the cached discovery result so that the next
+// call sees newly installed providers and XRDs
+func (c *Client) InvalidateDiscovery() {
+	c.discoveryMu.Lock()
+	defer c.discoveryMu.Unlock()
+
+	c.discovered = nil
+	if c.cachedDiscovery != nil {
+		c.cachedDiscovery.Invalidate()
+	}
+	if c.restMapper != nil {
+		c.restMapper.Reset()
+	}
+}
+
+func (c *Client) discoverResourceTypes(ctx context.Context) ([]DiscoveredType, error) {
+	lists, err := c.cachedDiscovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover API resources: %w", err)
+	}
+	// A failed group (e.g. an unavailable aggregated API) only hides that
+	// group; carry on with what was discovered.
+
+	owners := c.crdOwners(ctx)
+
+	var types []DiscoveredType
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, apiResource := range list.APIResources {
+			// Skip subresources such as status and scale
+			if strings.Contains(apiResource.Name, "/") {
+				continue
+			}
+			if !hasVerb(apiResource.Verbs, "list") {
+				continue
+			}
+
+			gr := schema.GroupResource{Group: gv.Group, Resource: apiResource.Name}
+			category, ok := classify(gr, apiResource, owners[gr])
+			if !ok {
+				continue
+			}
+
+			types = append(types, DiscoveredType{
+				GVR:        gv.WithResource(apiResource.Name),
+				Kind:       apiResource.Kind,
+				Namespaced: apiResource.Namespaced,
+				Category:   category,
+			})
+		}
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Category != types[j].Category {
+			return categoryOrder(types[i].Category) < categoryOrder(types[j].Category)
+		}
+		return types[i].GVR.String() < types[j].GVR.String()
+	})
+
+	return types, nil
+}
+
+// crdOwner is the Crossplane object that owns a CRD and the categories the
+// CRD declares
+type crdOwner struct {
+	// Kind is CompositeResourceDefinition or ProviderRevision
+	Kind string
+	// Version is the API version of the owner reference, e.g. v2 for the
+	// XRDs of Crossplane v2
+	Version    string
+	Categories []string
+}
+
+// crdOwners maps each CRD's group/resource to the Crossplane object that
+// owns it (CompositeResourceDefinition or ProviderRevision). Reading CRDs
+// is best effort; without permission we rely on discovery categories.
+func (c *Client) crdOwners(ctx context.Context) map[schema.GroupResource]crdOwner {
+	owners := make(map[schema.GroupResource]crdOwner)
+
+	list, err := c.dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return owners
+	}
+
+	for _, crd := range list.Items {
+		group, _, _ := unstructured.NestedString(crd.Object, "spec", "group")
+		plural, _, _ := unstructured.NestedString(crd.Object, "spec", "names", "plural")
+		if group == "" || plural == "" {
+			continue
+		}
+		categories, _, _ := unstructured.NestedStringSlice(crd.Object, "spec", "names", "categories")
+
+		for _, ref := range crd.GetOwnerReferences() {
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err != nil {
+				continue
+			}
+			if gv.Group == "apiextensions.crossplane.io" || gv.Group == "pkg.crossplane.io" {
+				owners[schema.GroupResource{Group: group, Resource: plural}] = crdOwner{
+					Kind:       ref.Kind,
+					Version:    gv.Version,
+					Categories: categories,
+				}
+				break
+			}
+		}
+	}
+
+	return owners
+}
+
+// classify decides whether an API resource is a Crossplane type and which
+// category it belongs to. Discovery does not always report categories, so
+// the categories declared by the owned CRD are used as a fallback.
+func classify(gr schema.GroupResource, apiResource metav1.APIResource, owner crdOwner) (Category, bool) {
+	if coreResourceTypes[gr] {
+		return CategoryCore, true
+	}
+
+	categories := apiResource.Categories
+	if len(categories) == 0 {
+		categories = owner.Categories
+	}
+
+	switch {
+	case hasCategory(categories, "claim"):
+		return CategoryClaim, true
+	case hasCategory(categories, "composite"):
+		return CategoryComposite, true
+	case hasCategory(categories, "managed"):
+		return CategoryManaged, true
+	}
+
+	switch owner.Kind {
+	case "CompositeResourceDefinition":
+		// Crossplane v1 XRs are cluster scoped and their claims namespaced.
+		// Crossplane v2 XRs may be namespaced themselves and have no claims.
+		if apiResource.Namespaced && owner.Version == "v1" {
+			return CategoryClaim, true
+		}
+		return CategoryComposite, true
+	case "ProviderRevision":
+		// Providers also install their configuration types; those are not
+		// managed resources.
+		if isProviderConfigKind(apiResource.Kind) {
+			return "", false
+		}
+		return CategoryManaged, true
+	}
+
+	return "", false
+}
+
+func isProviderConfigKind(kind string) bool {
+	return strings.HasSuffix(kind, "ProviderConfig") ||
+		strings.HasSuffix(kind, "ProviderConfigUsage") ||
+		strings.HasSuffix(kind, "StoreConfig")
+}
+
+func categoryOrder(category Category) int {
+	switch category {
+	case CategoryCore:
+		return 0
+	case CategoryClaim:
+		return 1
+	case CategoryComposite:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func hasVerb(verbs metav1.Verbs, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCategory(categories []string, category string) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
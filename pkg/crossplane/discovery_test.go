@@ -0,0 +1,117 @@
+package crossplane
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassify(t *testing.T) {
+	xrd := func(version string, categories ...string) crdOwner {
+		return crdOwner{Kind: "CompositeResourceDefinition", Version: version, Categories: categories}
+	}
+	providerRevision := crdOwner{Kind: "ProviderRevision", Version: "v1"}
+
+	tests := []struct {
+		name       string
+		group      string
+		resource   metav1.APIResource
+		owner      crdOwner
+		want       Category
+		wantListed bool
+	}{
+		{
+			name:       "core type",
+			group:      "pkg.crossplane.io",
+			resource:   metav1.APIResource{Name: "providers", Kind: "Provider"},
+			want:       CategoryCore,
+			wantListed: true,
+		},
+		{
+			name:     "core implementation detail",
+			group:    "pkg.crossplane.io",
+			resource: metav1.APIResource{Name: "providerrevisions", Kind: "ProviderRevision"},
+		},
+		{
+			name:       "managed by discovery category",
+			group:      "s3.aws.upbound.io",
+			resource:   metav1.APIResource{Name: "buckets", Kind: "Bucket", Categories: []string{"crossplane", "managed", "aws"}},
+			want:       CategoryManaged,
+			wantListed: true,
+		},
+		{
+			name:       "claim by discovery category",
+			group:      "database.example.org",
+			resource:   metav1.APIResource{Name: "postgresqlinstances", Kind: "PostgreSQLInstance", Namespaced: true, Categories: []string{"crossplane", "claim"}},
+			want:       CategoryClaim,
+			wantListed: true,
+		},
+		{
+			name:       "composite by CRD category",
+			group:      "database.example.org",
+			resource:   metav1.APIResource{Name: "xpostgresqlinstances", Kind: "XPostgreSQLInstance"},
+			owner:      xrd("v1", "crossplane", "composite"),
+			want:       CategoryComposite,
+			wantListed: true,
+		},
+		{
+			name:       "Crossplane v1 claim by XRD ownership",
+			group:      "database.example.org",
+			resource:   metav1.APIResource{Name: "postgresqlinstances", Kind: "PostgreSQLInstance", Namespaced: true},
+			owner:      xrd("v1"),
+			want:       CategoryClaim,
+			wantListed: true,
+		},
+		{
+			name:       "Crossplane v1 composite by XRD ownership",
+			group:      "database.example.org",
+			resource:   metav1.APIResource{Name: "xpostgresqlinstances", Kind: "XPostgreSQLInstance"},
+			owner:      xrd("v1"),
+			want:       CategoryComposite,
+			wantListed: true,
+		},
+		{
+			name:       "Crossplane v2 namespaced composite",
+			group:      "storage.example.org",
+			resource:   metav1.APIResource{Name: "xobjectstores", Kind: "XObjectStore", Namespaced: true},
+			owner:      xrd("v2"),
+			want:       CategoryComposite,
+			wantListed: true,
+		},
+		{
+			name:       "managed by provider ownership",
+			group:      "rds.aws.upbound.io",
+			resource:   metav1.APIResource{Name: "instances", Kind: "Instance"},
+			owner:      providerRevision,
+			want:       CategoryManaged,
+			wantListed: true,
+		},
+		{
+			name:     "ProviderConfig",
+			group:    "aws.upbound.io",
+			resource: metav1.APIResource{Name: "providerconfigs", Kind: "ProviderConfig"},
+			owner:    providerRevision,
+		},
+		{
+			name:     "ProviderConfigUsage",
+			group:    "aws.upbound.io",
+			resource: metav1.APIResource{Name: "providerconfigusages", Kind: "ProviderConfigUsage"},
+			owner:    providerRevision,
+		},
+		{
+			name:     "not a Crossplane type",
+			group:    "apps",
+			resource: metav1.APIResource{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gr := schema.GroupResource{Group: tt.group, Resource: tt.resource.Name}
+			got, listed := classify(gr, tt.resource, tt.owner)
+			if got != tt.want || listed != tt.wantListed {
+				t.Errorf("classify = %q, %v, want %q, %v", got, listed, tt.want, tt.wantListed)
+			}
+		})
+	}
+}
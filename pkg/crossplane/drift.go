@@ -0,0 +1,374 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DriftState explains why a managed resource's observed state differs from
+// its desired state
+type DriftState string
+
+const (
+	// DriftOutOfBand means the provider reports the resource as synced
+	// although the external resource differs, e.g. after an edit in the
+	// cloud console; the provider reverts it on its next poll, and drift
+	// that persists means another system fights Crossplane
+	DriftOutOfBand DriftState = "OutOfBand"
+	// DriftNotReconciled means the provider fails to update the external
+	// resource
+	DriftNotReconciled DriftState = "NotReconciled"
+	// DriftPending means a spec change has not been observed by the
+	// provider yet
+	DriftPending DriftState = "Pending"
+	// DriftIgnored means the management policies do not allow updates, so
+	// the provider leaves the difference alone
+	DriftIgnored DriftState = "Ignored"
+)
+
+// FieldDrift is a field of spec.forProvider whose value differs from the
+// one observed in status.atProvider. Path is relative to forProvider.
+type FieldDrift struct {
+	Path     string      `json:"path"`
+	Desired  interface{} `json:"desired,omitempty"`
+	Observed interface{} `json:"observed,omitempty"`
+}
+
+// ResourceDrift is a managed resource whose observed state differs from
+// its desired state
+type ResourceDrift struct {
+	Cluster  string       `json:"cluster,omitempty"`
+	Ref      ObjectRef    `json:"resource"`
+	Provider string       `json:"provider"`
+	State    DriftState   `json:"state"`
+	Message  string       `json:"message,omitempty"`
+	Fields   []FieldDrift `json:"fields"`
+
+	Resource *Resource `json:"-"`
+}
+
+// DriftReport lists the managed resources that drifted from their spec
+type DriftReport struct {
+	Drifted []*ResourceDrift `json:"drifted"`
+	// Checked is the number of managed resources compared and Unobserved
+	// the number without status.atProvider, which cannot be compared
+	Checked    int `json:"checked"`
+	Unobserved int `json:"unobserved"`
+}
+
+// versionFields are compared by prefix, since providers report the full
+// version ("16.3") of a requested major version ("16")
+var versionFields = map[string]bool{
+	"engineVersion":     true,
+	"version":           true,
+	"kubernetesVersion": true,
+	"minVersion":        true,
+}
+
+// stringMapFields hold free-form keys. A key missing from the observed map
+// was removed out of band, while a missing top-level field is usually just
+// not reported by the provider.
+var stringMapFields = map[string]bool{
+	"tags":           true,
+	"labels":         true,
+	"userLabels":     true,
+	"resourceLabels": true,
+}
+
+// unobservableFields are arguments that only steer the provider, such as
+// what to do on deletion, and have no counterpart in the cloud
+var unobservableFields = map[string]bool{
+	"region":                   true,
+	"applyImmediately":         true,
+	"autoGeneratePassword":     true,
+	"allowMajorVersionUpgrade": true,
+	"deleteAutomatedBackups":   true,
+	"finalSnapshotIdentifier":  true,
+	"forceDestroy":             true,
+	"forceDelete":              true,
+	"forceDetachPolicies":      true,
+	"skipFinalSnapshot":        true,
+}
+
+// DetectDrift compares spec.forProvider with status.atProvider. Only the
+// fields set in forProvider are compared; fields only the cloud reports,
+// references and selectors, arguments without a cloud counterpart and
+// spec.initProvider, which only applies on creation, are left out. Numbers,
+// booleans and JSON documents are compared by value, lists of scalars as
+// sets and versions by prefix. It returns false when the resource has not
+// been observed yet.
+func DetectDrift(resource *Resource) ([]FieldDrift, bool) {
+	if resource.Raw == nil {
+		return nil, false
+	}
+	desired, _, _ := unstructured.NestedMap(resource.Raw.Object, "spec", "forProvider")
+	observed, _, _ := unstructured.NestedMap(resource.Raw.Object, "status", "atProvider")
+	if len(desired) == 0 || len(observed) == 0 {
+		return nil, false
+	}
+
+	var fields []FieldDrift
+	diffForProvider("", desired, observed, &fields)
+	return fields, true
+}
+
+func diffForProvider(path string, desired, observed interface{}, fields *[]FieldDrift) {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		o, ok := observed.(map[string]interface{})
+		if !ok {
+			if !isEmptyValue(d) || !isEmptyValue(observed) {
+				*fields = append(*fields, FieldDrift{Path: path, Desired: desired, Observed: observed})
+			}
+			return
+		}
+		freeForm := stringMapFields[lastPathSegment(path)]
+		keys := make([]string, 0, len(d))
+		for key := range d {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !freeForm && ignoredDriftField(key) {
+				continue
+			}
+			value, found := o[key]
+			if !found {
+				if freeForm {
+					*fields = append(*fields, FieldDrift{Path: joinPath(path, key), Desired: d[key]})
+				}
+				continue
+			}
+			diffForProvider(joinPath(path, key), d[key], value, fields)
+		}
+
+	case []interface{}:
+		o, ok := observed.([]interface{})
+		switch {
+		case !ok:
+			if !isEmptyValue(d) || !isEmptyValue(observed) {
+				*fields = append(*fields, FieldDrift{Path: path, Desired: desired, Observed: observed})
+			}
+		case allScalars(d) && allScalars(o):
+			if !sameScalarSet(d, o) {
+				*fields = append(*fields, FieldDrift{Path: path, Desired: desired, Observed: observed})
+			}
+		case len(d) != len(o):
+			*fields = append(*fields, FieldDrift{Path: path, Desired: desired, Observed: observed})
+		default:
+			for i := range d {
+				diffForProvider(fmt.Sprintf("%s[%d]", path, i), d[i], o[i], fields)
+			}
+		}
+
+	default:
+		if !equalScalars(lastPathSegment(path), desired, observed) {
+			*fields = append(*fields, FieldDrift{Path: path, Desired: desired, Observed: observed})
+		}
+	}
+}
+
+// ignoredDriftField reports whether a forProvider field has no observed
+// counterpart: references and selectors resolve to other fields, secret
+// references are write-only
+func ignoredDriftField(key string) bool {
+	return unobservableFields[key] ||
+		strings.HasSuffix(key, "Ref") || strings.HasSuffix(key, "Refs") || strings.HasSuffix(key, "Selector")
+}
+
+// equalScalars compares two values after normalizing the representations
+// providers use for the same value
+func equalScalars(field string, desired, observed interface{}) bool {
+	if reflect.DeepEqual(desired, observed) || (isEmptyValue(desired) && isEmptyValue(observed)) {
+		return true
+	}
+
+	a, aIsString := desired.(string)
+	b, bIsString := observed.(string)
+	if aIsString && bIsString && versionFields[field] && strings.HasPrefix(b, a+".") {
+		return true
+	}
+
+	if a, ok := numericValue(desired); ok {
+		if b, ok := numericValue(observed); ok {
+			return a == b
+		}
+	}
+	if a, ok := boolValue(desired); ok {
+		if b, ok := boolValue(observed); ok {
+			return a == b
+		}
+	}
+
+	if !aIsString || !bIsString {
+		return false
+	}
+	return equalJSONDocuments(a, b)
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func boolValue(v interface{}) (bool, bool) {
+	switch b := v.(type) {
+	case bool:
+		return b, true
+	case string:
+		parsed, err := strconv.ParseBool(b)
+		return parsed, err == nil
+	}
+	return false, false
+}
+
+// equalJSONDocuments compares strings holding JSON objects or arrays, such
+// as IAM policies, which the cloud returns reformatted
+func equalJSONDocuments(a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if !strings.HasPrefix(a, "{") && !strings.HasPrefix(a, "[") {
+		return false
+	}
+	var decodedA, decodedB interface{}
+	if json.Unmarshal([]byte(a), &decodedA) != nil || json.Unmarshal([]byte(b), &decodedB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(decodedA, decodedB)
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case []interface{}:
+		return len(value) == 0
+	case map[string]interface{}:
+		return len(value) == 0
+	}
+	return false
+}
+
+func allScalars(list []interface{}) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+// sameScalarSet compares lists of scalars ignoring order, since providers
+// return sets such as subnet IDs in their own order
+func sameScalarSet(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	normalize := func(list []interface{}) []string {
+		values := make([]string, len(list))
+		for i, item := range list {
+			values[i] = fmt.Sprint(item)
+		}
+		sort.Strings(values)
+		return values
+	}
+	return slices.Equal(normalize(a), normalize(b))
+}
+
+func lastPathSegment(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+	if i := strings.Index(path, "["); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// driftState classifies the drift of a managed resource from its
+// management policies, generation and Synced condition
+func driftState(resource *Resource) (DriftState, string) {
+	if resource.Raw != nil {
+		policies, found, _ := unstructured.NestedStringSlice(resource.Raw.Object, "spec", "managementPolicies")
+		if found && len(policies) > 0 && !slices.Contains(policies, "*") && !slices.Contains(policies, "Update") {
+			return DriftIgnored, fmt.Sprintf("managementPolicies %v do not include Update", policies)
+		}
+	}
+	if resource.GenerationLag() {
+		return DriftPending, fmt.Sprintf("generation %d has not been observed yet (observed %d)", resource.Generation, resource.ObservedGeneration)
+	}
+	if synced := resource.GetCondition(ConditionSynced); synced != nil && synced.IsFalse() {
+		return DriftNotReconciled, conditionText(synced)
+	}
+	return DriftOutOfBand, ""
+}
+
+// AttachDrift records the drift of every managed resource on its Drift
+// field, so that it becomes part of the AI context
+func AttachDrift(resources []*Resource) {
+	for _, resource := range resources {
+		if resource.Category != CategoryManaged {
+			continue
+		}
+		resource.Drift, _ = DetectDrift(resource)
+	}
+}
+
+// BuildDriftReport compares desired and observed state of the managed
+// resources among resources. Resources being deleted are left out.
+func BuildDriftReport(resources []*Resource) *DriftReport {
+	report := &DriftReport{Drifted: []*ResourceDrift{}}
+
+	for _, resource := range resources {
+		if resource.Category != CategoryManaged || resource.IsDeleting() {
+			continue
+		}
+		fields, observed := DetectDrift(resource)
+		if !observed {
+			report.Unobserved++
+			continue
+		}
+		report.Checked++
+		if len(fields) == 0 {
+			continue
+		}
+
+		state, message := driftState(resource)
+		report.Drifted = append(report.Drifted, &ResourceDrift{
+			Cluster:  resource.Cluster,
+			Ref:      objectRefOf(resource),
+			Provider: resource.Provider,
+			State:    state,
+			Message:  message,
+			Fields:   fields,
+			Resource: resource,
+		})
+	}
+
+	sort.SliceStable(report.Drifted, func(i, j int) bool {
+		if report.Drifted[i].Cluster != report.Drifted[j].Cluster {
+			return report.Drifted[i].Cluster < report.Drifted[j].Cluster
+		}
+		return report.Drifted[i].Ref.String() < report.Drifted[j].Ref.String()
+	})
+	return report
+}
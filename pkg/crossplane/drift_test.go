@@ -0,0 +1,151 @@
+package crossplane
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// driftResource is a managed resource with the given spec.forProvider and
+// status.atProvider, written as YAML mappings indented by four spaces
+func driftResource(t *testing.T, forProvider, atProvider string) *Resource {
+	t.Helper()
+	manifest := "apiVersion: rds.aws.upbound.io/v1beta1\nkind: Instance\nmetadata:\n  name: orders-db\nspec:\n  forProvider:\n" + forProvider
+	if atProvider != "" {
+		manifest += "status:\n  atProvider:\n" + atProvider
+	}
+	return testResource(t, CategoryManaged, manifest)
+}
+
+func TestDetectDrift(t *testing.T) {
+	tests := []struct {
+		name        string
+		forProvider string
+		atProvider  string
+		want        []string
+	}{
+		{
+			name:        "in sync",
+			forProvider: "    instanceClass: db.t3.micro\n    allocatedStorage: 20\n",
+			atProvider:  "    instanceClass: db.t3.micro\n    allocatedStorage: 20\n    arn: arn:aws:rds:us-east-1:123:db:orders\n",
+		},
+		{
+			name:        "changed field",
+			forProvider: "    instanceClass: db.t3.micro\n",
+			atProvider:  "    instanceClass: db.t3.large\n",
+			want:        []string{"instanceClass"},
+		},
+		{
+			name:        "numbers and booleans as strings",
+			forProvider: "    allocatedStorage: 20\n    multiAz: true\n",
+			atProvider:  "    allocatedStorage: \"20\"\n    multiAz: \"true\"\n",
+		},
+		{
+			name:        "version prefix",
+			forProvider: "    engineVersion: \"16\"\n",
+			atProvider:  "    engineVersion: \"16.3\"\n",
+		},
+		{
+			name:        "other major version",
+			forProvider: "    engineVersion: \"16\"\n",
+			atProvider:  "    engineVersion: \"15.7\"\n",
+			want:        []string{"engineVersion"},
+		},
+		{
+			name:        "sets in another order",
+			forProvider: "    subnetIds: [subnet-a, subnet-b]\n",
+			atProvider:  "    subnetIds: [subnet-b, subnet-a]\n",
+		},
+		{
+			name:        "reformatted JSON document",
+			forProvider: "    policy: '{\"Version\": \"2012-10-17\", \"Statement\": []}'\n",
+			atProvider:  "    policy: '{\"Statement\":[],\"Version\":\"2012-10-17\"}'\n",
+		},
+		{
+			name:        "references, selectors and unobservable arguments",
+			forProvider: "    region: us-east-1\n    skipFinalSnapshot: true\n    dbSubnetGroupNameRef:\n      name: orders\n    vpcSecurityGroupIdSelector:\n      matchLabels:\n        app: orders\n    instanceClass: db.t3.micro\n",
+			atProvider:  "    instanceClass: db.t3.micro\n",
+		},
+		{
+			name:        "field the provider does not report",
+			forProvider: "    instanceClass: db.t3.micro\n    storageType: gp3\n",
+			atProvider:  "    instanceClass: db.t3.micro\n",
+		},
+		{
+			name:        "tag removed out of band",
+			forProvider: "    tags:\n      team: payments\n      env: prod\n",
+			atProvider:  "    tags:\n      team: payments\n",
+			want:        []string{"tags.env"},
+		},
+		{
+			name:        "nested list",
+			forProvider: "    ingress:\n    - fromPort: 5432\n      cidrBlocks: [10.0.0.0/16]\n",
+			atProvider:  "    ingress:\n    - fromPort: 5432\n      cidrBlocks: [0.0.0.0/0]\n",
+			want:        []string{"ingress[0].cidrBlocks"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, observed := DetectDrift(driftResource(t, tt.forProvider, tt.atProvider))
+			if !observed {
+				t.Fatal("DetectDrift reports the resource as unobserved")
+			}
+			var paths []string
+			for _, field := range fields {
+				paths = append(paths, field.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("drifted fields = %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDriftReport(t *testing.T) {
+	const drifted = "    instanceClass: db.t3.micro\n"
+	const observed = "    instanceClass: db.t3.large\n"
+
+	outOfBand := driftResource(t, drifted, observed)
+	outOfBand.Name = "out-of-band"
+
+	notReconciled := driftResource(t, drifted, observed)
+	notReconciled.Name = "not-reconciled"
+	notReconciled.Conditions = []Condition{{Type: ConditionSynced, Status: "False", Reason: "ReconcileError", Message: "api error"}}
+
+	pending := driftResource(t, drifted, observed)
+	pending.Name = "pending"
+	pending.Generation, pending.ObservedGeneration = 3, 2
+
+	ignored := driftResource(t, drifted, observed)
+	ignored.Name = "ignored"
+	ignored.Raw.Object["spec"].(map[string]interface{})["managementPolicies"] = []interface{}{"Observe", "Create", "Delete"}
+
+	inSync := driftResource(t, drifted, drifted)
+	unobserved := driftResource(t, drifted, "")
+
+	deleting := driftResource(t, drifted, observed)
+	deletedAt := time.Now()
+	deleting.DeletionTimestamp = &deletedAt
+
+	claim := driftResource(t, drifted, observed)
+	claim.Category = CategoryClaim
+
+	report := BuildDriftReport([]*Resource{outOfBand, notReconciled, pending, ignored, inSync, unobserved, deleting, claim})
+
+	if report.Checked != 5 || report.Unobserved != 1 {
+		t.Errorf("checked %d and unobserved %d, want 5 and 1", report.Checked, report.Unobserved)
+	}
+	got := make(map[string]DriftState)
+	for _, drift := range report.Drifted {
+		got[drift.Ref.Name] = drift.State
+	}
+	want := map[string]DriftState{
+		"ignored":        DriftIgnored,
+		"not-reconciled": DriftNotReconciled,
+		"out-of-band":    DriftOutOfBand,
+		"pending":        DriftPending,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("drift states = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,284 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// providerPodLabel is set by Crossplane on the pods of a provider
+const providerPodLabel = "pkg.crossplane.io/provider"
+
+// Event is a Kubernetes event about a Crossplane object
+type Event struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Source    string    `json:"source,omitempty"`
+	// Object is the Kind/name the event is about, which is not always the
+	// resource itself (e.g. a provider pod)
+	Object string `json:"object"`
+
+	uid string
+}
+
+// IsWarning reports whether the event is a Warning event
+func (e Event) IsWarning() bool {
+	return e.Type == corev1.EventTypeWarning
+}
+
+// objectRef identifies an object an event is about or a resource reference
+// points at
+type objectRef struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+	UID        string
+}
+
+func (r objectRef) String() string {
+	return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+}
+
+// GetEvents returns the events of a resource, oldest first. Both the core/v1
+// and events.k8s.io/v1 APIs are read and duplicates are merged. For a
+// provider the events of its current revision and of its pods are included.
+func (c *Client) GetEvents(ctx context.Context, resource *Resource) ([]Event, error) {
+	refs := []objectRef{resourceRef(resource)}
+	if resource.Kind == "Provider" && resource.Raw != nil {
+		refs = append(refs, c.providerRefs(ctx, resource)...)
+	}
+
+	seen := make(map[string]bool)
+	var events []Event
+	var lastErr error
+	failures := 0
+
+	for _, ref := range refs {
+		coreEvents, coreErr := c.coreEvents(ctx, ref)
+		newEvents, newErr := c.eventsV1Events(ctx, ref)
+		if coreErr != nil && newErr != nil {
+			lastErr = coreErr
+			failures++
+			continue
+		}
+
+		for _, event := range append(coreEvents, newEvents...) {
+			key := event.uid
+			if key == "" {
+				key = fmt.Sprintf("%s|%s|%s|%s", event.Object, event.Reason, event.Message, event.FirstSeen)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			events = append(events, event)
+		}
+	}
+
+	if failures == len(refs) && lastErr != nil {
+		return nil, fmt.Errorf("failed to list events for %s: %w", refs[0], lastErr)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastSeen.Before(events[j].LastSeen)
+	})
+
+	return events, nil
+}
+
+// MaxEventResources bounds how many unhealthy resources get their events
+// fetched for the analysis and AI context
+const MaxEventResources = 20
+
+// AttachEvents fetches events for up to limit resources that are not
+// healthy, so that they are part of the AI context. Resources whose events
+// cannot be read are left without events.
+func (c *Client) AttachEvents(ctx context.Context, resources []*Resource, limit int) {
+	attached := 0
+	for _, resource := range resources {
+		if attached >= limit {
+			return
+		}
+		if !NeedsAttention(resource) {
+			continue
+		}
+
+		events, err := c.GetEvents(ctx, resource)
+		if err != nil {
+			continue
+		}
+		resource.Events = events
+		attached++
+	}
+}
+
+// NeedsAttention reports whether a resource is not ready, not synced or
+// being deleted. Types that report no health only need attention when they
+// are being deleted.
+func NeedsAttention(resource *Resource) bool {
+	notReady := resource.Status != "Ready" && resource.Status != StatusNone
+	return notReady || resource.Synced == "False" || resource.IsDeleting()
+}
+
+func resourceRef(resource *Resource) objectRef {
+	ref := objectRef{Kind: resource.Kind, Name: resource.Name, Namespace: resource.Namespace}
+	if resource.Raw != nil {
+		ref.UID = string(resource.Raw.GetUID())
+		if ref.Kind == "" {
+			ref.Kind = resource.Raw.GetKind()
+		}
+	}
+	return ref
+}
+
+// providerRefs returns the current revision and the pods of a provider
+func (c *Client) providerRefs(ctx context.Context, provider *Resource) []objectRef {
+	var refs []objectRef
+
+	if revision, _, _ := unstructured.NestedString(provider.Raw.Object, "status", "currentRevision"); revision != "" {
+		refs = append(refs, objectRef{Kind: "ProviderRevision", Name: revision})
+	}
+
+	pods, err := c.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", providerPodLabel, provider.Name),
+	})
+	if err != nil {
+		return refs
+	}
+	for _, pod := range pods.Items {
+		refs = append(refs, objectRef{Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace, UID: string(pod.UID)})
+	}
+
+	return refs
+}
+
+func (c *Client) coreEvents(ctx context.Context, ref objectRef) ([]Event, error) {
+	selector := fields.Set{"involvedObject.kind": ref.Kind, "involvedObject.name": ref.Name}
+	if ref.UID != "" {
+		selector["involvedObject.uid"] = ref.UID
+	}
+
+	list, err := c.kubeClient.CoreV1().Events(ref.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, e := range list.Items {
+		// Field selectors are not honoured everywhere (e.g. fake clients)
+		if !ref.matches(e.InvolvedObject) {
+			continue
+		}
+
+		first := e.FirstTimestamp.Time
+		if first.IsZero() {
+			first = e.EventTime.Time
+		}
+		last := e.LastTimestamp.Time
+		if last.IsZero() {
+			last = first
+		}
+		if e.Series != nil && e.Series.LastObservedTime.After(last) {
+			last = e.Series.LastObservedTime.Time
+		}
+
+		source := e.Source.Component
+		if source == "" {
+			source = e.ReportingController
+		}
+
+		events = append(events, Event{
+			Type:      e.Type,
+			Reason:    e.Reason,
+			Message:   e.Message,
+			Count:     e.Count,
+			FirstSeen: first,
+			LastSeen:  last,
+			Source:    source,
+			Object:    ref.String(),
+			uid:       string(e.UID),
+		})
+	}
+
+	return events, nil
+}
+
+func (c *Client) eventsV1Events(ctx context.Context, ref objectRef) ([]Event, error) {
+	selector := fields.Set{"regarding.kind": ref.Kind, "regarding.name": ref.Name}
+
+	list, err := c.kubeClient.EventsV1().Events(ref.Namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: selector.AsSelector().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, e := range list.Items {
+		if !ref.matches(e.Regarding) {
+			continue
+		}
+		events = append(events, convertEventsV1(e, ref))
+	}
+
+	return events, nil
+}
+
+func convertEventsV1(e eventsv1.Event, ref objectRef) Event {
+	first := e.EventTime.Time
+	if first.IsZero() {
+		first = e.DeprecatedFirstTimestamp.Time
+	}
+	last := e.DeprecatedLastTimestamp.Time
+	if last.IsZero() {
+		last = first
+	}
+	count := e.DeprecatedCount
+	if e.Series != nil {
+		count = e.Series.Count
+		if e.Series.LastObservedTime.After(last) {
+			last = e.Series.LastObservedTime.Time
+		}
+	}
+
+	source := e.ReportingController
+	if source == "" {
+		source = e.DeprecatedSource.Component
+	}
+
+	return Event{
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Message:   e.Note,
+		Count:     count,
+		FirstSeen: first,
+		LastSeen:  last,
+		Source:    source,
+		Object:    ref.String(),
+		uid:       string(e.UID),
+	}
+}
+
+func (r objectRef) matches(obj corev1.ObjectReference) bool {
+	if obj.Kind != r.Kind || obj.Name != r.Name {
+		return false
+	}
+	if r.UID != "" && obj.UID != "" && string(obj.UID) != r.UID {
+		return false
+	}
+	return true
+}
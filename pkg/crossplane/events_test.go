@@ -0,0 +1,116 @@
+package crossplane
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// coreEvent is an event of the core/v1 API about obj
+func coreEvent(uid, reason string, obj corev1.ObjectReference, last time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: uid, Namespace: obj.Namespace, UID: types.UID(uid)},
+		InvolvedObject: obj,
+		Type:           corev1.EventTypeWarning,
+		Reason:         reason,
+		Message:        reason + " happened",
+		Count:          1,
+		FirstTimestamp: metav1.NewTime(last.Add(-time.Minute)),
+		LastTimestamp:  metav1.NewTime(last),
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	raw := &unstructured.Unstructured{}
+	raw.SetUID("bucket-uid")
+	bucket := &Resource{Name: "logs", Kind: "Bucket", Raw: raw}
+	about := corev1.ObjectReference{Kind: "Bucket", Name: "logs", UID: "bucket-uid"}
+
+	// The API server serves every event through both APIs, so the
+	// events.k8s.io/v1 copy of e1 must be merged with the core/v1 one
+	objects := []runtime.Object{
+		coreEvent("e1", "CannotConnect", about, now.Add(-time.Hour)),
+		coreEvent("e2", "CannotObserve", about, now),
+		&eventsv1.Event{
+			ObjectMeta: metav1.ObjectMeta{Name: "e1", UID: "e1"},
+			Regarding:  about,
+			Type:       corev1.EventTypeWarning,
+			Reason:     "CannotConnect",
+			Note:       "CannotConnect happened",
+			EventTime:  metav1.NewMicroTime(now.Add(-time.Hour - time.Minute)),
+		},
+		// Only in events.k8s.io/v1, e.g. from a newer controller
+		&eventsv1.Event{
+			ObjectMeta:          metav1.ObjectMeta{Name: "e3", UID: "e3"},
+			Regarding:           about,
+			Type:                corev1.EventTypeNormal,
+			Reason:              "Updated",
+			Note:                "Updated happened",
+			EventTime:           metav1.NewMicroTime(now.Add(-30 * time.Minute)),
+			ReportingController: "provider-aws-s3",
+		},
+		// A previous bucket of the same name
+		coreEvent("e4", "Deleted", corev1.ObjectReference{Kind: "Bucket", Name: "logs", UID: "old-uid"}, now),
+		coreEvent("e5", "CannotConnect", corev1.ObjectReference{Kind: "Bucket", Name: "other"}, now),
+	}
+	client := &Client{kubeClient: fake.NewClientset(objects...)}
+
+	events, err := client.GetEvents(context.Background(), bucket)
+	if err != nil {
+		t.Fatalf("GetEvents: %v", err)
+	}
+
+	var reasons []string
+	for _, event := range events {
+		reasons = append(reasons, event.Reason)
+		if event.Object != "Bucket/logs" {
+			t.Errorf("event %s is about %s, want Bucket/logs", event.Reason, event.Object)
+		}
+	}
+	if want := []string{"CannotConnect", "Updated", "CannotObserve"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("events = %v, want %v, oldest first and without duplicates", reasons, want)
+	}
+	if len(events) == 3 && events[1].Source != "provider-aws-s3" {
+		t.Errorf("source = %q, want the reporting controller", events[1].Source)
+	}
+}
+
+func TestGetEventsFailures(t *testing.T) {
+	bucket := &Resource{Name: "logs", Kind: "Bucket"}
+	about := corev1.ObjectReference{Kind: "Bucket", Name: "logs"}
+
+	// events.k8s.io/v1 is not served, core/v1 is
+	clientset := fake.NewClientset(coreEvent("e1", "CannotConnect", about, time.Now()))
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Group == "events.k8s.io" {
+			return true, nil, errors.New("the server could not find the requested resource")
+		}
+		return false, nil, nil
+	})
+	client := &Client{kubeClient: clientset}
+
+	events, err := client.GetEvents(context.Background(), bucket)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("GetEvents = %v, %v, want the core/v1 event", events, err)
+	}
+
+	// Neither API can be read
+	clientset.PrependReactor("list", "events", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	if _, err := client.GetEvents(context.Background(), bucket); err == nil {
+		t.Error("GetEvents succeeded, want an error when no events can be read")
+	}
+}
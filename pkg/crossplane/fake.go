@@ -0,0 +1,277 @@
+package crossplane
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// fakeCoreTypes are the Crossplane API types a fake source always serves,
+// as a cluster with Crossplane installed does, so that listing them finds
+// nothing instead of failing
+var fakeCoreTypes = []fakeType{
+	{GVK: schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositeResourceDefinition"}, Plural: "compositeresourcedefinitions"},
+	{GVK: schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "Composition"}, Plural: "compositions"},
+	{GVK: schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositionRevision"}, Plural: "compositionrevisions"},
+	{GVK: schema.GroupVersionKind{Group: packageGroup, Version: "v1", Kind: "Provider"}, Plural: "providers"},
+	{GVK: schema.GroupVersionKind{Group: packageGroup, Version: "v1", Kind: "ProviderRevision"}, Plural: "providerrevisions"},
+	{GVK: schema.GroupVersionKind{Group: packageGroup, Version: "v1", Kind: "Function"}, Plural: "functions"},
+	{GVK: schema.GroupVersionKind{Group: packageGroup, Version: "v1", Kind: "FunctionRevision"}, Plural: "functionrevisions"},
+	{GVK: schema.GroupVersionKind{Group: packageGroup, Version: "v1", Kind: "Configuration"}, Plural: "configurations"},
+	{GVK: crdGVR.GroupVersion().WithKind("CustomResourceDefinition"), Plural: crdGVR.Resource},
+}
+
+// fakeType is an API type served by a fake source
+type fakeType struct {
+	GVK        schema.GroupVersionKind
+	Plural     string
+	Namespaced bool
+	Categories []string
+}
+
+// NewFakeSource serves a set of objects from memory as if they were read
+// from a cluster with Crossplane installed. The API types are derived from
+// the objects: XRDs and CRDs among them declare their types, other kinds
+// are served under a guessed plural and classified by their spec, e.g.
+// spec.forProvider makes a managed resource. Events, Pods and Secrets of
+// the core API are served like the events, pods and secrets of a snapshot.
+// Objects without a UID or creation timestamp get one.
+func NewFakeSource(cluster string, objects []*unstructured.Unstructured) (ResourceSource, error) {
+	snapshot, err := fakeSnapshot(cluster, objects)
+	if err != nil {
+		return nil, err
+	}
+	return NewSnapshotSource(snapshot)
+}
+
+// ReadManifestDir reads the objects of every .yaml, .yml and .json file in
+// a directory, in file name order
+func ReadManifestDir(dir string) ([]*unstructured.Unstructured, error) {
+	entries, err := os.ReadDir(expandHome(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		path := filepath.Join(expandHome(dir), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		fileObjects, err := SplitManifest(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", dir)
+	}
+	return objects, nil
+}
+
+// fakeSnapshot builds the snapshot a fake source serves
+func fakeSnapshot(cluster string, objects []*unstructured.Unstructured) (*Snapshot, error) {
+	now := time.Now().UTC()
+	snapshot := &Snapshot{Version: snapshotFormat, Cluster: cluster, CreatedAt: now}
+
+	types := make(map[schema.GroupVersionKind]fakeType)
+	for _, t := range fakeCoreTypes {
+		types[t.GVK] = t
+	}
+	for _, obj := range objects {
+		for _, t := range declaredTypes(obj) {
+			types[t.GVK] = t
+		}
+	}
+
+	for _, obj := range objects {
+		obj = obj.DeepCopy()
+		if obj.GetUID() == "" {
+			obj.SetUID(uuid.NewUUID())
+		}
+		if created := obj.GetCreationTimestamp(); created.IsZero() {
+			obj.SetCreationTimestamp(metav1.NewTime(now))
+		}
+
+		gvk := obj.GroupVersionKind()
+		if gvk.Group == "" && gvk.Version == "v1" {
+			if err := addCoreObject(snapshot, obj); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		t, ok := types[gvk]
+		if !ok {
+			t = fakeType{GVK: gvk, Plural: guessPlural(gvk.Kind), Categories: guessCategories(obj)}
+		}
+		if obj.GetNamespace() != "" {
+			t.Namespaced = true
+		}
+		types[gvk] = t
+		snapshot.Objects = append(snapshot.Objects, obj)
+	}
+
+	byGroupVersion := make(map[schema.GroupVersion]*metav1.APIResourceList)
+	for _, t := range types {
+		gv := t.GVK.GroupVersion()
+		list, ok := byGroupVersion[gv]
+		if !ok {
+			list = &metav1.APIResourceList{GroupVersion: gv.String()}
+			byGroupVersion[gv] = list
+			snapshot.APIResources = append(snapshot.APIResources, list)
+		}
+		list.APIResources = append(list.APIResources, metav1.APIResource{
+			Name:       t.Plural,
+			Kind:       t.GVK.Kind,
+			Namespaced: t.Namespaced,
+			Categories: t.Categories,
+			Verbs:      metav1.Verbs{"get", "list", "watch"},
+		})
+	}
+	sort.Slice(snapshot.APIResources, func(i, j int) bool {
+		return snapshot.APIResources[i].GroupVersion < snapshot.APIResources[j].GroupVersion
+	})
+	for _, list := range snapshot.APIResources {
+		sort.Slice(list.APIResources, func(i, j int) bool { return list.APIResources[i].Name < list.APIResources[j].Name })
+	}
+
+	return snapshot, nil
+}
+
+// declaredTypes returns the API types declared by an XRD or CRD: the
+// composite and claim types of an XRD, the type of a CRD
+func declaredTypes(obj *unstructured.Unstructured) []fakeType {
+	group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+	versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+	scope, _, _ := unstructured.NestedString(obj.Object, "spec", "scope")
+
+	var names []string
+	for _, version := range versions {
+		if m, ok := version.(map[string]interface{}); ok {
+			if name, _ := m["name"].(string); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	declare := func(namesField string, namespaced bool, categories []string) []fakeType {
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", namesField, "kind")
+		plural, _, _ := unstructured.NestedString(obj.Object, "spec", namesField, "plural")
+		if kind == "" || plural == "" {
+			return nil
+		}
+		var types []fakeType
+		for _, version := range names {
+			types = append(types, fakeType{
+				GVK:        schema.GroupVersionKind{Group: group, Version: version, Kind: kind},
+				Plural:     plural,
+				Namespaced: namespaced,
+				Categories: categories,
+			})
+		}
+		return types
+	}
+
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Group: "apiextensions.crossplane.io", Kind: "CompositeResourceDefinition"}:
+		// Crossplane v2 XRs are namespaced unless declared otherwise
+		namespaced := scope == "Namespaced" || (scope == "" && obj.GroupVersionKind().Version == "v2")
+		types := declare("names", namespaced, []string{"crossplane", "composite"})
+		return append(types, declare("claimNames", true, []string{"crossplane", "claim"})...)
+	case schema.GroupKind{Group: crdGVR.Group, Kind: "CustomResourceDefinition"}:
+		categories, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "names", "categories")
+		return declare("names", scope == "Namespaced", categories)
+	}
+	return nil
+}
+
+// addCoreObject adds an Event, Pod or Secret of the core API to the
+// snapshot
+func addCoreObject(snapshot *Snapshot, obj *unstructured.Unstructured) error {
+	var target runtime.Object
+	switch obj.GetKind() {
+	case "Event":
+		snapshot.Events = append(snapshot.Events, corev1.Event{})
+		target = &snapshot.Events[len(snapshot.Events)-1]
+	case "Pod":
+		snapshot.Pods = append(snapshot.Pods, corev1.Pod{})
+		target = &snapshot.Pods[len(snapshot.Pods)-1]
+	case "Secret":
+		snapshot.Secrets = append(snapshot.Secrets, corev1.Secret{})
+		target = &snapshot.Secrets[len(snapshot.Secrets)-1]
+	default:
+		return fmt.Errorf("%s %q: only Events, Pods and Secrets of the core API can be faked", obj.GetKind(), obj.GetName())
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, target); err != nil {
+		return fmt.Errorf("%s %q: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// guessPlural derives the resource name of a kind the way CRDs are
+// conventionally named: Bucket buckets, Policy policies, Address addresses
+func guessPlural(kind string) string {
+	plural := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(plural, "s"), strings.HasSuffix(plural, "x"),
+		strings.HasSuffix(plural, "ch"), strings.HasSuffix(plural, "sh"):
+		return plural + "es"
+	case len(plural) > 1 && strings.HasSuffix(plural, "y") && !strings.ContainsRune("aeiou", rune(plural[len(plural)-2])):
+		return plural[:len(plural)-1] + "ies"
+	}
+	return plural + "s"
+}
+
+// guessCategories classifies an object whose type is not declared by an
+// XRD or CRD from its spec: spec.forProvider makes a managed resource,
+// spec.resourceRef a claim and a composition reference or composed
+// resource references a composite. Crossplane's own types and
+// ProviderConfigs get no category.
+func guessCategories(obj *unstructured.Unstructured) []string {
+	switch obj.GroupVersionKind().Group {
+	case "apiextensions.crossplane.io", packageGroup, "protection.crossplane.io", "ops.crossplane.io":
+		return nil
+	}
+	if isProviderConfigKind(obj.GetKind()) {
+		return nil
+	}
+
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	has := func(fields ...string) bool {
+		for _, field := range fields {
+			if _, ok := spec[field]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("forProvider"):
+		return []string{"crossplane", "managed"}
+	case obj.GetNamespace() != "" && has("resourceRef"):
+		return []string{"crossplane", "claim"}
+	case has("compositionRef", "compositionSelector", "resourceRefs", "crossplane"):
+		return []string{"crossplane", "composite"}
+	}
+	return nil
+}
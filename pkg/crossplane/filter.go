@@ -0,0 +1,261 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Status filter values accepted by ResourceFilter.Status
+const (
+	StatusFilterReady     = "ready"
+	StatusFilterNotReady  = "not-ready"
+	StatusFilterUnknown   = "unknown"
+	StatusFilterUnsynced  = "unsynced"
+	StatusFilterDeleting  = "deleting"
+	StatusFilterUnhealthy = "unhealthy"
+)
+
+// ResourceFilter selects resources. Label and field selectors, types and
+// the namespace are passed to the API server; the other criteria are
+// applied to the listed resources. Zero values match everything.
+type ResourceFilter struct {
+	// Name is an exact name, a glob such as "prod-*", or a regular
+	// expression between slashes such as "/^db-[0-9]+$/"
+	Name      string
+	Provider  string
+	Namespace string
+	// LabelSelector uses the Kubernetes syntax, e.g. "team=payments,env in (prod,staging)"
+	LabelSelector string
+	// FieldSelector uses the Kubernetes syntax; custom resources support
+	// metadata.name and metadata.namespace
+	FieldSelector string
+	// Types limits listing to these types, by plural, kind or plural.group
+	Types []string
+	// Status is one of ready, not-ready, unknown, unsynced, deleting or
+	// unhealthy (anything that needs attention)
+	Status string
+	// OlderThan matches resources created longer ago than this
+	OlderThan time.Duration
+}
+
+// IsZero reports whether the filter matches everything
+func (f ResourceFilter) IsZero() bool {
+	return f.Name == "" && f.Provider == "" && f.Namespace == "" && f.LabelSelector == "" &&
+		f.FieldSelector == "" && len(f.Types) == 0 && f.Status == "" && f.OlderThan == 0
+}
+
+// compiledFilter is a validated ResourceFilter
+type compiledFilter struct {
+	ResourceFilter
+	nameRegexp *regexp.Regexp
+	labels     labels.Selector
+	status     string
+}
+
+func (f ResourceFilter) compile() (*compiledFilter, error) {
+	compiled := &compiledFilter{ResourceFilter: f, labels: labels.Everything()}
+
+	if len(f.Name) > 2 && strings.HasPrefix(f.Name, "/") && strings.HasSuffix(f.Name, "/") {
+		re, err := regexp.Compile(f.Name[1 : len(f.Name)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %s: %w", f.Name, err)
+		}
+		compiled.nameRegexp = re
+	} else if strings.ContainsAny(f.Name, "*?[") {
+		if _, err := path.Match(f.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %s: %w", f.Name, err)
+		}
+	}
+
+	if f.LabelSelector != "" {
+		selector, err := labels.Parse(f.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector: %w", err)
+		}
+		compiled.labels = selector
+	}
+
+	if f.FieldSelector != "" {
+		if _, err := fields.ParseSelector(f.FieldSelector); err != nil {
+			return nil, fmt.Errorf("invalid field selector: %w", err)
+		}
+	}
+
+	if f.Status != "" {
+		status, err := normalizeStatusFilter(f.Status)
+		if err != nil {
+			return nil, err
+		}
+		compiled.status = status
+	}
+
+	return compiled, nil
+}
+
+func normalizeStatusFilter(status string) (string, error) {
+	s := strings.ToLower(strings.NewReplacer("_", "-", " ", "-").Replace(strings.TrimSpace(status)))
+	switch s {
+	case StatusFilterReady, StatusFilterUnknown, StatusFilterUnsynced, StatusFilterDeleting, StatusFilterUnhealthy:
+		return s, nil
+	case StatusFilterNotReady, "notready":
+		return StatusFilterNotReady, nil
+	}
+	return "", fmt.Errorf("invalid status %q (use %s, %s, %s, %s, %s or %s)", status,
+		StatusFilterReady, StatusFilterNotReady, StatusFilterUnknown, StatusFilterUnsynced,
+		StatusFilterDeleting, StatusFilterUnhealthy)
+}
+
+// ListResources lists the resources matching the filter and reports the
+// resource types that could not be listed
+func (c *Client) ListResources(ctx context.Context, filter ResourceFilter) (*ListResult, error) {
+	compiled, err := filter.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ListResult
+	// The cache holds every object, so label selectors can be applied in
+	// memory; field selectors are left to the API server
+	if cache := c.runningCache(); cache != nil && filter.FieldSelector == "" {
+		result = cache.List()
+	} else {
+		discovered, err := c.DiscoverResourceTypes(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var types []DiscoveredType
+		for _, t := range discovered {
+			if !c.includes(t) {
+				continue
+			}
+			if len(filter.Types) > 0 && !matchesResourceType(t, filter.Types) {
+				continue
+			}
+			// Cluster-scoped resources never match a namespace filter
+			if filter.Namespace != "" && !t.Namespaced {
+				continue
+			}
+			types = append(types, t)
+		}
+
+		result, err = c.listTypes(ctx, types, listOptions{
+			namespace:     filter.Namespace,
+			labelSelector: filter.LabelSelector,
+			fieldSelector: filter.FieldSelector,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var matched []*Resource
+	for _, resource := range result.Resources {
+		if compiled.matches(resource) {
+			matched = append(matched, resource)
+		}
+	}
+	result.Resources = matched
+
+	return result, nil
+}
+
+// matches applies the whole filter to a listed resource. Criteria that were
+// already passed to the API server are checked again so that cached results
+// are filtered the same way.
+func (f *compiledFilter) matches(resource *Resource) bool {
+	if !f.matchesName(resource.Name) {
+		return false
+	}
+	if f.Provider != "" && !strings.EqualFold(resource.Provider, f.Provider) {
+		return false
+	}
+	if f.Namespace != "" && resource.Namespace != f.Namespace {
+		return false
+	}
+	if len(f.Types) > 0 && !f.matchesType(resource) {
+		return false
+	}
+	if !f.labels.Matches(labels.Set(resource.Labels)) {
+		return false
+	}
+	if f.status != "" && !matchesStatus(resource, f.status) {
+		return false
+	}
+	if f.OlderThan > 0 && (resource.CreatedAt.IsZero() || time.Since(resource.CreatedAt) < f.OlderThan) {
+		return false
+	}
+	return true
+}
+
+func (f *compiledFilter) matchesType(resource *Resource) bool {
+	for _, t := range f.Types {
+		if matchesKind(resource, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *compiledFilter) matchesName(name string) bool {
+	switch {
+	case f.Name == "":
+		return true
+	case f.nameRegexp != nil:
+		return f.nameRegexp.MatchString(name)
+	case strings.ContainsAny(f.Name, "*?["):
+		matched, _ := path.Match(f.Name, name)
+		return matched
+	}
+	return name == f.Name
+}
+
+func matchesStatus(resource *Resource, status string) bool {
+	switch status {
+	case StatusFilterReady:
+		return resource.Status == "Ready"
+	case StatusFilterNotReady:
+		return resource.Status == "Not Ready"
+	case StatusFilterUnknown:
+		return resource.Status == "Unknown"
+	case StatusFilterUnsynced:
+		return resource.Synced == "False"
+	case StatusFilterDeleting:
+		return resource.IsDeleting()
+	case StatusFilterUnhealthy:
+		return NeedsAttention(resource)
+	}
+	return true
+}
+
+// ParseAge parses a duration such as "90m", "36h", "7d" or "2w"
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 90m, 36h, 7d or 2w)", s)
+	}
+	return d, nil
+}
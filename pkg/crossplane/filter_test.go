@@ -0,0 +1,137 @@
+package crossplane
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"90m", 90 * time.Minute, false},
+		{"36h", 36 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{" 1h30m ", 90 * time.Minute, false},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"-5m", 0, true},
+		{"7 days", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.age, func(t *testing.T) {
+			got, err := ParseAge(tt.age)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseAge(%q) = %v, want an error", tt.age, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAge(%q): %v", tt.age, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAge(%q) = %v, want %v", tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceFilterCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  ResourceFilter
+		wantErr string
+	}{
+		{"zero", ResourceFilter{}, ""},
+		{"glob", ResourceFilter{Name: "prod-*"}, ""},
+		{"regular expression", ResourceFilter{Name: "/^db-[0-9]+$/"}, ""},
+		{"status with underscore", ResourceFilter{Status: "Not_Ready"}, ""},
+		{"invalid glob", ResourceFilter{Name: "prod-[a"}, "invalid name pattern"},
+		{"invalid regular expression", ResourceFilter{Name: "/db-(/"}, "invalid name pattern"},
+		{"invalid label selector", ResourceFilter{LabelSelector: "team in payments"}, "invalid label selector"},
+		{"invalid field selector", ResourceFilter{FieldSelector: "metadata.name"}, "invalid field selector"},
+		{"invalid status", ResourceFilter{Status: "broken"}, `invalid status "broken"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.filter.compile()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("compile: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResourceFilterMatches(t *testing.T) {
+	deleted := time.Now()
+	resource := &Resource{
+		Name:       "db-42",
+		Namespace:  "team-a",
+		Type:       "instances",
+		Kind:       "Instance",
+		APIVersion: "rds.aws.upbound.io/v1beta1",
+		Provider:   "aws",
+		Status:     "Not Ready",
+		Synced:     "False",
+		Labels:     map[string]string{"team": "payments", "env": "prod"},
+		CreatedAt:  time.Now().Add(-48 * time.Hour),
+	}
+	deleting := &Resource{Name: "db-43", Status: "Ready", Synced: "True", DeletionTimestamp: &deleted}
+
+	tests := []struct {
+		name     string
+		filter   ResourceFilter
+		resource *Resource
+		want     bool
+	}{
+		{"zero", ResourceFilter{}, resource, true},
+		{"exact name", ResourceFilter{Name: "db-42"}, resource, true},
+		{"other name", ResourceFilter{Name: "db-4"}, resource, false},
+		{"glob", ResourceFilter{Name: "db-*"}, resource, true},
+		{"regular expression", ResourceFilter{Name: "/^db-[0-9]+$/"}, resource, true},
+		{"regular expression without match", ResourceFilter{Name: "/^db-[a-z]+$/"}, resource, false},
+		{"provider in another case", ResourceFilter{Provider: "AWS"}, resource, true},
+		{"other provider", ResourceFilter{Provider: "gcp"}, resource, false},
+		{"namespace", ResourceFilter{Namespace: "team-b"}, resource, false},
+		{"type by kind", ResourceFilter{Types: []string{"instance"}}, resource, true},
+		{"type by qualified plural", ResourceFilter{Types: []string{"buckets", "instances.rds.aws.upbound.io"}}, resource, true},
+		{"other type", ResourceFilter{Types: []string{"buckets"}}, resource, false},
+		{"label selector", ResourceFilter{LabelSelector: "team=payments,env in (prod,staging)"}, resource, true},
+		{"label selector without match", ResourceFilter{LabelSelector: "env!=prod"}, resource, false},
+		{"not ready", ResourceFilter{Status: "not-ready"}, resource, true},
+		{"ready", ResourceFilter{Status: "ready"}, resource, false},
+		{"unsynced", ResourceFilter{Status: "unsynced"}, resource, true},
+		{"unhealthy", ResourceFilter{Status: "unhealthy"}, resource, true},
+		{"deleting", ResourceFilter{Status: "deleting"}, deleting, true},
+		{"not deleting", ResourceFilter{Status: "deleting"}, resource, false},
+		{"deleting is unhealthy", ResourceFilter{Status: "unhealthy"}, deleting, true},
+		{"older than", ResourceFilter{OlderThan: 24 * time.Hour}, resource, true},
+		{"younger than", ResourceFilter{OlderThan: 7 * 24 * time.Hour}, resource, false},
+		{"unknown creation time", ResourceFilter{OlderThan: time.Hour}, deleting, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := tt.filter.compile()
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			if got := compiled.matches(tt.resource); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FindResource looks up a single resource by reference. A reference is
+// either a bare name or "[<namespace>/]<kind>/<name>", where kind may be the
+// Kind ("DBInstance"), the plural ("dbinstances") or the plural qualified by
+// its group ("dbinstances.rds.aws.upbound.io"), all case-insensitive. The
+// names QualifiedName renders are references.
+func (c *Client) FindResource(ctx context.Context, ref string) (*Resource, error) {
+	result, err := c.ListAllResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := MatchResources(result.Resources, ref)
+	switch len(matches) {
+	case 0:
+		if result.Partial() {
+			return nil, fmt.Errorf("resource %q not found (%d resource types could not be listed)", ref, len(result.Failures))
+		}
+		return nil, fmt.Errorf("resource %q not found", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		var candidates []string
+		for _, match := range matches {
+			candidates = append(candidates, QualifiedName(match))
+		}
+		return nil, fmt.Errorf("resource %q is ambiguous, use one of: %s", ref, strings.Join(candidates, ", "))
+	}
+}
+
+// MatchResources returns the resources matching a reference, see FindResource
+func MatchResources(resources []*Resource, ref string) []*Resource {
+	parts := strings.Split(ref, "/")
+	if len(parts) > 3 {
+		return nil
+	}
+	var namespace, kind string
+	name := parts[len(parts)-1]
+	if len(parts) > 1 {
+		kind = parts[len(parts)-2]
+	}
+	if len(parts) > 2 {
+		namespace = parts[0]
+	}
+
+	var matches []*Resource
+	for _, resource := range resources {
+		if resource.Name != name {
+			continue
+		}
+		if kind != "" && !matchesKind(resource, kind) {
+			continue
+		}
+		if namespace != "" && resource.Namespace != namespace {
+			continue
+		}
+		matches = append(matches, resource)
+	}
+	return matches
+}
+
+// QualifiedName renders a resource as "<plural>.<group>/<name>", prefixed
+// with the namespace for namespaced resources
+func QualifiedName(resource *Resource) string {
+	name := fmt.Sprintf("%s/%s", qualifiedType(resource), resource.Name)
+	if resource.Namespace != "" {
+		name = resource.Namespace + "/" + name
+	}
+	return name
+}
+
+func qualifiedType(resource *Resource) string {
+	group := strings.Split(resource.APIVersion, "/")[0]
+	if !strings.Contains(resource.APIVersion, "/") || group == "" {
+		return resource.Type
+	}
+	return resource.Type + "." + group
+}
+
+func matchesKind(resource *Resource, kind string) bool {
+	return strings.EqualFold(kind, resource.Kind) ||
+		strings.EqualFold(kind, resource.Type) ||
+		strings.EqualFold(kind, qualifiedType(resource))
+}
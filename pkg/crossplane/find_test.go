@@ -0,0 +1,57 @@
+package crossplane
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchResources(t *testing.T) {
+	resources := []*Resource{
+		{Name: "orders-db", Namespace: "team-a", Type: "postgresqlinstances", Kind: "PostgreSQLInstance", APIVersion: "database.example.org/v1alpha1"},
+		{Name: "orders-db", Namespace: "team-b", Type: "postgresqlinstances", Kind: "PostgreSQLInstance", APIVersion: "database.example.org/v1alpha1"},
+		{Name: "orders-db", Type: "instances", Kind: "Instance", APIVersion: "rds.aws.upbound.io/v1beta1"},
+		{Name: "logs", Type: "buckets", Kind: "Bucket", APIVersion: "s3.aws.upbound.io/v1beta1"},
+	}
+
+	tests := []struct {
+		ref  string
+		want []int
+	}{
+		{"logs", []int{3}},
+		{"orders-db", []int{0, 1, 2}},
+		{"instance/orders-db", []int{2}},
+		{"Instances/orders-db", []int{2}},
+		{"instances.rds.aws.upbound.io/orders-db", []int{2}},
+		{"postgresqlinstance/orders-db", []int{0, 1}},
+		{"team-b/postgresqlinstances.database.example.org/orders-db", []int{1}},
+		{"team-c/postgresqlinstance/orders-db", nil},
+		{"bucket/orders-db", nil},
+		{"a/b/c/orders-db", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			var want []*Resource
+			for _, i := range tt.want {
+				want = append(want, resources[i])
+			}
+			if got := MatchResources(resources, tt.ref); !reflect.DeepEqual(got, want) {
+				t.Errorf("MatchResources(%q) = %v, want %v", tt.ref, got, want)
+			}
+		})
+	}
+}
+
+func TestQualifiedNameMatches(t *testing.T) {
+	resources := []*Resource{
+		{Name: "orders-db", Namespace: "team-a", Type: "postgresqlinstances", Kind: "PostgreSQLInstance", APIVersion: "database.example.org/v1alpha1"},
+		{Name: "orders-db", Namespace: "team-b", Type: "postgresqlinstances", Kind: "PostgreSQLInstance", APIVersion: "database.example.org/v1alpha1"},
+		{Name: "orders-db", Type: "instances", Kind: "Instance", APIVersion: "rds.aws.upbound.io/v1beta1"},
+	}
+	// The candidates of an ambiguous reference must each find one resource
+	for _, resource := range resources {
+		ref := QualifiedName(resource)
+		if got := MatchResources(resources, ref); len(got) != 1 || got[0] != resource {
+			t.Errorf("MatchResources(%q) = %v, want only the resource it names", ref, got)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package crossplane
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Composition modes. Resources mode (spec.resources with patches) is the
+// legacy mode that Crossplane v2 removed in favour of function pipelines.
+const (
+	CompositionModePipeline  = "Pipeline"
+	CompositionModeResources = "Resources"
+)
+
+// PipelineStep is a step of a composition function pipeline
+type PipelineStep struct {
+	Step     string `json:"step"`
+	Function string `json:"function"`
+	// Input is the apiVersion and kind of the step's input, e.g.
+	// pt.fn.crossplane.io/v1beta1 Resources
+	Input string `json:"input,omitempty"`
+}
+
+// CompositionInfo describes how a Composition composes resources
+type CompositionInfo struct {
+	Cluster string `json:"cluster,omitempty"`
+	Name    string `json:"name"`
+	// CompositeType is the apiVersion and kind of the XRs it composes
+	CompositeType string         `json:"compositeType,omitempty"`
+	Mode          string         `json:"mode"`
+	Steps         []PipelineStep `json:"steps,omitempty"`
+	// Resources is the number of spec.resources templates in Resources mode
+	Resources int `json:"resources,omitempty"`
+}
+
+// NewCompositionInfo parses the mode and pipeline steps of a Composition.
+// Compositions without spec.mode use Resources mode.
+func NewCompositionInfo(resource *Resource) *CompositionInfo {
+	info := &CompositionInfo{Cluster: resource.Cluster, Name: resource.Name, Mode: CompositionModeResources}
+	if resource.Raw == nil {
+		return info
+	}
+	obj := resource.Raw.Object
+
+	if ref, found, _ := unstructured.NestedStringMap(obj, "spec", "compositeTypeRef"); found {
+		info.CompositeType = ref["apiVersion"] + " " + ref["kind"]
+	}
+	if mode, _, _ := unstructured.NestedString(obj, "spec", "mode"); mode != "" {
+		info.Mode = mode
+	}
+	if resources, found, _ := unstructured.NestedSlice(obj, "spec", "resources"); found {
+		info.Resources = len(resources)
+	}
+
+	steps, _, _ := unstructured.NestedSlice(obj, "spec", "pipeline")
+	for _, item := range steps {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		step := PipelineStep{}
+		step.Step, _, _ = unstructured.NestedString(m, "step")
+		step.Function, _, _ = unstructured.NestedString(m, "functionRef", "name")
+		apiVersion, _, _ := unstructured.NestedString(m, "input", "apiVersion")
+		kind, _, _ := unstructured.NestedString(m, "input", "kind")
+		if apiVersion != "" || kind != "" {
+			step.Input = apiVersion + " " + kind
+		}
+		info.Steps = append(info.Steps, step)
+	}
+
+	return info
+}
+
+// IsComposition reports whether the resource is a Composition
+func (r *Resource) IsComposition() bool {
+	return r.APIVersion == compositionsGVR.GroupVersion().String() && r.Type == compositionsGVR.Resource
+}
+
+// Functions returns the names of the functions the pipeline runs
+func (ci *CompositionInfo) Functions() []string {
+	seen := make(map[string]bool)
+	var functions []string
+	for _, step := range ci.Steps {
+		if step.Function != "" && !seen[step.Function] {
+			seen[step.Function] = true
+			functions = append(functions, step.Function)
+		}
+	}
+	return functions
+}
+
+// FunctionReference is a pipeline step that runs a function
+type FunctionReference struct {
+	Cluster     string `json:"cluster,omitempty"`
+	Composition string `json:"composition"`
+	Step        string `json:"step"`
+	Function    string `json:"function"`
+}
+
+// FunctionReport lists the installed functions and the compositions whose
+// pipelines run them
+type FunctionReport struct {
+	Functions    []*PackageInfo     `json:"functions"`
+	Compositions []*CompositionInfo `json:"compositions"`
+	// Missing are pipeline steps referencing functions that are not installed
+	Missing []FunctionReference `json:"missing,omitempty"`
+	// Failures are the types that could not be listed; Missing is empty
+	// when functions could not be listed
+	Failures []ListFailure `json:"failures,omitempty"`
+}
+
+// Function returns the installed function of a cluster with the given name
+func (r *FunctionReport) Function(cluster, name string) *PackageInfo {
+	for _, function := range r.Functions {
+		if function.Cluster == cluster && function.Name == name {
+			return function
+		}
+	}
+	return nil
+}
+
+// References returns the pipeline steps of all compositions that run the
+// given function
+func (r *FunctionReport) References(cluster, function string) []FunctionReference {
+	var refs []FunctionReference
+	for _, composition := range r.Compositions {
+		if composition.Cluster != cluster {
+			continue
+		}
+		for _, step := range composition.Steps {
+			if step.Function == function {
+				refs = append(refs, FunctionReference{Cluster: cluster, Composition: composition.Name, Step: step.Step, Function: function})
+			}
+		}
+	}
+	return refs
+}
+
+// FunctionReport lists functions and compositions and records the pipeline
+// steps that reference functions which are not installed
+func (c *Client) FunctionReport(ctx context.Context) (*FunctionReport, error) {
+	report := &FunctionReport{Functions: []*PackageInfo{}, Compositions: []*CompositionInfo{}}
+
+	compositions, err := c.GetCompositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, composition := range compositions {
+		report.Compositions = append(report.Compositions, NewCompositionInfo(composition))
+	}
+	sort.Slice(report.Compositions, func(i, j int) bool { return report.Compositions[i].Name < report.Compositions[j].Name })
+
+	functions, err := c.ListFunctions(ctx)
+	if functions == nil && err != nil {
+		// Without the installed functions no reference can be checked
+		failure := newListFailure(functionsGVR, err)
+		failure.Cluster = c.cluster
+		report.Failures = append(report.Failures, failure)
+		return report, nil
+	}
+	report.Functions = functions
+
+	for _, composition := range report.Compositions {
+		for _, step := range composition.Steps {
+			if step.Function != "" && report.Function(c.cluster, step.Function) == nil {
+				report.Missing = append(report.Missing, FunctionReference{
+					Cluster:     c.cluster,
+					Composition: composition.Name,
+					Step:        step.Step,
+					Function:    step.Function,
+				})
+			}
+		}
+	}
+
+	return report, nil
+}
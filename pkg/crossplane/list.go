@@ -0,0 +1,228 @@
+package crossplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// defaultListConcurrency is the number of resource types listed in parallel
+	defaultListConcurrency = 8
+	// defaultListTimeout bounds the time spent listing a single resource type
+	defaultListTimeout = 15 * time.Second
+	// defaultPageSize is the number of objects requested per list call
+	defaultPageSize int64 = 500
+)
+
+// FailureReason describes why a resource type could not be listed
+type FailureReason string
+
+const (
+	FailureNotFound  FailureReason = "NotFound"
+	FailureForbidden FailureReason = "Forbidden"
+	FailureTimeout   FailureReason = "Timeout"
+	FailureOther     FailureReason = "Error"
+)
+
+// ListFailure records a resource type that could not be listed. With
+// multiple clusters, Cluster names the cluster, and a failure without a GVR
+// means the whole cluster could not be reached.
+type ListFailure struct {
+	Cluster string                      `json:"cluster,omitempty"`
+	GVR     schema.GroupVersionResource `json:"gvr"`
+	Reason  FailureReason               `json:"reason"`
+	Message string                      `json:"message"`
+}
+
+// String returns a one-line description of the failure
+func (f ListFailure) String() string {
+	s := fmt.Sprintf("%s: %s (%s)", f.GVR.GroupResource(), f.Reason, f.Message)
+	if f.GVR.Resource == "" {
+		s = fmt.Sprintf("%s (%s)", f.Reason, f.Message)
+	}
+	if f.Cluster != "" {
+		s = f.Cluster + ": " + s
+	}
+	return s
+}
+
+// ListResult is the outcome of listing many resource types. Failures holds
+// the types that could not be listed, so that a partial result is never
+// mistaken for an empty cluster.
+type ListResult struct {
+	Resources []*Resource   `json:"resources"`
+	Failures  []ListFailure `json:"failures,omitempty"`
+	Types     int           `json:"types"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Partial reports whether some resource types could not be listed
+func (r *ListResult) Partial() bool {
+	return len(r.Failures) > 0
+}
+
+// ListAllResources lists every discovered Crossplane resource type that
+// passes the configured include lists concurrently and reports the types
+// that failed. While a cache is running (see StartCache) the result is
+// served from memory.
+func (c *Client) ListAllResources(ctx context.Context) (*ListResult, error) {
+	if cache := c.runningCache(); cache != nil {
+		return cache.List(), nil
+	}
+
+	discovered, err := c.DiscoverResourceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceTypes []DiscoveredType
+	for _, t := range discovered {
+		if c.includes(t) {
+			resourceTypes = append(resourceTypes, t)
+		}
+	}
+
+	return c.listTypes(ctx, resourceTypes, listOptions{})
+}
+
+// listOptions are passed to the API server when listing each type
+type listOptions struct {
+	// namespace overrides the client's namespace for namespaced types
+	namespace     string
+	labelSelector string
+	fieldSelector string
+}
+
+// listTypes fans the given types out over a bounded pool of workers. Results
+// are returned in the order of the input types.
+func (c *Client) listTypes(ctx context.Context, types []DiscoveredType, listOpts listOptions) (*ListResult, error) {
+	start := time.Now()
+
+	workers := c.listConcurrency
+	if workers <= 0 {
+		workers = defaultListConcurrency
+	}
+	if workers > len(types) {
+		workers = len(types)
+	}
+
+	type outcome struct {
+		resources []*Resource
+		err       error
+	}
+	outcomes := make([]outcome, len(types))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				resources, err := c.listType(ctx, types[i], listOpts)
+				outcomes[i] = outcome{resources: resources, err: err}
+			}
+		}()
+	}
+
+	for i := range types {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &ListResult{Types: len(types)}
+	for i, o := range outcomes {
+		if o.err != nil {
+			result.Failures = append(result.Failures, newListFailure(types[i].GVR, o.err))
+			continue
+		}
+		result.Resources = append(result.Resources, o.resources...)
+	}
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// listType lists a single resource type page by page under its own timeout
+func (c *Client) listType(ctx context.Context, t DiscoveredType, listOpts listOptions) ([]*Resource, error) {
+	timeout := c.listTimeout
+	if timeout <= 0 {
+		timeout = defaultListTimeout
+	}
+	pageSize := c.pageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var resources []*Resource
+	opts := metav1.ListOptions{
+		Limit:         pageSize,
+		LabelSelector: listOpts.labelSelector,
+		FieldSelector: listOpts.fieldSelector,
+	}
+	for {
+		list, err := c.resourceInterface(t, listOpts.namespace).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range list.Items {
+			resource := c.convertToResource(&list.Items[i], t)
+			resources = append(resources, resource)
+		}
+
+		opts.Continue = list.GetContinue()
+		if opts.Continue == "" {
+			return resources, nil
+		}
+	}
+}
+
+// resourceInterface returns the dynamic client for a type, scoped to the
+// given namespace, or else the client's namespace, when the type is
+// namespaced
+func (c *Client) resourceInterface(t DiscoveredType, namespace string) dynamic.ResourceInterface {
+	if namespace == "" {
+		namespace = c.namespace
+	}
+	if t.Namespaced && namespace != "" {
+		return c.dynamicClient.Resource(t.GVR).Namespace(namespace)
+	}
+	return c.dynamicClient.Resource(t.GVR)
+}
+
+func newListFailure(gvr schema.GroupVersionResource, err error) ListFailure {
+	reason := FailureOther
+	switch {
+	case apierrors.IsNotFound(err):
+		reason = FailureNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		reason = FailureForbidden
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		reason = FailureTimeout
+	}
+
+	return ListFailure{GVR: gvr, Reason: reason, Message: err.Error()}
+}
@@ -0,0 +1,168 @@
+package crossplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// listTestTypes are the types of the listing tests, one bucket of each
+func listTestTypes(n int) ([]DiscoveredType, []runtime.Object, map[schema.GroupVersionResource]string) {
+	var types []DiscoveredType
+	var objects []runtime.Object
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for i := 0; i < n; i++ {
+		kind := fmt.Sprintf("Bucket%d", i)
+		gvr := schema.GroupVersionResource{Group: "s3.aws.upbound.io", Version: "v1beta1", Resource: fmt.Sprintf("bucket%ds", i)}
+		types = append(types, DiscoveredType{GVR: gvr, Kind: kind, Category: CategoryManaged})
+		listKinds[gvr] = kind + "List"
+
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(gvr.GroupVersion().String())
+		obj.SetKind(kind)
+		obj.SetName(fmt.Sprintf("bucket-%d", i))
+		objects = append(objects, obj)
+	}
+	return types, objects, listKinds
+}
+
+func TestListTypesFailures(t *testing.T) {
+	types, objects, listKinds := listTestTypes(5)
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+
+	failures := map[string]error{
+		types[1].GVR.Resource: apierrors.NewForbidden(types[1].GVR.GroupResource(), "", errors.New("no RBAC")),
+		types[2].GVR.Resource: apierrors.NewNotFound(types[2].GVR.GroupResource(), ""),
+		types[3].GVR.Resource: apierrors.NewTimeoutError("list took too long", 0),
+	}
+	dyn.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if err, ok := failures[action.GetResource().Resource]; ok {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+
+	client := &Client{dynamicClient: dyn}
+	result, err := client.listTypes(context.Background(), types, listOptions{})
+	if err != nil {
+		t.Fatalf("listTypes: %v", err)
+	}
+
+	var names []string
+	for _, resource := range result.Resources {
+		names = append(names, resource.Name)
+	}
+	if fmt.Sprint(names) != "[bucket-0 bucket-4]" {
+		t.Errorf("resources = %v, want those of the types that listed", names)
+	}
+	if result.Types != 5 || !result.Partial() {
+		t.Errorf("Types = %d, Partial = %v, want 5 types and a partial result", result.Types, result.Partial())
+	}
+
+	want := map[string]FailureReason{
+		types[1].GVR.Resource: FailureForbidden,
+		types[2].GVR.Resource: FailureNotFound,
+		types[3].GVR.Resource: FailureTimeout,
+	}
+	if len(result.Failures) != len(want) {
+		t.Fatalf("failures = %v, want %d", result.Failures, len(want))
+	}
+	for _, failure := range result.Failures {
+		if reason := want[failure.GVR.Resource]; failure.Reason != reason {
+			t.Errorf("%s failed with %s, want %s", failure.GVR.Resource, failure.Reason, reason)
+		}
+	}
+}
+
+func TestListTypesConcurrency(t *testing.T) {
+	const workers = 3
+	types, objects, listKinds := listTestTypes(10)
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	dyn.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return false, nil, nil
+	})
+
+	client := &Client{dynamicClient: dyn, listConcurrency: workers}
+	result, err := client.listTypes(context.Background(), types, listOptions{})
+	if err != nil {
+		t.Fatalf("listTypes: %v", err)
+	}
+	if len(result.Resources) != len(types) || result.Partial() {
+		t.Fatalf("listed %d resources with failures %v, want %d", len(result.Resources), result.Failures, len(types))
+	}
+	if peak > workers {
+		t.Errorf("%d types were listed at once, want at most %d", peak, workers)
+	}
+
+	// Results keep the order of the types
+	for i, resource := range result.Resources {
+		if want := fmt.Sprintf("bucket-%d", i); resource.Name != want {
+			t.Errorf("resource %d is %s, want %s", i, resource.Name, want)
+		}
+	}
+}
+
+func TestListTypesCancelled(t *testing.T) {
+	types, objects, listKinds := listTestTypes(3)
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := &Client{dynamicClient: dyn}
+	if _, err := client.listTypes(ctx, types, listOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want the cancellation instead of a partial result", err)
+	}
+}
+
+func TestNewListFailure(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "s3.aws.upbound.io", Version: "v1beta1", Resource: "buckets"}
+	tests := []struct {
+		name string
+		err  error
+		want FailureReason
+	}{
+		{"not found", apierrors.NewNotFound(gvr.GroupResource(), ""), FailureNotFound},
+		{"forbidden", apierrors.NewForbidden(gvr.GroupResource(), "", errors.New("no RBAC")), FailureForbidden},
+		{"unauthorized", apierrors.NewUnauthorized("token expired"), FailureForbidden},
+		{"deadline", fmt.Errorf("list: %w", context.DeadlineExceeded), FailureTimeout},
+		{"server timeout", apierrors.NewServerTimeout(gvr.GroupResource(), "list", 1), FailureTimeout},
+		{"other", errors.New("connection refused"), FailureOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failure := newListFailure(gvr, tt.err)
+			if failure.Reason != tt.want {
+				t.Errorf("reason = %s, want %s", failure.Reason, tt.want)
+			}
+			if failure.Message != tt.err.Error() {
+				t.Errorf("message = %q, want the error", failure.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"crossplane-ai/internal/config"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MultiClient fans requests out to one Client per kubeconfig context and
+// merges the results. Every resource is tagged with its cluster.
+type MultiClient struct {
+	clients []*Client
+	// failures are the contexts no client could be created for
+	failures []ListFailure
+}
+
+// NewMultiClient creates a client for each of the given kubeconfig
+// contexts, with the remaining settings resolved as in NewClientFromConfig.
+// Contexts that cannot be loaded are reported by ListResources; an error is
+// only returned when none can.
+func NewMultiClient(ctx context.Context, cfg *config.Config, opts ClientOptions, contexts []string) (*MultiClient, error) {
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("no kubeconfig contexts given")
+	}
+
+	m := &MultiClient{}
+	for _, name := range contexts {
+		clusterOpts := opts
+		clusterOpts.Context = name
+
+		client, err := NewClientFromConfig(ctx, cfg, clusterOpts)
+		if err != nil {
+			m.failures = append(m.failures, ListFailure{Cluster: name, Reason: FailureOther, Message: err.Error()})
+			continue
+		}
+		m.clients = append(m.clients, client)
+	}
+
+	if len(m.clients) == 0 {
+		return nil, fmt.Errorf("no cluster could be loaded: %s", m.failures[0])
+	}
+
+	return m, nil
+}
+
+// Clients returns the client of each cluster that could be loaded
+func (m *MultiClient) Clients() []*Client {
+	return m.clients
+}
+
+// Clusters returns the names of the clusters that could be loaded
+func (m *MultiClient) Clusters() []string {
+	names := make([]string, len(m.clients))
+	for i, client := range m.clients {
+		names[i] = client.cluster
+	}
+	return names
+}
+
+// ListResources lists the resources matching the filter in every cluster
+// concurrently. A cluster that cannot be reached is reported as a failure
+// without a GVR; the other clusters' results are still returned.
+func (m *MultiClient) ListResources(ctx context.Context, filter ResourceFilter) (*ListResult, error) {
+	if _, err := filter.compile(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	results := make([]*ListResult, len(m.clients))
+	errs := make([]error, len(m.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range m.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			results[i], errs[i] = client.ListResources(ctx, filter)
+		}(i, client)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := &ListResult{Failures: append([]ListFailure{}, m.failures...)}
+	for i, client := range m.clients {
+		if errs[i] != nil {
+			failure := newListFailure(schema.GroupVersionResource{}, errs[i])
+			failure.Cluster = client.cluster
+			merged.Failures = append(merged.Failures, failure)
+			continue
+		}
+		merged.Resources = append(merged.Resources, results[i].Resources...)
+		merged.Types += results[i].Types
+		for _, failure := range results[i].Failures {
+			failure.Cluster = client.cluster
+			merged.Failures = append(merged.Failures, failure)
+		}
+	}
+	merged.Duration = time.Since(start)
+
+	return merged, nil
+}
+
+// AttachEvents fetches events for up to limit resources that are not
+// healthy, each from the cluster it belongs to
+func (m *MultiClient) AttachEvents(ctx context.Context, resources []*Resource, limit int) {
+	clients := make(map[string]*Client, len(m.clients))
+	for _, client := range m.clients {
+		clients[client.cluster] = client
+	}
+
+	attached := 0
+	for _, resource := range resources {
+		if attached >= limit {
+			return
+		}
+		client, ok := clients[resource.Cluster]
+		if !ok || !NeedsAttention(resource) {
+			continue
+		}
+
+		events, err := client.GetEvents(ctx, resource)
+		if err != nil {
+			continue
+		}
+		resource.Events = events
+		attached++
+	}
+}
+
+// ProviderConfigReport builds the ProviderConfig report of every cluster.
+// A cluster whose report fails is recorded as a failure without a GVR.
+func (m *MultiClient) ProviderConfigReport(ctx context.Context) (*ProviderConfigReport, error) {
+	reports := make([]*ProviderConfigReport, len(m.clients))
+	errs := make([]error, len(m.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range m.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			reports[i], errs[i] = client.ProviderConfigReport(ctx)
+		}(i, client)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := &ProviderConfigReport{Configs: []*ProviderConfig{}, Failures: append([]ListFailure{}, m.failures...)}
+	for i, client := range m.clients {
+		if errs[i] != nil {
+			failure := newListFailure(schema.GroupVersionResource{}, errs[i])
+			failure.Cluster = client.cluster
+			merged.Failures = append(merged.Failures, failure)
+			continue
+		}
+		merged.Configs = append(merged.Configs, reports[i].Configs...)
+		merged.Dangling = append(merged.Dangling, reports[i].Dangling...)
+		merged.Failures = append(merged.Failures, reports[i].Failures...)
+	}
+
+	return merged, nil
+}
+
+// FunctionReport builds the function report of every cluster. A cluster
+// whose report fails is recorded as a failure without a GVR.
+func (m *MultiClient) FunctionReport(ctx context.Context) (*FunctionReport, error) {
+	reports := make([]*FunctionReport, len(m.clients))
+	errs := make([]error, len(m.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range m.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			reports[i], errs[i] = client.FunctionReport(ctx)
+		}(i, client)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := &FunctionReport{Functions: []*PackageInfo{}, Compositions: []*CompositionInfo{}, Failures: append([]ListFailure{}, m.failures...)}
+	for i, client := range m.clients {
+		if errs[i] != nil {
+			failure := newListFailure(schema.GroupVersionResource{}, errs[i])
+			failure.Cluster = client.cluster
+			merged.Failures = append(merged.Failures, failure)
+			continue
+		}
+		merged.Functions = append(merged.Functions, reports[i].Functions...)
+		merged.Compositions = append(merged.Compositions, reports[i].Compositions...)
+		merged.Missing = append(merged.Missing, reports[i].Missing...)
+		merged.Failures = append(merged.Failures, reports[i].Failures...)
+	}
+
+	return merged, nil
+}
+
+// DeletionReport builds the deletion report of every cluster. A cluster
+// whose report fails is recorded as a failure without a GVR.
+func (m *MultiClient) DeletionReport(ctx context.Context) (*DeletionReport, error) {
+	reports := make([]*DeletionReport, len(m.clients))
+	errs := make([]error, len(m.clients))
+
+	var wg sync.WaitGroup
+	for i, client := range m.clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			reports[i], errs[i] = client.DeletionReport(ctx)
+		}(i, client)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := &DeletionReport{Terminating: []*TerminatingResource{}, Failures: append([]ListFailure{}, m.failures...)}
+	for i, client := range m.clients {
+		if errs[i] != nil {
+			failure := newListFailure(schema.GroupVersionResource{}, errs[i])
+			failure.Cluster = client.cluster
+			merged.Failures = append(merged.Failures, failure)
+			continue
+		}
+		merged.Terminating = append(merged.Terminating, reports[i].Terminating...)
+		merged.Failures = append(merged.Failures, reports[i].Failures...)
+	}
+
+	return merged, nil
+}
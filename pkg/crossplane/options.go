@@ -0,0 +1,102 @@
+package crossplane
+
+import (
+	"context"
+	"strings"
+
+	"crossplane-ai/internal/config"
+)
+
+// NewClientFromConfig creates a Crossplane client from the loaded
+// configuration. Settings are resolved in this order, first match wins:
+//
+//  1. opts, normally filled from command-line flags
+//  2. cfg, i.e. the kubernetes.* and crossplane.* keys of the config file
+//  3. the KUBECONFIG environment variable, ~/.kube/config and the
+//     kubeconfig's current context; resources are listed across all
+//     namespaces and from every provider
+//
+// With opts.Source the client reads from that source instead, and with
+// opts.Snapshot from the snapshot file.
+func NewClientFromConfig(ctx context.Context, cfg *config.Config, opts ClientOptions) (*Client, error) {
+	if cfg != nil {
+		if opts.Kubeconfig == "" {
+			opts.Kubeconfig = cfg.Kubernetes.Kubeconfig
+		}
+		if opts.Context == "" {
+			opts.Context = cfg.Kubernetes.Context
+		}
+		if opts.Namespace == "" {
+			opts.Namespace = cfg.Kubernetes.Namespace
+		}
+		if opts.Providers == nil {
+			opts.Providers = cfg.Crossplane.Providers
+		}
+		if opts.ResourceTypes == nil {
+			opts.ResourceTypes = cfg.Crossplane.ResourceTypes
+		}
+	}
+
+	if opts.Source != nil {
+		return NewClientFromSource(opts.Source, opts), nil
+	}
+	if opts.Snapshot != "" {
+		snapshot, err := LoadSnapshot(opts.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return NewSnapshotClient(snapshot, opts)
+	}
+	return NewClientWithOptions(ctx, opts)
+}
+
+// Namespace returns the namespace namespaced resources are listed from, or
+// "" when all namespaces are listed
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// Cluster returns the name of the kubeconfig context the client talks to
+func (c *Client) Cluster() string {
+	return c.cluster
+}
+
+// includes reports whether a discovered type passes the configured
+// provider and resource type include lists. An empty list includes
+// everything. The provider list only applies to managed resources, so that
+// core types, composites and claims stay visible.
+func (c *Client) includes(t DiscoveredType) bool {
+	if len(c.resourceTypes) > 0 && !matchesResourceType(t, c.resourceTypes) {
+		return false
+	}
+
+	if len(c.providers) > 0 && t.Category == CategoryManaged {
+		provider := ProviderFamily(t.GVR.Group)
+		found := false
+		for _, p := range c.providers {
+			if strings.EqualFold(p, provider) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesResourceType matches a type by plural name ("buckets"), kind
+// ("Bucket") or fully qualified plural ("buckets.s3.aws.upbound.io")
+func matchesResourceType(t DiscoveredType, resourceTypes []string) bool {
+	qualified := t.GVR.GroupResource().String()
+	for _, rt := range resourceTypes {
+		if strings.EqualFold(rt, t.GVR.Resource) ||
+			strings.EqualFold(rt, t.Kind) ||
+			strings.EqualFold(rt, qualified) {
+			return true
+		}
+	}
+	return false
+}
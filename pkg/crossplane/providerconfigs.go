@@ -0,0 +1,326 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// Kinds of the configuration types installed by provider families.
+// Namespaced (v2) providers install both; older providers only the first.
+const (
+	KindProviderConfig        = "ProviderConfig"
+	KindClusterProviderConfig = "ClusterProviderConfig"
+)
+
+// defaultProviderConfig is the name a managed resource without a
+// providerConfigRef uses
+const defaultProviderConfig = "default"
+
+// ProviderConfig is a provider family's configuration object and the
+// managed resources that use it
+type ProviderConfig struct {
+	Cluster    string `json:"cluster,omitempty"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Provider is the provider family, e.g. aws
+	Provider string `json:"provider"`
+	// Source is spec.credentials.source, e.g. Secret, IRSA or InjectedIdentity
+	Source string `json:"source,omitempty"`
+	// Secret is the credentials secret check, set when Source is Secret
+	Secret *SecretCheck               `json:"secret,omitempty"`
+	Users  []ObjectRef                `json:"users"`
+	Raw    *unstructured.Unstructured `json:"-"`
+}
+
+// ID returns the config as "Kind/name", prefixed with its namespace
+func (pc *ProviderConfig) ID() string {
+	if pc.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", pc.Namespace, pc.Kind, pc.Name)
+	}
+	return fmt.Sprintf("%s/%s", pc.Kind, pc.Name)
+}
+
+// SecretCheck is the result of looking up a credentials secret. Only the
+// existence of the secret and the names of its keys are recorded; secret
+// values are never kept, printed or sent to a language model.
+type SecretCheck struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+	Exists    bool   `json:"exists"`
+	// KeyExists reports whether Key is present; true when no key is named
+	KeyExists bool     `json:"keyExists"`
+	Keys      []string `json:"keys,omitempty"`
+	// Error is set when the secret could not be checked, e.g. forbidden
+	Error string `json:"error,omitempty"`
+}
+
+// OK reports whether the secret and the referenced key exist
+func (s *SecretCheck) OK() bool {
+	return s.Error == "" && s.Exists && s.KeyExists
+}
+
+// ObjectRef identifies a resource in a ProviderConfig report
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// String renders the reference as "Kind/name", prefixed with its namespace
+func (r ObjectRef) String() string {
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", r.Namespace, r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+}
+
+// DanglingReference is a managed resource referencing a ProviderConfig
+// that does not exist
+type DanglingReference struct {
+	Cluster  string    `json:"cluster,omitempty"`
+	Resource ObjectRef `json:"resource"`
+	// Config is the missing config as Kind/name
+	Config string `json:"config"`
+}
+
+// ProviderConfigReport maps every ProviderConfig to the managed resources
+// that use it and records references that lead nowhere
+type ProviderConfigReport struct {
+	Configs  []*ProviderConfig   `json:"configs"`
+	Dangling []DanglingReference `json:"dangling,omitempty"`
+	// Failures are the config types or resource types that could not be
+	// listed; the report is incomplete when there are any
+	Failures []ListFailure `json:"failures,omitempty"`
+}
+
+// DiscoverProviderConfigTypes returns the ProviderConfig and
+// ClusterProviderConfig types of every installed provider family
+func (c *Client) DiscoverProviderConfigTypes(ctx context.Context) ([]DiscoveredType, error) {
+	lists, err := c.cachedDiscovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover API resources: %w", err)
+	}
+
+	var types []DiscoveredType
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range list.APIResources {
+			if apiResource.Kind != KindProviderConfig && apiResource.Kind != KindClusterProviderConfig {
+				continue
+			}
+			if strings.Contains(apiResource.Name, "/") || !hasVerb(apiResource.Verbs, "list") {
+				continue
+			}
+			types = append(types, DiscoveredType{
+				GVR:        gv.WithResource(apiResource.Name),
+				Kind:       apiResource.Kind,
+				Namespaced: apiResource.Namespaced,
+				Category:   CategoryCore,
+			})
+		}
+	}
+
+	sort.Slice(types, func(i, j int) bool { return types[i].GVR.String() < types[j].GVR.String() })
+	return types, nil
+}
+
+// ProviderConfigReport lists every ProviderConfig, links the managed
+// resources to the configs named by their spec.providerConfigRef and checks
+// that the credentials secret of each config and its key exist
+func (c *Client) ProviderConfigReport(ctx context.Context) (*ProviderConfigReport, error) {
+	configTypes, err := c.DiscoverProviderConfigTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ProviderConfigReport{Configs: []*ProviderConfig{}}
+	var listed []DiscoveredType
+	for _, t := range configTypes {
+		// Configs are listed in all namespaces; a managed resource may use
+		// a config outside the namespace the client is scoped to
+		list, err := c.dynamicClient.Resource(t.GVR).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			failure := newListFailure(t.GVR, err)
+			failure.Cluster = c.cluster
+			report.Failures = append(report.Failures, failure)
+			continue
+		}
+		listed = append(listed, t)
+		for i := range list.Items {
+			report.Configs = append(report.Configs, c.newProviderConfig(&list.Items[i], t))
+		}
+	}
+
+	resources, err := c.ListAllResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, failure := range resources.Failures {
+		failure.Cluster = c.cluster
+		report.Failures = append(report.Failures, failure)
+	}
+
+	for _, resource := range resources.Resources {
+		if resource.Category != CategoryManaged {
+			continue
+		}
+		kind, name, namespace := providerConfigRef(resource)
+		group := resourceGroup(resource.APIVersion)
+
+		config := findProviderConfig(report.Configs, group, kind, name, namespace)
+		ref := ObjectRef{APIVersion: resource.APIVersion, Kind: resource.Kind, Name: resource.Name, Namespace: resource.Namespace}
+		if config == nil {
+			// Without a listed config type for the family the configs are
+			// unknown; that is not a dangling reference
+			if hasProviderConfigType(listed, group) {
+				report.Dangling = append(report.Dangling, DanglingReference{
+					Cluster:  c.cluster,
+					Resource: ref,
+					Config:   kind + "/" + name,
+				})
+			}
+			continue
+		}
+		config.Users = append(config.Users, ref)
+	}
+
+	for _, config := range report.Configs {
+		if config.Source == "Secret" {
+			config.Secret = c.checkCredentialsSecret(ctx, config)
+		}
+	}
+
+	return report, nil
+}
+
+func (c *Client) newProviderConfig(obj *unstructured.Unstructured, t DiscoveredType) *ProviderConfig {
+	config := &ProviderConfig{
+		Cluster:    c.cluster,
+		APIVersion: t.GVR.GroupVersion().String(),
+		Kind:       t.Kind,
+		Name:       obj.GetName(),
+		Namespace:  obj.GetNamespace(),
+		Provider:   ProviderFamily(t.GVR.Group),
+		Users:      []ObjectRef{},
+		Raw:        obj,
+	}
+	config.Source, _, _ = unstructured.NestedString(obj.Object, "spec", "credentials", "source")
+	return config
+}
+
+// checkCredentialsSecret looks up the secret named by
+// spec.credentials.secretRef. Only metadata and key names are read from
+// the returned object.
+func (c *Client) checkCredentialsSecret(ctx context.Context, config *ProviderConfig) *SecretCheck {
+	ref, found, _ := unstructured.NestedStringMap(config.Raw.Object, "spec", "credentials", "secretRef")
+	check := &SecretCheck{Namespace: ref["namespace"], Name: ref["name"], Key: ref["key"]}
+	if !found || check.Name == "" {
+		check.Error = "spec.credentials.secretRef is not set"
+		return check
+	}
+	// Namespaced configs may leave the namespace of the secret out
+	if check.Namespace == "" {
+		check.Namespace = config.Namespace
+	}
+
+	secret, err := c.kubeClient.CoreV1().Secrets(check.Namespace).Get(ctx, check.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		return check
+	case err != nil:
+		check.Error = fmt.Sprintf("cannot be checked: %v", err)
+		return check
+	}
+
+	check.Exists = true
+	for key := range secret.Data {
+		check.Keys = append(check.Keys, key)
+	}
+	sort.Strings(check.Keys)
+
+	check.KeyExists = check.Key == ""
+	for _, key := range check.Keys {
+		if key == check.Key {
+			check.KeyExists = true
+		}
+	}
+	return check
+}
+
+// providerConfigRef returns the config a managed resource uses. Crossplane
+// defaults the reference to the ProviderConfig named "default"; namespaced
+// managed resources look up a ProviderConfig in their own namespace.
+func providerConfigRef(resource *Resource) (kind, name, namespace string) {
+	kind, name = KindProviderConfig, defaultProviderConfig
+	if resource.Raw != nil {
+		if ref, found, _ := unstructured.NestedStringMap(resource.Raw.Object, "spec", "providerConfigRef"); found {
+			if ref["name"] != "" {
+				name = ref["name"]
+			}
+			if ref["kind"] != "" {
+				kind = ref["kind"]
+			}
+		}
+	}
+	if kind == KindProviderConfig {
+		namespace = resource.Namespace
+	}
+	return kind, name, namespace
+}
+
+// findProviderConfig finds the config of the family a managed resource
+// group belongs to. The family's group is the longest config group the
+// resource group ends with, e.g. aws.upbound.io for s3.aws.upbound.io.
+func findProviderConfig(configs []*ProviderConfig, group, kind, name, namespace string) *ProviderConfig {
+	var best *ProviderConfig
+	bestLen := 0
+	for _, config := range configs {
+		if config.Kind != kind || config.Name != name || config.Namespace != namespace {
+			continue
+		}
+		configGroup := resourceGroup(config.APIVersion)
+		if !belongsToFamily(group, configGroup) {
+			continue
+		}
+		if len(configGroup) > bestLen {
+			best, bestLen = config, len(configGroup)
+		}
+	}
+	return best
+}
+
+func hasProviderConfigType(types []DiscoveredType, group string) bool {
+	for _, t := range types {
+		if belongsToFamily(group, t.GVR.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+func belongsToFamily(group, familyGroup string) bool {
+	return group == familyGroup || strings.HasSuffix(group, "."+familyGroup)
+}
+
+// resourceGroup returns the group of an apiVersion, "" for the core group
+func resourceGroup(apiVersion string) string {
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
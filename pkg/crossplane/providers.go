@@ -0,0 +1,279 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// packageGroup is the API group of providers, configurations,
+	// functions and their revisions
+	packageGroup = "pkg.crossplane.io"
+	// packageLabel is set by Crossplane on package revisions to the name of
+	// the package they belong to
+	packageLabel = "pkg.crossplane.io/package"
+)
+
+var (
+	providersGVR         = schema.GroupVersionResource{Group: packageGroup, Version: "v1", Resource: "providers"}
+	providerRevisionsGVR = schema.GroupVersionResource{Group: packageGroup, Version: "v1", Resource: "providerrevisions"}
+	functionsGVR         = schema.GroupVersionResource{Group: packageGroup, Version: "v1", Resource: "functions"}
+	functionRevisionsGVR = schema.GroupVersionResource{Group: packageGroup, Version: "v1", Resource: "functionrevisions"}
+)
+
+// PackageInfo is the inventory entry of an installed provider or function
+// package
+type PackageInfo struct {
+	Cluster string `json:"cluster,omitempty"`
+	// Kind is Provider or Function
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Package is the package reference without the tag or digest, e.g.
+	// xpkg.upbound.io/upbound/provider-aws-s3
+	Package string `json:"package"`
+	// Version is the tag or digest of the package reference
+	Version string `json:"version"`
+	// Installed and Healthy are the status of the package conditions
+	Installed string `json:"installed"`
+	Healthy   string `json:"healthy"`
+	// Reason and Message explain the first condition that is not True
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// RuntimeConfig is the DeploymentRuntimeConfig, or the deprecated
+	// ControllerConfig, the package runs with, as Kind/name
+	RuntimeConfig   string            `json:"runtimeConfig,omitempty"`
+	CurrentRevision string            `json:"currentRevision,omitempty"`
+	Revisions       []PackageRevision `json:"revisions,omitempty"`
+	// CRDs is the number of CRDs owned by the active revision
+	CRDs      int       `json:"crds"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	Resource *Resource `json:"-"`
+}
+
+// PackageRevision is one revision of a provider or function package
+type PackageRevision struct {
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	Revision int64  `json:"revision"`
+	// DesiredState is Active or Inactive
+	DesiredState string `json:"desiredState"`
+	Healthy      string `json:"healthy"`
+	Reason       string `json:"reason,omitempty"`
+	Message      string `json:"message,omitempty"`
+	CRDs         int    `json:"crds"`
+
+	// crdNames are the names of the CRDs the revision owns, e.g.
+	// instances.rds.aws.upbound.io
+	crdNames []string
+}
+
+// IsHealthy reports whether the package is installed and healthy
+func (p *PackageInfo) IsHealthy() bool {
+	return p.Installed == "True" && p.Healthy == "True"
+}
+
+// ActiveRevision returns the revision that is currently active, or nil
+func (p *PackageInfo) ActiveRevision() *PackageRevision {
+	for i := range p.Revisions {
+		if p.Revisions[i].DesiredState == "Active" {
+			return &p.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// InactiveRevisions returns the number of revisions kept for rollback
+func (p *PackageInfo) InactiveRevisions() int {
+	inactive := 0
+	for _, revision := range p.Revisions {
+		if revision.DesiredState != "Active" {
+			inactive++
+		}
+	}
+	return inactive
+}
+
+// ListProviders returns the inventory of installed providers, sorted by
+// name. When the revisions cannot be listed the providers are still
+// returned, without revision details, together with the error.
+func (c *Client) ListProviders(ctx context.Context) ([]*PackageInfo, error) {
+	return c.listPackages(ctx, "Provider", providersGVR, providerRevisionsGVR)
+}
+
+// ListFunctions returns the inventory of installed composition functions,
+// sorted by name, like ListProviders
+func (c *Client) ListFunctions(ctx context.Context) ([]*PackageInfo, error) {
+	return c.listPackages(ctx, "Function", functionsGVR, functionRevisionsGVR)
+}
+
+func (c *Client) listPackages(ctx context.Context, kind string, gvr, revisionsGVR schema.GroupVersionResource) ([]*PackageInfo, error) {
+	plural := strings.ToLower(kind) + "s"
+	resources, err := c.getResourcesOfType(ctx, gvr, CategoryCore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", plural, err)
+	}
+
+	packages := make([]*PackageInfo, 0, len(resources))
+	for _, resource := range resources {
+		info := newPackageInfo(resource)
+		info.Kind = kind
+		packages = append(packages, info)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+
+	revisions, err := c.dynamicClient.Resource(revisionsGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return packages, fmt.Errorf("failed to list %s revisions: %w", strings.ToLower(kind), err)
+	}
+
+	byPackage := make(map[string][]PackageRevision)
+	for i := range revisions.Items {
+		obj := &revisions.Items[i]
+		owner := revisionOwner(obj, kind)
+		if owner == "" {
+			continue
+		}
+		byPackage[owner] = append(byPackage[owner], newPackageRevision(obj))
+	}
+
+	for _, pkg := range packages {
+		pkg.Revisions = byPackage[pkg.Name]
+		sort.Slice(pkg.Revisions, func(i, j int) bool {
+			return pkg.Revisions[i].Revision > pkg.Revisions[j].Revision
+		})
+		if active := pkg.ActiveRevision(); active != nil {
+			pkg.CRDs = active.CRDs
+		}
+	}
+
+	return packages, nil
+}
+
+func newPackageInfo(resource *Resource) *PackageInfo {
+	info := &PackageInfo{
+		Cluster:   resource.Cluster,
+		Name:      resource.Name,
+		CreatedAt: resource.CreatedAt,
+		Resource:  resource,
+	}
+
+	if resource.Raw != nil {
+		obj := resource.Raw.Object
+		ref, _, _ := unstructured.NestedString(obj, "spec", "package")
+		info.Package, info.Version = splitPackageRef(ref)
+		info.CurrentRevision, _, _ = unstructured.NestedString(obj, "status", "currentRevision")
+
+		if name, _, _ := unstructured.NestedString(obj, "spec", "runtimeConfigRef", "name"); name != "" {
+			info.RuntimeConfig = "DeploymentRuntimeConfig/" + name
+		} else if name, _, _ := unstructured.NestedString(obj, "spec", "controllerConfigRef", "name"); name != "" {
+			info.RuntimeConfig = "ControllerConfig/" + name
+		}
+	}
+
+	info.Installed = conditionStatus(resource.GetCondition(ConditionInstalled))
+	info.Healthy = conditionStatus(resource.GetCondition(ConditionHealthy))
+	if condition := resource.PackageProblem(); condition != nil {
+		info.Reason, info.Message = condition.Reason, condition.Message
+	}
+
+	return info
+}
+
+func newPackageRevision(obj *unstructured.Unstructured) PackageRevision {
+	revision := PackageRevision{Name: obj.GetName(), Healthy: "Unknown"}
+	revision.Image, _, _ = unstructured.NestedString(obj.Object, "spec", "image")
+	revision.Revision, _, _ = unstructured.NestedInt64(obj.Object, "spec", "revision")
+	revision.DesiredState, _, _ = unstructured.NestedString(obj.Object, "spec", "desiredState")
+
+	// Revisions report Healthy, or RevisionHealthy and RuntimeHealthy in
+	// newer Crossplane versions; the revision is healthy when all are True
+	for _, condition := range parseConditions(obj) {
+		if !strings.HasSuffix(condition.Type, ConditionHealthy) {
+			continue
+		}
+		if !condition.IsTrue() {
+			revision.Healthy = condition.Status
+			revision.Reason, revision.Message = condition.Reason, condition.Message
+			break
+		}
+		revision.Healthy = "True"
+	}
+
+	refs, _, _ := unstructured.NestedSlice(obj.Object, "status", "objectRefs")
+	for _, ref := range refs {
+		if m, ok := ref.(map[string]interface{}); ok && m["kind"] == "CustomResourceDefinition" {
+			revision.CRDs++
+			if name, ok := m["name"].(string); ok {
+				revision.crdNames = append(revision.crdNames, name)
+			}
+		}
+	}
+
+	return revision
+}
+
+// revisionOwner returns the name of the package of the given kind a
+// revision belongs to, from the package label or else the owner reference
+func revisionOwner(obj *unstructured.Unstructured, kind string) string {
+	if name := obj.GetLabels()[packageLabel]; name != "" {
+		return name
+	}
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.Kind == kind {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// splitPackageRef splits a package reference such as
+// xpkg.upbound.io/upbound/provider-aws-s3:v1.1.0 into the repository and
+// the tag or digest
+func splitPackageRef(ref string) (string, string) {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		return ref[:i], ref[i+1:]
+	}
+	// A colon before the last slash belongs to a registry port
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// PackageVersion returns the tag or digest of a package's spec.package,
+// or "" for resources that are not packages
+func (r *Resource) PackageVersion() string {
+	if r.Raw == nil || resourceGroup(r.APIVersion) != packageGroup {
+		return ""
+	}
+	ref, _, _ := unstructured.NestedString(r.Raw.Object, "spec", "package")
+	_, version := splitPackageRef(ref)
+	return version
+}
+
+// PackageProblem returns the first of a package's Installed and Healthy
+// conditions that is not True, or nil when both are. Providers,
+// configurations and functions report these instead of Ready.
+func (r *Resource) PackageProblem() *Condition {
+	for _, conditionType := range []string{ConditionInstalled, ConditionHealthy} {
+		if condition := r.GetCondition(conditionType); condition != nil && !condition.IsTrue() {
+			return condition
+		}
+	}
+	return nil
+}
+
+func conditionStatus(condition *Condition) string {
+	if condition == nil {
+		return "Unknown"
+	}
+	return condition.Status
+}
@@ -0,0 +1,317 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/discovery"
+)
+
+// Domains that provider API groups are published under
+const (
+	upboundDomain    = "upbound.io"
+	crossplaneDomain = "crossplane.io"
+)
+
+// namespacedGroupLabel marks the API groups of Crossplane v2 namespaced
+// managed resources, e.g. s3.aws.m.upbound.io next to s3.aws.upbound.io
+const namespacedGroupLabel = "m"
+
+// coreGroups are the API groups of Crossplane itself under crossplane.io
+var coreGroups = map[string]bool{
+	"apiextensions": true,
+	"pkg":           true,
+	"ops":           true,
+	"protection":    true,
+}
+
+// FamilyCrossplane is the family reported for Crossplane's own API groups
+const FamilyCrossplane = "crossplane"
+
+// APIGroup is what the registry knows about an API group
+type APIGroup struct {
+	Group string `json:"group"`
+	// Family is the provider family, e.g. aws for ec2.aws.upbound.io and
+	// kubernetes for kubernetes.crossplane.io
+	Family string `json:"family"`
+	// Service is the cloud service within the family, e.g. ec2; empty for
+	// the family's own group (aws.upbound.io)
+	Service string `json:"service,omitempty"`
+	// Domain is the publisher domain, e.g. upbound.io or crossplane.io
+	Domain string `json:"domain"`
+	// Namespaced is set for Crossplane v2 groups (*.m.upbound.io,
+	// *.m.crossplane.io) that serve namespaced managed resources
+	Namespaced bool `json:"namespaced"`
+	// Core is set for Crossplane's own groups (apiextensions, pkg, ...)
+	Core bool `json:"core"`
+}
+
+// ParseAPIGroup identifies the provider family of an API group. Groups are
+// read as [<service>.]<family>[.m][.jet].<domain>, which covers the Upbound
+// families (ec2.aws.upbound.io, s3.aws.m.upbound.io), the family groups
+// holding ProviderConfigs (aws.upbound.io), crossplane-contrib providers
+// (rds.aws.crossplane.io, kubernetes.crossplane.io, helm.m.crossplane.io),
+// Upjet-generated community providers (ec2.aws.jet.crossplane.io) and
+// providers published under their own domain.
+func ParseAPIGroup(group string) APIGroup {
+	info := APIGroup{Group: group}
+
+	labels := strings.Split(group, ".")
+	if len(labels) < 2 {
+		info.Family = group
+		return info
+	}
+	info.Domain = strings.Join(labels[len(labels)-2:], ".")
+	rest := labels[:len(labels)-2]
+
+	if info.Domain == crossplaneDomain && len(rest) == 1 && coreGroups[rest[0]] {
+		info.Family = FamilyCrossplane
+		info.Core = true
+		return info
+	}
+
+	if n := len(rest); n > 1 && rest[n-1] == "jet" {
+		rest = rest[:n-1]
+	}
+	if n := len(rest); n > 1 && rest[n-1] == namespacedGroupLabel {
+		info.Namespaced = true
+		rest = rest[:n-1]
+	}
+
+	switch len(rest) {
+	case 0:
+		// A group that is only a domain names the family itself
+		info.Family = labels[0]
+	case 1:
+		info.Family = rest[0]
+	default:
+		info.Family = rest[len(rest)-1]
+		info.Service = strings.Join(rest[:len(rest)-1], ".")
+	}
+	return info
+}
+
+// ProviderFamily returns the provider family of an API group, e.g. aws for
+// ec2.aws.upbound.io and rds.aws.m.upbound.io, and crossplane for
+// Crossplane's own groups
+func ProviderFamily(group string) string {
+	return ParseAPIGroup(group).Family
+}
+
+// PackageFamily returns the provider family of a provider package, e.g.
+// aws for xpkg.upbound.io/upbound/provider-aws-s3:v1,
+// xpkg.upbound.io/upbound/provider-family-aws:v1 and
+// xpkg.crossplane.io/crossplane-contrib/provider-upjet-aws:v1, or "" for
+// packages not named provider-<family>[-<service>]
+func PackageFamily(ref string) string {
+	name, _ := splitPackageRef(ref)
+	name = name[strings.LastIndex(name, "/")+1:]
+	rest, ok := strings.CutPrefix(name, "provider-")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimPrefix(rest, "family-")
+	rest = strings.TrimPrefix(rest, "upjet-")
+	family, _, _ := strings.Cut(rest, "-")
+	return family
+}
+
+// Roles of the resources manifests are generated for
+const (
+	APIPostgres      = "postgres"
+	APIMySQL         = "mysql"
+	APIBucket        = "bucket"
+	APINetwork       = "network"
+	APISubnet        = "subnet"
+	APIInstance      = "instance"
+	APILoadBalancer  = "loadbalancer"
+	APIResourceGroup = "resourcegroup"
+)
+
+// DefaultFamily is the family manifests are generated for when none is
+// given and the cluster serves none of the known families
+const DefaultFamily = "aws"
+
+// knownAPI is a managed resource of an Upbound provider family. Version is
+// the version the templates are written for.
+type knownAPI struct {
+	service string
+	kind    string
+	version string
+}
+
+// knownAPIs lists the managed resources generate knows per provider family
+var knownAPIs = map[string]map[string]knownAPI{
+	"aws": {
+		APIPostgres:     {service: "rds", kind: "Instance", version: "v1beta1"},
+		APIMySQL:        {service: "rds", kind: "Instance", version: "v1beta1"},
+		APIBucket:       {service: "s3", kind: "Bucket", version: "v1beta1"},
+		APINetwork:      {service: "ec2", kind: "VPC", version: "v1beta1"},
+		APISubnet:       {service: "ec2", kind: "Subnet", version: "v1beta1"},
+		APIInstance:     {service: "ec2", kind: "Instance", version: "v1beta1"},
+		APILoadBalancer: {service: "elbv2", kind: "LB", version: "v1beta1"},
+	},
+	"gcp": {
+		APIPostgres: {service: "sql", kind: "DatabaseInstance", version: "v1beta1"},
+		APIMySQL:    {service: "sql", kind: "DatabaseInstance", version: "v1beta1"},
+		APIBucket:   {service: "storage", kind: "Bucket", version: "v1beta1"},
+		APINetwork:  {service: "compute", kind: "Network", version: "v1beta1"},
+		APISubnet:   {service: "compute", kind: "Subnetwork", version: "v1beta1"},
+		APIInstance: {service: "compute", kind: "Instance", version: "v1beta1"},
+	},
+	"azure": {
+		APIResourceGroup: {kind: "ResourceGroup", version: "v1beta1"},
+		APIPostgres:      {service: "dbforpostgresql", kind: "FlexibleServer", version: "v1beta1"},
+		APIMySQL:         {service: "dbformysql", kind: "FlexibleServer", version: "v1beta1"},
+		APIBucket:        {service: "storage", kind: "Account", version: "v1beta1"},
+		APINetwork:       {service: "network", kind: "VirtualNetwork", version: "v1beta1"},
+		APISubnet:        {service: "network", kind: "Subnet", version: "v1beta1"},
+		APILoadBalancer:  {service: "network", kind: "LoadBalancer", version: "v1beta1"},
+	},
+}
+
+// KnownFamilies returns the provider families generate has templates for
+func KnownFamilies() []string {
+	var families []string
+	for family := range knownAPIs {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	return families
+}
+
+// APIRef is the apiVersion and kind a manifest is generated with
+type APIRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Namespaced is set for Crossplane v2 namespaced managed resources
+	Namespaced bool `json:"namespaced"`
+	// Served reports whether the cluster was seen serving the API
+	Served bool `json:"served"`
+}
+
+// String renders the reference as "apiVersion kind"
+func (r APIRef) String() string {
+	return r.APIVersion + " " + r.Kind
+}
+
+// APISet maps the roles of generated resources (APIBucket, ...) to the API
+// they are generated with
+type APISet map[string]APIRef
+
+// Served returns the number of APIs the cluster was seen serving
+func (s APISet) Served() int {
+	served := 0
+	for _, ref := range s {
+		if ref.Served {
+			served++
+		}
+	}
+	return served
+}
+
+// DefaultAPIs returns the cluster-scoped Upbound APIs of a family, used when
+// no cluster can be asked. Unknown families get no APIs.
+func DefaultAPIs(family string) APISet {
+	apis := APISet{}
+	for role, api := range knownAPIs[family] {
+		apis[role] = APIRef{
+			APIVersion: familyGroup(api.service, family, false) + "/" + api.version,
+			Kind:       api.kind,
+		}
+	}
+	return apis
+}
+
+// ResolveAPIs returns the APIs the cluster serves for a provider family's
+// generated resources. Crossplane v2 namespaced groups are preferred over
+// the cluster-scoped ones, and the version the templates are written for
+// over the group's preferred version. Roles the cluster does not serve keep
+// their defaults. With an empty family or "auto", the known family with
+// the most served APIs is used.
+func (c *Client) ResolveAPIs(ctx context.Context, family string) (string, APISet, error) {
+	if family == "auto" {
+		family = ""
+	}
+
+	groups, resources, err := c.cachedDiscovery.ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		if family == "" {
+			family = DefaultFamily
+		}
+		return family, DefaultAPIs(family), fmt.Errorf("failed to discover API resources: %w", err)
+	}
+
+	preferred := make(map[string]string)
+	for _, group := range groups {
+		preferred[group.Name] = group.PreferredVersion.Version
+	}
+	// served maps group/version to the kinds it serves and their scope
+	served := make(map[string]map[string]bool)
+	for _, list := range resources {
+		kinds := make(map[string]bool)
+		for _, apiResource := range list.APIResources {
+			if !strings.Contains(apiResource.Name, "/") {
+				kinds[apiResource.Kind] = apiResource.Namespaced
+			}
+		}
+		served[list.GroupVersion] = kinds
+	}
+
+	resolve := func(family string) APISet {
+		apis := DefaultAPIs(family)
+		for role, api := range knownAPIs[family] {
+			for _, namespaced := range []bool{true, false} {
+				group := familyGroup(api.service, family, namespaced)
+				versions := []string{api.version}
+				if v := preferred[group]; v != "" && v != api.version {
+					versions = append(versions, v)
+				}
+				if ref, ok := servedAPI(served, group, versions, api.kind); ok {
+					apis[role] = ref
+					break
+				}
+			}
+		}
+		return apis
+	}
+
+	if family != "" {
+		return family, resolve(family), nil
+	}
+
+	family, best := DefaultFamily, resolve(DefaultFamily)
+	for _, candidate := range KnownFamilies() {
+		if apis := resolve(candidate); apis.Served() > best.Served() {
+			family, best = candidate, apis
+		}
+	}
+	return family, best, nil
+}
+
+func servedAPI(served map[string]map[string]bool, group string, versions []string, kind string) (APIRef, bool) {
+	for _, version := range versions {
+		gv := group + "/" + version
+		namespaced, ok := served[gv][kind]
+		if !ok {
+			continue
+		}
+		return APIRef{APIVersion: gv, Kind: kind, Namespaced: namespaced, Served: true}, true
+	}
+	return APIRef{}, false
+}
+
+// familyGroup builds an Upbound API group, e.g. s3.aws.upbound.io or, for
+// namespaced managed resources, s3.aws.m.upbound.io
+func familyGroup(service, family string, namespaced bool) string {
+	labels := []string{family}
+	if service != "" {
+		labels = append([]string{service}, labels...)
+	}
+	if namespaced {
+		labels = append(labels, namespacedGroupLabel)
+	}
+	return strings.Join(append(labels, upboundDomain), ".")
+}
@@ -0,0 +1,50 @@
+package crossplane
+
+import "testing"
+
+func TestParseAPIGroup(t *testing.T) {
+	tests := []struct {
+		group string
+		want  APIGroup
+	}{
+		{"ec2.aws.upbound.io", APIGroup{Family: "aws", Service: "ec2", Domain: "upbound.io"}},
+		{"aws.upbound.io", APIGroup{Family: "aws", Domain: "upbound.io"}},
+		{"s3.aws.m.upbound.io", APIGroup{Family: "aws", Service: "s3", Domain: "upbound.io", Namespaced: true}},
+		{"rds.aws.crossplane.io", APIGroup{Family: "aws", Service: "rds", Domain: "crossplane.io"}},
+		{"ec2.aws.jet.crossplane.io", APIGroup{Family: "aws", Service: "ec2", Domain: "crossplane.io"}},
+		{"kubernetes.crossplane.io", APIGroup{Family: "kubernetes", Domain: "crossplane.io"}},
+		{"helm.m.crossplane.io", APIGroup{Family: "helm", Domain: "crossplane.io", Namespaced: true}},
+		{"pkg.crossplane.io", APIGroup{Family: FamilyCrossplane, Domain: "crossplane.io", Core: true}},
+		{"upbound.io", APIGroup{Family: "upbound", Domain: "upbound.io"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			tt.want.Group = tt.group
+			if got := ParseAPIGroup(tt.group); got != tt.want {
+				t.Errorf("ParseAPIGroup(%q) = %+v, want %+v", tt.group, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageFamily(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"xpkg.upbound.io/upbound/provider-aws-s3:v1.14.0", "aws"},
+		{"xpkg.upbound.io/upbound/provider-family-gcp:v1.8.0", "gcp"},
+		{"xpkg.crossplane.io/crossplane-contrib/provider-upjet-azure:v1", "azure"},
+		{"xpkg.crossplane.io/crossplane-contrib/provider-kubernetes@sha256:0123", "kubernetes"},
+		{"registry.example.org:5000/platform/provider-helm", "helm"},
+		{"xpkg.upbound.io/crossplane-contrib/function-patch-and-transform:v0.7.0", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := PackageFamily(tt.ref); got != tt.want {
+				t.Errorf("PackageFamily(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package crossplane
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// snapshotFormat is the version of the snapshot format written by
+// SaveSnapshot; LoadSnapshot rejects newer versions
+const snapshotFormat = 1
+
+// lastAppliedAnnotation is set by kubectl apply and repeats the whole
+// object, including the data of secrets
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// functionPodLabel is set by Crossplane on the pods of a function
+const functionPodLabel = "pkg.crossplane.io/function"
+
+// Snapshot is an offline copy of the Crossplane objects of a cluster. A
+// client created with NewSnapshotClient answers from it as if it were the
+// API server.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	Cluster   string    `json:"cluster"`
+	CreatedAt time.Time `json:"createdAt"`
+	// APIResources is the discovery information of the saved API groups,
+	// at their preferred version
+	APIResources []*metav1.APIResourceList `json:"apiResources"`
+	// Objects are the objects of every Crossplane, provider and XRD API
+	// type, and the CRDs of those types without their schemas
+	Objects []*unstructured.Unstructured `json:"objects"`
+	// Events are the core/v1 events about the saved objects and pods
+	Events []corev1.Event `json:"events,omitempty"`
+	// Pods are the provider and function pods, without their spec
+	Pods []corev1.Pod `json:"pods,omitempty"`
+	// Secrets are the credentials secrets of ProviderConfigs with their
+	// values removed; only the names of their keys are kept
+	Secrets []corev1.Secret `json:"secrets,omitempty"`
+	// Failures are the types that could not be saved
+	Failures []ListFailure `json:"failures,omitempty"`
+}
+
+// SaveSnapshot reads every object of the Crossplane core, provider and XRD
+// API groups together with their events, the provider and function pods
+// and the credentials secrets of ProviderConfigs. Secret values are never
+// read into the snapshot. Types that cannot be listed are recorded in
+// Failures.
+func (c *Client) SaveSnapshot(ctx context.Context) (*Snapshot, error) {
+	snapshot := &Snapshot{Version: snapshotFormat, Cluster: c.cluster, CreatedAt: time.Now().UTC()}
+
+	lists, err := c.cachedDiscovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("failed to discover API resources: %w", err)
+	}
+	groups, err := c.snapshotGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []DiscoveredType
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil || !groups[gv.Group] {
+			continue
+		}
+		saved := &metav1.APIResourceList{GroupVersion: list.GroupVersion}
+		for _, apiResource := range list.APIResources {
+			if strings.Contains(apiResource.Name, "/") || !hasVerb(apiResource.Verbs, "list") {
+				continue
+			}
+			saved.APIResources = append(saved.APIResources, apiResource)
+			types = append(types, DiscoveredType{GVR: gv.WithResource(apiResource.Name), Kind: apiResource.Kind, Namespaced: apiResource.Namespaced})
+		}
+		snapshot.APIResources = append(snapshot.APIResources, saved)
+	}
+
+	result, err := c.listTypes(ctx, types, listOptions{})
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Failures = result.Failures
+
+	uids := make(map[string]bool)
+	for _, resource := range result.Resources {
+		obj := resource.Raw.DeepCopy()
+		stripObject(obj)
+		snapshot.Objects = append(snapshot.Objects, obj)
+		uids[string(obj.GetUID())] = true
+	}
+
+	c.saveCRDs(ctx, snapshot, groups)
+	c.savePods(ctx, snapshot, uids)
+	c.saveEvents(ctx, snapshot, uids)
+	c.saveSecrets(ctx, snapshot)
+
+	return snapshot, nil
+}
+
+// snapshotGroups returns the API groups to save: Crossplane's own groups,
+// the groups of every discovered managed, composite and claim type and the
+// groups of CRDs owned by XRDs and provider revisions
+func (c *Client) snapshotGroups(ctx context.Context) (map[string]bool, error) {
+	discovered, err := c.DiscoverResourceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configTypes, err := c.DiscoverProviderConfigTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]bool)
+	for _, t := range append(discovered, configTypes...) {
+		groups[t.GVR.Group] = true
+	}
+	for gr := range c.crdOwners(ctx) {
+		groups[gr.Group] = true
+	}
+	for _, group := range c.serverGroups() {
+		if group == "crossplane.io" || strings.HasSuffix(group, ".crossplane.io") {
+			groups[group] = true
+		}
+	}
+	return groups, nil
+}
+
+func (c *Client) serverGroups() []string {
+	groups, err := c.cachedDiscovery.ServerGroups()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(groups.Groups))
+	for _, group := range groups.Groups {
+		names = append(names, group.Name)
+	}
+	return names
+}
+
+// saveCRDs adds the CRDs of the saved groups without their OpenAPI schemas,
+// which are only needed by the API server
+func (c *Client) saveCRDs(ctx context.Context, snapshot *Snapshot, groups map[string]bool) {
+	list, err := c.dynamicClient.Resource(crdGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		snapshot.Failures = append(snapshot.Failures, newListFailure(crdGVR, err))
+		return
+	}
+
+	snapshot.APIResources = append(snapshot.APIResources, &metav1.APIResourceList{
+		GroupVersion: crdGVR.GroupVersion().String(),
+		APIResources: []metav1.APIResource{{
+			Name:  crdGVR.Resource,
+			Kind:  "CustomResourceDefinition",
+			Verbs: metav1.Verbs{"get", "list", "watch"},
+		}},
+	})
+
+	for i := range list.Items {
+		crd := &list.Items[i]
+		if group, _, _ := unstructured.NestedString(crd.Object, "spec", "group"); !groups[group] {
+			continue
+		}
+		stripObject(crd)
+		if versions, found, _ := unstructured.NestedSlice(crd.Object, "spec", "versions"); found {
+			for _, version := range versions {
+				if m, ok := version.(map[string]interface{}); ok {
+					delete(m, "schema")
+				}
+			}
+			_ = unstructured.SetNestedSlice(crd.Object, versions, "spec", "versions")
+		}
+		snapshot.Objects = append(snapshot.Objects, crd)
+	}
+}
+
+// savePods adds the provider and function pods with their status, which
+// shows crash loops and image pull errors. The spec is dropped since it
+// may carry credentials in environment variables.
+func (c *Client) savePods(ctx context.Context, snapshot *Snapshot, uids map[string]bool) {
+	for _, label := range []string{providerPodLabel, functionPodLabel} {
+		pods, err := c.kubeClient.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: label})
+		if err != nil {
+			continue
+		}
+		for _, pod := range pods.Items {
+			snapshot.Pods = append(snapshot.Pods, corev1.Pod{
+				TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              pod.Name,
+					Namespace:         pod.Namespace,
+					UID:               pod.UID,
+					Labels:            pod.Labels,
+					OwnerReferences:   pod.OwnerReferences,
+					CreationTimestamp: pod.CreationTimestamp,
+				},
+				Status: pod.Status,
+			})
+			uids[string(pod.UID)] = true
+		}
+	}
+}
+
+// saveEvents adds the core/v1 events about the saved objects and pods.
+// events.k8s.io/v1 serves the same events, so it is not read.
+func (c *Client) saveEvents(ctx context.Context, snapshot *Snapshot, uids map[string]bool) {
+	events, err := c.kubeClient.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		snapshot.Failures = append(snapshot.Failures, newListFailure(corev1.SchemeGroupVersion.WithResource("events"), err))
+		return
+	}
+	for _, event := range events.Items {
+		if !uids[string(event.InvolvedObject.UID)] {
+			continue
+		}
+		event.ManagedFields = nil
+		snapshot.Events = append(snapshot.Events, event)
+	}
+	sort.SliceStable(snapshot.Events, func(i, j int) bool {
+		return snapshot.Events[i].LastTimestamp.Before(&snapshot.Events[j].LastTimestamp)
+	})
+}
+
+// saveSecrets adds the credentials secrets named by the saved
+// ProviderConfigs. Only the metadata needed to find them and the names of
+// their keys are kept; every value is replaced by an empty one.
+func (c *Client) saveSecrets(ctx context.Context, snapshot *Snapshot) {
+	seen := make(map[string]bool)
+	for _, obj := range snapshot.Objects {
+		if obj.GetKind() != KindProviderConfig && obj.GetKind() != KindClusterProviderConfig {
+			continue
+		}
+		ref, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "credentials", "secretRef")
+		if !found || ref["name"] == "" {
+			continue
+		}
+		namespace := ref["namespace"]
+		if namespace == "" {
+			namespace = obj.GetNamespace()
+		}
+		key := namespace + "/" + ref["name"]
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		secret, err := c.kubeClient.CoreV1().Secrets(namespace).Get(ctx, ref["name"], metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+		snapshot.Secrets = append(snapshot.Secrets, strippedSecret(secret))
+	}
+}
+
+// strippedSecret copies a secret without its values, annotations and
+// managed fields
+func strippedSecret(secret *corev1.Secret) corev1.Secret {
+	stripped := corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secret.Name,
+			Namespace: secret.Namespace,
+			UID:       secret.UID,
+		},
+		Type: secret.Type,
+		Data: make(map[string][]byte, len(secret.Data)+len(secret.StringData)),
+	}
+	for key := range secret.Data {
+		stripped.Data[key] = []byte{}
+	}
+	for key := range secret.StringData {
+		stripped.Data[key] = []byte{}
+	}
+	return stripped
+}
+
+// stripObject removes the managed fields and the last applied
+// configuration, which add nothing to the analysis
+func stripObject(obj *unstructured.Unstructured) {
+	obj.SetManagedFields(nil)
+	if annotations := obj.GetAnnotations(); annotations[lastAppliedAnnotation] != "" {
+		delete(annotations, lastAppliedAnnotation)
+		obj.SetAnnotations(annotations)
+	}
+}
+
+// WriteSnapshotFile writes a snapshot as JSON, gzip compressed when the
+// file name ends in .gz
+func WriteSnapshotFile(path string, snapshot *Snapshot) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var w io.Writer = file
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(file)
+		w = gz
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	return file.Close()
+}
+
+// LoadSnapshot reads a snapshot written by WriteSnapshotFile
+func LoadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	if snapshot.Version > snapshotFormat {
+		return nil, fmt.Errorf("snapshot %s has format version %d, this version of crossplane-ai reads up to %d",
+			path, snapshot.Version, snapshotFormat)
+	}
+	return &snapshot, nil
+}
+
+// NewSnapshotClient creates a read-only client that answers from a
+// snapshot instead of an API server. The namespace, provider and resource
+// type options apply as for a live client; applying manifests fails.
+func NewSnapshotClient(snapshot *Snapshot, opts ClientOptions) (*Client, error) {
+	source, err := NewSnapshotSource(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientFromSource(source, opts), nil
+}
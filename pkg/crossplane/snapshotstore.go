@@ -0,0 +1,153 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// snapshotStore serves the objects of a snapshot or fake through the dynamic
+// client interface. Lists honour namespaces and label and field selectors
+// but are never paginated, and watches never deliver events.
+type snapshotStore struct {
+	objects map[schema.GroupVersionResource][]*unstructured.Unstructured
+	kinds   map[schema.GroupVersionResource]string
+}
+
+func newSnapshotStore(snapshot *Snapshot) (*snapshotStore, error) {
+	store := &snapshotStore{
+		objects: make(map[schema.GroupVersionResource][]*unstructured.Unstructured),
+		kinds:   make(map[schema.GroupVersionResource]string),
+	}
+
+	resources := make(map[schema.GroupVersionKind]schema.GroupVersionResource)
+	for _, list := range snapshot.APIResources {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		for _, apiResource := range list.APIResources {
+			gvr := gv.WithResource(apiResource.Name)
+			store.kinds[gvr] = apiResource.Kind
+			resources[gv.WithKind(apiResource.Kind)] = gvr
+		}
+	}
+
+	for _, obj := range snapshot.Objects {
+		gvr, ok := resources[obj.GroupVersionKind()]
+		if !ok {
+			return nil, fmt.Errorf("invalid snapshot: %s %s has no API resource", obj.GroupVersionKind(), obj.GetName())
+		}
+		store.objects[gvr] = append(store.objects[gvr], obj)
+	}
+	return store, nil
+}
+
+func (s *snapshotStore) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &snapshotResource{store: s, gvr: gvr}
+}
+
+type snapshotResource struct {
+	store     *snapshotStore
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r *snapshotResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &snapshotResource{store: r.store, gvr: r.gvr, namespace: namespace}
+}
+
+func (r *snapshotResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if _, ok := r.store.kinds[r.gvr]; !ok {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+	}
+	for _, obj := range r.store.objects[r.gvr] {
+		if obj.GetName() == name && obj.GetNamespace() == r.namespace {
+			return obj.DeepCopy(), nil
+		}
+	}
+	return nil, apierrors.NewNotFound(r.gvr.GroupResource(), name)
+}
+
+func (r *snapshotResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	kind, ok := r.store.kinds[r.gvr]
+	if !ok {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), "")
+	}
+
+	labelSelector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	fieldSelector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion(r.gvr.GroupVersion().String())
+	list.SetKind(kind + "List")
+	for _, obj := range r.store.objects[r.gvr] {
+		if r.namespace != "" && obj.GetNamespace() != r.namespace {
+			continue
+		}
+		if !labelSelector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		if !fieldSelector.Matches(fields.Set{"metadata.name": obj.GetName(), "metadata.namespace": obj.GetNamespace()}) {
+			continue
+		}
+		list.Items = append(list.Items, *obj.DeepCopy())
+	}
+	return list, nil
+}
+
+// Watch returns a watch that stays open without events, since a snapshot
+// never changes
+func (r *snapshotResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if _, ok := r.store.kinds[r.gvr]; !ok {
+		return nil, apierrors.NewNotFound(r.gvr.GroupResource(), "")
+	}
+	return watch.NewFake(), nil
+}
+
+func (r *snapshotResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, ErrReadOnlySource
+}
+
+func (r *snapshotResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, ErrReadOnlySource
+}
+
+func (r *snapshotResource) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return nil, ErrReadOnlySource
+}
+
+func (r *snapshotResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions, subresources ...string) error {
+	return ErrReadOnlySource
+}
+
+func (r *snapshotResource) DeleteCollection(ctx context.Context, options metav1.DeleteOptions, listOptions metav1.ListOptions) error {
+	return ErrReadOnlySource
+}
+
+func (r *snapshotResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, ErrReadOnlySource
+}
+
+func (r *snapshotResource) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, ErrReadOnlySource
+}
+
+func (r *snapshotResource) ApplyStatus(ctx context.Context, name string, obj *unstructured.Unstructured, options metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	return nil, ErrReadOnlySource
+}
@@ -0,0 +1,155 @@
+package crossplane
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/restmapper"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// ErrReadOnlySource is returned for every write to a source that is not
+// backed by an API server, i.e. a snapshot or fake
+var ErrReadOnlySource = errors.New("resource source is read-only")
+
+// ResourceSource is where a Client reads Crossplane objects from: the API
+// server of a cluster (NewLiveSource), a snapshot (NewSnapshotSource) or an
+// in-memory fake (NewFakeSource). Every Client method works the same against
+// any of them, so mock and offline runs exercise the same analysis as a
+// live cluster.
+type ResourceSource interface {
+	// Cluster is the name every resource read from the source is tagged
+	// with
+	Cluster() string
+	Kubernetes() kubernetes.Interface
+	Dynamic() dynamic.Interface
+	Discovery() discovery.CachedDiscoveryInterface
+	// ReadOnly reports whether writes are refused with ErrReadOnlySource
+	ReadOnly() bool
+}
+
+// liveSource talks to the API server of a kubeconfig context
+type liveSource struct {
+	cluster         string
+	kubeClient      kubernetes.Interface
+	dynamicClient   dynamic.Interface
+	cachedDiscovery discovery.CachedDiscoveryInterface
+}
+
+// NewLiveSource connects to the cluster of a kubeconfig context, resolved
+// with the standard loading rules and falling back to the in-cluster config.
+// Empty arguments select the default kubeconfig and its current context.
+func NewLiveSource(kubeconfig, kubeContext string) (ResourceSource, error) {
+	config, cluster, err := buildRestConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Kubernetes client
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	// Create dynamic client for CRDs
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	return &liveSource{
+		cluster:         cluster,
+		kubeClient:      kubeClient,
+		dynamicClient:   dynamicClient,
+		cachedDiscovery: memory.NewMemCacheClient(kubeClient.Discovery()),
+	}, nil
+}
+
+func (s *liveSource) Cluster() string                               { return s.cluster }
+func (s *liveSource) Kubernetes() kubernetes.Interface              { return s.kubeClient }
+func (s *liveSource) Dynamic() dynamic.Interface                    { return s.dynamicClient }
+func (s *liveSource) Discovery() discovery.CachedDiscoveryInterface { return s.cachedDiscovery }
+func (s *liveSource) ReadOnly() bool                                { return false }
+
+// snapshotSource answers from the objects of a snapshot, held in memory.
+// Fake sources are snapshots built from a set of objects.
+type snapshotSource struct {
+	cluster         string
+	kubeClient      kubernetes.Interface
+	store           *snapshotStore
+	cachedDiscovery discovery.CachedDiscoveryInterface
+}
+
+// NewSnapshotSource serves a snapshot as if it were the API server. Events,
+// pods and secrets are served by a fake clientset, every other object by a
+// read-only dynamic client.
+func NewSnapshotSource(snapshot *Snapshot) (ResourceSource, error) {
+	store, err := newSnapshotStore(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []runtime.Object
+	for i := range snapshot.Events {
+		objects = append(objects, &snapshot.Events[i])
+	}
+	for i := range snapshot.Pods {
+		objects = append(objects, &snapshot.Pods[i])
+	}
+	for i := range snapshot.Secrets {
+		objects = append(objects, &snapshot.Secrets[i])
+	}
+
+	cluster := snapshot.Cluster
+	if cluster == "" {
+		cluster = "snapshot"
+	}
+
+	return &snapshotSource{
+		cluster:    cluster,
+		kubeClient: fake.NewClientset(objects...),
+		store:      store,
+		cachedDiscovery: memory.NewMemCacheClient(&fakediscovery.FakeDiscovery{
+			Fake: &ktesting.Fake{Resources: snapshot.APIResources},
+		}),
+	}, nil
+}
+
+func (s *snapshotSource) Cluster() string                               { return s.cluster }
+func (s *snapshotSource) Kubernetes() kubernetes.Interface              { return s.kubeClient }
+func (s *snapshotSource) Dynamic() dynamic.Interface                    { return s.store }
+func (s *snapshotSource) Discovery() discovery.CachedDiscoveryInterface { return s.cachedDiscovery }
+func (s *snapshotSource) ReadOnly() bool                                { return true }
+
+// NewClientFromSource creates a client reading from source. The namespace,
+// provider, resource type and listing options apply as for a live client;
+// Context, Kubeconfig, Snapshot and Source are ignored.
+func NewClientFromSource(source ResourceSource, opts ClientOptions) *Client {
+	cachedDiscovery := source.Discovery()
+	return &Client{
+		source:          source,
+		kubeClient:      source.Kubernetes(),
+		dynamicClient:   source.Dynamic(),
+		cachedDiscovery: cachedDiscovery,
+		restMapper:      restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery),
+		cluster:         source.Cluster(),
+		namespace:       opts.Namespace,
+		providers:       opts.Providers,
+		resourceTypes:   opts.ResourceTypes,
+		listConcurrency: opts.ListConcurrency,
+		listTimeout:     opts.ListTimeout,
+		pageSize:        opts.PageSize,
+	}
+}
+
+// Source returns the source the client reads from
+func (c *Client) Source() ResourceSource {
+	return c.source
+}
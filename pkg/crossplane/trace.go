@@ -0,0 +1,343 @@
+package crossplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// maxTraceDepth guards against reference cycles and runaway nesting
+const maxTraceDepth = 10
+
+// TraceNode is a node in a claim → composite → composed resource tree
+type TraceNode struct {
+	Cluster    string `json:"cluster,omitempty"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Ready      string `json:"ready,omitempty"`
+	Synced     string `json:"synced,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Message    string `json:"message,omitempty"`
+	// Missing is set when the node is referenced but does not exist
+	Missing bool `json:"missing,omitempty"`
+	// Unlisted is set when the node is referenced but was not among the
+	// resources the tree was built from, so its state is unknown
+	Unlisted bool `json:"unlisted,omitempty"`
+	// Error is set when the node could not be read
+	Error string `json:"error,omitempty"`
+	// RootCause marks the deepest failing node of the tree
+	RootCause bool         `json:"rootCause,omitempty"`
+	Children  []*TraceNode `json:"children,omitempty"`
+
+	Resource *Resource `json:"-"`
+}
+
+// ID returns "Kind/name" for the node
+func (n *TraceNode) ID() string {
+	return fmt.Sprintf("%s/%s", n.Kind, n.Name)
+}
+
+// Failing reports whether the node is missing, unreadable, not synced or
+// not ready
+func (n *TraceNode) Failing() bool {
+	return n.Missing || n.Error != "" || n.Synced == "False" || n.Ready == "False"
+}
+
+// Walk calls fn for the node and all of its descendants, depth first
+func (n *TraceNode) Walk(fn func(node *TraceNode, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *TraceNode) walk(fn func(node *TraceNode, depth int), depth int) {
+	fn(n, depth)
+	for _, child := range n.Children {
+		child.walk(fn, depth+1)
+	}
+}
+
+// FindRootCause returns the deepest failing node of the tree, or nil if nothing
+// in the tree is failing
+func (n *TraceNode) FindRootCause() *TraceNode {
+	var cause *TraceNode
+	causeDepth := -1
+	n.Walk(func(node *TraceNode, depth int) {
+		if node.Failing() && depth > causeDepth {
+			cause = node
+			causeDepth = depth
+		}
+	})
+	return cause
+}
+
+// Trace resolves the tree below a resource: a claim's composite, and a
+// composite's composed resources, recursively
+func (c *Client) Trace(ctx context.Context, resource *Resource) (*TraceNode, error) {
+	resolve := func(ref objectRef) (*Resource, error) {
+		return c.getResource(ctx, ref)
+	}
+
+	root := buildTraceNode(resource, resolve, map[string]bool{}, 0)
+	markRootCause(root)
+	return root, nil
+}
+
+// BuildTrees links already listed resources into claim and composite trees
+// without calling the API server. Only the roots are returned: claims, and
+// composites that do not belong to a listed claim. Resources of different
+// clusters are never linked. complete reports whether resources holds
+// every resource of the cluster; if not, e.g. because a filter left some
+// out, a referenced resource that was not listed is marked Unlisted rather
+// than Missing, since it may well exist.
+func BuildTrees(resources []*Resource, complete bool) []*TraceNode {
+	index := make(map[string]*Resource)
+	for _, resource := range resources {
+		index[resourceKey(resource)] = resource
+	}
+
+	referenced := make(map[string]bool)
+	for _, resource := range resources {
+		for _, ref := range childRefs(resource) {
+			referenced[refKey(resource.Cluster, ref)] = true
+		}
+	}
+
+	var trees []*TraceNode
+	for _, resource := range resources {
+		if resource.Category != CategoryClaim && resource.Category != CategoryComposite {
+			continue
+		}
+		if referenced[resourceKey(resource)] {
+			continue
+		}
+
+		cluster := resource.Cluster
+		resolve := func(ref objectRef) (*Resource, error) {
+			if resource, ok := index[refKey(cluster, ref)]; ok {
+				return resource, nil
+			}
+			if complete {
+				return nil, nil
+			}
+			return nil, errNotListed
+		}
+
+		root := buildTraceNode(resource, resolve, map[string]bool{}, 0)
+		markRootCause(root)
+		trees = append(trees, root)
+	}
+
+	return trees
+}
+
+func resourceKey(resource *Resource) string {
+	return indexKey(resource.Cluster, resource.APIVersion, resource.Kind, resource.Namespace, resource.Name)
+}
+
+func refKey(cluster string, ref objectRef) string {
+	return indexKey(cluster, ref.APIVersion, ref.Kind, ref.Namespace, ref.Name)
+}
+
+// errNotListed is returned by the resolve function of BuildTrees for
+// resources that were not listed
+var errNotListed = errors.New("not listed")
+
+// buildTraceNode creates the node for a resource and resolves its children.
+// resolve returns nil without error when a referenced object does not exist.
+func buildTraceNode(resource *Resource, resolve func(objectRef) (*Resource, error), visited map[string]bool, depth int) *TraceNode {
+	node := newTraceNode(resource)
+
+	key := resourceKey(resource)
+	if visited[key] || depth >= maxTraceDepth {
+		return node
+	}
+	visited[key] = true
+
+	for _, ref := range childRefs(resource) {
+		child, err := resolve(ref)
+		switch {
+		case errors.Is(err, errNotListed):
+			node.Children = append(node.Children, &TraceNode{
+				APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace,
+				Unlisted: true,
+			})
+		case err != nil:
+			node.Children = append(node.Children, &TraceNode{
+				APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace,
+				Error: err.Error(),
+			})
+		case child == nil:
+			node.Children = append(node.Children, &TraceNode{
+				APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, Namespace: ref.Namespace,
+				Missing: true,
+				Message: "referenced resource does not exist",
+			})
+		default:
+			node.Children = append(node.Children, buildTraceNode(child, resolve, visited, depth+1))
+		}
+	}
+
+	return node
+}
+
+func newTraceNode(resource *Resource) *TraceNode {
+	node := &TraceNode{
+		Cluster:    resource.Cluster,
+		APIVersion: resource.APIVersion,
+		Kind:       resource.Kind,
+		Name:       resource.Name,
+		Namespace:  resource.Namespace,
+		Synced:     resource.Synced,
+		Resource:   resource,
+	}
+
+	ready := resource.GetCondition(ConditionReady)
+	if ready != nil {
+		node.Ready = ready.Status
+	}
+
+	if failing := resource.FailingCondition(); failing != nil {
+		node.Reason, node.Message = failing.Reason, failing.Message
+	} else if ready != nil {
+		node.Reason = ready.Reason
+	}
+
+	return node
+}
+
+func markRootCause(root *TraceNode) {
+	if cause := root.FindRootCause(); cause != nil {
+		cause.RootCause = true
+	}
+}
+
+// childRefs returns the references from a claim to its composite, or from a
+// composite to its composed resources
+func childRefs(resource *Resource) []objectRef {
+	if resource.Raw == nil {
+		return nil
+	}
+	obj := resource.Raw.Object
+
+	var refs []objectRef
+
+	// Claim: spec.resourceRef points at the composite
+	if ref, found, _ := unstructured.NestedMap(obj, "spec", "resourceRef"); found {
+		if r, ok := parseRef(ref); ok {
+			refs = append(refs, r)
+		}
+	}
+
+	// Composite: spec.resourceRefs (Crossplane v1) or
+	// spec.crossplane.resourceRefs (Crossplane v2) list composed resources
+	for _, path := range [][]string{{"spec", "resourceRefs"}, {"spec", "crossplane", "resourceRefs"}} {
+		items, found, _ := unstructured.NestedSlice(obj, path...)
+		if !found {
+			continue
+		}
+		for _, item := range items {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if r, ok := parseRef(m); ok {
+				refs = append(refs, r)
+			}
+		}
+	}
+
+	// Composed resources of a namespaced composite live in its namespace
+	// unless the reference says otherwise
+	for i := range refs {
+		if refs[i].Namespace == "" && resource.Category != CategoryClaim {
+			refs[i].Namespace = resource.Namespace
+		}
+	}
+
+	return refs
+}
+
+func parseRef(m map[string]interface{}) (objectRef, bool) {
+	ref := objectRef{}
+	ref.APIVersion, _, _ = unstructured.NestedString(m, "apiVersion")
+	ref.Kind, _, _ = unstructured.NestedString(m, "kind")
+	ref.Name, _, _ = unstructured.NestedString(m, "name")
+	ref.Namespace, _, _ = unstructured.NestedString(m, "namespace")
+	return ref, ref.Kind != "" && ref.Name != ""
+}
+
+// indexKey identifies an object by cluster, group, kind, namespace and
+// name. The version is left out because references may use a different
+// version than the one the object was listed with.
+func indexKey(cluster, apiVersion, kind, namespace, name string) string {
+	group := ""
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		group = apiVersion[:i]
+	}
+	return strings.Join([]string{cluster, group, kind, namespace, name}, "|")
+}
+
+// getResource reads a single object by reference. It returns nil without
+// error when the object does not exist.
+func (c *Client) getResource(ctx context.Context, ref objectRef) (*Resource, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apiVersion %q: %w", ref.APIVersion, err)
+	}
+
+	t, err := c.typeForKind(ctx, gv.WithKind(ref.Kind))
+	if err != nil {
+		return nil, err
+	}
+
+	var obj *unstructured.Unstructured
+	if t.Namespaced {
+		obj, err = c.dynamicClient.Resource(t.GVR).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	} else {
+		obj, err = c.dynamicClient.Resource(t.GVR).Get(ctx, ref.Name, metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resource := c.convertToResource(obj, t)
+	return resource, nil
+}
+
+// typeForKind maps a group/version/kind to its resource type, using the
+// discovered Crossplane types first and the REST mapper for anything else,
+// including versions other than the preferred one
+func (c *Client) typeForKind(ctx context.Context, gvk schema.GroupVersionKind) (DiscoveredType, error) {
+	if types, err := c.DiscoverResourceTypes(ctx); err == nil {
+		for _, t := range types {
+			if t.GVR.Group == gvk.Group && t.GVR.Version == gvk.Version && t.Kind == gvk.Kind {
+				return t, nil
+			}
+		}
+	}
+
+	if c.restMapper == nil {
+		return DiscoveredType{}, fmt.Errorf("no resource type known for %s", gvk)
+	}
+
+	mapping, err := c.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return DiscoveredType{}, fmt.Errorf("failed to map %s to a resource: %w", gvk, err)
+	}
+
+	return DiscoveredType{
+		GVR:        mapping.Resource,
+		Kind:       gvk.Kind,
+		Namespaced: mapping.Scope.Name() == "namespace",
+	}, nil
+}
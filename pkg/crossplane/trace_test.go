@@ -0,0 +1,296 @@
+package crossplane
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// testResource converts a YAML object to a resource of the given category
+// the way listing it would
+func testResource(t *testing.T, category Category, manifest string) *Resource {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj.Object); err != nil {
+		t.Fatalf("invalid test object: %v", err)
+	}
+	gvr := obj.GroupVersionKind().GroupVersion().WithResource(strings.ToLower(obj.GetKind()) + "s")
+	return (&Client{}).convertToResource(obj, DiscoveredType{
+		GVR:        gvr,
+		Kind:       obj.GetKind(),
+		Namespaced: obj.GetNamespace() != "",
+		Category:   category,
+	})
+}
+
+// treeLines renders a tree one node per line, indented by depth and
+// followed by the flags the node carries
+func treeLines(root *TraceNode) []string {
+	var lines []string
+	root.Walk(func(node *TraceNode, depth int) {
+		line := strings.Repeat("  ", depth) + node.ID()
+		if node.Missing {
+			line += " missing"
+		}
+		if node.Unlisted {
+			line += " unlisted"
+		}
+		if node.RootCause {
+			line += " (root cause)"
+		}
+		lines = append(lines, line)
+	})
+	return lines
+}
+
+const (
+	traceClaim = `apiVersion: database.example.org/v1alpha1
+kind: PostgreSQLInstance
+metadata:
+  name: orders-db
+  namespace: team-a
+spec:
+  resourceRef:
+    apiVersion: database.example.org/v1alpha1
+    kind: XPostgreSQLInstance
+    name: orders-db-x7k2p
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+    reason: Creating
+`
+	traceComposite = `apiVersion: database.example.org/v1alpha1
+kind: XPostgreSQLInstance
+metadata:
+  name: orders-db-x7k2p
+spec:
+  resourceRefs:
+  - apiVersion: rds.aws.upbound.io/v1beta1
+    kind: Instance
+    name: orders-db-x7k2p-rds
+  - apiVersion: rds.aws.upbound.io/v1beta1
+    kind: SubnetGroup
+    name: orders-db-x7k2p-sng
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+    reason: Creating
+    message: "Unready resources: rds-instance, subnet-group"
+`
+	traceInstance = `apiVersion: rds.aws.upbound.io/v1beta1
+kind: Instance
+metadata:
+  name: orders-db-x7k2p-rds
+status:
+  conditions:
+  - type: Ready
+    status: "True"
+    reason: Available
+  - type: Synced
+    status: "True"
+    reason: ReconcileSuccess
+`
+	traceSubnetGroup = `apiVersion: rds.aws.upbound.io/v1beta1
+kind: SubnetGroup
+metadata:
+  name: orders-db-x7k2p-sng
+status:
+  conditions:
+  - type: Synced
+    status: "False"
+    reason: ReconcileError
+    message: "cannot get credentials secret"
+`
+	// traceNamespacedComposite is a Crossplane v2 composite whose
+	// composed resources live in its namespace
+	traceNamespacedComposite = `apiVersion: storage.example.org/v1alpha1
+kind: XObjectStore
+metadata:
+  name: receipts
+  namespace: team-a
+spec:
+  crossplane:
+    resourceRefs:
+    - apiVersion: s3.aws.m.upbound.io/v1beta1
+      kind: Bucket
+      name: receipts-bkt
+status:
+  conditions:
+  - type: Ready
+    status: "True"
+`
+	traceNamespacedBucket = `apiVersion: s3.aws.m.upbound.io/v1beta1
+kind: Bucket
+metadata:
+  name: receipts-bkt
+  namespace: team-a
+status:
+  conditions:
+  - type: Ready
+    status: "True"
+  - type: Synced
+    status: "True"
+`
+)
+
+func TestBuildTrees(t *testing.T) {
+	claim := testResource(t, CategoryClaim, traceClaim)
+	composite := testResource(t, CategoryComposite, traceComposite)
+	instance := testResource(t, CategoryManaged, traceInstance)
+	subnetGroup := testResource(t, CategoryManaged, traceSubnetGroup)
+	namespacedComposite := testResource(t, CategoryComposite, traceNamespacedComposite)
+	namespacedBucket := testResource(t, CategoryManaged, traceNamespacedBucket)
+
+	otherCluster := testResource(t, CategoryComposite, traceComposite)
+	otherCluster.Cluster = "staging"
+
+	tests := []struct {
+		name      string
+		resources []*Resource
+		complete  bool
+		want      [][]string
+	}{
+		{
+			name:      "claim tree",
+			resources: []*Resource{instance, composite, claim, subnetGroup},
+			complete:  true,
+			want: [][]string{{
+				"PostgreSQLInstance/orders-db",
+				"  XPostgreSQLInstance/orders-db-x7k2p",
+				"    Instance/orders-db-x7k2p-rds",
+				"    SubnetGroup/orders-db-x7k2p-sng (root cause)",
+			}},
+		},
+		{
+			name:      "missing composed resource",
+			resources: []*Resource{claim, composite, instance},
+			complete:  true,
+			want: [][]string{{
+				"PostgreSQLInstance/orders-db",
+				"  XPostgreSQLInstance/orders-db-x7k2p",
+				"    Instance/orders-db-x7k2p-rds",
+				"    SubnetGroup/orders-db-x7k2p-sng missing (root cause)",
+			}},
+		},
+		{
+			name:      "resources left out by a filter",
+			resources: []*Resource{claim, composite},
+			want: [][]string{{
+				"PostgreSQLInstance/orders-db",
+				"  XPostgreSQLInstance/orders-db-x7k2p (root cause)",
+				"    Instance/orders-db-x7k2p-rds unlisted",
+				"    SubnetGroup/orders-db-x7k2p-sng unlisted",
+			}},
+		},
+		{
+			name:      "composite without claim",
+			resources: []*Resource{composite, instance, subnetGroup},
+			complete:  true,
+			want: [][]string{{
+				"XPostgreSQLInstance/orders-db-x7k2p",
+				"  Instance/orders-db-x7k2p-rds",
+				"  SubnetGroup/orders-db-x7k2p-sng (root cause)",
+			}},
+		},
+		{
+			name:      "namespaced composite",
+			resources: []*Resource{namespacedBucket, namespacedComposite},
+			complete:  true,
+			want: [][]string{{
+				"XObjectStore/receipts",
+				"  Bucket/receipts-bkt",
+			}},
+		},
+		{
+			name:      "clusters are not linked",
+			resources: []*Resource{claim, otherCluster},
+			complete:  true,
+			want: [][]string{
+				{
+					"PostgreSQLInstance/orders-db",
+					"  XPostgreSQLInstance/orders-db-x7k2p missing (root cause)",
+				},
+				{
+					"XPostgreSQLInstance/orders-db-x7k2p",
+					"  Instance/orders-db-x7k2p-rds missing (root cause)",
+					"  SubnetGroup/orders-db-x7k2p-sng missing",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, tree := range BuildTrees(tt.resources, tt.complete) {
+				got = append(got, treeLines(tree))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildTrees =\n%s\nwant\n%s", joinTrees(got), joinTrees(tt.want))
+			}
+		})
+	}
+}
+
+func joinTrees(trees [][]string) string {
+	var parts []string
+	for _, tree := range trees {
+		parts = append(parts, strings.Join(tree, "\n"))
+	}
+	return strings.Join(parts, "\n--\n")
+}
+
+func TestFindRootCause(t *testing.T) {
+	tests := []struct {
+		name string
+		tree *TraceNode
+		want string
+	}{
+		{
+			name: "healthy",
+			tree: &TraceNode{Kind: "XBucket", Name: "a", Ready: "True", Children: []*TraceNode{
+				{Kind: "Bucket", Name: "b", Ready: "True", Synced: "True"},
+			}},
+		},
+		{
+			name: "deepest failing node",
+			tree: &TraceNode{Kind: "Claim", Name: "a", Ready: "False", Children: []*TraceNode{
+				{Kind: "XR", Name: "b", Ready: "False", Children: []*TraceNode{
+					{Kind: "Bucket", Name: "c", Ready: "True", Synced: "True"},
+					{Kind: "Policy", Name: "d", Synced: "False"},
+				}},
+			}},
+			want: "Policy/d",
+		},
+		{
+			name: "first of equally deep nodes",
+			tree: &TraceNode{Kind: "XR", Name: "a", Children: []*TraceNode{
+				{Kind: "Bucket", Name: "b", Error: "forbidden"},
+				{Kind: "Policy", Name: "c", Missing: true},
+			}},
+			want: "Bucket/b",
+		},
+		{
+			name: "unlisted nodes are not failing",
+			tree: &TraceNode{Kind: "XR", Name: "a", Ready: "False", Children: []*TraceNode{
+				{Kind: "Bucket", Name: "b", Unlisted: true},
+			}},
+			want: "XR/a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if cause := tt.tree.FindRootCause(); cause != nil {
+				got = cause.ID()
+			}
+			if got != tt.want {
+				t.Errorf("FindRootCause = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
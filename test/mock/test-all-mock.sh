@@ -87,6 +87,27 @@ echo "--------------------------------"
 ./run-mock.sh --binary "$CLI_BINARY" suggest network
 echo
 
+# Test 12: Failure scenarios of the embedded mock cluster
+for scenario in healthy credential-failure stuck-deletion composition-error provider-crash drift; do
+    echo "🧨 Test 12: Scenario $scenario"
+    echo "--------------------------------"
+    "$CLI_BINARY" --scenario "$scenario" analyze
+    echo
+done
+
+# Test 13: Stuck deletion diagnosis
+echo "🧹 Test 13: Stuck Deletions"
+echo "---------------------------"
+"$CLI_BINARY" --scenario stuck-deletion unstick
+"$CLI_BINARY" --scenario stuck-deletion unstick instance/payments-db-7xk2p-rds --dry-run
+echo
+
+# Test 14: Drift between desired and observed state
+echo "🔀 Test 14: Drift"
+echo "-----------------"
+"$CLI_BINARY" --scenario drift drift
+echo
+
 echo "🎉 All mock tests completed successfully!"
 echo
 echo "Summary:"